@@ -111,6 +111,12 @@ func main() {
 		authCmd()
 	case "cron":
 		cronCmd()
+	case "usage":
+		usageCmd()
+	case "mcp":
+		mcpCmd()
+	case "policy":
+		policyCmd()
 	case "setup-oracle":
 		setupOracleCmd()
 	case "oracle-inspect":
@@ -186,6 +192,9 @@ func printHelp() {
 	fmt.Println("  gateway        Start picooraclaw gateway")
 	fmt.Println("  status         Show picooraclaw status")
 	fmt.Println("  cron           Manage scheduled tasks")
+	fmt.Println("  usage          Report estimated LLM spend")
+	fmt.Println("  mcp            Serve memory and tools over MCP")
+	fmt.Println("  policy         Check commands against the exec policy")
 	fmt.Println("  migrate        Migrate from OpenClaw/PicoClaw")
 	fmt.Println("  skills         Manage skills (install, list, remove)")
 	fmt.Println("  setup-oracle   Initialize Oracle Database schema and ONNX model")
@@ -389,13 +398,15 @@ func agentCmd() {
 	var oracleConn *oracledb.ConnectionManager
 
 	if cfg.Oracle.Enabled {
-		agentLoop, oracleConn, _, err = initOracleAgent(cfg, msgBus, provider)
+		var oraStores *oracleStores
+		agentLoop, oracleConn, oraStores, err = initOracleAgent(cfg, msgBus, provider)
 		if err != nil {
 			fmt.Printf("Oracle initialization failed: %v\n", err)
 			fmt.Println("Falling back to file-based storage...")
 			agentLoop = agent.NewAgentLoop(cfg, msgBus, provider)
 		} else {
 			defer oracleConn.Close()
+			defer oraStores.Close()
 			fmt.Println("✓ Oracle AI Database storage enabled")
 		}
 	} else {
@@ -554,6 +565,7 @@ func gatewayCmd() {
 			agentLoop = agent.NewAgentLoop(cfg, msgBus, provider)
 		} else {
 			defer oracleConn.Close()
+			defer oraStores.Close()
 			fmt.Println("✓ Oracle AI Database storage enabled")
 		}
 	} else {
@@ -579,7 +591,12 @@ func gatewayCmd() {
 		})
 
 	// Setup cron tool and service
-	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath(), cfg.Tools.Cron.ExecTimeoutMinutes)
+	execPolicy, err := agent.NewExecPolicy(cfg, cfg.WorkspacePath())
+	if err != nil {
+		fmt.Printf("Error in exec policy: %v\n", err)
+		os.Exit(1)
+	}
+	cronService := setupCronTool(agentLoop, msgBus, cfg.WorkspacePath(), cfg.Tools.Cron.ExecTimeoutMinutes, agent.NewExecSandbox(cfg, cfg.WorkspacePath()), execPolicy)
 
 	heartbeatService := heartbeat.NewHeartbeatService(
 		cfg.WorkspacePath(),
@@ -593,11 +610,12 @@ func gatewayCmd() {
 			channel, chatID = "cli", "direct"
 		}
 		// Use ProcessHeartbeat - no session history, each heartbeat is independent
-		response, err := agentLoop.ProcessHeartbeat(context.Background(), prompt, channel, chatID)
+		ctx := context.Background()
+		response, err := agentLoop.ProcessHeartbeat(ctx, prompt, channel, chatID)
 		if err != nil {
 			return tools.ErrorResult(fmt.Sprintf("Heartbeat error: %v", err))
 		}
-		if response == "HEARTBEAT_OK" {
+		if agentLoop.HeartbeatOK(ctx, response) {
 			return tools.SilentResult("Heartbeat OK")
 		}
 		// For heartbeat, always return silent - the subagent result will be
@@ -1037,7 +1055,7 @@ func getConfigPath() string {
 	return filepath.Join(home, ".picooraclaw", "config.json")
 }
 
-func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string, execTimeoutMinutes int) *cron.CronService {
+func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace string, execTimeoutMinutes int, sandbox *tools.Sandbox, policy *tools.ExecPolicySet) *cron.CronService {
 	cronStorePath := filepath.Join(workspace, "cron", "jobs.json")
 
 	// Create cron service
@@ -1048,6 +1066,12 @@ func setupCronTool(agentLoop *agent.AgentLoop, msgBus *bus.MessageBus, workspace
 
 	// Create and register CronTool
 	cronTool := tools.NewCronTool(cronService, agentLoop, msgBus, workspace, execTimeout)
+	if sandbox != nil {
+		cronTool.SetExecSandbox(sandbox)
+	}
+	if policy != nil {
+		cronTool.SetExecPolicy(policy)
+	}
 	agentLoop.RegisterTool(cronTool)
 
 	// Set the onJob handler
@@ -1601,6 +1625,15 @@ func setupOracleCmd() {
 type oracleStores struct {
 	session *oracledb.SessionStore
 	memory  *oracledb.MemoryStore
+	prompts *oracledb.PromptStore
+	sqlConn *oracledb.ConnectionManager // read-only sql_query connection, if enabled
+}
+
+// Close releases connections owned by the stores besides the main pool.
+func (s *oracleStores) Close() {
+	if s != nil && s.sqlConn != nil {
+		s.sqlConn.Close()
+	}
 }
 
 // initOracleAgent creates an agent loop with Oracle-backed stores.
@@ -1647,8 +1680,109 @@ func initOracleAgent(cfg *config.Config, msgBus *bus.MessageBus, provider provid
 	promptStore := oracledb.NewPromptStore(db, agentID)
 	agentLoop.SetPromptStore(promptStore)
 
+	stores := &oracleStores{session: sessionStore, memory: memoryStore, prompts: promptStore}
+	if cfg.Tools.SQL.Enabled {
+		sqlConn, err := openSQLToolConn(cfg)
+		if err != nil {
+			logger.ErrorCF("agent", "sql_query disabled: invalid tools.sql config", map[string]interface{}{"error": err.Error()})
+		} else {
+			stores.sqlConn = sqlConn
+			querier := oracledb.NewReadOnlyQuerier(sqlConn.DB(), cfg.Tools.SQL.MaxRows,
+				time.Duration(cfg.Tools.SQL.TimeoutSeconds)*time.Second, cfg.Tools.SQL.Schemas)
+			agentLoop.RegisterTool(tools.NewSQLQueryTool(&sqlAdapter{q: querier}))
+		}
+	}
+
+	// Give the sensor tool somewhere to log readings for time-series queries
+	if t, ok := agentLoop.Tools().Get("sensor"); ok {
+		if st, ok := t.(*tools.SensorTool); ok {
+			st.SetLogger(&sensorAdapter{store: oracledb.NewSensorStore(db, agentID)})
+		}
+	}
+
 	logger.InfoC("oracle", "Oracle stores initialized")
-	return agentLoop, conn, &oracleStores{session: sessionStore, memory: memoryStore}, nil
+	return agentLoop, conn, stores, nil
+}
+
+// openSQLToolConn opens the sql_query connection: the oracle settings with
+// the read-only user from tools.sql.
+func openSQLToolConn(cfg *config.Config) (*oracledb.ConnectionManager, error) {
+	sqlCfg := cfg.Tools.SQL
+	if sqlCfg.User == "" {
+		return nil, fmt.Errorf("tools.sql.user is required")
+	}
+	if strings.EqualFold(sqlCfg.User, cfg.Oracle.User) {
+		return nil, fmt.Errorf("tools.sql.user must be a separate read-only user, not the agent's own user %s", cfg.Oracle.User)
+	}
+	oraCfg := cfg.Oracle
+	oraCfg.User = sqlCfg.User
+	oraCfg.Password = sqlCfg.Password
+	oraCfg.DSN = sqlCfg.DSN
+	oraCfg.PoolMaxOpen = 2
+	oraCfg.PoolMaxIdle = 1
+	return oracledb.NewConnectionManager(&oraCfg)
+}
+
+// sqlAdapter adapts oracle.ReadOnlyQuerier to the tools.SQLQuerier interface.
+type sqlAdapter struct {
+	q *oracledb.ReadOnlyQuerier
+}
+
+func (a *sqlAdapter) Query(ctx context.Context, stmt string, binds interface{}, maxRows int) (*tools.SQLResult, error) {
+	res, err := a.q.Query(ctx, stmt, binds, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	return &tools.SQLResult{Columns: res.Columns, Rows: res.Rows, Truncated: res.Truncated}, nil
+}
+
+func (a *sqlAdapter) ListTables(ctx context.Context, schema string) (*tools.SQLResult, error) {
+	res, err := a.q.ListTables(ctx, schema)
+	if err != nil {
+		return nil, err
+	}
+	return &tools.SQLResult{Columns: res.Columns, Rows: res.Rows, Truncated: res.Truncated}, nil
+}
+
+func (a *sqlAdapter) DescribeTable(ctx context.Context, name string) (*tools.SQLTable, error) {
+	info, err := a.q.DescribeTable(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	table := &tools.SQLTable{Owner: info.Owner, Name: info.Name, Kind: info.Kind, Comment: info.Comment, PrimaryKey: info.PrimaryKey}
+	for _, c := range info.Columns {
+		table.Columns = append(table.Columns, tools.SQLColumn{Name: c.Name, Type: c.Type, Nullable: c.Nullable, Comment: c.Comment})
+	}
+	return table, nil
+}
+
+func (a *sqlAdapter) Schemas() []string { return a.q.Schemas() }
+
+// sensorAdapter adapts oracle.SensorStore to the tools.SensorLogger interface.
+type sensorAdapter struct {
+	store *oracledb.SensorStore
+}
+
+func (a *sensorAdapter) LogReadings(ctx context.Context, samples []tools.SensorSample) error {
+	readings := make([]oracledb.SensorReading, len(samples))
+	for i, s := range samples {
+		readings[i] = oracledb.SensorReading{Sensor: s.Sensor, Bus: s.Bus, Address: s.Address,
+			Quantity: s.Quantity, Value: s.Value, Unit: s.Unit, ReadAt: s.Time}
+	}
+	return a.store.Log(ctx, readings)
+}
+
+func (a *sensorAdapter) History(ctx context.Context, sensor, quantity string, since time.Time, limit int) ([]tools.SensorSample, error) {
+	readings, err := a.store.History(ctx, sensor, quantity, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]tools.SensorSample, len(readings))
+	for i, r := range readings {
+		samples[i] = tools.SensorSample{Sensor: r.Sensor, Bus: r.Bus, Address: r.Address,
+			Quantity: r.Quantity, Value: r.Value, Unit: r.Unit, Time: r.ReadAt}
+	}
+	return samples, nil
 }
 
 // recallAdapter adapts oracle.MemoryStore to tools.Recaller interface.
@@ -1802,6 +1936,7 @@ func inspectOverview(db *sql.DB, agentID string) {
 		{"PICO_PROMPTS", "Prompts"},
 		{"PICO_CONFIG", "Config"},
 		{"PICO_META", "Meta"},
+		{"PICO_SENSOR_READINGS", "Sensor Readings"},
 	}
 
 	fmt.Println("  Table                  Rows")
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jasperan/picooraclaw/pkg/agent"
+	"github.com/jasperan/picooraclaw/pkg/bus"
+	"github.com/jasperan/picooraclaw/pkg/logger"
+	"github.com/jasperan/picooraclaw/pkg/mcp"
+	"github.com/jasperan/picooraclaw/pkg/providers"
+	"github.com/jasperan/picooraclaw/pkg/tools"
+)
+
+const defaultMCPListen = "127.0.0.1:18795"
+
+// mcpMemoryTools are always exposed when the agent has them.
+var mcpMemoryTools = []string{"remember", "recall", "write_daily_note"}
+
+func mcpCmd() {
+	if len(os.Args) < 3 {
+		mcpHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "serve":
+		mcpServeCmd()
+	default:
+		fmt.Printf("Unknown mcp command: %s\n", os.Args[2])
+		mcpHelp()
+	}
+}
+
+func mcpHelp() {
+	fmt.Println("\nMCP commands:")
+	fmt.Println("  serve            Serve memory, tools, resources and prompts over MCP")
+	fmt.Println()
+	fmt.Println("Serve options:")
+	fmt.Println("  --http [addr]    Serve streamable HTTP instead of stdio (default " + defaultMCPListen + ")")
+}
+
+func mcpServeCmd() {
+	useHTTP := false
+	listen := ""
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--http":
+			useHTTP = true
+			if i+1 < len(args) && args[i+1][0] != '-' {
+				listen = args[i+1]
+				i++
+			}
+		case "--debug", "-d":
+			logger.SetLevel(logger.DEBUG)
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n", args[i])
+			mcpHelp()
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	serveCfg := cfg.Tools.MCP.Serve
+	if listen == "" {
+		listen = serveCfg.Listen
+	}
+	if listen == "" {
+		listen = defaultMCPListen
+	}
+
+	// Tools such as spawn need a provider; the server works without one as
+	// long as such tools are not exposed.
+	provider, err := providers.CreateProvider(cfg)
+	if err != nil {
+		logger.WarnCF("mcp", "No LLM provider; tools that need one will fail", map[string]interface{}{"error": err.Error()})
+	}
+
+	msgBus := bus.NewMessageBus()
+	var agentLoop *agent.AgentLoop
+	var prompts mcp.PromptSource = mcp.WorkspacePrompts{Workspace: cfg.WorkspacePath()}
+	if cfg.Oracle.Enabled {
+		loop, conn, stores, err := initOracleAgent(cfg, msgBus, provider)
+		if err != nil {
+			logger.WarnCF("mcp", "Oracle initialization failed, using file-based memory", map[string]interface{}{"error": err.Error()})
+		} else {
+			defer conn.Close()
+			defer stores.Close()
+			agentLoop = loop
+			prompts = stores.prompts
+		}
+	}
+	if agentLoop == nil {
+		agentLoop = agent.NewAgentLoop(cfg, msgBus, provider)
+	}
+	defer agentLoop.Stop()
+
+	exposed := tools.NewToolRegistry()
+	for _, name := range append(append([]string{}, mcpMemoryTools...), serveCfg.Tools...) {
+		if tool, ok := agentLoop.Tools().Get(name); ok {
+			exposed.Register(tool)
+		}
+	}
+
+	server := mcp.NewServer(mcp.ServerOptions{
+		Name:        "picooraclaw",
+		Version:     version,
+		Tools:       exposed,
+		Resources:   mcp.NewWorkspaceResources(cfg.WorkspacePath(), agentLoop.MemoryStore(), serveCfg.Resources),
+		Prompts:     prompts,
+		PromptAllow: serveCfg.Prompts,
+		AuthToken:   serveCfg.AuthToken,
+	})
+
+	logger.InfoCF("mcp", "MCP server starting", map[string]interface{}{"tools": exposed.List(), "http": useHTTP})
+
+	if !useHTTP {
+		// stdout carries the protocol; everything else goes to stderr. The
+		// session ends when the client closes stdin.
+		if err := server.ServeStdio(context.Background(), os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "MCP server error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if serveCfg.AuthToken == "" {
+		logger.WarnCF("mcp", "Serving MCP over HTTP without auth_token", map[string]interface{}{"listen": listen})
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	httpServer := &http.Server{Addr: listen, Handler: server}
+	go func() {
+		<-ctx.Done()
+		httpServer.Close()
+	}()
+	fmt.Fprintf(os.Stderr, "✓ MCP server listening on http://%s\n", listen)
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintf(os.Stderr, "MCP server error: %v\n", err)
+		os.Exit(1)
+	}
+}
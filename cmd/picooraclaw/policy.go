@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jasperan/picooraclaw/pkg/agent"
+	"github.com/jasperan/picooraclaw/pkg/tools"
+)
+
+func policyCmd() {
+	if len(os.Args) < 3 {
+		policyHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "check":
+		policyCheckCmd()
+	default:
+		fmt.Printf("Unknown policy command: %s\n", os.Args[2])
+		policyHelp()
+	}
+}
+
+func policyHelp() {
+	fmt.Println("\nPolicy commands:")
+	fmt.Println("  check \"<cmd>\"    Show whether the exec tool would run a command, and which rule decides")
+	fmt.Println()
+	fmt.Println("Check options:")
+	fmt.Println("  --agent          Agent to check as: main, subagent or cron (default main)")
+	fmt.Println("  --channel        Channel the command comes from (e.g. telegram)")
+	fmt.Println("  --chat           Chat ID the command comes from")
+	fmt.Println("  --sender         Sender ID the command comes from")
+	fmt.Println("  --dir            Working directory (default workspace)")
+}
+
+func policyCheckCmd() {
+	caller := tools.ExecCaller{Agent: "main"}
+	dir := ""
+	var command []string
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		switch flag {
+		case "--agent", "--channel", "--chat", "--sender", "--dir":
+			if i+1 >= len(args) {
+				fmt.Printf("Missing value for %s\n", flag)
+				os.Exit(1)
+			}
+			i++
+			switch flag {
+			case "--agent":
+				caller.Agent = args[i]
+			case "--channel":
+				caller.Channel = args[i]
+			case "--chat":
+				caller.ChatID = args[i]
+			case "--sender":
+				caller.Sender = args[i]
+			case "--dir":
+				dir = args[i]
+			}
+		default:
+			command = append(command, flag)
+		}
+	}
+	if len(command) == 0 {
+		policyHelp()
+		os.Exit(1)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	set, err := agent.NewExecPolicy(cfg, cfg.WorkspacePath())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if dir == "" {
+		dir = cfg.WorkspacePath()
+	}
+
+	cmd := strings.Join(command, " ")
+	policy := set.Resolve(caller)
+	decision := policy.Check(cmd)
+	if decision.Allowed {
+		if reason := policy.CheckDir(dir); reason != "" {
+			decision = tools.ExecDecision{Source: "exec policy", Reason: reason}
+		}
+	}
+
+	fmt.Printf("Command:  %s\n", cmd)
+	fmt.Printf("Caller:   %s\n", describeCaller(caller))
+	fmt.Printf("Layers:   %s\n", strings.Join(policy.Layers(), ", "))
+	if decision.Allowed {
+		rule := decision.Rule
+		if rule == "" {
+			rule = "no allow list"
+		}
+		fmt.Printf("Result:   allowed (%s)\n", rule)
+	} else {
+		fmt.Printf("Result:   blocked by %s: %s\n", decision.Source, decision.Reason)
+		if decision.Rule != "" {
+			fmt.Printf("Rule:     %s\n", decision.Rule)
+		}
+	}
+	if policy.Timeout > 0 {
+		fmt.Printf("Timeout:  %s\n", policy.Timeout)
+	}
+	if env := policy.Environ(os.Environ()); env != nil {
+		fmt.Printf("Env:      %d of %d variables passed\n", len(env), len(os.Environ()))
+	}
+
+	if !decision.Allowed {
+		os.Exit(1)
+	}
+}
+
+func describeCaller(c tools.ExecCaller) string {
+	var parts []string
+	for _, kv := range [][2]string{{"agent", c.Agent}, {"channel", c.Channel}, {"chat_id", c.ChatID}, {"sender", c.Sender}} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/usage"
+)
+
+func usageCmd() {
+	if len(os.Args) < 3 {
+		usageHelp()
+		return
+	}
+
+	switch os.Args[2] {
+	case "report":
+		usageReportCmd()
+	default:
+		fmt.Printf("Unknown usage command: %s\n", os.Args[2])
+		usageHelp()
+	}
+}
+
+func usageHelp() {
+	fmt.Println("\nUsage commands:")
+	fmt.Println("  report           Show estimated LLM spend")
+	fmt.Println()
+	fmt.Println("Report options:")
+	fmt.Println("  --since          Window to report on: 7d, 24h or 2006-01-02 (default 7d)")
+	fmt.Println("  --by             Group by model, channel or sender (default model)")
+	fmt.Println("  --format         table or csv (default table)")
+}
+
+func usageReportCmd() {
+	since := "7d"
+	by := "model"
+	format := "table"
+
+	args := os.Args[3:]
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--since":
+			if i+1 < len(args) {
+				since = args[i+1]
+				i++
+			}
+		case "--by":
+			if i+1 < len(args) {
+				by = args[i+1]
+				i++
+			}
+		case "--format":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case "--csv":
+			format = "csv"
+		default:
+			fmt.Printf("Unknown option: %s\n", args[i])
+			usageHelp()
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	start, err := usage.ParseSince(since, time.Now())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	records, err := usage.NewStore(cfg.WorkspacePath()).Load(start)
+	if err != nil {
+		fmt.Printf("Error reading usage: %v\n", err)
+		os.Exit(1)
+	}
+	rows, err := usage.Summarize(records, by)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "csv":
+		err = usage.WriteCSV(os.Stdout, rows, by)
+	case "table":
+		if len(rows) == 0 {
+			fmt.Printf("No usage recorded since %s.\n", start.Format("2006-01-02 15:04"))
+			return
+		}
+		fmt.Printf("Estimated spend since %s:\n\n", start.Format("2006-01-02 15:04"))
+		err = usage.WriteTable(os.Stdout, rows, by)
+	default:
+		fmt.Printf("Unknown format: %s (use table or csv)\n", format)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error writing report: %v\n", err)
+		os.Exit(1)
+	}
+}
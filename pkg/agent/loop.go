@@ -8,10 +8,13 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,10 +25,13 @@ import (
 	"github.com/jasperan/picooraclaw/pkg/config"
 	"github.com/jasperan/picooraclaw/pkg/constants"
 	"github.com/jasperan/picooraclaw/pkg/logger"
+	"github.com/jasperan/picooraclaw/pkg/mcp"
+	"github.com/jasperan/picooraclaw/pkg/models"
 	"github.com/jasperan/picooraclaw/pkg/providers"
 	"github.com/jasperan/picooraclaw/pkg/session"
 	"github.com/jasperan/picooraclaw/pkg/state"
 	"github.com/jasperan/picooraclaw/pkg/tools"
+	"github.com/jasperan/picooraclaw/pkg/usage"
 	"github.com/jasperan/picooraclaw/pkg/utils"
 )
 
@@ -34,7 +40,7 @@ type AgentLoop struct {
 	provider                  providers.LLMProvider
 	workspace                 string
 	model                     string
-	contextWindow             int // Maximum context window size in tokens
+	contextWindow             atomic.Int64 // Context window budget in tokens; see updateContextWindow
 	maxIterations             int
 	summarizeMessageThreshold int // Trigger summarization after this many messages
 	summarizeTokenPercent     int // Trigger summarization when history exceeds this % of context window
@@ -46,6 +52,16 @@ type AgentLoop struct {
 	summarizing               sync.Map // Tracks which sessions are currently being summarized
 	channelManager            channelManagerInterface
 	emitter                   EventEmitter // Structured event emitter (defaults to NoopEmitter)
+	catalog                   *models.Catalog
+	maxTokens                 int        // agents.defaults.max_tokens: fallback and upper bound for contextWindow
+	windowMu                  sync.Mutex // Guards windowModel
+	windowModel               string     // Model the latest updateContextWindow call is for
+	firstTurn                 sync.Once  // Setup deferred to the first message, once every tool is registered
+	toolSettingNames          []string   // tools.<name> objects with generic settings, checked on the first message
+	usage                     *usage.Recorder
+	mcp                       *mcp.Manager
+	processes                 *tools.ProcessManager
+	toolOutputs               *tools.ToolOutputStore
 }
 
 // channelManagerInterface allows the agent loop to query enabled channels.
@@ -64,23 +80,38 @@ type processOptions struct {
 	EnableSummary   bool   // Whether to trigger summarization
 	SendResponse    bool   // Whether to send response via bus
 	NoHistory       bool   // If true, don't load session history (for heartbeat)
+	SenderID        string // Sender the turn is attributed to in usage records
 	MessageID       string // Structured event message ID for this turn
 }
 
 // createToolRegistry creates a tool registry with common tools.
 // This is shared between main agent and subagents.
-func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus) *tools.ToolRegistry {
+// agentName ("main" or "subagent") selects per-agent exec policy overrides.
+func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msgBus *bus.MessageBus, agentName string) *tools.ToolRegistry {
 	registry := tools.NewToolRegistry()
+	registry.Configure(toolSettings(cfg))
 
 	// File system tools
 	registry.Register(tools.NewReadFileTool(workspace, restrict))
 	registry.Register(tools.NewWriteFileTool(workspace, restrict))
 	registry.Register(tools.NewListDirTool(workspace, restrict))
+	registry.Register(tools.NewSearchFilesTool(workspace, restrict))
 	registry.Register(tools.NewEditFileTool(workspace, restrict))
+	registry.Register(tools.NewApplyPatchTool(workspace, restrict))
 	registry.Register(tools.NewAppendFileTool(workspace, restrict))
 
-	// Shell execution
-	registry.Register(tools.NewExecTool(workspace, restrict))
+	// Shell execution. An invalid exec policy disables the tool rather than
+	// running commands without the rules the user asked for.
+	if policy, err := NewExecPolicy(cfg, workspace); err != nil {
+		logger.ErrorCF("agent", "Exec tool disabled: invalid exec policy", map[string]interface{}{"error": err.Error()})
+	} else {
+		execTool := tools.NewExecTool(workspace, restrict)
+		execTool.SetPolicy(policy, agentName)
+		if sandbox := NewExecSandbox(cfg, workspace); sandbox != nil {
+			execTool.SetSandbox(sandbox)
+		}
+		registry.Register(execTool)
+	}
 
 	if searchTool := tools.NewWebSearchTool(tools.WebSearchToolOptions{
 		BraveAPIKey:          cfg.Tools.Web.Brave.APIKey,
@@ -91,14 +122,65 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 		PerplexityAPIKey:     cfg.Tools.Web.Perplexity.APIKey,
 		PerplexityMaxResults: cfg.Tools.Web.Perplexity.MaxResults,
 		PerplexityEnabled:    cfg.Tools.Web.Perplexity.Enabled,
+		SearXNGURL:           cfg.Tools.Web.SearXNG.URL,
+		SearXNGMaxResults:    cfg.Tools.Web.SearXNG.MaxResults,
+		SearXNGEnabled:       cfg.Tools.Web.SearXNG.Enabled,
+		SearXNGCategories:    cfg.Tools.Web.SearXNG.Categories,
+		SearXNGLanguage:      cfg.Tools.Web.SearXNG.Language,
+		JSON: tools.JSONSearchOptions{
+			Name:         cfg.Tools.Web.JSONSearch.Name,
+			URL:          cfg.Tools.Web.JSONSearch.URL,
+			Method:       cfg.Tools.Web.JSONSearch.Method,
+			Headers:      cfg.Tools.Web.JSONSearch.Headers,
+			Body:         cfg.Tools.Web.JSONSearch.Body,
+			ResultsPath:  cfg.Tools.Web.JSONSearch.ResultsPath,
+			TitleField:   cfg.Tools.Web.JSONSearch.TitleField,
+			URLField:     cfg.Tools.Web.JSONSearch.URLField,
+			SnippetField: cfg.Tools.Web.JSONSearch.SnippetField,
+			DateField:    cfg.Tools.Web.JSONSearch.DateField,
+		},
+		JSONMaxResults: cfg.Tools.Web.JSONSearch.MaxResults,
+		JSONEnabled:    cfg.Tools.Web.JSONSearch.Enabled,
+		Order:          cfg.Tools.Web.SearchOrder,
+		CacheTTL:       time.Duration(cfg.Tools.Web.SearchCacheMinutes) * time.Minute,
 	}); searchTool != nil {
 		registry.Register(searchTool)
 	}
-	registry.Register(tools.NewWebFetchTool(50000))
+	if fetchTool := newWebFetchTool(cfg, workspace); fetchTool != nil {
+		registry.Register(fetchTool)
+	}
+	if httpTool := newHTTPRequestTool(cfg); httpTool != nil {
+		registry.Register(httpTool)
+	}
 
-	// Hardware tools (I2C, SPI) - Linux only, returns error on other platforms
-	registry.Register(tools.NewI2CTool())
-	registry.Register(tools.NewSPITool())
+	if cfg.Tools.Git.Enabled {
+		gitTool := tools.NewGitTool(workspace, restrict)
+		gitTool.SetAuthor(cfg.Tools.Git.AuthorName, cfg.Tools.Git.AuthorEmail)
+		remotes := make([]tools.GitRemote, 0, len(cfg.Tools.Git.PushRemotes))
+		for _, r := range cfg.Tools.Git.PushRemotes {
+			remotes = append(remotes, tools.GitRemote{URL: r.URL, Username: r.Username, Token: r.Token})
+		}
+		gitTool.SetPushRemotes(remotes)
+		registry.Register(gitTool)
+	}
+
+	// Hardware tools (I2C, SPI, serial, GPIO, sensors) - Linux only. Registered when the board has
+	// the bus or tools.<name>.enabled asks for them.
+	if registry.Enabled("i2c", hasDevice("/dev/i2c-*")) {
+		registry.Register(tools.NewI2CTool())
+	}
+	if registry.Enabled("spi", hasDevice("/dev/spidev*")) {
+		registry.Register(tools.NewSPITool())
+	}
+	if registry.Enabled("serial", hasDevice("/dev/ttyUSB*") || hasDevice("/dev/ttyACM*") || hasDevice("/dev/serial0")) {
+		registry.Register(tools.NewSerialTool())
+	}
+	if registry.Enabled("gpio", hasDevice("/dev/gpiochip*")) {
+		registry.Register(tools.NewGPIOTool())
+	}
+	if registry.Enabled("sensor", hasDevice("/dev/i2c-*")) {
+		registry.Register(tools.NewSensorTool())
+	}
 
 	// Message tool - available to both agent and subagent
 	// Subagent uses it to communicate directly with user
@@ -116,6 +198,117 @@ func createToolRegistry(workspace string, restrict bool, cfg *config.Config, msg
 	return registry
 }
 
+// newWebFetchTool applies tools.web to web_fetch. An invalid network policy
+// disables the tool rather than fetching without it.
+func newWebFetchTool(cfg *config.Config, workspace string) *tools.WebFetchTool {
+	wc := cfg.Tools.Web
+	policy, err := tools.NewNetPolicy(tools.NetPolicyOptions{
+		AllowDomains: wc.AllowDomains,
+		DenyDomains:  wc.DenyDomains,
+		AllowPrivate: wc.AllowPrivate,
+	})
+	if err != nil {
+		logger.ErrorCF("agent", "web_fetch disabled: invalid network policy", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+	fetchTool := tools.NewWebFetchTool(wc.Fetch.MaxChars)
+	fetchTool.SetNetPolicy(policy)
+	fetchTool.SetCache(tools.NewWebCache(filepath.Join(workspace, "cache", "web"),
+		time.Duration(wc.Fetch.CacheTTLMinutes)*time.Minute, int64(wc.Fetch.CacheMaxMB)<<20))
+	return fetchTool
+}
+
+// newHTTPRequestTool builds http_request from tools.http, or returns nil
+// when it is disabled or misconfigured.
+func newHTTPRequestTool(cfg *config.Config) *tools.HTTPRequestTool {
+	hc := cfg.Tools.HTTP
+	if !hc.Enabled {
+		return nil
+	}
+	wc := cfg.Tools.Web
+	policy, err := tools.NewNetPolicy(tools.NetPolicyOptions{
+		AllowDomains: wc.AllowDomains,
+		DenyDomains:  wc.DenyDomains,
+		AllowPrivate: wc.AllowPrivate,
+	})
+	if err != nil {
+		logger.ErrorCF("agent", "http_request disabled: invalid network policy", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+	httpTool := tools.NewHTTPRequestTool(time.Duration(hc.TimeoutSeconds)*time.Second, int64(hc.MaxResponseKB)<<10)
+	httpTool.SetNetPolicy(policy)
+	httpTool.SetRequireProfile(hc.RequireProfile)
+	profiles := make([]tools.HTTPProfile, 0, len(hc.Profiles))
+	for name, p := range hc.Profiles {
+		profiles = append(profiles, tools.HTTPProfile{
+			Name:        name,
+			BaseURL:     p.BaseURL,
+			Headers:     p.Headers,
+			BearerToken: p.BearerToken,
+			Username:    p.Username,
+			Password:    p.Password,
+			AllowHosts:  p.AllowHosts,
+		})
+	}
+	if err := httpTool.SetProfiles(profiles); err != nil {
+		logger.ErrorCF("agent", "http_request disabled: invalid profile", map[string]interface{}{"error": err.Error()})
+		return nil
+	}
+	return httpTool
+}
+
+// NewExecPolicy compiles the exec policy in tools.exec.
+func NewExecPolicy(cfg *config.Config, workspace string) (*tools.ExecPolicySet, error) {
+	ec := cfg.Tools.Exec
+	overrides := make([]tools.ExecPolicyOverride, 0, len(ec.Overrides))
+	for _, o := range ec.Overrides {
+		overrides = append(overrides, tools.ExecPolicyOverride{
+			Agent:   o.Agent,
+			Channel: o.Channel,
+			ChatID:  o.ChatID,
+			Sender:  o.Sender,
+			Policy:  execPolicyFromConfig(o.ExecPolicyConfig),
+		})
+	}
+	return tools.NewExecPolicySet(workspace, execPolicyFromConfig(ec.ExecPolicyConfig), overrides)
+}
+
+func execPolicyFromConfig(pc config.ExecPolicyConfig) tools.ExecPolicy {
+	rules := func(list []config.ExecRuleConfig) []tools.ExecRule {
+		out := make([]tools.ExecRule, 0, len(list))
+		for _, r := range list {
+			out = append(out, tools.ExecRule{Prefix: r.Prefix, Regex: r.Regex, Binary: r.Binary})
+		}
+		return out
+	}
+	return tools.ExecPolicy{
+		Allow:       rules(pc.Allow),
+		Deny:        rules(pc.Deny),
+		BuiltinDeny: pc.BuiltinDeny,
+		AllowedDirs: pc.AllowedDirs,
+		EnvAllow:    pc.EnvAllow,
+		EnvDeny:     pc.EnvDeny,
+		Timeout:     time.Duration(pc.TimeoutSeconds) * time.Second,
+	}
+}
+
+// NewExecSandbox returns the exec sandbox configured in tools.exec.sandbox,
+// or nil when it is disabled.
+func NewExecSandbox(cfg *config.Config, workspace string) *tools.Sandbox {
+	sc := cfg.Tools.Exec.Sandbox
+	if !sc.Enabled {
+		return nil
+	}
+	return tools.NewSandbox(tools.SandboxOptions{
+		Workspace:     workspace,
+		ReadOnlyPaths: sc.ReadOnlyPaths,
+		Network:       sc.Network,
+		CPUSeconds:    sc.CPUSeconds,
+		MemoryMB:      sc.MemoryMB,
+		MaxProcesses:  sc.MaxProcesses,
+	})
+}
+
 func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers.LLMProvider) *AgentLoop {
 	workspace := cfg.WorkspacePath()
 	os.MkdirAll(workspace, 0755)
@@ -123,14 +316,23 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	restrict := cfg.Agents.Defaults.RestrictToWorkspace
 
 	// Create tool registry for main agent
-	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
+	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus, "main")
 
 	// Create subagent manager with its own tool registry
 	subagentManager := tools.NewSubagentManager(provider, cfg.Agents.Defaults.Model, workspace, msgBus)
-	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus)
+	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus, "subagent")
 	// Subagent doesn't need spawn/subagent tools to avoid recursion
 	subagentManager.SetTools(subagentTools)
 
+	// Mount configured MCP servers; subagents get each server's subset
+	mcpManager := startMCP(cfg, toolsRegistry, subagentTools)
+
+	// Background processes, shared by agent and subagents
+	processManager := registerProcessTools(cfg, toolsRegistry, subagentTools)
+
+	// Oversized tool results go to per-session scratch files
+	toolOutputs := registerToolOutputs(cfg, toolsRegistry, subagentTools)
+
 	// Register spawn tool (for main agent)
 	spawnTool := tools.NewSpawnTool(subagentManager)
 	toolsRegistry.Register(spawnTool)
@@ -151,7 +353,11 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 	// Register write_daily_note with the file-based memory store
 	toolsRegistry.Register(tools.NewWriteDailyNoteTool(contextBuilder.GetMemoryStore()))
 
-	return newAgentLoop(cfg, msgBus, provider, sessionsManager, stateManager, contextBuilder, toolsRegistry)
+	al := newAgentLoop(cfg, msgBus, provider, sessionsManager, stateManager, contextBuilder, toolsRegistry)
+	al.mcp = mcpManager
+	al.processes = processManager
+	al.toolOutputs = toolOutputs
+	return al
 }
 
 // NewAgentLoopWithStores creates an AgentLoop with custom storage backends.
@@ -162,11 +368,14 @@ func NewAgentLoopWithStores(cfg *config.Config, msgBus *bus.MessageBus, provider
 
 	restrict := cfg.Agents.Defaults.RestrictToWorkspace
 
-	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus)
+	toolsRegistry := createToolRegistry(workspace, restrict, cfg, msgBus, "main")
 
 	subagentManager := tools.NewSubagentManager(provider, cfg.Agents.Defaults.Model, workspace, msgBus)
-	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus)
+	subagentTools := createToolRegistry(workspace, restrict, cfg, msgBus, "subagent")
 	subagentManager.SetTools(subagentTools)
+	mcpManager := startMCP(cfg, toolsRegistry, subagentTools)
+	processManager := registerProcessTools(cfg, toolsRegistry, subagentTools)
+	toolOutputs := registerToolOutputs(cfg, toolsRegistry, subagentTools)
 
 	spawnTool := tools.NewSpawnTool(subagentManager)
 	toolsRegistry.Register(spawnTool)
@@ -182,7 +391,145 @@ func NewAgentLoopWithStores(cfg *config.Config, msgBus *bus.MessageBus, provider
 		contextBuilder.SetMemoryStore(memoryStore)
 	}
 
-	return newAgentLoop(cfg, msgBus, provider, sessions, stateStore, contextBuilder, toolsRegistry)
+	al := newAgentLoop(cfg, msgBus, provider, sessions, stateStore, contextBuilder, toolsRegistry)
+	al.mcp = mcpManager
+	al.processes = processManager
+	al.toolOutputs = toolOutputs
+	return al
+}
+
+// startMCP connects to the configured MCP servers in the background and
+// mounts their tools into the agent and subagent registries.
+func startMCP(cfg *config.Config, agentTools, subagentTools *tools.ToolRegistry) *mcp.Manager {
+	manager := mcp.NewManager(cfg.Tools.MCP)
+	manager.Attach(agentTools, false)
+	manager.Attach(subagentTools, true)
+	manager.Start()
+	return manager
+}
+
+// registerProcessTools adds the process tool to each registry that has an
+// exec tool, reusing that tool's policy and sandbox.
+func registerProcessTools(cfg *config.Config, registries ...*tools.ToolRegistry) *tools.ProcessManager {
+	pc := cfg.Tools.Process
+	manager := tools.NewProcessManager(tools.ProcessManagerOptions{
+		MaxPerSession: pc.MaxPerSession,
+		BufferSize:    pc.BufferKB * 1024,
+		IdleTimeout:   time.Duration(pc.IdleTimeoutMinutes) * time.Minute,
+	})
+	for _, registry := range registries {
+		if tool, ok := registry.Get("exec"); ok {
+			if execTool, ok := tool.(*tools.ExecTool); ok {
+				registry.Register(tools.NewProcessTool(manager, execTool))
+			}
+		}
+	}
+	return manager
+}
+
+// sectionTools maps the tools.<section> names that differ from tool names
+// to the tools they configure.
+var sectionTools = map[string][]string{
+	"web":  {"web_search", "web_fetch"},
+	"http": {"http_request"},
+	"sql":  {"sql_query"},
+}
+
+// toolSettings converts the generic tools.<name> settings for the registry.
+// A section such as tools.web applies to each of its tools; fields set under
+// a tool's own name take precedence.
+func toolSettings(cfg *config.Config) map[string]tools.ToolSettings {
+	settings := make(map[string]tools.ToolSettings, len(cfg.Tools.Settings))
+	for section, s := range cfg.Tools.Settings {
+		for _, name := range sectionTools[section] {
+			settings[name] = mergeToolSettings(convertToolSettings(s), settings[name])
+		}
+	}
+	for name, s := range cfg.Tools.Settings {
+		if _, ok := sectionTools[name]; !ok {
+			settings[name] = mergeToolSettings(settings[name], convertToolSettings(s))
+		}
+	}
+	return settings
+}
+
+func convertToolSettings(s config.ToolSettings) tools.ToolSettings {
+	return tools.ToolSettings{
+		Enabled:   s.Enabled,
+		Timeout:   time.Duration(s.TimeoutSeconds) * time.Second,
+		MaxOutput: s.MaxOutputBytes,
+		Options:   s.Options,
+	}
+}
+
+// mergeToolSettings returns base with the fields set in override replacing it.
+func mergeToolSettings(base, override tools.ToolSettings) tools.ToolSettings {
+	if override.Enabled != nil {
+		base.Enabled = override.Enabled
+	}
+	if override.Timeout > 0 {
+		base.Timeout = override.Timeout
+	}
+	if override.MaxOutput > 0 {
+		base.MaxOutput = override.MaxOutput
+	}
+	if len(override.Options) > 0 {
+		base.Options = override.Options
+	}
+	return base
+}
+
+// warnUnusedToolSettings logs tools.<name> settings that match no tool, so
+// typos and tools missing on this machine do not go unnoticed. It runs once
+// every tool has been registered.
+func (al *AgentLoop) warnUnusedToolSettings() {
+	for _, name := range al.toolSettingNames {
+		if _, ok := sectionTools[name]; ok || al.tools.Known(name) {
+			continue
+		}
+		if strings.HasPrefix(name, "mcp_") {
+			continue // MCP tools mount in the background
+		}
+		logger.WarnCF("agent", "Tool settings match no tool; they are ignored",
+			map[string]interface{}{"setting": "tools." + name})
+	}
+}
+
+// hasDevice reports whether a device node matching pattern exists.
+func hasDevice(pattern string) bool {
+	matches, _ := filepath.Glob(pattern)
+	return len(matches) > 0
+}
+
+// registerToolOutputs makes the registries spill oversized results to
+// scratch files, shared by agent and subagents.
+func registerToolOutputs(cfg *config.Config, registries ...*tools.ToolRegistry) *tools.ToolOutputStore {
+	oc := cfg.Tools.Output
+	if !oc.SpillEnabled {
+		return nil
+	}
+	store := tools.NewToolOutputStore(tools.ToolOutputOptions{
+		Dir:          toolOutputDir(cfg.WorkspacePath()),
+		Threshold:    oc.SpillBytes,
+		PreviewChars: oc.PreviewBytes,
+		IdleTimeout:  time.Duration(oc.IdleTimeoutMinutes) * time.Minute,
+	})
+	for _, registry := range registries {
+		registry.SetOutputStore(store)
+	}
+	return store
+}
+
+// toolOutputDir returns where spilled tool results are kept: outside the
+// workspace, so file search, directory listings and git never see them, with
+// one directory per workspace.
+func toolOutputDir(workspace string) string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = filepath.Join(os.TempDir(), fmt.Sprintf("picooraclaw-%d", os.Getuid()))
+	}
+	sum := sha256.Sum256([]byte(filepath.Clean(workspace)))
+	return filepath.Join(base, "picooraclaw", "tool_output", hex.EncodeToString(sum[:4]))
 }
 
 // newAgentLoop creates the AgentLoop with configurable summarization thresholds.
@@ -196,12 +543,13 @@ func newAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		summarizeTokenPercent = 75
 	}
 
-	return &AgentLoop{
+	model := cfg.Agents.Defaults.Model
+
+	al := &AgentLoop{
 		bus:                       msgBus,
 		provider:                  provider,
 		workspace:                 cfg.WorkspacePath(),
-		model:                     cfg.Agents.Defaults.Model,
-		contextWindow:             cfg.Agents.Defaults.MaxTokens,
+		model:                     model,
 		maxIterations:             cfg.Agents.Defaults.MaxToolIterations,
 		summarizeMessageThreshold: summarizeMessageThreshold,
 		summarizeTokenPercent:     summarizeTokenPercent,
@@ -211,7 +559,91 @@ func newAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		tools:                     toolsRegistry,
 		summarizing:               sync.Map{},
 		emitter:                   NoopEmitter{},
+		catalog:                   models.NewCatalog(cfg),
+		maxTokens:                 cfg.Agents.Defaults.MaxTokens,
+		toolSettingNames:          sortedSettingNames(cfg.Tools.Settings),
+		usage:                     newUsageRecorder(cfg, toolsRegistry, stateStore),
+	}
+	// Start from the built-in table; the live catalog is consulted on the
+	// first message so construction never waits on the network.
+	info, ok := models.Builtin(model)
+	al.contextWindow.Store(int64(contextBudget(info, ok, al.maxTokens)))
+	return al
+}
+
+// newUsageRecorder creates the usage recorder and routes its daily spend
+// alerts through the message tool, to the configured target or else the
+// last active channel.
+func newUsageRecorder(cfg *config.Config, registry *tools.ToolRegistry, stateStore StateManagerInterface) *usage.Recorder {
+	recorder := usage.NewRecorder(usage.NewStore(cfg.WorkspacePath()), cfg.Usage)
+
+	tool, ok := registry.Get("message")
+	if !ok {
+		return recorder
+	}
+	mt, ok := tool.(*tools.MessageTool)
+	if !ok {
+		return recorder
+	}
+	recorder.SetAlertFunc(func(content string) error {
+		channel, chatID := cfg.Usage.AlertChannel, cfg.Usage.AlertChatID
+		if channel == "" || chatID == "" {
+			last := stateStore.GetLastChannel()
+			idx := strings.Index(last, ":")
+			if idx <= 0 {
+				return fmt.Errorf("no alert channel configured and no last channel recorded")
+			}
+			channel, chatID = last[:idx], last[idx+1:]
+		}
+		return mt.Send(channel, chatID, content)
+	})
+	return recorder
+}
+
+func sortedSettingNames(settings map[string]config.ToolSettings) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// contextBudget returns the token budget used for summarization: the model's
+// context window, capped by maxTokens when that is configured. maxTokens is
+// also the fallback when the window is unknown.
+func contextBudget(info models.ModelInfo, known bool, maxTokens int) int {
+	if !known || info.ContextWindow <= 0 {
+		return maxTokens
+	}
+	if maxTokens > 0 && maxTokens < info.ContextWindow {
+		return maxTokens
 	}
+	return info.ContextWindow
+}
+
+// updateContextWindow sets the context window budget for model from the
+// catalog, which prefers live provider listings over the built-in table.
+// A result that arrives after a later call for another model is dropped.
+func (al *AgentLoop) updateContextWindow(ctx context.Context, model string) {
+	al.windowMu.Lock()
+	al.windowModel = model
+	al.windowMu.Unlock()
+
+	info, ok := al.catalog.Lookup(ctx, model)
+
+	al.windowMu.Lock()
+	defer al.windowMu.Unlock()
+	if al.windowModel == model {
+		al.contextWindow.Store(int64(contextBudget(info, ok, al.maxTokens)))
+	}
+}
+
+// modelSupportsVision reports whether the built-in catalog lists model as
+// accepting images.
+func modelSupportsVision(model string) bool {
+	info, ok := models.Builtin(model)
+	return ok && info.Vision
 }
 
 // SetEventEmitter installs a structured event emitter. Passing nil resets the
@@ -247,7 +679,13 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				continue
 			}
 
-			response, err := al.processMessage(ctx, msg)
+			// Someone is waiting on inbound chat messages; subagent results
+			// arriving on the system channel are not urgent.
+			priority := providers.PriorityInteractive
+			if msg.Channel == "system" {
+				priority = providers.PriorityNormal
+			}
+			response, err := al.processMessage(providers.WithPriority(ctx, priority), msg)
 			if err != nil {
 				response = fmt.Sprintf("Error processing message: %v", err)
 			}
@@ -278,6 +716,25 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 
 func (al *AgentLoop) Stop() {
 	al.running.Store(false)
+	if al.mcp != nil {
+		al.mcp.Close()
+	}
+	if al.processes != nil {
+		al.processes.Close()
+	}
+	if al.toolOutputs != nil {
+		al.toolOutputs.Close()
+	}
+}
+
+// Tools returns the agent's tool registry.
+func (al *AgentLoop) Tools() *tools.ToolRegistry {
+	return al.tools
+}
+
+// MemoryStore returns the memory store the agent's context is built from.
+func (al *AgentLoop) MemoryStore() MemoryStoreInterface {
+	return al.contextBuilder.GetMemoryStore()
 }
 
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
@@ -315,7 +772,7 @@ func (al *AgentLoop) ProcessDirectWithChannel(ctx context.Context, content, sess
 // ProcessHeartbeat processes a heartbeat request without session history.
 // Each heartbeat is independent and doesn't accumulate context.
 func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, chatID string) (string, error) {
-	return al.runAgentLoop(ctx, processOptions{
+	return al.runAgentLoop(providers.WithPriority(ctx, providers.PriorityBackground), processOptions{
 		SessionKey:      "heartbeat",
 		Channel:         channel,
 		ChatID:          chatID,
@@ -324,10 +781,56 @@ func (al *AgentLoop) ProcessHeartbeat(ctx context.Context, content, channel, cha
 		EnableSummary:   false,
 		SendResponse:    false,
 		NoHistory:       true, // Don't load session history for heartbeat
+		SenderID:        "heartbeat",
 	})
 }
 
+// heartbeatOKSchema is the structured verdict on a heartbeat reply.
+var heartbeatOKSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"ok": map[string]interface{}{
+			"type":        "boolean",
+			"description": "true when the reply says nothing needs the user's attention",
+		},
+	},
+	"required":             []interface{}{"ok"},
+	"additionalProperties": false,
+}
+
+// HeartbeatOK reports whether a ProcessHeartbeat reply means nothing needs
+// attention. A bare HEARTBEAT_OK is taken as is; anything else, such as
+// "All done. HEARTBEAT_OK.", is classified with a structured request.
+func (al *AgentLoop) HeartbeatOK(ctx context.Context, response string) bool {
+	response = strings.TrimSpace(response)
+	if response == "HEARTBEAT_OK" {
+		return true
+	}
+	if response == "" {
+		return false
+	}
+	verdict, err := providers.ChatJSON[struct {
+		OK bool `json:"ok"`
+	}](providers.WithPriority(ctx, providers.PriorityBackground), al.provider, []providers.Message{{
+		Role: "user",
+		Content: "A scheduled heartbeat check was asked to reply HEARTBEAT_OK when nothing needs attention. " +
+			"Does this reply say that nothing needs the user's attention?\n\nREPLY:\n" + response,
+	}}, heartbeatOKSchema, al.model, map[string]interface{}{"max_tokens": 64, "temperature": 0.0})
+	if err != nil {
+		logger.WarnCF("agent", "Heartbeat reply check failed", map[string]interface{}{"error": err.Error()})
+		return strings.Contains(response, "HEARTBEAT_OK")
+	}
+	return verdict.OK
+}
+
 func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
+	al.firstTurn.Do(func() {
+		// The built-in window set by newAgentLoop serves until the live
+		// listing arrives, so a slow provider does not delay this reply.
+		go al.updateContextWindow(context.Background(), al.model)
+		al.warnUnusedToolSettings()
+	})
+
 	// Add message preview to log (show full content for error messages)
 	var logContent string
 	if strings.Contains(msg.Content, "Error:") || strings.Contains(msg.Content, "error") {
@@ -407,6 +910,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 		EnableSummary:   true,
 		SendResponse:    false,
 		MessageID:       messageID,
+		SenderID:        msg.SenderID,
 	})
 	if err != nil {
 		emitter.Emit(Event{
@@ -521,7 +1025,17 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	al.sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
 
 	// 4. Run LLM iteration loop
-	finalContent, iteration, err := al.runLLMIteration(ctx, messages, opts)
+	turn := usage.Record{
+		SessionKey: opts.SessionKey,
+		Channel:    opts.Channel,
+		ChatID:     opts.ChatID,
+		SenderID:   opts.SenderID,
+		Model:      al.model,
+	}
+	finalContent, iteration, err := al.runLLMIteration(ctx, messages, opts, &turn)
+	if al.usage != nil {
+		al.usage.Record(turn)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -564,9 +1078,10 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, opts processOptions) (str
 	return finalContent, nil
 }
 
-// runLLMIteration executes the LLM call loop with tool handling.
+// runLLMIteration executes the LLM call loop with tool handling, adding
+// each call's token usage to turn.
 // Returns the final content, iteration count, and any error.
-func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions) (string, int, error) {
+func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.Message, opts processOptions, turn *usage.Record) (string, int, error) {
 	iteration := 0
 	var finalContent string
 
@@ -621,8 +1136,9 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 		maxRetries := 3
 		for retry := 0; retry <= maxRetries; retry++ {
 			response, err = al.provider.Chat(ctx, messages, providerToolDefs, al.model, map[string]interface{}{
-				"max_tokens":  8192,
-				"temperature": 0.7,
+				"max_tokens":       8192,
+				"temperature":      0.7,
+				"prompt_cache_key": opts.SessionKey,
 			})
 
 			if err == nil {
@@ -704,6 +1220,9 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				})
 			return "", iteration, fmt.Errorf("LLM call failed: %w", err)
 		}
+		if al.usage != nil {
+			al.usage.Add(turn, response.Usage)
+		}
 
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
@@ -840,7 +1359,8 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 					Timestamp:  time.Now(),
 				})
 
-				toolResult := al.tools.ExecuteWithContext(ctx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
+				toolCtx := tools.WithToolVision(tools.WithToolSender(ctx, opts.SenderID), modelSupportsVision(al.model))
+				toolResult := al.tools.ExecuteWithContext(toolCtx, tc.Name, tc.Arguments, opts.Channel, opts.ChatID, asyncCallback)
 				toolResults[idx].result = toolResult
 
 				// Build result + ok fields for tool_call_end.
@@ -913,11 +1433,12 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 				Role:       "tool",
 				Content:    contentForLLM,
 				ToolCallID: r.tc.ID,
+				Images:     r.result.Images,
 			}
 			messages = append(messages, toolResultMsg)
 
-			// Save tool result message to session
-			al.sessions.AddFullMessage(opts.SessionKey, toolResultMsg)
+			// Save tool result message to session, without its images
+			al.sessions.AddFullMessage(opts.SessionKey, historyMessage(toolResultMsg))
 		}
 	}
 
@@ -929,7 +1450,7 @@ func (al *AgentLoop) runLLMIteration(ctx context.Context, messages []providers.M
 func (al *AgentLoop) maybeSummarize(sessionKey string) {
 	newHistory := al.sessions.GetHistory(sessionKey)
 	tokenEstimate := al.estimateTokens(newHistory)
-	threshold := al.contextWindow * al.summarizeTokenPercent / 100
+	threshold := int(al.contextWindow.Load()) * al.summarizeTokenPercent / 100
 
 	if len(newHistory) > al.summarizeMessageThreshold || tokenEstimate > threshold {
 		if _, loading := al.summarizing.LoadOrStore(sessionKey, true); !loading {
@@ -1032,7 +1553,7 @@ func (al *AgentLoop) summarizeSession(sessionKey string) {
 
 	// Oversized Message Guard
 	// Skip messages larger than 50% of context window to prevent summarizer overflow
-	maxMessageTokens := al.contextWindow / 2
+	maxMessageTokens := int(al.contextWindow.Load()) / 2
 	validMessages := make([]providers.Message, 0)
 	omitted := false
 
@@ -1111,15 +1632,32 @@ func (al *AgentLoop) summarizeBatch(ctx context.Context, batch []providers.Messa
 	return response.Content, nil
 }
 
+// imageTokenEstimate is roughly what a vision model charges for one image.
+const imageTokenEstimate = 1500
+
+// historyMessage returns the copy of a tool result kept in the session.
+// Images are only sent with the turn that produced them; the history keeps
+// a placeholder so they are not re-sent with every later request.
+func historyMessage(msg providers.Message) providers.Message {
+	if len(msg.Images) == 0 {
+		return msg
+	}
+	msg.Content += fmt.Sprintf("\n[%d image(s) were shown in an earlier turn and are not kept in history; read the file again to see them]", len(msg.Images))
+	msg.Images = nil
+	return msg
+}
+
 // estimateTokens estimates the number of tokens in a message list.
 // Uses model-aware character-per-token ratios for better accuracy.
-// Accounts for message structure overhead (~4 tokens per message).
+// Accounts for message structure overhead (~4 tokens per message) and a
+// flat cost per attached image.
 func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
-	totalChars := 0
+	totalChars, images := 0, 0
 	for _, m := range messages {
 		totalChars += utf8.RuneCountInString(m.Content)
 		// Account for message structure overhead (~4 tokens per message)
 		totalChars += 16
+		images += len(m.Images)
 	}
 	// Model-specific ratios (chars per token)
 	ratio := 4.0 // conservative default
@@ -1136,12 +1674,12 @@ func (al *AgentLoop) estimateTokens(messages []providers.Message) int {
 	case strings.Contains(model, "deepseek"):
 		ratio = 2.8
 	}
-	return int(float64(totalChars) / ratio)
+	return int(float64(totalChars)/ratio) + images*imageTokenEstimate
 }
 
 // handleCommand handles slash commands like /show, /list, /switch.
 // Returns the response and true if the message was a command, false otherwise.
-func (al *AgentLoop) handleCommand(_ context.Context, msg bus.InboundMessage) (string, bool) {
+func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage) (string, bool) {
 	content := strings.TrimSpace(msg.Content)
 	if !strings.HasPrefix(content, "/") {
 		return "", false
@@ -1175,7 +1713,7 @@ func (al *AgentLoop) handleCommand(_ context.Context, msg bus.InboundMessage) (s
 		}
 		switch args[0] {
 		case "models":
-			return fmt.Sprintf("Configured model: %s (change in config.json)", al.model), true
+			return al.catalog.Format(ctx, al.model), true
 		case "channels":
 			if al.channelManager == nil {
 				return "Channel manager not initialized", true
@@ -1198,9 +1736,18 @@ func (al *AgentLoop) handleCommand(_ context.Context, msg bus.InboundMessage) (s
 
 		switch target {
 		case "model":
+			warning, err := al.catalog.Validate(ctx, value)
+			if err != nil {
+				return fmt.Sprintf("Cannot switch model: %v", err), true
+			}
 			oldModel := al.model
 			al.model = value
-			return fmt.Sprintf("Switched model from %s to %s", oldModel, value), true
+			al.updateContextWindow(ctx, value)
+			reply := fmt.Sprintf("Switched model from %s to %s", oldModel, value)
+			if warning != "" {
+				reply += " (warning: " + warning + ")"
+			}
+			return reply, true
 		case "channel":
 			if al.channelManager == nil {
 				return "Channel manager not initialized", true
@@ -2,8 +2,11 @@ package agent
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -501,3 +504,162 @@ func TestToolResult_UserFacingToolDoesSendMessage(t *testing.T) {
 		t.Errorf("Expected 'Command output: hello world', got: %s", response)
 	}
 }
+
+// TestHistoryMessage_DropsImages verifies tool result images stay out of the session
+func TestHistoryMessage_DropsImages(t *testing.T) {
+	img := providers.ImagePart{MediaType: "image/png", Data: strings.Repeat("A", 1000)}
+	msg := providers.Message{Role: "tool", Content: "screenshot.png (PNG, 800x600)", ToolCallID: "call_1", Images: []providers.ImagePart{img, img}}
+
+	saved := historyMessage(msg)
+	if len(saved.Images) != 0 {
+		t.Errorf("images should not be kept in history, got %d", len(saved.Images))
+	}
+	if !strings.HasPrefix(saved.Content, msg.Content) || !strings.Contains(saved.Content, "2 image(s)") {
+		t.Errorf("expected a placeholder after the original content, got %q", saved.Content)
+	}
+	if len(msg.Images) != 2 {
+		t.Error("the current turn's message must keep its images")
+	}
+
+	al := &AgentLoop{model: "test-model"}
+	text := al.estimateTokens([]providers.Message{{Role: "tool", Content: msg.Content}})
+	if got := al.estimateTokens([]providers.Message{msg}); got != text+2*imageTokenEstimate {
+		t.Errorf("estimateTokens with 2 images = %d, want %d", got, text+2*imageTokenEstimate)
+	}
+}
+
+// TestUpdateContextWindow_UsesCatalogCappedByMaxTokens verifies the live catalog
+// drives the summarization budget and max_tokens stays the upper bound
+func TestUpdateContextWindow_UsesCatalogCappedByMaxTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": "custom-model", "context_window": 32768}]}`))
+	}))
+	defer server.Close()
+
+	for _, tc := range []struct {
+		model     string
+		maxTokens int
+		want      int64
+	}{
+		{"custom-model", 0, 32768},
+		{"custom-model", 8192, 8192},
+		{"custom-model", 200000, 32768},
+		{"unknown-model", 4096, 4096},
+	} {
+		cfg := &config.Config{
+			Agents: config.AgentsConfig{
+				Defaults: config.AgentDefaults{
+					Workspace:         t.TempDir(),
+					Model:             tc.model,
+					MaxTokens:         tc.maxTokens,
+					MaxToolIterations: 10,
+				},
+			},
+		}
+		cfg.Providers.Groq = config.ProviderConfig{APIKey: "gsk", APIBase: server.URL}
+		al := NewAgentLoop(cfg, bus.NewMessageBus(), &simpleMockProvider{response: "OK"})
+
+		al.updateContextWindow(context.Background(), tc.model)
+		if got := al.contextWindow.Load(); got != tc.want {
+			t.Errorf("%s with max_tokens %d: context window = %d, want %d", tc.model, tc.maxTokens, got, tc.want)
+		}
+	}
+}
+
+// TestToolSettings_MapsSectionsToTools verifies tools.web, tools.http and tools.sql
+// configure the tools they cover, with per-tool settings taking precedence
+func TestToolSettings_MapsSectionsToTools(t *testing.T) {
+	off, on := false, true
+	cfg := &config.Config{}
+	cfg.Tools.Settings = map[string]config.ToolSettings{
+		"web":       {Enabled: &off, MaxOutputBytes: 4000},
+		"web_fetch": {Enabled: &on},
+		"http":      {TimeoutSeconds: 20},
+		"sql":       {MaxOutputBytes: 9000},
+		"serail":    {TimeoutSeconds: 5},
+	}
+
+	settings := toolSettings(cfg)
+	if s := settings["web_search"]; s.Enabled == nil || *s.Enabled || s.MaxOutput != 4000 {
+		t.Errorf("web_search = %+v, want the tools.web settings", s)
+	}
+	if s := settings["web_fetch"]; s.Enabled == nil || !*s.Enabled || s.MaxOutput != 4000 {
+		t.Errorf("web_fetch = %+v, want enabled by its own settings and the tools.web cap", s)
+	}
+	if settings["http_request"].Timeout != 20*time.Second || settings["sql_query"].MaxOutput != 9000 {
+		t.Errorf("http/sql sections not mapped: %+v", settings)
+	}
+	if _, ok := settings["web"]; ok {
+		t.Error("section names should not be kept as tool names")
+	}
+
+	registry := tools.NewToolRegistry()
+	registry.Configure(settings)
+	registry.Register(&mockCustomTool{})
+	registry.Enabled("gpio", false)
+	for name, want := range map[string]bool{"mock_custom": true, "gpio": true, "serail": false} {
+		if registry.Known(name) != want {
+			t.Errorf("Known(%q) = %v, want %v", name, !want, want)
+		}
+	}
+}
+
+// schemaMockProvider answers every request with a fixed reply and records the options
+type schemaMockProvider struct {
+	reply string
+	calls int
+	opts  map[string]interface{}
+}
+
+func (m *schemaMockProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, opts map[string]interface{}) (*providers.LLMResponse, error) {
+	m.calls++
+	m.opts = opts
+	return &providers.LLMResponse{Content: m.reply}, nil
+}
+
+func (m *schemaMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+// TestHeartbeatOK_UsesStructuredCheck verifies heartbeat replies other than a bare
+// HEARTBEAT_OK are classified through a schema-constrained request
+func TestHeartbeatOK_UsesStructuredCheck(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{Workspace: t.TempDir(), Model: "test-model", MaxTokens: 4096},
+		},
+	}
+	provider := &schemaMockProvider{reply: `{"ok": false}`}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	ctx := context.Background()
+
+	if !al.HeartbeatOK(ctx, " HEARTBEAT_OK\n") || provider.calls != 0 {
+		t.Error("a bare HEARTBEAT_OK should not need a model call")
+	}
+	if al.HeartbeatOK(ctx, "Your 3pm meeting moved to 4pm.") {
+		t.Error("reply classified as needing attention should not be OK")
+	}
+	if provider.calls != 1 || provider.opts["response_schema"] == nil {
+		t.Errorf("expected one structured request, got %d calls with options %v", provider.calls, provider.opts)
+	}
+	provider.reply = "```json\n{\"ok\": true}\n```"
+	if !al.HeartbeatOK(ctx, "All tasks done. HEARTBEAT_OK.") {
+		t.Error("reply classified as OK should be OK")
+	}
+}
+
+// TestToolOutputDir_OutsideWorkspace verifies spilled results stay out of the
+// workspace the file and git tools operate on
+func TestToolOutputDir_OutsideWorkspace(t *testing.T) {
+	workspace := t.TempDir()
+	dir := toolOutputDir(workspace)
+	if rel, err := filepath.Rel(workspace, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		t.Errorf("toolOutputDir(%q) = %q, inside the workspace", workspace, dir)
+	}
+	if dir != toolOutputDir(workspace+"/") {
+		t.Error("toolOutputDir should not depend on a trailing separator")
+	}
+	if dir == toolOutputDir(t.TempDir()) {
+		t.Error("workspaces should not share a tool output directory")
+	}
+}
@@ -3,11 +3,16 @@ package channels
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/jasperan/picooraclaw/pkg/bus"
+	"github.com/jasperan/picooraclaw/pkg/docs"
 	"github.com/jasperan/picooraclaw/pkg/logger"
+	"github.com/jasperan/picooraclaw/pkg/utils"
 )
 
 type Channel interface {
@@ -98,6 +103,10 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 	// Build session key: channel:chatID
 	sessionKey := fmt.Sprintf("%s:%s", c.name, chatID)
 
+	// Channels delete downloaded media once this returns, so documents are
+	// converted to text now.
+	content = c.appendDocumentText(content, media)
+
 	msg := bus.InboundMessage{
 		Channel:    c.name,
 		SenderID:   senderID,
@@ -111,6 +120,62 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 	c.bus.PublishInbound(msg)
 }
 
+const (
+	// maxAttachmentText caps the text added to a message per document.
+	maxAttachmentText = 20000
+	// maxAttachmentBytes skips documents too large to convert inline.
+	maxAttachmentBytes = 20 << 20
+)
+
+// appendDocumentText adds the extracted text of any PDF, Office, CSV or
+// HTML attachments to the message content.
+func (c *BaseChannel) appendDocumentText(content string, media []string) string {
+	for _, path := range media {
+		format, size := detectDocument(path)
+		if format == "" {
+			continue
+		}
+		// Downloads are stored as "<8 char id>_<original name>".
+		name := filepath.Base(path)
+		if i := strings.IndexByte(name, '_'); i == 8 {
+			name = name[i+1:]
+		}
+
+		var text string
+		if size > maxAttachmentBytes {
+			text = fmt.Sprintf("[file %s: too large to extract text]", name)
+		} else if doc, err := docs.ExtractFile(path, docs.Options{}); err != nil {
+			logger.WarnCF(c.name, "Failed to extract attachment text", map[string]interface{}{
+				"file":  name,
+				"error": err.Error(),
+			})
+			text = fmt.Sprintf("[file %s: could not extract text: %v]", name, err)
+		} else {
+			text = fmt.Sprintf("[file %s: %s]\n%s", name, doc.Summary(), utils.Truncate(strings.TrimSpace(doc.Text), maxAttachmentText))
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += text
+	}
+	return content
+}
+
+func detectDocument(path string) (string, int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		return "", 0
+	}
+	head := make([]byte, 8000)
+	n, _ := io.ReadFull(f, head)
+	return docs.Detect(path, head[:n]), info.Size()
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
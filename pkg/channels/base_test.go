@@ -1,6 +1,14 @@
 package channels
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jasperan/picooraclaw/pkg/bus"
+)
 
 func TestBaseChannelIsAllowed(t *testing.T) {
 	tests := []struct {
@@ -50,3 +58,27 @@ func TestBaseChannelIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseChannelHandleMessageExtractsDocuments(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "1a2b3c4d_prices.csv")
+	os.WriteFile(csvPath, []byte("item,price\ntea,3\n"), 0644)
+	imgPath := filepath.Join(dir, "photo.jpg")
+	os.WriteFile(imgPath, []byte("\xff\xd8\xff\xe0"), 0644)
+
+	mb := bus.NewMessageBus()
+	ch := NewBaseChannel("test", nil, mb, nil)
+	ch.HandleMessage("user", "chat", "see attached", []string{csvPath, imgPath}, nil)
+
+	msg, ok := mb.ConsumeInbound(context.Background())
+	if !ok {
+		t.Fatal("no inbound message")
+	}
+	want := "see attached\n[file prices.csv: CSV]\n| item | price |\n| --- | --- |\n| tea | 3 |"
+	if msg.Content != want {
+		t.Errorf("content = %q", msg.Content)
+	}
+	if len(msg.Media) != 2 || strings.Contains(msg.Content, "photo") {
+		t.Errorf("media should pass through untouched: %v", msg.Media)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/models"
 	"github.com/mymmrac/telego"
 )
 
@@ -17,14 +18,16 @@ type TelegramCommander interface {
 }
 
 type cmd struct {
-	bot    *telego.Bot
-	config *config.Config
+	bot     *telego.Bot
+	config  *config.Config
+	catalog *models.Catalog
 }
 
 func NewTelegramCommands(bot *telego.Bot, cfg *config.Config) TelegramCommander {
 	return &cmd{
-		bot:    bot,
-		config: cfg,
+		bot:     bot,
+		config:  cfg,
+		catalog: models.NewCatalog(cfg),
 	}
 }
 
@@ -114,12 +117,7 @@ func (c *cmd) List(ctx context.Context, message telego.Message) error {
 	var response string
 	switch args {
 	case "models":
-		provider := c.config.Agents.Defaults.Provider
-		if provider == "" {
-			provider = "configured default"
-		}
-		response = fmt.Sprintf("Configured Model: %s\nProvider: %s\n\nTo change models, update config.json",
-			c.config.Agents.Defaults.Model, provider)
+		response = c.catalog.Format(ctx, c.config.Agents.Defaults.Model)
 
 	case "channels":
 		var enabled []string
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/caarlos0/env/v11"
@@ -52,6 +53,7 @@ type Config struct {
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
 	Devices   DevicesConfig   `json:"devices"`
 	Oracle    OracleDBConfig  `json:"oracle"`
+	Usage     UsageConfig     `json:"usage"`
 	mu        sync.RWMutex
 }
 
@@ -179,6 +181,26 @@ type HeartbeatConfig struct {
 	Interval int  `json:"interval" env:"PICOCLAW_HEARTBEAT_INTERVAL"` // minutes, min 5
 }
 
+// UsageConfig controls cost estimation and spend alerts. Pricing entries
+// are keyed by model ID prefix and override the built-in list prices.
+type UsageConfig struct {
+	Pricing       map[string]ModelPriceConfig `json:"pricing,omitempty"`
+	DailyAlertUSD float64                     `json:"daily_alert_usd,omitempty" env:"PICOCLAW_USAGE_DAILY_ALERT_USD"` // 0 disables alerts
+	AlertChannel  string                      `json:"alert_channel,omitempty" env:"PICOCLAW_USAGE_ALERT_CHANNEL"`     // defaults to the last active channel
+	AlertChatID   string                      `json:"alert_chat_id,omitempty" env:"PICOCLAW_USAGE_ALERT_CHAT_ID"`
+}
+
+// ModelPriceConfig is a model's price in USD per million tokens. Zero
+// cached, cache-write and reasoning prices fall back to the defaults
+// derived from the input and output prices.
+type ModelPriceConfig struct {
+	Input       float64 `json:"input"`
+	Output      float64 `json:"output"`
+	CachedInput float64 `json:"cached_input,omitempty"`
+	CacheWrite  float64 `json:"cache_write,omitempty"`
+	Reasoning   float64 `json:"reasoning,omitempty"`
+}
+
 type DevicesConfig struct {
 	Enabled    bool `json:"enabled" env:"PICOCLAW_DEVICES_ENABLED"`
 	MonitorUSB bool `json:"monitor_usb" env:"PICOCLAW_DEVICES_MONITOR_USB"`
@@ -236,6 +258,48 @@ type ProviderConfig struct {
 	Proxy       string `json:"proxy,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_PROXY"`
 	AuthMethod  string `json:"auth_method,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_AUTH_METHOD"`
 	ConnectMode string `json:"connect_mode,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_CONNECT_MODE"` //only for Github Copilot, `stdio` or `grpc`
+
+	SafetySettings map[string]string `json:"safety_settings,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_SAFETY_SETTINGS"` // only for Gemini, harm category -> threshold
+
+	RateLimit RateLimitConfig `json:"rate_limit,omitempty"`
+}
+
+// RateLimitConfig caps how hard picooraclaw drives a provider. Zero values
+// are not enforced.
+type RateLimitConfig struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_RATE_LIMIT_RPM"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_RATE_LIMIT_TPM"`
+	MaxConcurrency    int `json:"max_concurrency,omitempty" env:"PICOCLAW_PROVIDERS_{{.Name}}_RATE_LIMIT_MAX_CONCURRENCY"`
+}
+
+// ByName returns the provider section for a provider name or alias as
+// accepted by agents.defaults.provider (e.g. "claude", "google", "copilot").
+func (p ProvidersConfig) ByName(name string) (ProviderConfig, bool) {
+	switch strings.ToLower(name) {
+	case "anthropic", "claude":
+		return p.Anthropic, true
+	case "openai", "gpt":
+		return p.OpenAI, true
+	case "openrouter":
+		return p.OpenRouter, true
+	case "groq":
+		return p.Groq, true
+	case "vllm":
+		return p.VLLM, true
+	case "gemini", "google":
+		return p.Gemini, true
+	case "nvidia":
+		return p.Nvidia, true
+	case "ollama":
+		return p.Ollama, true
+	case "moonshot":
+		return p.Moonshot, true
+	case "deepseek":
+		return p.DeepSeek, true
+	case "github_copilot", "copilot":
+		return p.GitHubCopilot, true
+	}
+	return ProviderConfig{}, false
 }
 
 type GatewayConfig struct {
@@ -260,19 +324,332 @@ type PerplexityConfig struct {
 	MaxResults int    `json:"max_results" env:"PICOCLAW_TOOLS_WEB_PERPLEXITY_MAX_RESULTS"`
 }
 
+// SearXNGConfig points web_search at a self-hosted SearXNG instance. The
+// instance must have "json" enabled in search.formats.
+type SearXNGConfig struct {
+	Enabled    bool   `json:"enabled" env:"PICOCLAW_TOOLS_WEB_SEARXNG_ENABLED"`
+	URL        string `json:"url" env:"PICOCLAW_TOOLS_WEB_SEARXNG_URL"`
+	Categories string `json:"categories,omitempty" env:"PICOCLAW_TOOLS_WEB_SEARXNG_CATEGORIES"`
+	Language   string `json:"language,omitempty" env:"PICOCLAW_TOOLS_WEB_SEARXNG_LANGUAGE"`
+	MaxResults int    `json:"max_results" env:"PICOCLAW_TOOLS_WEB_SEARXNG_MAX_RESULTS"`
+}
+
+// JSONSearchConfig describes any search API that answers in JSON. URL and
+// Body may contain {query} and {count}; ResultsPath and the field paths
+// use dotted paths such as "data.items" or "meta.published[0]".
+type JSONSearchConfig struct {
+	Enabled      bool              `json:"enabled" env:"PICOCLAW_TOOLS_WEB_JSON_ENABLED"`
+	Name         string            `json:"name,omitempty" env:"PICOCLAW_TOOLS_WEB_JSON_NAME"`
+	URL          string            `json:"url" env:"PICOCLAW_TOOLS_WEB_JSON_URL"`
+	Method       string            `json:"method,omitempty" env:"PICOCLAW_TOOLS_WEB_JSON_METHOD"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	ResultsPath  string            `json:"results_path" env:"PICOCLAW_TOOLS_WEB_JSON_RESULTS_PATH"`
+	TitleField   string            `json:"title_field,omitempty"`
+	URLField     string            `json:"url_field,omitempty"`
+	SnippetField string            `json:"snippet_field,omitempty"`
+	DateField    string            `json:"date_field,omitempty"`
+	MaxResults   int               `json:"max_results" env:"PICOCLAW_TOOLS_WEB_JSON_MAX_RESULTS"`
+}
+
+// WebFetchConfig tunes web_fetch. Responses are cached under the
+// workspace for CacheTTLMinutes and revalidated with their ETag after that;
+// 0 disables the cache.
+type WebFetchConfig struct {
+	MaxChars        int `json:"max_chars" env:"PICOCLAW_TOOLS_WEB_FETCH_MAX_CHARS"`
+	CacheTTLMinutes int `json:"cache_ttl_minutes" env:"PICOCLAW_TOOLS_WEB_FETCH_CACHE_TTL_MINUTES"`
+	CacheMaxMB      int `json:"cache_max_mb" env:"PICOCLAW_TOOLS_WEB_FETCH_CACHE_MAX_MB"`
+}
+
+// WebToolsConfig configures the web tools. Domain entries match the domain
+// and its subdomains. Private, loopback and link-local destinations are
+// refused, after redirects and DNS resolution too, unless listed in
+// AllowPrivate (host names, IPs or CIDRs).
+//
+// web_search tries the enabled providers in SearchOrder (default
+// perplexity, brave, searxng, json, duckduckgo), falling back to the next
+// one on errors or empty results, and caches answers for SearchCacheMinutes.
 type WebToolsConfig struct {
-	Brave      BraveConfig      `json:"brave"`
-	DuckDuckGo DuckDuckGoConfig `json:"duckduckgo"`
-	Perplexity PerplexityConfig `json:"perplexity"`
+	Brave              BraveConfig      `json:"brave"`
+	DuckDuckGo         DuckDuckGoConfig `json:"duckduckgo"`
+	Perplexity         PerplexityConfig `json:"perplexity"`
+	SearXNG            SearXNGConfig    `json:"searxng"`
+	JSONSearch         JSONSearchConfig `json:"json_search"`
+	SearchOrder        []string         `json:"search_order,omitempty"`
+	SearchCacheMinutes int              `json:"search_cache_minutes" env:"PICOCLAW_TOOLS_WEB_SEARCH_CACHE_MINUTES"`
+	Fetch              WebFetchConfig   `json:"fetch"`
+	AllowDomains       []string         `json:"allow_domains,omitempty"` // only these domains when set
+	DenyDomains        []string         `json:"deny_domains,omitempty"`
+	AllowPrivate       []string         `json:"allow_private,omitempty"`
 }
 
 type CronToolsConfig struct {
 	ExecTimeoutMinutes int `json:"exec_timeout_minutes" env:"PICOCLAW_TOOLS_CRON_EXEC_TIMEOUT_MINUTES"` // 0 means no timeout
 }
 
+// MCPServerConfig describes one Model Context Protocol server. Set Command
+// for a stdio server, or URL for a remote one.
+type MCPServerConfig struct {
+	Enabled   bool              `json:"enabled"`
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Transport string            `json:"transport,omitempty"` // "stdio", "http" (streamable HTTP) or "sse"; inferred when empty
+	Headers   map[string]string `json:"headers,omitempty"`
+	Timeout   int               `json:"timeout,omitempty"` // seconds per request, default 60
+
+	// Tools limits which server tools are mounted (all when empty).
+	Tools []string `json:"tools,omitempty"`
+	// SubagentTools lists the tools subagents may use; "*" allows all
+	// mounted tools. Subagents get none when empty.
+	SubagentTools []string `json:"subagent_tools,omitempty"`
+}
+
+// MCPServeConfig controls what `picooraclaw mcp serve` exposes. Tool names
+// are registry names; resource and prompt entries may use glob patterns
+// (e.g. "notes/*").
+type MCPServeConfig struct {
+	Tools     []string `json:"tools,omitempty"`                                                // exposed in addition to remember, recall and write_daily_note
+	Resources []string `json:"resources,omitempty"`                                            // all when empty
+	Prompts   []string `json:"prompts,omitempty"`                                              // all when empty
+	Listen    string   `json:"listen,omitempty" env:"PICOCLAW_TOOLS_MCP_SERVE_LISTEN"`         // HTTP address, default 127.0.0.1:18795
+	AuthToken string   `json:"auth_token,omitempty" env:"PICOCLAW_TOOLS_MCP_SERVE_AUTH_TOKEN"` // bearer token required over HTTP
+}
+
+type MCPConfig struct {
+	Servers map[string]MCPServerConfig `json:"servers,omitempty"`
+	Serve   MCPServeConfig             `json:"serve"`
+}
+
+// ExecSandboxConfig runs exec commands in fresh Linux user, mount, pid and
+// network namespaces. The workspace is mounted read-write, ReadOnlyPaths
+// read-only and everything else is hidden. Limits of 0 mean unlimited.
+type ExecSandboxConfig struct {
+	Enabled       bool     `json:"enabled" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_ENABLED"`
+	Network       bool     `json:"network" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_NETWORK"` // keep host networking
+	ReadOnlyPaths []string `json:"read_only_paths,omitempty"`                         // defaults to the system directories (/usr, /bin, /lib, /etc, ...)
+	CPUSeconds    int      `json:"cpu_seconds" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_CPU_SECONDS"`
+	MemoryMB      int      `json:"memory_mb" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_MEMORY_MB"`         // address space limit
+	MaxProcesses  int      `json:"max_processes" env:"PICOCLAW_TOOLS_EXEC_SANDBOX_MAX_PROCESSES"` // counts every process of the host user
+}
+
+// ExecRuleConfig matches commands by exactly one of its fields. Prefix
+// matches whole leading words ("git status"), Regex is a Go regular
+// expression and Binary is a program name, found in every command of a
+// pipeline or list ("rm" also catches "/bin/rm -r x" and "env rm x").
+type ExecRuleConfig struct {
+	Prefix string `json:"prefix,omitempty"`
+	Regex  string `json:"regex,omitempty"`
+	Binary string `json:"binary,omitempty"`
+}
+
+// ExecPolicyConfig is one layer of exec rules. With an allow list, every
+// command of a pipeline or list has to match an allow rule.
+type ExecPolicyConfig struct {
+	Allow          []ExecRuleConfig `json:"allow,omitempty"`
+	Deny           []ExecRuleConfig `json:"deny,omitempty"`
+	BuiltinDeny    *bool            `json:"builtin_deny,omitempty"`    // built-in dangerous command patterns, on unless false
+	AllowedDirs    []string         `json:"allowed_dirs,omitempty"`    // working directories, relative to the workspace or absolute
+	EnvAllow       []string         `json:"env_allow,omitempty"`       // only these variables reach commands (glob patterns)
+	EnvDeny        []string         `json:"env_deny,omitempty"`        // variables removed from the environment (glob patterns)
+	TimeoutSeconds int              `json:"timeout_seconds,omitempty"` // 0 keeps the tool default
+}
+
+// ExecPolicyOverride is applied on top of the base policy when every match
+// field it sets equals the caller's agent ("main" or "subagent"), channel,
+// chat ID or sender ID. Allow, BuiltinDeny, AllowedDirs, EnvAllow and
+// TimeoutSeconds replace the values below when set; Deny and EnvDeny add
+// to them. Overrides apply in order.
+type ExecPolicyOverride struct {
+	Agent   string `json:"agent,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	ChatID  string `json:"chat_id,omitempty"`
+	Sender  string `json:"sender,omitempty"`
+	ExecPolicyConfig
+}
+
+type ExecToolsConfig struct {
+	ExecPolicyConfig
+	Overrides []ExecPolicyOverride `json:"overrides,omitempty"`
+	Sandbox   ExecSandboxConfig    `json:"sandbox"`
+}
+
+// ProcessToolsConfig bounds the background processes of the process tool.
+// A chat's processes are killed after IdleTimeoutMinutes without process
+// tool calls or when the agent shuts down.
+type ProcessToolsConfig struct {
+	MaxPerSession      int `json:"max_per_session" env:"PICOCLAW_TOOLS_PROCESS_MAX_PER_SESSION"`           // running processes per chat
+	BufferKB           int `json:"buffer_kb" env:"PICOCLAW_TOOLS_PROCESS_BUFFER_KB"`                       // output kept per process
+	IdleTimeoutMinutes int `json:"idle_timeout_minutes" env:"PICOCLAW_TOOLS_PROCESS_IDLE_TIMEOUT_MINUTES"` // processes of a chat without process tool calls this long are killed
+}
+
+// ToolOutputConfig controls spilling of oversized tool results to scratch
+// files that read_tool_output pages through. The files are kept in the user
+// cache directory, outside the workspace, and a chat's files are deleted
+// after IdleTimeoutMinutes without tool calls or when the agent shuts down.
+type ToolOutputConfig struct {
+	SpillEnabled       bool `json:"spill_enabled" env:"PICOCLAW_TOOLS_OUTPUT_SPILL_ENABLED"`
+	SpillBytes         int  `json:"spill_bytes" env:"PICOCLAW_TOOLS_OUTPUT_SPILL_BYTES"`                   // results longer than this are saved to a file
+	PreviewBytes       int  `json:"preview_bytes" env:"PICOCLAW_TOOLS_OUTPUT_PREVIEW_BYTES"`               // head and tail shown in place of a saved result
+	IdleTimeoutMinutes int  `json:"idle_timeout_minutes" env:"PICOCLAW_TOOLS_OUTPUT_IDLE_TIMEOUT_MINUTES"` // saved results of a chat without tool calls this long are deleted
+}
+
+// GitRemoteConfig allows the git tool to push to a remote. URL matches the
+// remote URL exactly, or as a prefix when it ends in "/". Token is sent as
+// the HTTP password.
+type GitRemoteConfig struct {
+	URL      string `json:"url"`
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token,omitempty"`
+}
+
+// GitToolsConfig configures the git tool. Commits are authored as
+// AuthorName <AuthorEmail>, or from the repository's git config when
+// those are empty. push is refused unless the remote is in PushRemotes.
+type GitToolsConfig struct {
+	Enabled     bool              `json:"enabled" env:"PICOCLAW_TOOLS_GIT_ENABLED"`
+	AuthorName  string            `json:"author_name" env:"PICOCLAW_TOOLS_GIT_AUTHOR_NAME"`
+	AuthorEmail string            `json:"author_email" env:"PICOCLAW_TOOLS_GIT_AUTHOR_EMAIL"`
+	PushRemotes []GitRemoteConfig `json:"push_remotes,omitempty"`
+}
+
+// HTTPProfileConfig is a named credential profile for http_request. The
+// model refers to it by name; its headers and credentials are added to
+// requests for AllowHosts (host names or IPs, subdomains included, private
+// addresses allowed), which default to the BaseURL host.
+type HTTPProfileConfig struct {
+	BaseURL     string            `json:"base_url,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Password    string            `json:"password,omitempty"`
+	AllowHosts  []string          `json:"allow_hosts,omitempty"`
+}
+
+// HTTPToolsConfig configures http_request. Requests without a profile
+// follow the tools.web network policy; RequireProfile refuses them.
+type HTTPToolsConfig struct {
+	Enabled        bool                         `json:"enabled" env:"PICOCLAW_TOOLS_HTTP_ENABLED"`
+	TimeoutSeconds int                          `json:"timeout_seconds" env:"PICOCLAW_TOOLS_HTTP_TIMEOUT_SECONDS"`
+	MaxResponseKB  int                          `json:"max_response_kb" env:"PICOCLAW_TOOLS_HTTP_MAX_RESPONSE_KB"`
+	RequireProfile bool                         `json:"require_profile" env:"PICOCLAW_TOOLS_HTTP_REQUIRE_PROFILE"`
+	Profiles       map[string]HTTPProfileConfig `json:"profiles,omitempty"`
+}
+
+// SQLToolsConfig configures sql_query. It needs oracle.enabled and runs
+// on its own connection, which should log in as a user that only has
+// SELECT grants; the oracle host, port, service and wallet are reused.
+// Schemas lists the owners describe_table and list_tables may inspect.
+type SQLToolsConfig struct {
+	Enabled        bool     `json:"enabled" env:"PICOCLAW_TOOLS_SQL_ENABLED"`
+	User           string   `json:"user" env:"PICOCLAW_TOOLS_SQL_USER"`
+	Password       string   `json:"password" env:"PICOCLAW_TOOLS_SQL_PASSWORD"`
+	DSN            string   `json:"dsn" env:"PICOCLAW_TOOLS_SQL_DSN"`
+	MaxRows        int      `json:"max_rows" env:"PICOCLAW_TOOLS_SQL_MAX_ROWS"`
+	TimeoutSeconds int      `json:"timeout_seconds" env:"PICOCLAW_TOOLS_SQL_TIMEOUT_SECONDS"`
+	Schemas        []string `json:"schemas" env:"PICOCLAW_TOOLS_SQL_SCHEMAS"`
+}
+
+// ToolSettings are the settings every tool accepts under tools.<name>,
+// next to any tool-specific fields of that section. Enabled false removes
+// the tool; unset keeps its default. TimeoutSeconds and MaxOutputBytes
+// bound a call and its result; Options go to tools that take them.
+type ToolSettings struct {
+	Enabled        *bool                  `json:"enabled,omitempty"`
+	TimeoutSeconds int                    `json:"timeout_seconds,omitempty"`
+	MaxOutputBytes int                    `json:"max_output_bytes,omitempty"`
+	Options        map[string]interface{} `json:"options,omitempty"`
+}
+
+func (s ToolSettings) isZero() bool {
+	return s.Enabled == nil && s.TimeoutSeconds == 0 && s.MaxOutputBytes == 0 && len(s.Options) == 0
+}
+
 type ToolsConfig struct {
-	Web  WebToolsConfig  `json:"web"`
-	Cron CronToolsConfig `json:"cron"`
+	Web     WebToolsConfig     `json:"web"`
+	Cron    CronToolsConfig    `json:"cron"`
+	Exec    ExecToolsConfig    `json:"exec"`
+	Process ProcessToolsConfig `json:"process"`
+	Output  ToolOutputConfig   `json:"output"`
+	Git     GitToolsConfig     `json:"git"`
+	HTTP    HTTPToolsConfig    `json:"http"`
+	SQL     SQLToolsConfig     `json:"sql"`
+	MCP     MCPConfig          `json:"mcp"`
+
+	// Settings holds the generic settings of every tools.<name> object,
+	// keyed by that name: a tool name, or a section above such as "web",
+	// which the agent applies to the tools it configures. It is read from
+	// and written to the same objects as the sections above.
+	Settings map[string]ToolSettings `json:"-"`
+}
+
+// Setting returns the generic settings of a tool.
+func (t *ToolsConfig) Setting(name string) ToolSettings {
+	return t.Settings[name]
+}
+
+func (t *ToolsConfig) UnmarshalJSON(data []byte) error {
+	type plain ToolsConfig
+	if err := json.Unmarshal(data, (*plain)(t)); err != nil {
+		return err
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return err
+	}
+	for name, raw := range sections {
+		var s ToolSettings
+		if err := json.Unmarshal(raw, &s); err != nil {
+			// Not an object, or a section whose fields only share names
+			// with the generic ones (e.g. a non-boolean "enabled").
+			continue
+		}
+		if s.isZero() {
+			continue
+		}
+		if t.Settings == nil {
+			t.Settings = make(map[string]ToolSettings)
+		}
+		t.Settings[name] = s
+	}
+	return nil
+}
+
+func (t ToolsConfig) MarshalJSON() ([]byte, error) {
+	type plain ToolsConfig
+	data, err := json.Marshal(plain(t))
+	if err != nil || len(t.Settings) == 0 {
+		return data, err
+	}
+	var sections map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, err
+	}
+	for name, s := range t.Settings {
+		section := make(map[string]json.RawMessage)
+		if raw, ok := sections[name]; ok {
+			if err := json.Unmarshal(raw, &section); err != nil {
+				return nil, fmt.Errorf("tools.%s: %w", name, err)
+			}
+		}
+		generic, err := json.Marshal(s)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]json.RawMessage
+		json.Unmarshal(generic, &fields)
+		for k, v := range fields {
+			if _, ok := section[k]; !ok {
+				section[k] = v
+			}
+		}
+		if sections[name], err = json.Marshal(section); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(sections)
 }
 
 func DefaultConfig() *Config {
@@ -390,10 +767,57 @@ func DefaultConfig() *Config {
 					APIKey:     "",
 					MaxResults: 5,
 				},
+				SearXNG: SearXNGConfig{
+					Enabled:    false,
+					MaxResults: 5,
+				},
+				JSONSearch: JSONSearchConfig{
+					Enabled:    false,
+					MaxResults: 5,
+				},
+				SearchCacheMinutes: 10,
+				Fetch: WebFetchConfig{
+					MaxChars:        50000,
+					CacheTTLMinutes: 15,
+					CacheMaxMB:      50,
+				},
 			},
 			Cron: CronToolsConfig{
 				ExecTimeoutMinutes: 5,
 			},
+			Exec: ExecToolsConfig{
+				Sandbox: ExecSandboxConfig{
+					Enabled:    false,
+					CPUSeconds: 60,
+					MemoryMB:   2048,
+				},
+			},
+			Process: ProcessToolsConfig{
+				MaxPerSession:      4,
+				BufferKB:           256,
+				IdleTimeoutMinutes: 60,
+			},
+			Output: ToolOutputConfig{
+				SpillEnabled:       true,
+				SpillBytes:         16000,
+				PreviewBytes:       2000,
+				IdleTimeoutMinutes: 1440,
+			},
+			Git: GitToolsConfig{
+				Enabled:     true,
+				AuthorName:  "picooraclaw",
+				AuthorEmail: "picooraclaw@localhost",
+			},
+			HTTP: HTTPToolsConfig{
+				Enabled:        true,
+				TimeoutSeconds: 30,
+				MaxResponseKB:  1024,
+			},
+			SQL: SQLToolsConfig{
+				MaxRows:        200,
+				TimeoutSeconds: 30,
+				Schemas:        []string{},
+			},
 		},
 		Heartbeat: HeartbeatConfig{
 			Enabled:  true,
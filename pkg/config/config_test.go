@@ -1,6 +1,7 @@
 package config
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -195,3 +196,46 @@ func TestConfig_Complete(t *testing.T) {
 		t.Error("Heartbeat should be enabled by default")
 	}
 }
+
+func TestToolsConfig_Settings(t *testing.T) {
+	cfg := DefaultConfig()
+	data := []byte(`{
+		"exec": {"timeout_seconds": 120, "deny": [{"pattern": "reboot"}]},
+		"i2c": {"enabled": true, "options": {"buses": [1]}},
+		"web_fetch": {"enabled": false, "max_output_bytes": 8000},
+		"git": {"author_name": "bot"}
+	}`)
+	if err := json.Unmarshal(data, &cfg.Tools); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Tools.Exec.TimeoutSeconds != 120 || len(cfg.Tools.Exec.Deny) != 1 || cfg.Tools.Git.AuthorName != "bot" {
+		t.Errorf("section fields not decoded: %+v", cfg.Tools.Exec)
+	}
+	if !cfg.Tools.Git.Enabled || cfg.Tools.Process.MaxPerSession != 4 {
+		t.Error("defaults should survive decoding")
+	}
+	if s := cfg.Tools.Setting("exec"); s.TimeoutSeconds != 120 {
+		t.Errorf("exec settings = %+v", s)
+	}
+	if s := cfg.Tools.Setting("web_fetch"); s.Enabled == nil || *s.Enabled || s.MaxOutputBytes != 8000 {
+		t.Errorf("web_fetch settings = %+v", s)
+	}
+	if _, ok := cfg.Tools.Settings["git"]; ok {
+		t.Error("sections without generic settings should not get an entry")
+	}
+
+	out, err := json.Marshal(cfg.Tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var round ToolsConfig
+	if err := json.Unmarshal(out, &round); err != nil {
+		t.Fatal(err)
+	}
+	if s := round.Setting("i2c"); s.Enabled == nil || !*s.Enabled || s.Options["buses"] == nil {
+		t.Errorf("i2c settings lost on save: %s", out)
+	}
+	if round.Exec.TimeoutSeconds != 120 || round.Setting("web_fetch").MaxOutputBytes != 8000 {
+		t.Errorf("settings lost on save: %s", out)
+	}
+}
@@ -0,0 +1,47 @@
+package docs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+func extractCSV(data []byte) (*Document, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = sniffDelimiter(data)
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+
+	doc := &Document{}
+	var rows [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) > MaxRows {
+			doc.Truncated = true
+			break
+		}
+		rows = append(rows, rec)
+	}
+	doc.Text = MarkdownTable(rows)
+	return doc, nil
+}
+
+// sniffDelimiter picks the most common of , ; and tab in the first line.
+func sniffDelimiter(data []byte) rune {
+	line, _, _ := strings.Cut(string(data[:min(len(data), 4096)]), "\n")
+	best, bestCount := ',', 0
+	for _, d := range []rune{',', ';', '\t'} {
+		if n := strings.Count(line, string(d)); n > bestCount {
+			best, bestCount = d, n
+		}
+	}
+	return best
+}
@@ -0,0 +1,285 @@
+// Package docs extracts text from documents (PDF, DOCX, XLSX, CSV and
+// HTML) as markdown, without external tools or cgo.
+package docs
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats understood by Extract.
+const (
+	FormatPDF  = "pdf"
+	FormatDOCX = "docx"
+	FormatXLSX = "xlsx"
+	FormatCSV  = "csv"
+	FormatHTML = "html"
+)
+
+// MaxRows caps the rows converted per sheet or CSV file.
+const MaxRows = 1000
+
+// Options select what to extract.
+type Options struct {
+	// Pages selects PDF pages or XLSX sheets, e.g. "1-3,5" or "4-".
+	// Empty means all.
+	Pages string
+}
+
+// Document is the text extracted from a file.
+type Document struct {
+	Format string
+	Title  string
+	Text   string // markdown
+
+	Pages  int      // page count for PDF (and DOCX when the file records it)
+	Sheets []string // sheet names for XLSX
+
+	// Selected lists the extracted pages or sheets (1-based) when only part
+	// of the document was extracted.
+	Selected []int
+
+	// Truncated is set when rows were dropped to stay within MaxRows.
+	Truncated bool
+}
+
+// Summary describes the document in one line, e.g.
+// "PDF, 12 pages (extracted pages 1-3)".
+func (d *Document) Summary() string {
+	parts := []string{strings.ToUpper(d.Format)}
+	switch {
+	case d.Format == FormatPDF || (d.Format == FormatDOCX && d.Pages > 0):
+		parts = append(parts, plural(d.Pages, "page"))
+	case d.Format == FormatXLSX:
+		parts = append(parts, fmt.Sprintf("%s: %s", plural(len(d.Sheets), "sheet"), strings.Join(d.Sheets, ", ")))
+	}
+	s := strings.Join(parts, ", ")
+	if len(d.Selected) > 0 {
+		unit := "pages"
+		if d.Format == FormatXLSX {
+			unit = "sheets"
+		}
+		s += fmt.Sprintf(" (extracted %s %s)", unit, FormatRange(d.Selected))
+	}
+	if d.Truncated {
+		s += fmt.Sprintf(" (tables cut at %d rows)", MaxRows)
+	}
+	return s
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// Detect returns the format of a file from its name and first bytes, or ""
+// if it is not a document Extract handles.
+func Detect(name string, head []byte) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		switch ext {
+		case ".docx", ".docm":
+			return FormatDOCX
+		case ".xlsx", ".xlsm":
+			return FormatXLSX
+		}
+		// Office files without a useful name: look at the zip entries.
+		if bytes.Contains(head, []byte("word/")) {
+			return FormatDOCX
+		}
+		if bytes.Contains(head, []byte("xl/")) {
+			return FormatXLSX
+		}
+		return ""
+	}
+	switch ext {
+	case ".csv", ".tsv":
+		return FormatCSV
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML
+	}
+	if strings.HasPrefix(http.DetectContentType(head), "text/html") {
+		return FormatHTML
+	}
+	return ""
+}
+
+// DetectMediaType maps a MIME type to a format, or "".
+func DetectMediaType(mediaType string) string {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.SplitN(mediaType, ";", 2)[0]))
+	switch mediaType {
+	case "application/pdf", "application/x-pdf":
+		return FormatPDF
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return FormatDOCX
+	case "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return FormatXLSX
+	case "text/csv", "text/tab-separated-values":
+		return FormatCSV
+	case "text/html", "application/xhtml+xml":
+		return FormatHTML
+	}
+	return ""
+}
+
+// Extract converts data in the given format to markdown.
+func Extract(data []byte, format string, opts Options) (doc *Document, err error) {
+	// The parsers read untrusted attachments; a malformed file must not take
+	// down the caller.
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("%s: malformed document: %v", format, r)
+		}
+	}()
+	switch format {
+	case FormatPDF:
+		doc, err = extractPDF(data, opts)
+	case FormatDOCX:
+		if opts.Pages != "" {
+			return nil, fmt.Errorf("page ranges are not supported for DOCX files")
+		}
+		doc, err = extractDOCX(data)
+	case FormatXLSX:
+		doc, err = extractXLSX(data, opts)
+	case FormatCSV:
+		doc, err = extractCSV(data)
+	case FormatHTML:
+		doc = extractHTML(data)
+	default:
+		return nil, fmt.Errorf("unsupported document format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", format, err)
+	}
+	doc.Format = format
+	return doc, nil
+}
+
+// ExtractFile detects the format of a file and extracts it.
+func ExtractFile(path string, opts Options) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := Detect(path, data)
+	if format == "" {
+		return nil, fmt.Errorf("%s is not a supported document", filepath.Base(path))
+	}
+	return Extract(data, format, opts)
+}
+
+// ParseRange parses a 1-based selection like "1-3,5,8-" against n items.
+// An empty spec selects everything.
+func ParseRange(spec string, n int) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i + 1
+		}
+		return all, nil
+	}
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		if i := strings.IndexByte(part, '-'); i >= 0 {
+			lo, hi = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+		from, to := 1, n
+		var err error
+		if lo != "" {
+			if from, err = strconv.Atoi(lo); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		if hi != "" {
+			if to, err = strconv.Atoi(hi); err != nil {
+				return nil, fmt.Errorf("invalid range %q", part)
+			}
+		}
+		if from < 1 || from > to {
+			return nil, fmt.Errorf("invalid range %q", part)
+		}
+		if from > n {
+			return nil, fmt.Errorf("range %q is past the end (%d)", part, n)
+		}
+		for i := from; i <= min(to, n); i++ {
+			seen[i] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("empty range %q", spec)
+	}
+	out := make([]int, 0, len(seen))
+	for i := range seen {
+		out = append(out, i)
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// FormatRange is the inverse of ParseRange: [1 2 3 5] becomes "1-3,5".
+func FormatRange(items []int) string {
+	var parts []string
+	for i := 0; i < len(items); {
+		j := i
+		for j+1 < len(items) && items[j+1] == items[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", items[i], items[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(items[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// MarkdownTable renders rows with the first row as the header.
+func MarkdownTable(rows [][]string) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r))
+	}
+	if width == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	writeRow := func(r []string) {
+		sb.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(r) {
+				cell = r[i]
+			}
+			cell = strings.ReplaceAll(cell, "|", `\|`)
+			cell = strings.Join(strings.Fields(cell), " ")
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+	}
+	writeRow(rows[0])
+	sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, r := range rows[1:] {
+		writeRow(r)
+	}
+	return sb.String()
+}
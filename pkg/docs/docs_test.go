@@ -0,0 +1,285 @@
+package docs
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		spec string
+		n    int
+		want []int
+	}{
+		{"", 3, []int{1, 2, 3}},
+		{"2", 5, []int{2}},
+		{"1-3,5", 6, []int{1, 2, 3, 5}},
+		{"4-", 6, []int{4, 5, 6}},
+		{"-2, 2-3", 6, []int{1, 2, 3}},
+		{"2-99", 4, []int{2, 3, 4}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRange(tt.spec, tt.n)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseRange(%q, %d) = %v, %v; want %v", tt.spec, tt.n, got, err, tt.want)
+		}
+	}
+	for _, spec := range []string{"0", "3-1", "x", "7"} {
+		if _, err := ParseRange(spec, 6); err == nil {
+			t.Errorf("ParseRange(%q) should fail", spec)
+		}
+	}
+	if got := FormatRange([]int{1, 2, 3, 5, 7, 8}); got != "1-3,5,7-8" {
+		t.Errorf("FormatRange = %q", got)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"a.bin", "%PDF-1.7\n", FormatPDF},
+		{"report.docx", "PK\x03\x04", FormatDOCX},
+		{"data.xlsx", "PK\x03\x04", FormatXLSX},
+		{"upload", "PK\x03\x04....xl/workbook.xml", FormatXLSX},
+		{"a.zip", "PK\x03\x04", ""},
+		{"a.tsv", "x\ty", FormatCSV},
+		{"page", "<!DOCTYPE html><html>", FormatHTML},
+		{"notes.txt", "hello", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.name, []byte(tt.head)); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtractCSV(t *testing.T) {
+	doc, err := Extract([]byte("\xef\xbb\xbfname;qty\nwidget;3\n\"a;b\";4\n"), FormatCSV, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "| name | qty |\n| --- | --- |\n| widget | 3 |\n| a;b | 4 |\n"
+	if doc.Text != want {
+		t.Errorf("text =\n%s", doc.Text)
+	}
+
+	var big strings.Builder
+	for i := 0; i < MaxRows+10; i++ {
+		fmt.Fprintf(&big, "%d,x\n", i)
+	}
+	doc, _ = Extract([]byte(big.String()), FormatCSV, Options{})
+	if !doc.Truncated || !strings.Contains(doc.Summary(), "cut at") {
+		t.Errorf("expected truncation: %s", doc.Summary())
+	}
+}
+
+func TestExtractHTML(t *testing.T) {
+	page := `<html><head><title>Docs  Page</title><style>p{}</style></head><body>
+<nav><a href="/">Home</a></nav>
+<h1>Install</h1>
+<p>Run <code>make</code> then <b>restart</b> the <a href="https://example.com/x">service</a>.</p>
+<ul><li>one</li><li>two<ol><li>nested</li></ol></li></ul>
+<pre>line 1
+  line 2</pre>
+<table><tr><th>Key</th><th>Value</th></tr><tr><td>a</td><td>1</td></tr></table>
+<script>alert(1)</script>
+</body></html>`
+	doc, err := Extract([]byte(page), FormatHTML, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Docs Page" {
+		t.Errorf("title = %q", doc.Title)
+	}
+	for _, want := range []string{
+		"# Install",
+		"Run `make` then **restart** the [service](https://example.com/x).",
+		"- one\n- two\n  1. nested",
+		"```\nline 1\n  line 2\n```",
+		"| Key | Value |\n| --- | --- |\n| a | 1 |",
+	} {
+		if !strings.Contains(doc.Text, want) {
+			t.Errorf("missing %q in:\n%s", want, doc.Text)
+		}
+	}
+	if strings.Contains(doc.Text, "alert") || strings.Contains(doc.Text, "p{}") {
+		t.Errorf("script or style leaked:\n%s", doc.Text)
+	}
+}
+
+func zipFile(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractDOCX(t *testing.T) {
+	data := zipFile(t, map[string]string{
+		"docProps/app.xml": `<Properties><Pages>2</Pages></Properties>`,
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Quarterly</w:t></w:r><w:r><w:t xml:space="preserve"> report</w:t></w:r></w:p>
+<w:p><w:r><w:t>Revenue grew.</w:t><w:tab/><w:t>Costs fell.</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>First point</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>EMEA</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>42</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`,
+	})
+	if got := Detect("", data); got != FormatDOCX {
+		t.Errorf("Detect = %q", got)
+	}
+	doc, err := Extract(data, FormatDOCX, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Quarterly report\n\nRevenue grew.\tCosts fell.\n\n- First point\n\n| Region | Sales |\n| --- | --- |\n| EMEA | 42 |\n"
+	if doc.Text != want {
+		t.Errorf("text =\n%q", doc.Text)
+	}
+	if doc.Summary() != "DOCX, 2 pages" {
+		t.Errorf("summary = %q", doc.Summary())
+	}
+	if _, err := Extract(data, FormatDOCX, Options{Pages: "1"}); err == nil {
+		t.Error("page ranges should be rejected for DOCX")
+	}
+}
+
+func TestExtractXLSX(t *testing.T) {
+	data := zipFile(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>
+<sheet name="Sales" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/other.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>Item</t></si><si><t>Date</t></si><si><r><t>Wid</t></r><r><t>get</t></r></si></sst>`,
+		"xl/styles.xml":              `<styleSheet><cellXfs><xf numFmtId="0"/><xf numFmtId="14"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>Ok</t></is></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" s="1"><v>45292</v></c><c r="C2" t="b"><v>1</v></c></row>
+<row r="4"><c r="B4"><v>3.5</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/other.xml": `<worksheet><sheetData><row r="1"><c r="1" t="str"><v>memo</v></c><c r="AAAAAAAAAAAAAAA1"><v>9</v></c></row></sheetData></worksheet>`,
+	})
+	doc, err := Extract(data, FormatXLSX, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Sales\n\n| Item | Date | Ok |\n| --- | --- | --- |\n| Widget | 2024-01-01 | TRUE |\n|  |  |  |\n|  | 3.5 |  |\n\n## Notes\n\n| memo |\n| --- |\n"
+	if doc.Text != want {
+		t.Errorf("text =\n%q", doc.Text)
+	}
+	if doc.Summary() != "XLSX, 2 sheets: Sales, Notes" {
+		t.Errorf("summary = %q", doc.Summary())
+	}
+
+	doc, err = Extract(data, FormatXLSX, Options{Pages: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(doc.Text, "Sales\n") || !strings.Contains(doc.Text, "memo") {
+		t.Errorf("sheet selection ignored:\n%s", doc.Text)
+	}
+	if !strings.HasSuffix(doc.Summary(), "(extracted sheets 2)") {
+		t.Errorf("summary = %q", doc.Summary())
+	}
+}
+
+// buildPDF assembles a PDF from object bodies; object i+1 is objs[i].
+func buildPDF(objs ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.5\n%\xe2\xe3\xcf\xd3\n")
+	for i, o := range objs {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R /Info 2 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func flateStream(content string) string {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte(content))
+	zw.Close()
+	return fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", buf.Len(), buf.String())
+}
+
+func TestExtractPDF(t *testing.T) {
+	toUnicode := "/CIDInit /ProcSet findresource begin\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0001> <0048> <0002> <0069> endbfchar\n1 beginbfrange <0010> <0012> <0061> endbfrange\nendcmap"
+	data := buildPDF(
+		"<< /Type /Catalog /Pages 3 0 R >>",
+		"<< /Title (Test \\(doc\\)) >>",
+		"<< /Type /Pages /Kids [4 0 R 5 0 R] /Count 2 /Resources << /Font << /F1 6 0 R /F2 8 0 R >> >> >>",
+		"<< /Type /Page /Parent 3 0 R /Contents 7 0 R >>",
+		"<< /Type /Page /Parent 3 0 R /Contents [9 0 R 10 0 R] /Resources << /Font << /F1 6 0 R /F2 8 0 R >> /XObject << /X1 12 0 R >> >> >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding << /Differences [65 /eacute /fi] >> >>",
+		flateStream("BT /F1 12 Tf 72 720 Td (Hello) Tj [(W) 120 (orld) -300 (again)] TJ 0 -14 Td (Caf) Tj (A) Tj ( Bnd) Tj ET"),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /X /ToUnicode 11 0 R >>",
+		"<< /Length 44 >>\nstream\nBT /F2 10 Tf 1 0 0 1 50 700 Tm <00010002> Tj\nendstream",
+		"<< /Length 67 >>\nstream\n1 0 0 1 50 680 Tm <001000110012> Tj ET BI /W 1 /H 1 ID \x00EI\x01 EI /X1 Do\nendstream",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(toUnicode), toUnicode),
+		"<< /Type /XObject /Subtype /Form /Length 30 >>\nstream\nBT /F1 9 Tf 0 0 Td (form) Tj ET\nendstream",
+	)
+
+	doc, err := Extract(data, FormatPDF, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "## Page 1\n\nHelloWorld again\nCafé find\n\n## Page 2\n\nHi\nabc\nform\n"
+	if doc.Text != want {
+		t.Errorf("text =\n%q", doc.Text)
+	}
+	if doc.Pages != 2 || doc.Title != "Test (doc)" {
+		t.Errorf("pages = %d, title = %q", doc.Pages, doc.Title)
+	}
+
+	doc, err = Extract(data, FormatPDF, Options{Pages: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(doc.Text, "Hello") || !strings.Contains(doc.Text, "## Page 2") {
+		t.Errorf("page selection ignored:\n%s", doc.Text)
+	}
+	if doc.Summary() != "PDF, 2 pages (extracted pages 2)" {
+		t.Errorf("summary = %q", doc.Summary())
+	}
+
+	encrypted := buildPDF("<< /Type /Catalog >>", "<< >>")
+	encrypted = bytes.Replace(encrypted, []byte("/Info 2 0 R"), []byte("/Encrypt 2 0 R"), 1)
+	if _, err := Extract(encrypted, FormatPDF, Options{}); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("expected encrypted error, got %v", err)
+	}
+}
+
+func FuzzExtractPDF(f *testing.F) {
+	f.Add(buildPDF(
+		"<< /Type /Catalog /Pages 3 0 R >>",
+		"<< /Title <54657374> >>",
+		"<< /Type /Pages /Kids [4 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 3 0 R /Contents 5 0 R /Resources << /Font << /F1 6 0 R >> >> >>",
+		flateStream("BT /F1 12 Tf 72 720 Td (Hello) Tj [(W) 120 (orld)] TJ <414243> Tj ET"),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	))
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /A <"))
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /Length 99 >>\nstream"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Call the parser directly so Extract's recover cannot hide a panic.
+		extractPDF(data, Options{})
+	})
+}
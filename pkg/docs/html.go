@@ -0,0 +1,523 @@
+package docs
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func extractHTML(data []byte) *Document {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		// html.Parse only fails on reader errors; fall back to the raw text.
+		return &Document{Text: string(data)}
+	}
+	return &Document{Title: HTMLTitle(root), Text: HTMLToMarkdown(root)}
+}
+
+// HTMLTitle returns the text of the document's <title>.
+func HTMLTitle(root *html.Node) string {
+	var title string
+	var find func(*html.Node) bool
+	find = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Title {
+			title = strings.Join(strings.Fields(nodeText(n)), " ")
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if find(c) {
+				return true
+			}
+		}
+		return false
+	}
+	find(root)
+	return title
+}
+
+// HTMLToMarkdown renders a parsed HTML tree as markdown: headings, lists,
+// links, code blocks and tables are kept; scripts, styles and form
+// controls are dropped.
+func HTMLToMarkdown(root *html.Node) string {
+	w := &mdWriter{}
+	w.render(root)
+	return w.String()
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// Readable returns the main content of a page as markdown, leaving out
+// navigation, sidebars, footers and other page furniture the way a
+// browser's reader mode does. Links and images are resolved against base
+// when it is not nil.
+func Readable(root *html.Node, base *url.URL) string {
+	body := findElement(root, atom.Body)
+	if body == nil {
+		body = root
+	}
+	w := &mdWriter{base: base, readable: true}
+	w.render(mainContent(body))
+	if text := w.String(); strings.TrimSpace(text) != "" {
+		return text
+	}
+	// Everything looked like furniture; show the whole page instead.
+	w = &mdWriter{base: base}
+	w.render(body)
+	return w.String()
+}
+
+var (
+	unlikelyContent = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cookie|consent|disqus|extra|footer|gdpr|header|menu|modal|nav|pager|pagination|popup|promo|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|subscribe`)
+	maybeContent    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positiveContent = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|post|text|blog|story`)
+	negativeContent = regexp.MustCompile(`(?i)hidden|banner|combx|comment|com-|contact|foot|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+
+	furnitureRoles = map[string]bool{"navigation": true, "complementary": true, "banner": true, "contentinfo": true, "dialog": true, "menu": true}
+)
+
+// furniture reports whether an element is page furniture rather than
+// content: navigation, asides, footers, forms, or anything whose class or
+// id says so.
+func furniture(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Nav, atom.Aside, atom.Footer, atom.Form:
+		return true
+	case atom.Body, atom.Article, atom.Main:
+		return false
+	}
+	if furnitureRoles[attr(n, "role")] {
+		return true
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyContent.MatchString(names) && !maybeContent.MatchString(names)
+}
+
+// mainContent picks the element holding the page's main text, scoring
+// containers by the paragraphs inside them (the Readability heuristic).
+// It returns body when nothing stands out.
+func mainContent(body *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	credit := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = tagScore(n) + classWeight(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode || skipElements[c.DataAtom] || furniture(c) {
+				continue
+			}
+			if isParagraph(c) {
+				text := strings.Join(strings.Fields(nodeText(c)), " ")
+				if len(text) >= 25 {
+					score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+					credit(c.Parent, score)
+					if c.Parent != nil {
+						credit(c.Parent.Parent, score/2)
+					}
+				}
+			}
+			walk(c)
+		}
+	}
+	walk(body)
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range candidates {
+		if score := scores[n] * (1 - linkDensity(n)); best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil || len(strings.Join(strings.Fields(nodeText(best)), " ")) < 200 {
+		return body
+	}
+	return best
+}
+
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote, atom.Dd:
+		return true
+	case atom.Div, atom.Section:
+		// A div holding only inline content is used as a paragraph.
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (blockElements[c.DataAtom] || c.DataAtom == atom.Table ||
+				c.DataAtom == atom.Ul || c.DataAtom == atom.Ol || c.DataAtom == atom.Pre) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func tagScore(n *html.Node) float64 {
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		return 10
+	case atom.Div:
+		return 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		return 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		return -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		return -5
+	}
+	return 0
+}
+
+func classWeight(n *html.Node) float64 {
+	names := attr(n, "class") + " " + attr(n, "id")
+	weight := 0.0
+	if negativeContent.MatchString(names) {
+		weight -= 25
+	}
+	if positiveContent.MatchString(names) {
+		weight += 25
+	}
+	return weight
+}
+
+// linkDensity is the share of an element's text that is inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(strings.Join(strings.Fields(nodeText(n)), ""))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.DataAtom == atom.A {
+				links += len(strings.Join(strings.Fields(nodeText(c)), ""))
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+	return float64(links) / float64(total)
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// mdWriter accumulates markdown, collapsing whitespace like a browser.
+type mdWriter struct {
+	sb        strings.Builder
+	pre       int // depth of <pre> elements
+	listDepth int
+	ordered   []int    // item counter per list level; 0 for unordered
+	base      *url.URL // resolves relative links when set
+	readable  bool     // drop page furniture
+}
+
+// resolve makes a link absolute against the writer's base URL.
+func (w *mdWriter) resolve(ref string) string {
+	if w.base == nil {
+		return ref
+	}
+	u, err := w.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+func (w *mdWriter) String() string {
+	lines := strings.Split(w.sb.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")) + "\n"
+}
+
+func (w *mdWriter) text(s string) {
+	if w.pre > 0 {
+		w.sb.WriteString(s)
+		return
+	}
+	collapsed := strings.Join(strings.Fields(s), " ")
+	if collapsed == "" {
+		if s != "" && !w.atLineStart() && !strings.HasSuffix(w.sb.String(), " ") {
+			w.sb.WriteByte(' ')
+		}
+		return
+	}
+	if startsWithSpace(s) && !w.atLineStart() && !strings.HasSuffix(w.sb.String(), " ") {
+		w.sb.WriteByte(' ')
+	}
+	w.sb.WriteString(collapsed)
+	if endsWithSpace(s) {
+		w.sb.WriteByte(' ')
+	}
+}
+
+func startsWithSpace(s string) bool {
+	return s != "" && strings.ContainsRune(" \t\r\n\f", rune(s[0]))
+}
+
+func endsWithSpace(s string) bool {
+	return s != "" && strings.ContainsRune(" \t\r\n\f", rune(s[len(s)-1]))
+}
+
+func (w *mdWriter) atLineStart() bool {
+	s := w.sb.String()
+	return s == "" || strings.HasSuffix(s, "\n")
+}
+
+func (w *mdWriter) newline() {
+	if !w.atLineStart() {
+		w.sb.WriteByte('\n')
+	}
+}
+
+func (w *mdWriter) paragraph() {
+	w.newline()
+	if !strings.HasSuffix(w.sb.String(), "\n\n") && w.sb.Len() > 0 {
+		w.sb.WriteByte('\n')
+	}
+}
+
+var skipElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Head: true, atom.Svg: true, atom.Iframe: true,
+	atom.Button: true, atom.Select: true, atom.Input: true, atom.Textarea: true,
+	atom.Canvas: true, atom.Object: true,
+}
+
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Main: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Nav: true, atom.Blockquote: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Address: true, atom.Details: true,
+	atom.Summary: true,
+}
+
+func (w *mdWriter) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+	if skipElements[n.DataAtom] || hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return
+	}
+	if w.readable && furniture(n) {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.paragraph()
+		w.sb.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		w.sb.WriteString(strings.Join(strings.Fields(nodeText(n)), " "))
+		w.paragraph()
+	case atom.Br:
+		w.sb.WriteByte('\n')
+	case atom.Hr:
+		w.paragraph()
+		w.sb.WriteString("---")
+		w.paragraph()
+	case atom.Pre:
+		w.paragraph()
+		w.sb.WriteString("```\n")
+		w.pre++
+		w.children(n)
+		w.pre--
+		w.newline()
+		w.sb.WriteString("```")
+		w.paragraph()
+	case atom.Code:
+		if w.pre > 0 {
+			w.children(n)
+			return
+		}
+		w.sb.WriteString("`" + strings.TrimSpace(nodeText(n)) + "`")
+	case atom.Strong, atom.B:
+		w.inline(n, "**")
+	case atom.Em, atom.I:
+		w.inline(n, "_")
+	case atom.A:
+		text := strings.Join(strings.Fields(nodeText(n)), " ")
+		href := attr(n, "href")
+		if text == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			w.children(n)
+			return
+		}
+		if startsWithSpace(nodeText(n)) {
+			w.text(" ")
+		}
+		w.sb.WriteString("[" + text + "](" + w.resolve(href) + ")")
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			if src := attr(n, "src"); w.base != nil && src != "" && !strings.HasPrefix(src, "data:") {
+				w.sb.WriteString("![" + alt + "](" + w.resolve(src) + ")")
+			} else {
+				w.sb.WriteString("[image: " + alt + "]")
+			}
+		}
+	case atom.Ul, atom.Ol:
+		w.newline()
+		counter := 0
+		if n.DataAtom == atom.Ol {
+			counter = 1
+		}
+		w.ordered = append(w.ordered, counter)
+		w.listDepth++
+		w.children(n)
+		w.listDepth--
+		w.ordered = w.ordered[:len(w.ordered)-1]
+		if w.listDepth == 0 {
+			w.paragraph()
+		}
+	case atom.Li:
+		w.newline()
+		w.sb.WriteString(strings.Repeat("  ", max(0, w.listDepth-1)))
+		if level := len(w.ordered) - 1; level >= 0 && w.ordered[level] > 0 {
+			w.sb.WriteString(strconv.Itoa(w.ordered[level]) + ". ")
+			w.ordered[level]++
+		} else {
+			w.sb.WriteString("- ")
+		}
+		w.children(n)
+		w.newline()
+	case atom.Table:
+		w.paragraph()
+		w.sb.WriteString(MarkdownTable(tableRows(n)))
+		w.paragraph()
+	default:
+		if blockElements[n.DataAtom] {
+			w.paragraph()
+			if n.DataAtom == atom.Blockquote {
+				inner := &mdWriter{base: w.base, readable: w.readable}
+				inner.children(n)
+				for _, l := range strings.Split(strings.TrimSpace(inner.String()), "\n") {
+					w.sb.WriteString("> " + l + "\n")
+				}
+			} else {
+				w.children(n)
+			}
+			w.paragraph()
+			return
+		}
+		w.children(n)
+	}
+}
+
+func (w *mdWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.render(c)
+	}
+}
+
+func (w *mdWriter) inline(n *html.Node, marker string) {
+	text := strings.Join(strings.Fields(nodeText(n)), " ")
+	if text == "" {
+		return
+	}
+	if startsWithSpace(nodeText(n)) {
+		w.text(" ")
+	}
+	w.sb.WriteString(marker + text + marker)
+	if endsWithSpace(nodeText(n)) {
+		w.sb.WriteByte(' ')
+	}
+}
+
+// tableRows collects the text of each cell, ignoring nested tables'
+// structure.
+func tableRows(table *html.Node) [][]string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+						row = append(row, strings.Join(strings.Fields(nodeText(cell)), " "))
+					}
+				}
+				rows = append(rows, row)
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(c)
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// nodeText returns the text content of n, skipping scripts and styles.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			return
+		}
+		if n.Type == html.ElementNode && skipElements[n.DataAtom] {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+			sb.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,481 @@
+package docs
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxZipEntry bounds how much of one archive member is decompressed, so a
+// zip bomb cannot exhaust memory.
+const maxZipEntry = 64 << 20
+
+func openZip(data []byte) (*zip.Reader, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid Office file: %w", err)
+	}
+	return zr, nil
+}
+
+func readZipEntry(zr *zip.Reader, name string) ([]byte, error) {
+	for _, f := range zr.File {
+		if f.Name == name {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			data, err := io.ReadAll(io.LimitReader(rc, maxZipEntry+1))
+			if err != nil {
+				return nil, err
+			}
+			if len(data) > maxZipEntry {
+				return nil, fmt.Errorf("%s is too large", name)
+			}
+			return data, nil
+		}
+	}
+	return nil, fmt.Errorf("%s not found", name)
+}
+
+// extractDOCX converts word/document.xml: headings, list items and tables
+// become markdown, other paragraphs plain text.
+func extractDOCX(data []byte) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := readZipEntry(zr, "word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{}
+	if app, err := readZipEntry(zr, "docProps/app.xml"); err == nil {
+		var props struct {
+			Pages int `xml:"Pages"`
+		}
+		if xml.Unmarshal(app, &props) == nil {
+			doc.Pages = props.Pages
+		}
+	}
+	if core, err := readZipEntry(zr, "docProps/core.xml"); err == nil {
+		var props struct {
+			Title string `xml:"title"`
+		}
+		if xml.Unmarshal(core, &props) == nil {
+			doc.Title = props.Title
+		}
+	}
+
+	var out strings.Builder
+	var para strings.Builder
+	var prefix string
+	var table [][]string // rows of the outermost table being read
+	var cell strings.Builder
+	tableDepth := 0
+
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				prefix = ""
+			case "pStyle":
+				style := strings.ToLower(xmlAttr(t, "val"))
+				if level, ok := strings.CutPrefix(style, "heading"); ok {
+					if n, err := strconv.Atoi(level); err == nil && n >= 1 && n <= 6 {
+						prefix = strings.Repeat("#", n) + " "
+					}
+				} else if style == "title" {
+					prefix = "# "
+				} else if strings.Contains(style, "list") && prefix == "" {
+					prefix = "- "
+				}
+			case "numPr":
+				if prefix == "" {
+					prefix = "- "
+				}
+			case "tab":
+				para.WriteByte('\t')
+			case "br", "cr":
+				para.WriteByte('\n')
+			case "tbl":
+				tableDepth++
+				if tableDepth == 1 {
+					table = nil
+				}
+			case "tr":
+				if tableDepth == 1 {
+					table = append(table, nil)
+				}
+			case "tc":
+				if tableDepth == 1 {
+					cell.Reset()
+				}
+			case "t":
+				var text string
+				if err := dec.DecodeElement(&text, &t); err != nil {
+					return nil, err
+				}
+				para.WriteString(text)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				text := strings.TrimRight(para.String(), " \t")
+				if tableDepth > 0 {
+					if cell.Len() > 0 && text != "" {
+						cell.WriteByte(' ')
+					}
+					cell.WriteString(text)
+					continue
+				}
+				if strings.TrimSpace(text) == "" {
+					continue
+				}
+				out.WriteString(prefix + text + "\n\n")
+			case "tc":
+				if tableDepth == 1 && len(table) > 0 {
+					table[len(table)-1] = append(table[len(table)-1], cell.String())
+				}
+			case "tbl":
+				tableDepth--
+				if tableDepth == 0 {
+					out.WriteString(MarkdownTable(table) + "\n")
+				}
+			}
+		}
+	}
+	doc.Text = strings.TrimSpace(out.String()) + "\n"
+	return doc, nil
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// extractXLSX converts the selected sheets to markdown tables.
+func extractXLSX(data []byte, opts Options) (*Document, error) {
+	zr, err := openZip(data)
+	if err != nil {
+		return nil, err
+	}
+	wbData, err := readZipEntry(zr, "xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	var wb struct {
+		Props struct {
+			Date1904 bool `xml:"date1904,attr"`
+		} `xml:"workbookPr"`
+		Sheets []struct {
+			Name string     `xml:"name,attr"`
+			RID  []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(wbData, &wb); err != nil {
+		return nil, fmt.Errorf("workbook.xml: %w", err)
+	}
+
+	targets := make(map[string]string)
+	if relData, err := readZipEntry(zr, "xl/_rels/workbook.xml.rels"); err == nil {
+		var rels struct {
+			Rels []struct {
+				ID     string `xml:"Id,attr"`
+				Target string `xml:"Target,attr"`
+			} `xml:"Relationship"`
+		}
+		if xml.Unmarshal(relData, &rels) == nil {
+			for _, r := range rels.Rels {
+				target := r.Target
+				if strings.HasPrefix(target, "/") {
+					target = strings.TrimPrefix(target, "/")
+				} else {
+					target = path.Join("xl", target)
+				}
+				targets[r.ID] = target
+			}
+		}
+	}
+
+	shared, err := readSharedStrings(zr)
+	if err != nil {
+		return nil, err
+	}
+	dateStyles := readDateStyles(zr)
+
+	doc := &Document{}
+	for _, s := range wb.Sheets {
+		doc.Sheets = append(doc.Sheets, s.Name)
+	}
+	selected, err := ParseRange(opts.Pages, len(doc.Sheets))
+	if err != nil {
+		return nil, err
+	}
+	if opts.Pages != "" {
+		doc.Selected = selected
+	}
+
+	var out strings.Builder
+	for _, idx := range selected {
+		sheet := wb.Sheets[idx-1]
+		target := ""
+		for _, a := range sheet.RID {
+			if a.Name.Local == "id" {
+				target = targets[a.Value]
+			}
+		}
+		if target == "" {
+			target = fmt.Sprintf("xl/worksheets/sheet%d.xml", idx)
+		}
+		sheetData, err := readZipEntry(zr, target)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet.Name, err)
+		}
+		rows, truncated, err := readSheet(sheetData, shared, dateStyles, wb.Props.Date1904)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet.Name, err)
+		}
+		doc.Truncated = doc.Truncated || truncated
+		fmt.Fprintf(&out, "## %s\n\n", sheet.Name)
+		if len(rows) == 0 {
+			out.WriteString("(empty)\n\n")
+			continue
+		}
+		out.WriteString(MarkdownTable(rows) + "\n")
+	}
+	doc.Text = strings.TrimSpace(out.String()) + "\n"
+	return doc, nil
+}
+
+func readSharedStrings(zr *zip.Reader) ([]string, error) {
+	data, err := readZipEntry(zr, "xl/sharedStrings.xml")
+	if err != nil {
+		return nil, nil // workbooks without text have none
+	}
+	var sst struct {
+		Items []struct {
+			T    string `xml:"t"`
+			Runs []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.Unmarshal(data, &sst); err != nil {
+		return nil, fmt.Errorf("sharedStrings.xml: %w", err)
+	}
+	out := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		text := si.T
+		for _, r := range si.Runs {
+			text += r.T
+		}
+		out[i] = text
+	}
+	return out, nil
+}
+
+// readDateStyles returns which cell style indexes format numbers as dates.
+func readDateStyles(zr *zip.Reader) map[int]bool {
+	data, err := readZipEntry(zr, "xl/styles.xml")
+	if err != nil {
+		return nil
+	}
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		Xfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if xml.Unmarshal(data, &styles) != nil {
+		return nil
+	}
+	custom := make(map[int]bool)
+	for _, f := range styles.NumFmts {
+		custom[f.ID] = isDateFormat(f.Code)
+	}
+	out := make(map[int]bool)
+	for i, xf := range styles.Xfs {
+		id := xf.NumFmtID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || custom[id] {
+			out[i] = true
+		}
+	}
+	return out
+}
+
+// isDateFormat reports whether a number format code shows a date or time:
+// it has d, m, y, h or s outside quotes and brackets.
+func isDateFormat(code string) bool {
+	inQuote, inBracket := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '[':
+			inBracket = true
+		case c == ']':
+			inBracket = false
+		case inBracket:
+		case c == '\\':
+			i++
+		case strings.IndexByte("dmyhsDMYHS", c) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+// readSheet returns the cell text of a worksheet as rows.
+func readSheet(data []byte, shared []string, dateStyles map[int]bool, date1904 bool) ([][]string, bool, error) {
+	var rows [][]string
+	truncated := false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Style  int    `xml:"s,attr"`
+				Value  string `xml:"v"`
+				Inline struct {
+					T    string `xml:"t"`
+					Runs []struct {
+						T string `xml:"t"`
+					} `xml:"r"`
+				} `xml:"is"`
+			} `xml:"c"`
+		}
+		if err := dec.DecodeElement(&row, &start); err != nil {
+			return nil, false, err
+		}
+		index := len(rows)
+		if row.R > 0 {
+			index = row.R - 1
+		}
+		if index >= MaxRows+1 {
+			truncated = true
+			break
+		}
+		for len(rows) <= index {
+			rows = append(rows, nil)
+		}
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if n := columnIndex(c.Ref); n >= 0 {
+					col = n
+				}
+			}
+			var text string
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared) {
+					text = shared[n]
+				}
+			case "inlineStr":
+				text = c.Inline.T
+				for _, r := range c.Inline.Runs {
+					text += r.T
+				}
+			case "b":
+				text = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			case "str", "e":
+				text = c.Value
+			default:
+				text = c.Value
+				if dateStyles[c.Style] {
+					if f, err := strconv.ParseFloat(c.Value, 64); err == nil {
+						text = excelDate(f, date1904)
+					}
+				}
+			}
+			if col < 0 || col >= 16384 {
+				continue
+			}
+			for len(rows[index]) <= col {
+				rows[index] = append(rows[index], "")
+			}
+			rows[index][col] = text
+		}
+	}
+	// Drop trailing empty rows.
+	for len(rows) > 0 && strings.TrimSpace(strings.Join(rows[len(rows)-1], "")) == "" {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, truncated, nil
+}
+
+// columnIndex converts a cell reference like "AB12" to a 0-based column.
+// It returns -1 when the reference has no column letters, and 16384 (past
+// the last column, XFD) when it has more than three.
+func columnIndex(ref string) int {
+	col := 0
+	for i, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		if i == 3 {
+			return 16384
+		}
+		col = col*26 + int(c-'A'+1)
+	}
+	return col - 1
+}
+
+// excelDate converts a serial date to text. Whole numbers are dates.
+func excelDate(serial float64, date1904 bool) string {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	days := math.Floor(serial)
+	t := base.AddDate(0, 0, int(days)).Add(time.Duration(math.Round((serial-days)*86400)) * time.Second)
+	if serial == days {
+		return t.Format("2006-01-02")
+	}
+	if days == 0 {
+		return t.Format("15:04:05")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,1335 @@
+package docs
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// The PDF reader below is deliberately forgiving: instead of trusting the
+// cross-reference table it scans the file for "N G obj" headers, so files
+// with broken or incremental xrefs still open. It understands object
+// streams, the common stream filters and enough of the content stream
+// operators to recover the text of each page in reading order.
+
+// maxStream bounds the decompressed size of a single stream.
+const maxStream = 64 << 20
+
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfDict    map[string]interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		raw  []byte
+	}
+)
+
+func extractPDF(data []byte, opts Options) (*Document, error) {
+	r, err := newPDFReader(data)
+	if err != nil {
+		return nil, err
+	}
+	pages := r.pages()
+	if len(pages) == 0 {
+		return nil, errors.New("no pages found")
+	}
+	selected, err := ParseRange(opts.Pages, len(pages))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &Document{Pages: len(pages), Title: r.title()}
+	if opts.Pages != "" {
+		doc.Selected = selected
+	}
+	x := &textExtractor{r: r, fonts: make(map[pdfRef]*pdfFont)}
+	var out strings.Builder
+	found := false
+	for _, n := range selected {
+		text := x.page(pages[n-1])
+		fmt.Fprintf(&out, "## Page %d\n\n", n)
+		if text == "" {
+			out.WriteString("(no text)\n\n")
+			continue
+		}
+		found = true
+		out.WriteString(text + "\n\n")
+	}
+	if !found {
+		out.WriteString("No extractable text: the pages are probably scanned images.\n")
+	}
+	doc.Text = strings.TrimSpace(out.String()) + "\n"
+	return doc, nil
+}
+
+// --- lexer ---
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFWhite(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *pdfLexer) peek(k int) byte {
+	if l.pos+k < len(l.data) {
+		return l.data[l.pos+k]
+	}
+	return 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFWhite(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// object reads the next object. Operators and stray delimiters come back
+// as pdfKeyword; strings as []byte; numbers as float64. Every call
+// consumes at least one byte until io.EOF.
+func (l *pdfLexer) object() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literal(), nil
+	case c == '<' && l.peek(1) == '<':
+		l.pos += 2
+		return l.dict(), nil
+	case c == '<':
+		return l.hexString(), nil
+	case c == '[':
+		l.pos++
+		return l.array(), nil
+	case c == '+' || c == '-' || c == '.' || isDigit(c):
+		return l.number(), nil
+	}
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhite(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+	}
+	switch s := string(l.data[start:l.pos]); s {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	default:
+		return pdfKeyword(s), nil
+	}
+}
+
+func (l *pdfLexer) dict() pdfDict {
+	d := pdfDict{}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return d
+		}
+		if l.data[l.pos] == '>' {
+			l.pos++
+			if l.peek(0) == '>' {
+				l.pos++
+			}
+			return d
+		}
+		k, err := l.object()
+		if err != nil {
+			return d
+		}
+		key, ok := k.(pdfName)
+		if !ok {
+			continue
+		}
+		v, err := l.object()
+		if err != nil {
+			return d
+		}
+		d[string(key)] = v
+	}
+}
+
+func (l *pdfLexer) array() []interface{} {
+	var a []interface{}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return a
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return a
+		}
+		v, err := l.object()
+		if err != nil {
+			return a
+		}
+		a = append(a, v)
+	}
+}
+
+// number reads a number, or an indirect reference "N G R".
+func (l *pdfLexer) number() interface{} {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && (isDigit(l.data[l.pos]) || l.data[l.pos] == '.') {
+		l.pos++
+	}
+	s := string(l.data[start:l.pos])
+	f, _ := strconv.ParseFloat(s, 64)
+	if strings.ContainsAny(s, ".+-") {
+		return f
+	}
+
+	save := l.pos
+	l.skipSpace()
+	genStart := l.pos
+	for l.pos < len(l.data) && isDigit(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos > genStart {
+		gen, _ := strconv.Atoi(string(l.data[genStart:l.pos]))
+		l.skipSpace()
+		if l.peek(0) == 'R' && (l.pos+1 >= len(l.data) || isPDFWhite(l.data[l.pos+1]) || isPDFDelim(l.data[l.pos+1])) {
+			l.pos++
+			return pdfRef{int(f), gen}
+		}
+	}
+	l.pos = save
+	return f
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	var sb strings.Builder
+	for l.pos < len(l.data) && !isPDFWhite(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				sb.WriteByte(byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		l.pos++
+	}
+	return pdfName(sb.String())
+}
+
+func (l *pdfLexer) literal() []byte {
+	l.pos++
+	out := []byte{}
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.peek(0) == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := int(e - '0')
+				for k := 0; k < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; k++ {
+					v = v*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				c = byte(v)
+			default:
+				c = e
+			}
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() []byte {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFWhite(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	if l.pos < len(l.data) {
+		l.pos++ // '>'
+	}
+	return decodeHex(digits)
+}
+
+func decodeHex(digits []byte) []byte {
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i+1 < len(digits); i += 2 {
+		v, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		out = append(out, byte(v))
+	}
+	return out
+}
+
+// skipInlineImage skips the binary data of an inline image after "ID".
+func (l *pdfLexer) skipInlineImage() {
+	for i := l.pos + 1; i+1 < len(l.data); i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && isPDFWhite(l.data[i-1]) &&
+			(i+2 == len(l.data) || isPDFWhite(l.data[i+2]) || isPDFDelim(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// --- object store ---
+
+type pdfReader struct {
+	data     []byte
+	offsets  map[int]int       // object number -> offset of "N G obj"
+	inStream map[int][2]int    // object number -> (object stream, index)
+	streams  map[int]objStream // decoded object streams
+	cache    map[int]interface{}
+	loading  map[int]bool
+	trailer  pdfDict
+}
+
+type objStream struct {
+	data    []byte
+	offsets []int
+}
+
+func newPDFReader(data []byte) (*pdfReader, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+	r := &pdfReader{
+		data:     data,
+		offsets:  make(map[int]int),
+		inStream: make(map[int][2]int),
+		streams:  make(map[int]objStream),
+		cache:    make(map[int]interface{}),
+		loading:  make(map[int]bool),
+		trailer:  pdfDict{},
+	}
+	r.index()
+
+	// Classic trailers, oldest first so later updates win.
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("trailer"))
+		if j < 0 {
+			break
+		}
+		l := &pdfLexer{data: data, pos: i + j + len("trailer")}
+		i += j + 1
+		if d, ok := mustObject(l).(pdfDict); ok {
+			for k, v := range d {
+				r.trailer[k] = v
+			}
+		}
+	}
+
+	// Object and cross-reference streams, in file order.
+	nums := make([]int, 0, len(r.offsets))
+	for n := range r.offsets {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(a, b int) bool { return r.offsets[nums[a]] < r.offsets[nums[b]] })
+	for _, n := range nums {
+		st, ok := r.object(n).(*pdfStream)
+		if !ok {
+			continue
+		}
+		switch r.name(st.dict["Type"]) {
+		case "ObjStm":
+			r.loadObjStream(n, st)
+		case "XRef":
+			for _, k := range []string{"Root", "Info", "Encrypt"} {
+				if v, ok := st.dict[k]; ok {
+					r.trailer[k] = v
+				}
+			}
+		}
+	}
+
+	if _, ok := r.trailer["Encrypt"]; ok {
+		return nil, errors.New("encrypted PDFs are not supported")
+	}
+	return r, nil
+}
+
+func mustObject(l *pdfLexer) interface{} {
+	v, _ := l.object()
+	return v
+}
+
+// index records the offset of every "N G obj" header in the file.
+func (r *pdfReader) index() {
+	data := r.data
+	for i := 0; ; {
+		j := bytes.Index(data[i:], []byte("obj"))
+		if j < 0 {
+			return
+		}
+		pos := i + j
+		i = pos + 3
+		if i < len(data) && !isPDFWhite(data[i]) && !isPDFDelim(data[i]) {
+			continue
+		}
+		_, genStart, ok := intBefore(data, pos)
+		if !ok {
+			continue
+		}
+		num, numStart, ok := intBefore(data, genStart)
+		if !ok {
+			continue
+		}
+		if numStart > 0 && !isPDFWhite(data[numStart-1]) && !isPDFDelim(data[numStart-1]) {
+			continue
+		}
+		r.offsets[num] = numStart
+	}
+}
+
+// intBefore reads the whitespace-separated integer that ends before end.
+func intBefore(data []byte, end int) (int, int, bool) {
+	k := end
+	for k > 0 && isPDFWhite(data[k-1]) {
+		k--
+	}
+	if k == end {
+		return 0, 0, false
+	}
+	stop := k
+	for k > 0 && isDigit(data[k-1]) && stop-k < 10 {
+		k--
+	}
+	if k == stop {
+		return 0, 0, false
+	}
+	n, err := strconv.Atoi(string(data[k:stop]))
+	return n, k, err == nil
+}
+
+func (r *pdfReader) loadObjStream(num int, st *pdfStream) {
+	data, err := r.decode(st)
+	if err != nil {
+		return
+	}
+	n, first := r.int(st.dict["N"]), r.int(st.dict["First"])
+	if first <= 0 || first > len(data) {
+		return
+	}
+	l := &pdfLexer{data: data[:first]}
+	os := objStream{data: data}
+	for i := 0; i < n; i++ {
+		objNum, ok1 := mustObject(l).(float64)
+		off, ok2 := mustObject(l).(float64)
+		if !ok1 || !ok2 || off < 0 || first+int(off) > len(data) {
+			break
+		}
+		os.offsets = append(os.offsets, first+int(off))
+		if _, direct := r.offsets[int(objNum)]; !direct {
+			r.inStream[int(objNum)] = [2]int{num, len(os.offsets) - 1}
+		}
+	}
+	r.streams[num] = os
+}
+
+func (r *pdfReader) object(num int) interface{} {
+	if v, ok := r.cache[num]; ok {
+		return v
+	}
+	if r.loading[num] {
+		return nil
+	}
+	r.loading[num] = true
+	defer delete(r.loading, num)
+
+	var v interface{}
+	if off, ok := r.offsets[num]; ok {
+		v = r.parseIndirect(off)
+	} else if loc, ok := r.inStream[num]; ok {
+		if os, ok := r.streams[loc[0]]; ok && loc[1] < len(os.offsets) {
+			v = mustObject(&pdfLexer{data: os.data, pos: os.offsets[loc[1]]})
+		}
+	}
+	r.cache[num] = v
+	return v
+}
+
+func (r *pdfReader) parseIndirect(off int) interface{} {
+	l := &pdfLexer{data: r.data, pos: off}
+	for i := 0; i < 3; i++ { // N G obj
+		mustObject(l)
+	}
+	v := mustObject(l)
+	dict, ok := v.(pdfDict)
+	if !ok {
+		return v
+	}
+	l.skipSpace()
+	if l.pos >= len(r.data) || !bytes.HasPrefix(r.data[l.pos:], []byte("stream")) {
+		return dict
+	}
+	start := l.pos + len("stream")
+	if l.peek(len("stream")) == '\r' {
+		start++
+	}
+	if start < len(r.data) && r.data[start] == '\n' {
+		start++
+	}
+	return &pdfStream{dict: dict, raw: r.streamData(dict, start)}
+}
+
+// streamData trusts /Length when "endstream" follows it, and otherwise
+// searches for the keyword.
+func (r *pdfReader) streamData(dict pdfDict, start int) []byte {
+	if start >= len(r.data) {
+		return nil
+	}
+	if n, ok := r.resolve(dict["Length"]).(float64); ok {
+		end := start + int(n)
+		if n >= 0 && end <= len(r.data) {
+			rest := bytes.TrimLeft(r.data[end:min(len(r.data), end+32)], "\x00\t\n\f\r ")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return r.data[start:end]
+			}
+		}
+	}
+	end := bytes.Index(r.data[start:], []byte("endstream"))
+	if end < 0 {
+		return r.data[start:]
+	}
+	raw := r.data[start : start+end]
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	return bytes.TrimSuffix(raw, []byte("\r"))
+}
+
+func (r *pdfReader) resolve(v interface{}) interface{} {
+	for i := 0; i < 16; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = r.object(ref.num)
+	}
+	return nil
+}
+
+func (r *pdfReader) dict(v interface{}) pdfDict {
+	switch d := r.resolve(v).(type) {
+	case pdfDict:
+		return d
+	case *pdfStream:
+		return d.dict
+	}
+	return nil
+}
+
+func (r *pdfReader) array(v interface{}) []interface{} {
+	a, _ := r.resolve(v).([]interface{})
+	return a
+}
+
+func (r *pdfReader) name(v interface{}) string {
+	n, _ := r.resolve(v).(pdfName)
+	return string(n)
+}
+
+func (r *pdfReader) int(v interface{}) int {
+	f, _ := r.resolve(v).(float64)
+	return int(f)
+}
+
+func (r *pdfReader) title() string {
+	info := r.dict(r.trailer["Info"])
+	s, _ := r.resolve(info["Title"]).([]byte)
+	return strings.TrimSpace(pdfTextString(s))
+}
+
+// pdfTextString decodes a text string: UTF-16BE with a BOM, otherwise
+// treated as Latin-1.
+func pdfTextString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xFE && s[1] == 0xFF {
+		return utf16BE(s[2:])
+	}
+	runes := make([]rune, len(s))
+	for i, b := range s {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func utf16BE(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(u))
+}
+
+// --- filters ---
+
+func (r *pdfReader) decode(st *pdfStream) ([]byte, error) {
+	var filters []string
+	var parms []pdfDict
+	switch f := r.resolve(st.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []string{string(f)}
+		parms = []pdfDict{r.dict(st.dict["DecodeParms"])}
+	case []interface{}:
+		pa := r.array(st.dict["DecodeParms"])
+		for i, x := range f {
+			filters = append(filters, r.name(x))
+			var p pdfDict
+			if i < len(pa) {
+				p = r.dict(pa[i])
+			}
+			parms = append(parms, p)
+		}
+	}
+
+	data := st.raw
+	for i, name := range filters {
+		var err error
+		switch name {
+		case "FlateDecode", "Fl":
+			if data, err = inflate(data); err == nil {
+				data, err = r.unpredict(data, parms[i])
+			}
+		case "ASCIIHexDecode", "AHx":
+			data = decodeHex(bytes.Map(func(c rune) rune {
+				if c == '>' || isPDFWhite(byte(c)) {
+					return -1
+				}
+				return c
+			}, data))
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported filter %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, keeping whatever was recovered from a
+// truncated stream.
+func inflate(data []byte) ([]byte, error) {
+	var rd io.Reader
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		defer zr.Close()
+		rd = zr
+	} else {
+		rd = flate.NewReader(bytes.NewReader(data))
+	}
+	out, err := io.ReadAll(io.LimitReader(rd, maxStream+1))
+	if len(out) > maxStream {
+		return nil, errors.New("stream is too large")
+	}
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	return out, nil
+}
+
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	data = bytes.TrimPrefix(data, []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, len(data))
+	n, _, err := ascii85.Decode(out, data, true)
+	return out[:n], err
+}
+
+// unpredict reverses the PNG predictors used by cross-reference and
+// object streams.
+func (r *pdfReader) unpredict(data []byte, parms pdfDict) ([]byte, error) {
+	if r.int(parms["Predictor"]) < 10 {
+		return data, nil
+	}
+	colors, bpc, columns := 1, 8, 1
+	if v := r.int(parms["Colors"]); v > 0 {
+		colors = v
+	}
+	if v := r.int(parms["BitsPerComponent"]); v > 0 {
+		bpc = v
+	}
+	if v := r.int(parms["Columns"]); v > 0 {
+		columns = v
+	}
+	bpp := max(1, colors*bpc/8)
+	rowLen := (colors*bpc*columns + 7) / 8
+
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, rowLen)
+	for i := 0; i < len(data); i += rowLen + 1 {
+		filter := data[i]
+		row := make([]byte, rowLen)
+		copy(row, data[i+1:min(len(data), i+1+rowLen)])
+		for j := range row {
+			var left, upLeft byte
+			if j >= bpp {
+				left, upLeft = row[j-bpp], prev[j-bpp]
+			}
+			up := prev[j]
+			switch filter {
+			case 1:
+				row[j] += left
+			case 2:
+				row[j] += up
+			case 3:
+				row[j] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[j] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// --- pages and text ---
+
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+func (r *pdfReader) pages() []pdfPage {
+	var pages []pdfPage
+	catalog := r.dict(r.trailer["Root"])
+	if catalog == nil {
+		for n := range r.offsets {
+			if d := r.dict(r.object(n)); r.name(d["Type"]) == "Catalog" {
+				catalog = d
+				break
+			}
+		}
+	}
+	if catalog != nil {
+		r.walkPages(r.dict(catalog["Pages"]), nil, 0, make(map[pdfRef]bool), &pages)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	// No usable page tree: take page objects in file order.
+	nums := make([]int, 0, len(r.offsets))
+	for n := range r.offsets {
+		nums = append(nums, n)
+	}
+	sort.Slice(nums, func(a, b int) bool { return r.offsets[nums[a]] < r.offsets[nums[b]] })
+	for _, n := range nums {
+		if d := r.dict(r.object(n)); r.name(d["Type"]) == "Page" {
+			pages = append(pages, pdfPage{dict: d, resources: r.dict(d["Resources"])})
+		}
+	}
+	return pages
+}
+
+func (r *pdfReader) walkPages(node, resources pdfDict, depth int, seen map[pdfRef]bool, out *[]pdfPage) {
+	if node == nil || depth > 64 {
+		return
+	}
+	if res := r.dict(node["Resources"]); res != nil {
+		resources = res
+	}
+	kids, hasKids := node["Kids"]
+	if !hasKids && r.name(node["Type"]) != "Pages" {
+		*out = append(*out, pdfPage{dict: node, resources: resources})
+		return
+	}
+	for _, kid := range r.array(kids) {
+		if ref, ok := kid.(pdfRef); ok {
+			if seen[ref] {
+				continue
+			}
+			seen[ref] = true
+		}
+		r.walkPages(r.dict(kid), resources, depth+1, seen, out)
+	}
+}
+
+func (r *pdfReader) content(v interface{}) []byte {
+	var parts [][]byte
+	add := func(v interface{}) {
+		if st, ok := r.resolve(v).(*pdfStream); ok {
+			if data, err := r.decode(st); err == nil {
+				parts = append(parts, data)
+			}
+		}
+	}
+	if a := r.array(v); a != nil {
+		for _, x := range a {
+			add(x)
+		}
+	} else {
+		add(v)
+	}
+	return bytes.Join(parts, []byte("\n"))
+}
+
+// textExtractor interprets content streams, keeping only the text.
+type textExtractor struct {
+	r      *pdfReader
+	fonts  map[pdfRef]*pdfFont
+	font   *pdfFont
+	out    strings.Builder
+	lineY  float64
+	inLine bool
+	depth  int
+}
+
+func (x *textExtractor) page(p pdfPage) string {
+	x.out.Reset()
+	x.font, x.inLine, x.lineY = nil, false, 0
+	x.run(x.r.content(p.dict["Contents"]), p.resources)
+
+	lines := strings.Split(x.out.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func (x *textExtractor) run(content []byte, res pdfDict) {
+	l := &pdfLexer{data: content}
+	var operands []interface{}
+	for {
+		v, err := l.object()
+		if err != nil {
+			return
+		}
+		op, ok := v.(pdfKeyword)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+		x.op(string(op), operands, res, l)
+		operands = operands[:0]
+	}
+}
+
+func (x *textExtractor) op(op string, args []interface{}, res pdfDict, l *pdfLexer) {
+	num := func(i int) float64 {
+		if i < len(args) {
+			f, _ := args[i].(float64)
+			return f
+		}
+		return 0
+	}
+	last := func() interface{} {
+		if len(args) == 0 {
+			return nil
+		}
+		return args[len(args)-1]
+	}
+
+	switch op {
+	case "BT":
+		x.lineY = 0
+	case "Tf":
+		if len(args) >= 2 {
+			name, _ := args[len(args)-2].(pdfName)
+			x.font = x.loadFont(res, string(name))
+		}
+	case "Td", "TD":
+		if len(args) < 2 {
+			return
+		}
+		tx, ty := num(len(args)-2), num(len(args)-1)
+		if ty != 0 {
+			x.newline()
+		} else if tx != 0 {
+			x.space()
+		}
+		x.lineY += ty
+	case "Tm":
+		if len(args) < 6 {
+			return
+		}
+		y := num(5)
+		if x.inLine && math.Abs(y-x.lineY) > 0.5 {
+			x.newline()
+		} else {
+			x.space()
+		}
+		x.lineY = y
+	case "T*":
+		x.newline()
+	case "Tj":
+		x.show(last())
+	case "'", "\"":
+		x.newline()
+		x.show(last())
+	case "TJ":
+		items, _ := last().([]interface{})
+		for _, item := range items {
+			if n, ok := item.(float64); ok {
+				if n < -200 {
+					x.space()
+				}
+				continue
+			}
+			x.show(item)
+		}
+	case "Do":
+		name, _ := last().(pdfName)
+		x.form(res, string(name))
+	case "ID":
+		l.skipInlineImage()
+	}
+}
+
+func (x *textExtractor) show(v interface{}) {
+	s, ok := v.([]byte)
+	if !ok {
+		return
+	}
+	var text string
+	if x.font != nil {
+		text = x.font.decode(s)
+	} else {
+		text = winAnsi.decode(s)
+	}
+	if text == "" {
+		return
+	}
+	x.out.WriteString(text)
+	x.inLine = true
+}
+
+func (x *textExtractor) space() {
+	if s := x.out.String(); x.inLine && !strings.HasSuffix(s, " ") {
+		x.out.WriteByte(' ')
+	}
+}
+
+func (x *textExtractor) newline() {
+	if x.out.Len() > 0 && !strings.HasSuffix(x.out.String(), "\n") {
+		x.out.WriteByte('\n')
+	}
+	x.inLine = false
+}
+
+// form runs a form XObject's content with its own resources.
+func (x *textExtractor) form(res pdfDict, name string) {
+	if x.depth >= 8 {
+		return
+	}
+	v := x.r.dict(res["XObject"])[name]
+	st, ok := x.r.resolve(v).(*pdfStream)
+	if !ok || x.r.name(st.dict["Subtype"]) != "Form" {
+		return
+	}
+	data, err := x.r.decode(st)
+	if err != nil {
+		return
+	}
+	if own := x.r.dict(st.dict["Resources"]); own != nil {
+		res = own
+	}
+	x.newline()
+	x.depth++
+	x.run(data, res)
+	x.depth--
+	x.newline()
+}
+
+func (x *textExtractor) loadFont(res pdfDict, name string) *pdfFont {
+	v := x.r.dict(res["Font"])[name]
+	ref, isRef := v.(pdfRef)
+	if isRef {
+		if f, ok := x.fonts[ref]; ok {
+			return f
+		}
+	}
+	f := x.r.font(x.r.dict(v))
+	if isRef {
+		x.fonts[ref] = f
+	}
+	return f
+}
+
+// --- fonts ---
+
+type pdfFont struct {
+	twoByte bool // composite (Type0) font
+	cmap    *toUnicode
+	enc     *[256]string
+}
+
+type toUnicode struct {
+	ranges [][2][]byte // codespace ranges
+	m      map[string]string
+}
+
+var winAnsi = &pdfFont{enc: &winAnsiEncoding}
+
+func (r *pdfReader) font(d pdfDict) *pdfFont {
+	if d == nil {
+		return winAnsi
+	}
+	f := &pdfFont{twoByte: r.name(d["Subtype"]) == "Type0"}
+	if st, ok := r.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := r.decode(st); err == nil {
+			f.cmap = parseToUnicode(data)
+		}
+	}
+	if f.twoByte {
+		return f
+	}
+
+	f.enc = &winAnsiEncoding
+	enc := r.dict(d["Encoding"])
+	diffs := r.array(enc["Differences"])
+	if len(diffs) == 0 {
+		return f
+	}
+	custom := winAnsiEncoding
+	code := 0
+	for _, item := range diffs {
+		switch v := r.resolve(item).(type) {
+		case float64:
+			code = int(v)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				custom[code] = glyphText(string(v))
+			}
+			code++
+		}
+	}
+	f.enc = &custom
+	return f
+}
+
+func (f *pdfFont) decode(s []byte) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		n := min(f.codeLen(s[i:]), len(s)-i)
+		code := s[i : i+n]
+		i += n
+		if f.cmap != nil {
+			if u, ok := f.cmap.m[string(code)]; ok {
+				sb.WriteString(u)
+				continue
+			}
+		}
+		switch {
+		case f.twoByte && f.cmap == nil && n == 2:
+			// No ToUnicode map: many producers use Unicode code points as CIDs.
+			if r := rune(code[0])<<8 | rune(code[1]); r >= 0x20 && (r < 0xD800 || r > 0xDFFF) {
+				sb.WriteRune(r)
+			}
+		case !f.twoByte && f.enc != nil:
+			sb.WriteString(f.enc[code[0]])
+		}
+	}
+	return sb.String()
+}
+
+func (f *pdfFont) codeLen(b []byte) int {
+	if f.cmap != nil {
+		for n := 1; n <= 4 && n <= len(b); n++ {
+			for _, rg := range f.cmap.ranges {
+				if len(rg[0]) == n && inCodeRange(b[:n], rg[0], rg[1]) {
+					return n
+				}
+			}
+		}
+	}
+	if f.twoByte {
+		return 2
+	}
+	return 1
+}
+
+func inCodeRange(b, lo, hi []byte) bool {
+	for i := range b {
+		if i >= len(hi) || b[i] < lo[i] || b[i] > hi[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseToUnicode reads the codespace ranges and bfchar/bfrange mappings of
+// a ToUnicode CMap.
+func parseToUnicode(data []byte) *toUnicode {
+	c := &toUnicode{m: make(map[string]string)}
+	l := &pdfLexer{data: data}
+	var args []interface{}
+	for {
+		v, err := l.object()
+		if err != nil {
+			return c
+		}
+		kw, ok := v.(pdfKeyword)
+		if !ok {
+			args = append(args, v)
+			continue
+		}
+		switch kw {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(args); i += 2 {
+				lo, ok1 := args[i].([]byte)
+				hi, ok2 := args[i+1].([]byte)
+				if ok1 && ok2 && len(lo) == len(hi) && len(lo) > 0 {
+					c.ranges = append(c.ranges, [2][]byte{lo, hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(args); i += 2 {
+				src, ok1 := args[i].([]byte)
+				dst, ok2 := args[i+1].([]byte)
+				if ok1 && ok2 {
+					c.m[string(src)] = utf16BE(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(args); i += 3 {
+				lo, ok1 := args[i].([]byte)
+				hi, ok2 := args[i+1].([]byte)
+				if !ok1 || !ok2 || len(lo) != len(hi) || len(lo) == 0 || len(lo) > 4 {
+					continue
+				}
+				c.addRange(lo, hi, args[i+2])
+			}
+		}
+		args = args[:0]
+	}
+}
+
+func (c *toUnicode) addRange(lo, hi []byte, dst interface{}) {
+	from, to := beUint(lo), beUint(hi)
+	if to < from || to-from > 0xFFFF {
+		return
+	}
+	list, _ := dst.([]interface{})
+	base, _ := dst.([]byte)
+	for code := from; code <= to; code++ {
+		key := make([]byte, len(lo))
+		for i, v := len(key)-1, code; i >= 0; i, v = i-1, v>>8 {
+			key[i] = byte(v)
+		}
+		off := int(code - from)
+		switch {
+		case list != nil:
+			if off < len(list) {
+				if b, ok := list[off].([]byte); ok {
+					c.m[string(key)] = utf16BE(b)
+				}
+			}
+		case len(base) >= 2:
+			b := append([]byte(nil), base...)
+			last := uint16(b[len(b)-2])<<8 | uint16(b[len(b)-1])
+			last += uint16(off)
+			b[len(b)-2], b[len(b)-1] = byte(last>>8), byte(last)
+			c.m[string(key)] = utf16BE(b)
+		}
+	}
+}
+
+func beUint(b []byte) uint32 {
+	var v uint32
+	for _, c := range b {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+// winAnsiEncoding is the default single-byte encoding: Latin-1 with the
+// Windows-1252 punctuation in 0x80-0x9F.
+var winAnsiEncoding = func() [256]string {
+	var t [256]string
+	for i := 0x20; i < 0x7F; i++ {
+		t[i] = string(rune(i))
+	}
+	for i := 0xA0; i <= 0xFF; i++ {
+		t[i] = string(rune(i))
+	}
+	t['\t'], t['\n'], t['\r'] = " ", "\n", "\n"
+	cp1252 := "€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ"
+	i := 0x80
+	for _, r := range cp1252 {
+		if r != 0 {
+			t[i] = string(r)
+		}
+		i++
+	}
+	return t
+}()
+
+var glyphNames = func() map[string]string {
+	m := map[string]string{
+		"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+		"percent": "%", "ampersand": "&", "quotesingle": "'", "quoteright": "’", "quoteleft": "‘",
+		"parenleft": "(", "parenright": ")", "asterisk": "*", "plus": "+", "comma": ",",
+		"hyphen": "-", "period": ".", "slash": "/", "colon": ":", "semicolon": ";",
+		"less": "<", "equal": "=", "greater": ">", "question": "?", "at": "@",
+		"bracketleft": "[", "backslash": "\\", "bracketright": "]", "asciicircum": "^",
+		"underscore": "_", "grave": "`", "braceleft": "{", "bar": "|", "braceright": "}",
+		"asciitilde": "~", "bullet": "•", "endash": "–", "emdash": "—", "quotedblleft": "“",
+		"quotedblright": "”", "quotesinglbase": "‚", "quotedblbase": "„", "ellipsis": "…",
+		"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl", "dagger": "†",
+		"daggerdbl": "‡", "trademark": "™", "minus": "−", "Euro": "€", "perthousand": "‰",
+		"OE": "Œ", "oe": "œ", "Scaron": "Š", "scaron": "š", "Zcaron": "Ž", "zcaron": "ž",
+		"Ydieresis": "Ÿ", "florin": "ƒ", "circumflex": "ˆ", "tilde": "˜", "nbspace": " ",
+		"guilsinglleft": "‹", "guilsinglright": "›", "dotlessi": "ı",
+	}
+	digits := strings.Fields("zero one two three four five six seven eight nine")
+	for i, d := range digits {
+		m[d] = strconv.Itoa(i)
+	}
+	latin1 := strings.Fields(`space exclamdown cent sterling currency yen brokenbar section
+		dieresis copyright ordfeminine guillemotleft logicalnot softhyphen registered macron
+		degree plusminus twosuperior threesuperior acute mu paragraph periodcentered
+		cedilla onesuperior ordmasculine guillemotright onequarter onehalf threequarters questiondown
+		Agrave Aacute Acircumflex Atilde Adieresis Aring AE Ccedilla
+		Egrave Eacute Ecircumflex Edieresis Igrave Iacute Icircumflex Idieresis
+		Eth Ntilde Ograve Oacute Ocircumflex Otilde Odieresis multiply
+		Oslash Ugrave Uacute Ucircumflex Udieresis Yacute Thorn germandbls
+		agrave aacute acircumflex atilde adieresis aring ae ccedilla
+		egrave eacute ecircumflex edieresis igrave iacute icircumflex idieresis
+		eth ntilde ograve oacute ocircumflex otilde odieresis divide
+		oslash ugrave uacute ucircumflex udieresis yacute thorn ydieresis`)
+	for i, name := range latin1 {
+		if _, ok := m[name]; !ok {
+			m[name] = string(rune(0xA0 + i))
+		}
+	}
+	return m
+}()
+
+// glyphText maps a glyph name from an encoding's /Differences to text.
+func glyphText(name string) string {
+	if s, ok := glyphNames[name]; ok {
+		return s
+	}
+	if len(name) == 1 {
+		return name
+	}
+	if hexPart, ok := strings.CutPrefix(name, "uni"); ok && len(hexPart) >= 4 && len(hexPart)%4 == 0 {
+		var sb strings.Builder
+		for i := 0; i < len(hexPart); i += 4 {
+			v, err := strconv.ParseUint(hexPart[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			sb.WriteRune(rune(v))
+		}
+		return sb.String()
+	}
+	if hexPart, ok := strings.CutPrefix(name, "u"); ok && len(hexPart) >= 4 && len(hexPart) <= 6 {
+		if v, err := strconv.ParseUint(hexPart, 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	if base, _, ok := strings.Cut(name, "."); ok && base != "" {
+		return glyphText(base)
+	}
+	return ""
+}
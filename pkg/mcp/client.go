@@ -0,0 +1,340 @@
+// Package mcp mounts Model Context Protocol servers as agent tools and
+// serves the agent's own tools over MCP.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/logger"
+)
+
+// ProtocolVersion is the MCP revision this client speaks.
+const ProtocolVersion = "2025-06-18"
+
+const defaultRequestTimeout = 60 * time.Second
+
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// ToolInfo is a tool advertised by a server.
+type ToolInfo struct {
+	Name        string                 `json:"name"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// Content is one block of a tool result.
+type Content struct {
+	Type     string           `json:"type"`
+	Text     string           `json:"text,omitempty"`
+	Data     string           `json:"data,omitempty"`
+	MimeType string           `json:"mimeType,omitempty"`
+	URI      string           `json:"uri,omitempty"`
+	Name     string           `json:"name,omitempty"`
+	Resource *ResourceContent `json:"resource,omitempty"`
+}
+
+// ResourceContent is an embedded resource in a tool result.
+type ResourceContent struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// CallToolResult is the result of tools/call.
+type CallToolResult struct {
+	Content           []Content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           bool        `json:"isError,omitempty"`
+}
+
+// Client is a connection to one MCP server.
+type Client struct {
+	name      string
+	transport transport
+	timeout   time.Duration
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[int64]chan *rpcMessage
+
+	// onToolsChanged is called (in its own goroutine) when the server
+	// sends notifications/tools/list_changed.
+	onToolsChanged func()
+}
+
+func newClient(name string, cfg config.MCPServerConfig) (*Client, error) {
+	timeout := defaultRequestTimeout
+	if cfg.Timeout > 0 {
+		timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+
+	kind := cfg.Transport
+	if kind == "" {
+		switch {
+		case cfg.Command != "":
+			kind = "stdio"
+		case strings.HasSuffix(strings.TrimRight(cfg.URL, "/"), "/sse"):
+			kind = "sse"
+		default:
+			kind = "http"
+		}
+	}
+
+	var t transport
+	switch kind {
+	case "stdio":
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("mcp server %q: command is required for stdio", name)
+		}
+		t = &stdioTransport{name: name, command: cfg.Command, args: cfg.Args, env: cfg.Env, dir: cfg.Dir}
+	case "http", "streamable-http":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("mcp server %q: url is required for http", name)
+		}
+		t = &httpTransport{name: name, url: cfg.URL, headers: cfg.Headers, client: &http.Client{}}
+	case "sse":
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("mcp server %q: url is required for sse", name)
+		}
+		t = &sseTransport{name: name, url: cfg.URL, headers: cfg.Headers, client: &http.Client{}}
+	default:
+		return nil, fmt.Errorf("mcp server %q: unknown transport %q", name, kind)
+	}
+
+	return &Client{
+		name:      name,
+		transport: t,
+		timeout:   timeout,
+		pending:   make(map[int64]chan *rpcMessage),
+	}, nil
+}
+
+// Connect starts the transport and performs the initialize handshake.
+func (c *Client) Connect(ctx context.Context) error {
+	if err := c.transport.start(ctx, c.handleMessage); err != nil {
+		return err
+	}
+
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+	}
+	err := c.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo":      map[string]interface{}{"name": "picooraclaw", "version": "1.0"},
+	}, &init)
+	if err != nil {
+		c.transport.close()
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		c.transport.close()
+		return fmt.Errorf("initialized notification: %w", err)
+	}
+
+	logger.InfoCF("mcp", "Connected to MCP server", map[string]interface{}{
+		"server":   c.name,
+		"remote":   init.ServerInfo.Name,
+		"version":  init.ServerInfo.Version,
+		"protocol": init.ProtocolVersion,
+	})
+	return nil
+}
+
+// Done is closed when the connection to the server is lost.
+func (c *Client) Done() <-chan struct{} {
+	return c.transport.done()
+}
+
+// Close disconnects from the server, stopping it if it is a child process.
+func (c *Client) Close() error {
+	return c.transport.close()
+}
+
+// ListTools returns every tool the server advertises, following pagination.
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var all []ToolInfo
+	cursor := ""
+	for {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			Tools      []ToolInfo `json:"tools"`
+			NextCursor string     `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, err
+		}
+		all = append(all, page.Tools...)
+		if page.NextCursor == "" {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool on the server.
+func (c *Client) CallTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", map[string]interface{}{"name": name, "arguments": args}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	id := c.nextID.Add(1)
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	msg, err := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(id)), Method: method, Params: raw})
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *rpcMessage, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	if err := c.transport.send(ctx, msg); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if out == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, out)
+	case <-c.transport.done():
+		return errors.New("connection to MCP server lost")
+	case <-ctx.Done():
+		c.notify(context.Background(), "notifications/cancelled", map[string]interface{}{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	}
+}
+
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	m := rpcMessage{JSONRPC: "2.0", Method: method}
+	if params != nil {
+		raw, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		m.Params = raw
+	}
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return c.transport.send(ctx, msg)
+}
+
+// handleMessage dispatches one incoming message (or batch) from the server.
+func (c *Client) handleMessage(data []byte) {
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err == nil {
+			for _, m := range batch {
+				c.handleMessage(m)
+			}
+		}
+		return
+	}
+
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logger.WarnCF("mcp", "Invalid message from server", map[string]interface{}{"server": c.name, "error": err.Error()})
+		return
+	}
+
+	switch {
+	case msg.Method == "" && len(msg.ID) > 0:
+		var id int64
+		if err := json.Unmarshal(msg.ID, &id); err != nil {
+			return
+		}
+		c.mu.Lock()
+		ch := c.pending[id]
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- &msg
+		}
+	case msg.Method != "" && len(msg.ID) > 0:
+		c.handleRequest(&msg)
+	case msg.Method == "notifications/tools/list_changed":
+		if c.onToolsChanged != nil {
+			go c.onToolsChanged()
+		}
+	}
+}
+
+// handleRequest answers server-to-client requests. Only ping is supported;
+// this client advertises no sampling, roots or elicitation capabilities.
+func (c *Client) handleRequest(msg *rpcMessage) {
+	reply := rpcMessage{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = json.RawMessage(`{}`)
+	} else {
+		reply.Error = &rpcError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		c.transport.send(ctx, data)
+	}()
+}
@@ -0,0 +1,333 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/tools"
+)
+
+// The test binary doubles as a stdio MCP server when this variable is set.
+const fakeServerEnv = "PICOORACLAW_MCP_FAKE_SERVER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "1" {
+		runFakeStdioServer(os.Stdin, os.Stdout)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakeServer answers the subset of MCP the client uses. Calling the
+// "add_tool" tool adds a tool and emits tools/list_changed; "crash" makes a
+// stdio server exit.
+type fakeServer struct {
+	mu    sync.Mutex
+	tools []ToolInfo
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{tools: []ToolInfo{
+		{Name: "echo", Description: "Echo text back", InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+		}},
+		{Name: "fail", Description: "Always fails"},
+		{Name: "add_tool", Description: "Adds a tool"},
+		{Name: "crash", Description: "Exits the server"},
+	}}
+}
+
+// handle returns the response (nil for notifications) and any notification
+// to emit afterwards.
+func (f *fakeServer) handle(raw []byte) (resp []byte, notification []byte, exit bool) {
+	var msg rpcMessage
+	json.Unmarshal(raw, &msg)
+	if len(msg.ID) == 0 {
+		return nil, nil, false
+	}
+
+	var result interface{}
+	switch msg.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{"listChanged": true}},
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "0.1"},
+		}
+	case "tools/list":
+		f.mu.Lock()
+		result = map[string]interface{}{"tools": f.tools}
+		f.mu.Unlock()
+	case "tools/call":
+		var p struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		json.Unmarshal(msg.Params, &p)
+		switch p.Name {
+		case "echo":
+			result = map[string]interface{}{"content": []map[string]interface{}{
+				{"type": "text", "text": fmt.Sprintf("echo: %v", p.Arguments["text"])},
+				{"type": "image", "mimeType": "image/png", "data": "aGVsbG8="},
+			}}
+		case "fail":
+			result = map[string]interface{}{"isError": true, "content": []map[string]interface{}{{"type": "text", "text": "boom"}}}
+		case "add_tool":
+			f.mu.Lock()
+			f.tools = append(f.tools, ToolInfo{Name: "late", Description: "Added later"})
+			f.mu.Unlock()
+			result = map[string]interface{}{"content": []map[string]interface{}{{"type": "text", "text": "added"}}}
+			notification = []byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)
+		case "crash":
+			return nil, nil, true
+		}
+	default:
+		resp, _ := json.Marshal(rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: &rpcError{Code: -32601, Message: "not found"}})
+		return resp, nil, false
+	}
+
+	data, _ := json.Marshal(result)
+	resp, _ = json.Marshal(rpcMessage{JSONRPC: "2.0", ID: msg.ID, Result: data})
+	return resp, notification, false
+}
+
+func runFakeStdioServer(r io.Reader, w io.Writer) {
+	f := newFakeServer()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		resp, note, exit := f.handle(scanner.Bytes())
+		if exit {
+			return
+		}
+		if resp != nil {
+			fmt.Fprintf(w, "%s\n", resp)
+		}
+		if note != nil {
+			fmt.Fprintf(w, "%s\n", note)
+		}
+	}
+}
+
+func stdioServerConfig(t *testing.T) config.MCPServerConfig {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.MCPServerConfig{
+		Enabled:       true,
+		Command:       exe,
+		Env:           map[string]string{fakeServerEnv: "1"},
+		SubagentTools: []string{"echo"},
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %s", what)
+}
+
+func TestToolName(t *testing.T) {
+	if got := ToolName("my server", "read.file"); got != "mcp_my_server_read_file" {
+		t.Errorf("ToolName() = %q", got)
+	}
+	if got := ToolName("s", strings.Repeat("x", 100)); len(got) != maxToolNameLen {
+		t.Errorf("ToolName() length = %d, want %d", len(got), maxToolNameLen)
+	}
+	long := strings.Repeat("x", 70)
+	a, b := ToolName("s", long+"_read"), ToolName("s", long+"_write")
+	if a == b {
+		t.Errorf("ToolName() gave %q for two tools sharing a long prefix", a)
+	}
+	if a != ToolName("s", long+"_read") {
+		t.Error("ToolName() is not stable")
+	}
+}
+
+func TestMCPTool_ParametersLeavesInfoAlone(t *testing.T) {
+	tool := &mcpTool{info: ToolInfo{Name: "ping", InputSchema: map[string]interface{}{"required": []interface{}{}}}}
+	params := tool.Parameters()
+	if params["type"] != "object" || params["properties"] == nil {
+		t.Errorf("Parameters() = %v, want type and properties filled in", params)
+	}
+	if len(tool.info.InputSchema) != 1 {
+		t.Errorf("Parameters() modified the server schema: %v", tool.info.InputSchema)
+	}
+	if params := (&mcpTool{}).Parameters(); params["type"] != "object" {
+		t.Errorf("Parameters() without a schema = %v", params)
+	}
+}
+
+func TestManager_StdioMountsToolsAndSubagentSubset(t *testing.T) {
+	agentTools := tools.NewToolRegistry()
+	subTools := tools.NewToolRegistry()
+
+	m := NewManager(config.MCPConfig{Servers: map[string]config.MCPServerConfig{"fake": stdioServerConfig(t)}})
+	m.Attach(agentTools, false)
+	m.Attach(subTools, true)
+	m.Start()
+	defer m.Close()
+
+	waitFor(t, "tools to mount", func() bool { return agentTools.Count() == 4 })
+	if subTools.Count() != 1 {
+		t.Errorf("subagent tools = %v, want only echo", subTools.List())
+	}
+
+	result := agentTools.Execute(context.Background(), "mcp_fake_echo", map[string]interface{}{"text": "hi"})
+	if result.IsError || result.ForLLM != "echo: hi\n[image: image/png, 5 bytes]" {
+		t.Errorf("echo result = %+v", result)
+	}
+
+	result = agentTools.Execute(context.Background(), "mcp_fake_fail", nil)
+	if !result.IsError || result.ForLLM != "boom" {
+		t.Errorf("fail result = %+v", result)
+	}
+
+	// tools/list_changed adds the new tool without a reconnect.
+	agentTools.Execute(context.Background(), "mcp_fake_add_tool", nil)
+	waitFor(t, "list_changed refresh", func() bool {
+		_, ok := agentTools.Get("mcp_fake_late")
+		return ok
+	})
+}
+
+func TestManager_RestartsCrashedServer(t *testing.T) {
+	registry := tools.NewToolRegistry()
+	m := NewManager(config.MCPConfig{Servers: map[string]config.MCPServerConfig{"fake": stdioServerConfig(t)}})
+	m.Attach(registry, false)
+	m.Start()
+	defer m.Close()
+
+	waitFor(t, "tools to mount", func() bool { return registry.Count() == 4 })
+	first := m.servers[0].current()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	registry.Execute(ctx, "mcp_fake_crash", nil)
+
+	waitFor(t, "server restart", func() bool {
+		c := m.servers[0].current()
+		return c != nil && c != first && registry.Count() == 4
+	})
+	result := registry.Execute(context.Background(), "mcp_fake_echo", map[string]interface{}{"text": "again"})
+	if result.IsError {
+		t.Errorf("echo after restart = %+v", result)
+	}
+}
+
+func TestClient_StreamableHTTP(t *testing.T) {
+	f := newFakeServer()
+	var sawSession bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			http.Error(w, "no stream", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Mcp-Session-Id") == "s1" {
+			sawSession = true
+		}
+		resp, _, _ := f.handle(body)
+		if resp == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		w.Header().Set("Mcp-Session-Id", "s1")
+		if strings.Contains(string(body), "tools/call") {
+			// Answer tool calls as an SSE stream, as streaming servers do.
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", resp)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(resp)
+	}))
+	defer server.Close()
+
+	client, err := newClient("remote", config.MCPServerConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer t"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer client.Close()
+
+	list, err := client.ListTools(context.Background())
+	if err != nil || len(list) != 4 {
+		t.Fatalf("ListTools() = %v, %v", list, err)
+	}
+	res, err := client.CallTool(context.Background(), "echo", map[string]interface{}{"text": "web"})
+	if err != nil || formatContent(res) != "echo: web\n[image: image/png, 5 bytes]" {
+		t.Fatalf("CallTool() = %+v, %v", res, err)
+	}
+	if !sawSession {
+		t.Error("client did not send Mcp-Session-Id after initialize")
+	}
+}
+
+func TestClient_LegacySSE(t *testing.T) {
+	f := newFakeServer()
+	events := make(chan []byte, 16)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: endpoint\ndata: /messages?session=1\n\n")
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case msg := <-events:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", msg)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if resp, _, _ := f.handle(body); resp != nil {
+			events <- resp
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := newClient("legacy", config.MCPServerConfig{URL: server.URL + "/sse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(context.Background()); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer client.Close()
+
+	res, err := client.CallTool(context.Background(), "fail", nil)
+	if err != nil || !res.IsError {
+		t.Fatalf("CallTool() = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,259 @@
+package mcp
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/logger"
+	"github.com/jasperan/picooraclaw/pkg/tools"
+)
+
+const (
+	connectTimeout    = 30 * time.Second
+	minRestartBackoff = time.Second
+	maxRestartBackoff = time.Minute
+)
+
+// serverState tracks one configured server across reconnects.
+type serverState struct {
+	name string
+	cfg  config.MCPServerConfig
+
+	mu     sync.RWMutex
+	client *Client
+	tools  map[string]*mcpTool // by registry name
+}
+
+func (s *serverState) current() *Client {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.client
+}
+
+type attachment struct {
+	registry *tools.ToolRegistry
+	subagent bool
+}
+
+// Manager connects to the configured MCP servers, keeps their tools
+// registered in the attached registries, and restarts servers that exit.
+type Manager struct {
+	servers []*serverState
+
+	mu       sync.Mutex
+	attached []attachment
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewManager creates a manager for the enabled servers in cfg. Nothing is
+// started until Start is called.
+func NewManager(cfg config.MCPConfig) *Manager {
+	names := make([]string, 0, len(cfg.Servers))
+	for name, sc := range cfg.Servers {
+		if sc.Enabled {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	m := &Manager{}
+	for _, name := range names {
+		m.servers = append(m.servers, &serverState{
+			name:  name,
+			cfg:   cfg.Servers[name],
+			tools: make(map[string]*mcpTool),
+		})
+	}
+	m.ctx, m.cancel = context.WithCancel(context.Background())
+	return m
+}
+
+// Attach registers server tools into registry. Subagent registries only
+// receive the tools listed in each server's subagent_tools.
+func (m *Manager) Attach(registry *tools.ToolRegistry, subagent bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attached = append(m.attached, attachment{registry: registry, subagent: subagent})
+}
+
+// Start connects to every server in the background.
+func (m *Manager) Start() {
+	for _, s := range m.servers {
+		m.wg.Add(1)
+		go m.supervise(s)
+	}
+}
+
+// Close disconnects from all servers and removes their tools.
+func (m *Manager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// supervise keeps one server connected, reconnecting with exponential
+// backoff when it exits or the connection drops.
+func (m *Manager) supervise(s *serverState) {
+	defer m.wg.Done()
+
+	backoff := minRestartBackoff
+	for {
+		connectedAt := time.Now()
+		err := m.runOnce(s)
+		if m.ctx.Err() != nil {
+			return
+		}
+		if time.Since(connectedAt) > maxRestartBackoff {
+			backoff = minRestartBackoff
+		}
+
+		fields := map[string]interface{}{"server": s.name, "retry_in": backoff.String()}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		logger.WarnCF("mcp", "MCP server disconnected", fields)
+
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxRestartBackoff {
+			backoff = maxRestartBackoff
+		}
+	}
+}
+
+// runOnce connects, mounts the server's tools and blocks until the
+// connection is lost or the manager is closed.
+func (m *Manager) runOnce(s *serverState) error {
+	client, err := newClient(s.name, s.cfg)
+	if err != nil {
+		return err
+	}
+	client.onToolsChanged = func() {
+		if err := m.syncTools(s, client); err != nil {
+			logger.WarnCF("mcp", "Failed to refresh MCP tools", map[string]interface{}{"server": s.name, "error": err.Error()})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(m.ctx, connectTimeout)
+	err = client.Connect(ctx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.client = client
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.client = nil
+		s.mu.Unlock()
+		m.unmountAll(s)
+		client.Close()
+	}()
+
+	if err := m.syncTools(s, client); err != nil {
+		return err
+	}
+
+	select {
+	case <-client.Done():
+		return nil
+	case <-m.ctx.Done():
+		return nil
+	}
+}
+
+// syncTools lists the server's tools and brings the registries in line:
+// new tools are registered, removed ones unregistered, changed ones
+// replaced.
+func (m *Manager) syncTools(s *serverState, client *Client) error {
+	ctx, cancel := context.WithTimeout(m.ctx, connectTimeout)
+	defer cancel()
+	infos, err := client.ListTools(ctx)
+	if err != nil {
+		return err
+	}
+
+	next := make(map[string]*mcpTool)
+	for _, info := range infos {
+		if !allowed(s.cfg.Tools, info.Name, true) {
+			continue
+		}
+		name := ToolName(s.name, info.Name)
+		next[name] = &mcpTool{server: s, info: info, regName: name}
+	}
+
+	m.mu.Lock()
+	attached := append([]attachment(nil), m.attached...)
+	m.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != client {
+		return nil // connection replaced or lost while listing
+	}
+	for name := range s.tools {
+		if _, ok := next[name]; !ok {
+			for _, a := range attached {
+				a.registry.Unregister(name)
+			}
+		}
+	}
+	for name, tool := range next {
+		if old, ok := s.tools[name]; ok && reflect.DeepEqual(old.info, tool.info) {
+			next[name] = old
+			continue
+		}
+		for _, a := range attached {
+			if a.subagent && !allowed(s.cfg.SubagentTools, tool.info.Name, false) {
+				continue
+			}
+			a.registry.Register(tool)
+		}
+	}
+	s.tools = next
+
+	logger.InfoCF("mcp", "MCP tools mounted", map[string]interface{}{"server": s.name, "count": len(next)})
+	return nil
+}
+
+func (m *Manager) unmountAll(s *serverState) {
+	m.mu.Lock()
+	attached := append([]attachment(nil), m.attached...)
+	m.mu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name := range s.tools {
+		for _, a := range attached {
+			a.registry.Unregister(name)
+		}
+	}
+	s.tools = make(map[string]*mcpTool)
+}
+
+// allowed reports whether tool is in list; an empty list allows
+// everything when emptyAllows is set, and "*" always allows everything.
+func allowed(list []string, tool string, emptyAllows bool) bool {
+	if len(list) == 0 {
+		return emptyAllows
+	}
+	for _, entry := range list {
+		if entry == "*" || entry == tool {
+			return true
+		}
+	}
+	return false
+}
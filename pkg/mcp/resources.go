@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const resourceScheme = "picooraclaw://"
+
+// bootstrapFiles are the workspace files the agent loads into its system
+// prompt.
+var bootstrapFiles = []string{"AGENTS.md", "SOUL.md", "USER.md", "IDENTITY.md"}
+
+// MemoryReader is the part of the agent's memory store resources read
+// from. Both the file and Oracle stores implement it.
+type MemoryReader interface {
+	ReadLongTerm() string
+	GetRecentDailyNotes(days int) string
+}
+
+// WorkspaceResources exposes bootstrap files, long-term memory and daily
+// notes as resources. Names look like "AGENTS.md", "MEMORY.md",
+// "notes/recent" and "notes/20260131".
+type WorkspaceResources struct {
+	workspace string
+	memory    MemoryReader
+	allow     []string
+}
+
+// NewWorkspaceResources creates the resource source. allow holds glob
+// patterns matched against resource names; empty allows everything.
+func NewWorkspaceResources(workspace string, memory MemoryReader, allow []string) *WorkspaceResources {
+	return &WorkspaceResources{workspace: workspace, memory: memory, allow: allow}
+}
+
+func (w *WorkspaceResources) ListResources() []Resource {
+	var list []Resource
+	add := func(name, desc string) {
+		if matchAny(w.allow, name) {
+			list = append(list, Resource{URI: resourceScheme + name, Name: name, Description: desc, MimeType: "text/markdown"})
+		}
+	}
+
+	for _, name := range bootstrapFiles {
+		if _, err := os.Stat(filepath.Join(w.workspace, name)); err == nil {
+			add(name, "Workspace bootstrap file")
+		}
+	}
+	if w.memory != nil {
+		add("MEMORY.md", "Long-term memory")
+		add("notes/recent", "Daily notes from the last 7 days")
+	}
+
+	// Individual daily notes: memory/YYYYMM/YYYYMMDD.md
+	matches, _ := filepath.Glob(filepath.Join(w.workspace, "memory", "[0-9][0-9][0-9][0-9][0-9][0-9]", "*.md"))
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	for _, m := range matches {
+		add("notes/"+strings.TrimSuffix(filepath.Base(m), ".md"), "Daily note")
+	}
+	return list
+}
+
+func (w *WorkspaceResources) ReadResource(uri string) (string, error) {
+	name := strings.TrimPrefix(uri, resourceScheme)
+	if name == uri || !matchAny(w.allow, name) {
+		return "", fmt.Errorf("resource not found: %s", uri)
+	}
+
+	switch {
+	case name == "MEMORY.md" && w.memory != nil:
+		return w.memory.ReadLongTerm(), nil
+	case name == "notes/recent" && w.memory != nil:
+		return w.memory.GetRecentDailyNotes(7), nil
+	case strings.HasPrefix(name, "notes/"):
+		day := strings.TrimPrefix(name, "notes/")
+		if len(day) != 8 || strings.Trim(day, "0123456789") != "" {
+			return "", fmt.Errorf("resource not found: %s", uri)
+		}
+		data, err := os.ReadFile(filepath.Join(w.workspace, "memory", day[:6], day+".md"))
+		if err != nil {
+			return "", fmt.Errorf("resource not found: %s", uri)
+		}
+		return string(data), nil
+	}
+
+	for _, f := range bootstrapFiles {
+		if name == f {
+			data, err := os.ReadFile(filepath.Join(w.workspace, f))
+			if err != nil {
+				return "", fmt.Errorf("resource not found: %s", uri)
+			}
+			return string(data), nil
+		}
+	}
+	return "", fmt.Errorf("resource not found: %s", uri)
+}
+
+// WorkspacePrompts serves the workspace bootstrap files as prompts when no
+// Oracle prompt store is configured, keyed like the store ("AGENTS").
+type WorkspacePrompts struct {
+	Workspace string
+}
+
+func (w WorkspacePrompts) LoadBootstrapFiles() map[string]string {
+	out := make(map[string]string)
+	for _, f := range bootstrapFiles {
+		if data, err := os.ReadFile(filepath.Join(w.Workspace, f)); err == nil {
+			out[strings.TrimSuffix(f, ".md")] = string(data)
+		}
+	}
+	return out
+}
+
+// matchAny reports whether name matches one of the glob patterns; an empty
+// list matches everything.
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,347 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jasperan/picooraclaw/pkg/logger"
+	"github.com/jasperan/picooraclaw/pkg/tools"
+)
+
+// Resource describes a readable document exposed by the server.
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ResourceSource lists and reads the server's resources.
+type ResourceSource interface {
+	ListResources() []Resource
+	ReadResource(uri string) (string, error)
+}
+
+// PromptSource supplies named prompts. It matches the agent's prompt store,
+// whose entries map prompt name to content.
+type PromptSource interface {
+	LoadBootstrapFiles() map[string]string
+}
+
+// ServerOptions configures a Server.
+type ServerOptions struct {
+	Name      string
+	Version   string
+	Tools     *tools.ToolRegistry // every registered tool is exposed
+	Resources ResourceSource      // optional
+	Prompts   PromptSource        // optional
+	// PromptAllow limits the exposed prompts (glob patterns, all when empty).
+	PromptAllow []string
+	// AuthToken, when set, is required as a bearer token over HTTP.
+	AuthToken string
+}
+
+// Server answers MCP requests over stdio or streamable HTTP.
+type Server struct {
+	opts ServerOptions
+
+	mu       sync.Mutex
+	sessions map[string]bool
+}
+
+// NewServer creates a server.
+func NewServer(opts ServerOptions) *Server {
+	if opts.Name == "" {
+		opts.Name = "picooraclaw"
+	}
+	return &Server{opts: opts, sessions: make(map[string]bool)}
+}
+
+// ServeStdio reads newline-delimited JSON-RPC messages from r and writes
+// responses to w until r is closed or ctx is cancelled.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	reader := bufio.NewReaderSize(r, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if trimmed := strings.TrimSpace(string(line)); trimmed != "" {
+			wg.Add(1)
+			go func(data []byte) {
+				defer wg.Done()
+				resp := s.handle(ctx, data)
+				if resp == nil {
+					return
+				}
+				writeMu.Lock()
+				defer writeMu.Unlock()
+				w.Write(append(resp, '\n'))
+			}([]byte(trimmed))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// ServeHTTP implements the streamable HTTP transport with plain JSON
+// responses. Server-initiated streams are not offered, so GET returns 405.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.AuthToken != "" {
+		got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.AuthToken)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	sessionID := r.Header.Get("Mcp-Session-Id")
+	switch r.Method {
+	case http.MethodPost:
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.sessions, sessionID)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 4*1024*1024))
+	if err != nil {
+		http.Error(w, "reading body", http.StatusBadRequest)
+		return
+	}
+
+	var probe struct {
+		Method string `json:"method"`
+	}
+	json.Unmarshal(body, &probe)
+	if probe.Method == "initialize" {
+		sessionID = newSessionID()
+		s.mu.Lock()
+		s.sessions[sessionID] = true
+		s.mu.Unlock()
+	} else {
+		s.mu.Lock()
+		known := s.sessions[sessionID]
+		s.mu.Unlock()
+		if sessionID == "" {
+			http.Error(w, "missing Mcp-Session-Id", http.StatusBadRequest)
+			return
+		}
+		if !known {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+	}
+
+	w.Header().Set("Mcp-Session-Id", sessionID)
+	resp := s.handle(r.Context(), body)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// handle processes one message and returns the encoded response, or nil
+// for notifications.
+func (s *Server) handle(ctx context.Context, data []byte) []byte {
+	var msg rpcMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return encodeResponse(json.RawMessage("null"), nil, &rpcError{Code: -32700, Message: "parse error"})
+	}
+	if len(msg.ID) == 0 {
+		return nil // notification (initialized, cancelled, ...)
+	}
+
+	result, rerr := s.dispatch(ctx, msg.Method, msg.Params)
+	return encodeResponse(msg.ID, result, rerr)
+}
+
+func encodeResponse(id json.RawMessage, result interface{}, rerr *rpcError) []byte {
+	resp := rpcMessage{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = &rpcError{Code: -32603, Message: err.Error()}
+		} else {
+			resp.Result = raw
+		}
+	}
+	data, _ := json.Marshal(resp)
+	return data
+}
+
+func invalidParams(err error) *rpcError {
+	return &rpcError{Code: -32602, Message: err.Error()}
+}
+
+func (s *Server) dispatch(ctx context.Context, method string, params json.RawMessage) (interface{}, *rpcError) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(params, &p)
+		version := ProtocolVersion
+		if p.ProtocolVersion != "" && p.ProtocolVersion < ProtocolVersion {
+			version = p.ProtocolVersion // speak the older revision the client asked for
+		}
+		capabilities := map[string]interface{}{"tools": map[string]interface{}{}}
+		if s.opts.Resources != nil {
+			capabilities["resources"] = map[string]interface{}{}
+		}
+		if s.opts.Prompts != nil {
+			capabilities["prompts"] = map[string]interface{}{}
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    capabilities,
+			"serverInfo":      map[string]interface{}{"name": s.opts.Name, "version": s.opts.Version},
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		list := []map[string]interface{}{}
+		if s.opts.Tools != nil {
+			for _, name := range s.opts.Tools.List() {
+				tool, _ := s.opts.Tools.Get(name)
+				list = append(list, map[string]interface{}{
+					"name":        tool.Name(),
+					"description": tool.Description(),
+					"inputSchema": tool.Parameters(),
+				})
+			}
+		}
+		return map[string]interface{}{"tools": list}, nil
+
+	case "tools/call":
+		var p struct {
+			Name      string                 `json:"name"`
+			Arguments map[string]interface{} `json:"arguments"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		if s.opts.Tools == nil {
+			return nil, invalidParams(fmt.Errorf("unknown tool %q", p.Name))
+		}
+		if _, ok := s.opts.Tools.Get(p.Name); !ok {
+			return nil, invalidParams(fmt.Errorf("unknown tool %q", p.Name))
+		}
+		result := s.opts.Tools.ExecuteWithContext(ctx, p.Name, p.Arguments, "mcp", "", nil)
+		text := result.ForLLM
+		if text == "" {
+			text = result.ForUser
+		}
+		return CallToolResult{
+			Content: []Content{{Type: "text", Text: text}},
+			IsError: result.IsError,
+		}, nil
+
+	case "resources/list":
+		list := []Resource{}
+		if s.opts.Resources != nil {
+			list = append(list, s.opts.Resources.ListResources()...)
+		}
+		return map[string]interface{}{"resources": list}, nil
+
+	case "resources/read":
+		var p struct {
+			URI string `json:"uri"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		if s.opts.Resources == nil {
+			return nil, &rpcError{Code: -32002, Message: "resource not found"}
+		}
+		text, err := s.opts.Resources.ReadResource(p.URI)
+		if err != nil {
+			return nil, &rpcError{Code: -32002, Message: err.Error()}
+		}
+		return map[string]interface{}{"contents": []ResourceContent{{URI: p.URI, MimeType: "text/markdown", Text: text}}}, nil
+
+	case "prompts/list":
+		list := []map[string]interface{}{}
+		prompts := s.prompts()
+		names := make([]string, 0, len(prompts))
+		for name := range prompts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			list = append(list, map[string]interface{}{"name": name, "description": "picooraclaw " + name + " prompt"})
+		}
+		return map[string]interface{}{"prompts": list}, nil
+
+	case "prompts/get":
+		var p struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		content, ok := s.prompts()[p.Name]
+		if !ok {
+			return nil, invalidParams(fmt.Errorf("unknown prompt %q", p.Name))
+		}
+		return map[string]interface{}{
+			"description": "picooraclaw " + p.Name + " prompt",
+			"messages": []map[string]interface{}{{
+				"role":    "user",
+				"content": Content{Type: "text", Text: content},
+			}},
+		}, nil
+	}
+
+	logger.DebugCF("mcp", "Unsupported method", map[string]interface{}{"method": method})
+	return nil, &rpcError{Code: -32601, Message: "method not found: " + method}
+}
+
+// prompts returns the allowlisted prompts.
+func (s *Server) prompts() map[string]string {
+	if s.opts.Prompts == nil {
+		return nil
+	}
+	all := s.opts.Prompts.LoadBootstrapFiles()
+	out := make(map[string]string, len(all))
+	for name, content := range all {
+		if matchAny(s.opts.PromptAllow, name) {
+			out[name] = content
+		}
+	}
+	return out
+}
@@ -0,0 +1,181 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/tools"
+)
+
+type upperTool struct{}
+
+func (upperTool) Name() string        { return "upper" }
+func (upperTool) Description() string { return "Uppercase text" }
+func (upperTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object", "properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}}
+}
+func (upperTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	text, _ := args["text"].(string)
+	return tools.NewToolResult(strings.ToUpper(text))
+}
+
+type fakeMemory struct{}
+
+func (fakeMemory) ReadLongTerm() string                { return "likes Go" }
+func (fakeMemory) GetRecentDailyNotes(days int) string { return "did things" }
+
+func testWorkspace(t *testing.T) string {
+	t.Helper()
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "AGENTS.md"), []byte("be helpful"), 0644)
+	os.MkdirAll(filepath.Join(ws, "memory", "202601"), 0755)
+	os.WriteFile(filepath.Join(ws, "memory", "202601", "20260131.md"), []byte("note body"), 0644)
+	return ws
+}
+
+func testServer(t *testing.T, opts ServerOptions) *Server {
+	t.Helper()
+	registry := tools.NewToolRegistry()
+	registry.Register(upperTool{})
+	ws := testWorkspace(t)
+	opts.Tools = registry
+	if opts.Resources == nil {
+		opts.Resources = NewWorkspaceResources(ws, fakeMemory{}, nil)
+	}
+	opts.Prompts = WorkspacePrompts{Workspace: ws}
+	return NewServer(opts)
+}
+
+func TestServer_StdioRoundTrip(t *testing.T) {
+	s := testServer(t, ServerOptions{})
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"upper","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"nope"}`,
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	if err := s.ServeStdio(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatalf("ServeStdio() error: %v", err)
+	}
+
+	responses := map[string]rpcMessage{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg rpcMessage
+		json.Unmarshal([]byte(line), &msg)
+		responses[string(msg.ID)] = msg
+	}
+	if len(responses) != 3 {
+		t.Fatalf("got %d responses, want 3 (notification unanswered):\n%s", len(responses), out.String())
+	}
+	if !strings.Contains(string(responses["1"].Result), `"protocolVersion":"2025-03-26"`) {
+		t.Errorf("initialize should negotiate the older revision: %s", responses["1"].Result)
+	}
+	if !strings.Contains(string(responses["2"].Result), `"text":"HI"`) {
+		t.Errorf("tools/call result = %s", responses["2"].Result)
+	}
+	if responses["3"].Error == nil || responses["3"].Error.Code != -32601 {
+		t.Errorf("unknown method response = %+v", responses["3"])
+	}
+}
+
+func TestServer_HTTPWithClient(t *testing.T) {
+	s := testServer(t, ServerOptions{AuthToken: "secret", PromptAllow: []string{"AGENT*"}})
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	resp, _ := http.Post(server.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d", resp.StatusCode)
+	}
+
+	client, err := newClient("self", config.MCPServerConfig{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := client.Connect(ctx); err != nil {
+		t.Fatalf("Connect() error: %v", err)
+	}
+	defer client.Close()
+
+	list, err := client.ListTools(ctx)
+	if err != nil || len(list) != 1 || list[0].Name != "upper" {
+		t.Fatalf("ListTools() = %+v, %v", list, err)
+	}
+	res, err := client.CallTool(ctx, "upper", map[string]interface{}{"text": "mcp"})
+	if err != nil || formatContent(res) != "MCP" {
+		t.Fatalf("CallTool() = %+v, %v", res, err)
+	}
+
+	var resources struct {
+		Resources []Resource `json:"resources"`
+	}
+	if err := client.call(ctx, "resources/list", nil, &resources); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range resources.Resources {
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, ",") != "AGENTS.md,MEMORY.md,notes/recent,notes/20260131" {
+		t.Errorf("resources = %v", names)
+	}
+
+	var read struct {
+		Contents []ResourceContent `json:"contents"`
+	}
+	if err := client.call(ctx, "resources/read", map[string]string{"uri": "picooraclaw://notes/20260131"}, &read); err != nil || read.Contents[0].Text != "note body" {
+		t.Errorf("resources/read = %+v, %v", read, err)
+	}
+
+	var prompt struct {
+		Messages []struct {
+			Content Content `json:"content"`
+		} `json:"messages"`
+	}
+	if err := client.call(ctx, "prompts/get", map[string]string{"name": "AGENTS"}, &prompt); err != nil || prompt.Messages[0].Content.Text != "be helpful" {
+		t.Errorf("prompts/get = %+v, %v", prompt, err)
+	}
+}
+
+func TestServer_HTTPRequiresSession(t *testing.T) {
+	server := httptest.NewServer(testServer(t, ServerOptions{}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	req.Header.Set("Mcp-Session-Id", "bogus")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown session status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestWorkspaceResources_Allowlist(t *testing.T) {
+	ws := testWorkspace(t)
+	r := NewWorkspaceResources(ws, fakeMemory{}, []string{"notes/*"})
+
+	for _, res := range r.ListResources() {
+		if !strings.HasPrefix(res.Name, "notes/") {
+			t.Errorf("resource %q should be filtered out", res.Name)
+		}
+	}
+	if _, err := r.ReadResource("picooraclaw://AGENTS.md"); err == nil {
+		t.Error("reading a non-allowlisted resource should fail")
+	}
+	if _, err := r.ReadResource("picooraclaw://notes/../../etc"); err == nil {
+		t.Error("malformed note name should be rejected")
+	}
+}
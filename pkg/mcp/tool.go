@@ -0,0 +1,129 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jasperan/picooraclaw/pkg/tools"
+)
+
+// maxToolNameLen is the longest function name LLM APIs accept.
+const maxToolNameLen = 64
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// ToolName returns the registry name of a server tool: "mcp_<server>_<tool>",
+// sanitized and truncated to what LLM function-calling APIs accept. Truncated
+// names end in a hash of the full name so tools sharing a long prefix stay
+// distinct.
+func ToolName(server, tool string) string {
+	name := "mcp_" + invalidNameChars.ReplaceAllString(server, "_") + "_" + invalidNameChars.ReplaceAllString(tool, "_")
+	if len(name) > maxToolNameLen {
+		sum := sha256.Sum256([]byte(server + "\x00" + tool))
+		suffix := "_" + hex.EncodeToString(sum[:4])
+		name = name[:maxToolNameLen-len(suffix)] + suffix
+	}
+	return name
+}
+
+// mcpTool exposes one server tool through the agent's ToolRegistry. Calls
+// go to whichever connection the manager currently holds, so the tool stays
+// valid across server restarts.
+type mcpTool struct {
+	server  *serverState
+	info    ToolInfo
+	regName string
+}
+
+func (t *mcpTool) Name() string {
+	return t.regName
+}
+
+func (t *mcpTool) Description() string {
+	desc := t.info.Description
+	if desc == "" {
+		desc = t.info.Title
+	}
+	return fmt.Sprintf("[MCP %s] %s", t.server.name, desc)
+}
+
+// Parameters returns a copy of the server's input schema with "type" and
+// "properties" filled in. The copy keeps info unchanged, so concurrent calls
+// do not race and list_changed comparisons still see the server's schema.
+func (t *mcpTool) Parameters() map[string]interface{} {
+	schema := make(map[string]interface{}, len(t.info.InputSchema)+2)
+	for k, v := range t.info.InputSchema {
+		schema[k] = v
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "object"
+	}
+	if _, ok := schema["properties"]; !ok {
+		schema["properties"] = map[string]interface{}{}
+	}
+	return schema
+}
+
+func (t *mcpTool) Execute(ctx context.Context, args map[string]interface{}) *tools.ToolResult {
+	client := t.server.current()
+	if client == nil {
+		return tools.ErrorResult(fmt.Sprintf("MCP server %q is not connected", t.server.name))
+	}
+
+	result, err := client.CallTool(ctx, t.info.Name, args)
+	if err != nil {
+		return tools.ErrorResult(fmt.Sprintf("MCP %s/%s failed: %v", t.server.name, t.info.Name, err)).WithError(err)
+	}
+
+	text := formatContent(result)
+	if result.IsError {
+		return tools.ErrorResult(text)
+	}
+	return tools.NewToolResult(text)
+}
+
+// formatContent flattens a tool result into text for the LLM. Binary
+// content is summarized rather than inlined.
+func formatContent(result *CallToolResult) string {
+	var parts []string
+	for _, c := range result.Content {
+		switch c.Type {
+		case "text":
+			parts = append(parts, c.Text)
+		case "image", "audio":
+			parts = append(parts, fmt.Sprintf("[%s: %s, %d bytes]", c.Type, c.MimeType, base64Size(c.Data)))
+		case "resource_link":
+			parts = append(parts, fmt.Sprintf("[resource: %s %s]", c.Name, c.URI))
+		case "resource":
+			if c.Resource == nil {
+				continue
+			}
+			if c.Resource.Text != "" {
+				parts = append(parts, fmt.Sprintf("[resource %s]\n%s", c.Resource.URI, c.Resource.Text))
+			} else {
+				parts = append(parts, fmt.Sprintf("[resource %s: %s, %d bytes]", c.Resource.URI, c.Resource.MimeType, base64Size(c.Resource.Blob)))
+			}
+		}
+	}
+
+	if len(parts) == 0 && result.StructuredContent != nil {
+		if data, err := json.MarshalIndent(result.StructuredContent, "", "  "); err == nil {
+			parts = append(parts, string(data))
+		}
+	}
+	if len(parts) == 0 {
+		return "(no output)"
+	}
+	return strings.Join(parts, "\n")
+}
+
+// base64Size returns the decoded size of standard base64 data.
+func base64Size(data string) int {
+	padding := len(data) - len(strings.TrimRight(data, "="))
+	return len(data)*3/4 - padding
+}
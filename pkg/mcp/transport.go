@@ -0,0 +1,419 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/jasperan/picooraclaw/pkg/logger"
+)
+
+// transport moves JSON-RPC messages between the client and one server.
+// Incoming messages are passed to the handler given to start; done is
+// closed when the connection is lost.
+type transport interface {
+	start(ctx context.Context, handle func([]byte)) error
+	send(ctx context.Context, msg []byte) error
+	close() error
+	done() <-chan struct{}
+}
+
+// --- stdio ---
+
+// stdioTransport runs the server as a child process speaking
+// newline-delimited JSON on stdin/stdout.
+type stdioTransport struct {
+	name    string
+	command string
+	args    []string
+	env     map[string]string
+	dir     string
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	mu     sync.Mutex
+	doneCh chan struct{}
+}
+
+func (t *stdioTransport) start(ctx context.Context, handle func([]byte)) error {
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Dir = t.dir
+	cmd.Env = os.Environ()
+	for k, v := range t.env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %s: %w", t.command, err)
+	}
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.doneCh = make(chan struct{})
+
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.DebugCF("mcp", "Server stderr", map[string]interface{}{"server": t.name, "line": scanner.Text()})
+		}
+	}()
+
+	go func() {
+		defer close(t.doneCh)
+		reader := bufio.NewReaderSize(stdout, 64*1024)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if line = bytes.TrimSpace(line); len(line) > 0 {
+				handle(line)
+			}
+			if err != nil {
+				break
+			}
+		}
+		cmd.Wait()
+	}()
+	return nil
+}
+
+func (t *stdioTransport) send(ctx context.Context, msg []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stdin == nil {
+		return errors.New("not started")
+	}
+	_, err := t.stdin.Write(append(msg, '\n'))
+	return err
+}
+
+func (t *stdioTransport) close() error {
+	if t.cmd == nil {
+		return nil
+	}
+	t.stdin.Close()
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+	}
+	<-t.doneCh
+	return nil
+}
+
+func (t *stdioTransport) done() <-chan struct{} {
+	return t.doneCh
+}
+
+// --- streamable HTTP ---
+
+// httpTransport implements the streamable HTTP transport: every message is
+// POSTed to one endpoint, which answers with JSON or an SSE stream. A
+// standalone GET stream carries server-initiated notifications when the
+// server offers one.
+type httpTransport struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	handle    func([]byte)
+	mu        sync.Mutex
+	sessionID string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	doneCh    chan struct{}
+	closeOnce sync.Once
+	listening bool
+}
+
+func (t *httpTransport) start(ctx context.Context, handle func([]byte)) error {
+	t.handle = handle
+	t.ctx, t.cancel = context.WithCancel(context.Background())
+	t.doneCh = make(chan struct{})
+	return nil
+}
+
+func (t *httpTransport) send(ctx context.Context, msg []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	if sid := resp.Header.Get("Mcp-Session-Id"); sid != "" {
+		t.mu.Lock()
+		t.sessionID = sid
+		t.mu.Unlock()
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound && t.hasSession():
+		// The server dropped our session; treat it like a lost connection
+		// so the manager reconnects and initializes a new one.
+		resp.Body.Close()
+		t.close()
+		return errors.New("session expired")
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+		return nil
+	case resp.StatusCode >= 300:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		go func() {
+			defer resp.Body.Close()
+			readSSE(resp.Body, func(event, data string) {
+				if event == "" || event == "message" {
+					t.handle([]byte(data))
+				}
+			})
+		}()
+	} else {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		if body = bytes.TrimSpace(body); len(body) > 0 {
+			t.handle(body)
+		}
+	}
+
+	t.startListening()
+	return nil
+}
+
+func (t *httpTransport) hasSession() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID != ""
+}
+
+// startListening opens the optional GET stream once a session exists.
+func (t *httpTransport) startListening() {
+	t.mu.Lock()
+	if t.listening || t.sessionID == "" {
+		t.mu.Unlock()
+		return
+	}
+	t.listening = true
+	t.mu.Unlock()
+
+	go func() {
+		req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.url, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		t.setHeaders(req)
+		resp, err := t.client.Do(req)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return // server does not offer a notification stream
+		}
+		readSSE(resp.Body, func(event, data string) {
+			if event == "" || event == "message" {
+				t.handle([]byte(data))
+			}
+		})
+	}()
+}
+
+func (t *httpTransport) setHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", t.sessionID)
+	}
+	t.mu.Unlock()
+}
+
+func (t *httpTransport) close() error {
+	t.closeOnce.Do(func() {
+		if t.cancel != nil {
+			t.cancel()
+		}
+		if t.doneCh != nil {
+			close(t.doneCh)
+		}
+	})
+	return nil
+}
+
+func (t *httpTransport) done() <-chan struct{} {
+	return t.doneCh
+}
+
+// --- legacy HTTP+SSE ---
+
+// sseTransport implements the older HTTP+SSE transport: a long-lived GET
+// stream whose first "endpoint" event names the URL to POST messages to.
+type sseTransport struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu       sync.Mutex
+	endpoint string
+	cancel   context.CancelFunc
+	doneCh   chan struct{}
+}
+
+func (t *sseTransport) start(ctx context.Context, handle func([]byte)) error {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.url, nil)
+	if err != nil {
+		cancel()
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		cancel()
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("SSE stream: HTTP %d", resp.StatusCode)
+	}
+
+	t.cancel = cancel
+	t.doneCh = make(chan struct{})
+	endpointReady := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		defer close(t.doneCh)
+		defer resp.Body.Close()
+		readSSE(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				base, _ := url.Parse(t.url)
+				ref, err := url.Parse(strings.TrimSpace(data))
+				if err != nil || base == nil {
+					return
+				}
+				t.mu.Lock()
+				t.endpoint = base.ResolveReference(ref).String()
+				t.mu.Unlock()
+				once.Do(func() { close(endpointReady) })
+			case "", "message":
+				handle([]byte(data))
+			}
+		})
+	}()
+
+	select {
+	case <-endpointReady:
+		return nil
+	case <-t.doneCh:
+		return errors.New("SSE stream closed before endpoint event")
+	case <-ctx.Done():
+		cancel()
+		return ctx.Err()
+	}
+}
+
+func (t *sseTransport) send(ctx context.Context, msg []byte) error {
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (t *sseTransport) close() error {
+	if t.cancel != nil {
+		t.cancel()
+		<-t.doneCh
+	}
+	return nil
+}
+
+func (t *sseTransport) done() <-chan struct{} {
+	return t.doneCh
+}
+
+// readSSE parses a text/event-stream body, calling fn for each event.
+func readSSE(r io.Reader, fn func(event, data string)) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				fn(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if len(data) > 0 {
+		fn(event, strings.Join(data, "\n"))
+	}
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// ModelInfo describes a model's capabilities and list price. Prices are in
+// USD per million tokens; zero means unknown or free (local models).
+type ModelInfo struct {
+	ID            string  `json:"id"`
+	Provider      string  `json:"provider,omitempty"`
+	ContextWindow int     `json:"context_window,omitempty"`
+	MaxOutput     int     `json:"max_output,omitempty"`
+	Vision        bool    `json:"vision,omitempty"`
+	Tools         bool    `json:"tools,omitempty"`
+	Reasoning     bool    `json:"reasoning,omitempty"`
+	InputPrice    float64 `json:"input_price,omitempty"`
+	OutputPrice   float64 `json:"output_price,omitempty"`
+}
+
+// builtinModels is keyed by normalized model ID prefix; the longest matching
+// prefix wins, so "gpt-4o-mini" is found before "gpt-4o".
+var builtinModels = map[string]ModelInfo{
+	// Anthropic
+	"claude-opus-4":     {ContextWindow: 200000, MaxOutput: 32000, Vision: true, Tools: true, Reasoning: true, InputPrice: 15, OutputPrice: 75},
+	"claude-opus-4-5":   {ContextWindow: 200000, MaxOutput: 64000, Vision: true, Tools: true, Reasoning: true, InputPrice: 5, OutputPrice: 25},
+	"claude-sonnet-4":   {ContextWindow: 200000, MaxOutput: 64000, Vision: true, Tools: true, Reasoning: true, InputPrice: 3, OutputPrice: 15},
+	"claude-haiku-4-5":  {ContextWindow: 200000, MaxOutput: 64000, Vision: true, Tools: true, Reasoning: true, InputPrice: 1, OutputPrice: 5},
+	"claude-3-7-sonnet": {ContextWindow: 200000, MaxOutput: 64000, Vision: true, Tools: true, Reasoning: true, InputPrice: 3, OutputPrice: 15},
+	"claude-3-5-sonnet": {ContextWindow: 200000, MaxOutput: 8192, Vision: true, Tools: true, InputPrice: 3, OutputPrice: 15},
+	"claude-3-5-haiku":  {ContextWindow: 200000, MaxOutput: 8192, Tools: true, InputPrice: 0.8, OutputPrice: 4},
+
+	// OpenAI
+	"gpt-5":        {ContextWindow: 400000, MaxOutput: 128000, Vision: true, Tools: true, Reasoning: true, InputPrice: 1.25, OutputPrice: 10},
+	"gpt-5-mini":   {ContextWindow: 400000, MaxOutput: 128000, Vision: true, Tools: true, Reasoning: true, InputPrice: 0.25, OutputPrice: 2},
+	"gpt-5-nano":   {ContextWindow: 400000, MaxOutput: 128000, Vision: true, Tools: true, Reasoning: true, InputPrice: 0.05, OutputPrice: 0.4},
+	"gpt-4.1":      {ContextWindow: 1047576, MaxOutput: 32768, Vision: true, Tools: true, InputPrice: 2, OutputPrice: 8},
+	"gpt-4.1-mini": {ContextWindow: 1047576, MaxOutput: 32768, Vision: true, Tools: true, InputPrice: 0.4, OutputPrice: 1.6},
+	"gpt-4.1-nano": {ContextWindow: 1047576, MaxOutput: 32768, Vision: true, Tools: true, InputPrice: 0.1, OutputPrice: 0.4},
+	"gpt-4o":       {ContextWindow: 128000, MaxOutput: 16384, Vision: true, Tools: true, InputPrice: 2.5, OutputPrice: 10},
+	"gpt-4o-mini":  {ContextWindow: 128000, MaxOutput: 16384, Vision: true, Tools: true, InputPrice: 0.15, OutputPrice: 0.6},
+	"o3":           {ContextWindow: 200000, MaxOutput: 100000, Vision: true, Tools: true, Reasoning: true, InputPrice: 2, OutputPrice: 8},
+	"o4-mini":      {ContextWindow: 200000, MaxOutput: 100000, Vision: true, Tools: true, Reasoning: true, InputPrice: 1.1, OutputPrice: 4.4},
+	"gpt-oss-120b": {ContextWindow: 131072, MaxOutput: 65536, Tools: true, Reasoning: true, InputPrice: 0.15, OutputPrice: 0.75},
+	"gpt-oss-20b":  {ContextWindow: 131072, MaxOutput: 65536, Tools: true, Reasoning: true, InputPrice: 0.1, OutputPrice: 0.5},
+
+	// Google
+	"gemini-2.5-pro":        {ContextWindow: 1048576, MaxOutput: 65536, Vision: true, Tools: true, Reasoning: true, InputPrice: 1.25, OutputPrice: 10},
+	"gemini-2.5-flash":      {ContextWindow: 1048576, MaxOutput: 65536, Vision: true, Tools: true, Reasoning: true, InputPrice: 0.3, OutputPrice: 2.5},
+	"gemini-2.5-flash-lite": {ContextWindow: 1048576, MaxOutput: 65536, Vision: true, Tools: true, Reasoning: true, InputPrice: 0.1, OutputPrice: 0.4},
+	"gemini-2.0-flash":      {ContextWindow: 1048576, MaxOutput: 8192, Vision: true, Tools: true, InputPrice: 0.1, OutputPrice: 0.4},
+
+	// xAI (also served through OCI Generative AI as xai.grok-*)
+	"grok-4":      {ContextWindow: 256000, MaxOutput: 64000, Vision: true, Tools: true, Reasoning: true, InputPrice: 3, OutputPrice: 15},
+	"grok-3":      {ContextWindow: 131072, MaxOutput: 16384, Tools: true, InputPrice: 3, OutputPrice: 15},
+	"grok-3-mini": {ContextWindow: 131072, MaxOutput: 16384, Tools: true, Reasoning: true, InputPrice: 0.3, OutputPrice: 0.5},
+
+	// DeepSeek
+	"deepseek-chat":     {ContextWindow: 128000, MaxOutput: 8192, Tools: true, InputPrice: 0.27, OutputPrice: 1.1},
+	"deepseek-reasoner": {ContextWindow: 128000, MaxOutput: 64000, Tools: true, Reasoning: true, InputPrice: 0.55, OutputPrice: 2.19},
+
+	// Moonshot
+	"kimi-k2": {ContextWindow: 131072, MaxOutput: 16384, Tools: true, InputPrice: 0.6, OutputPrice: 2.5},
+
+	// Open-weight models commonly served by Groq, NVIDIA, vLLM and Ollama.
+	// Prices are Groq's; local serving is free.
+	"llama-3.3-70b": {ContextWindow: 131072, MaxOutput: 32768, Tools: true, InputPrice: 0.59, OutputPrice: 0.79},
+	"llama-3.1-8b":  {ContextWindow: 131072, MaxOutput: 8192, Tools: true, InputPrice: 0.05, OutputPrice: 0.08},
+	"llama3.1":      {ContextWindow: 131072, Tools: true},
+	"llama3.2":      {ContextWindow: 131072, Tools: true},
+	"qwen2.5":       {ContextWindow: 32768, Tools: true},
+	"qwen3":         {ContextWindow: 40960, Tools: true, Reasoning: true},
+	"mistral":       {ContextWindow: 32768, Tools: true},
+	"gemma3":        {ContextWindow: 131072, Vision: true},
+}
+
+var builtinKeys = func() []string {
+	keys := make([]string, 0, len(builtinModels))
+	for k := range builtinModels {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return keys
+}()
+
+// ociVendorPrefixes are the vendor namespaces OCI Generative AI puts in
+// front of model IDs (e.g. "xai.grok-4", "meta.llama-3.3-70b-instruct").
+var ociVendorPrefixes = []string{"xai.", "meta.", "openai.", "google.", "cohere."}
+
+// NormalizeID strips routing prefixes ("groq/", "openrouter/anthropic/",
+// "models/", "xai.") so IDs from different providers can be compared.
+func NormalizeID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if idx := strings.LastIndex(id, "/"); idx != -1 {
+		id = id[idx+1:]
+	}
+	for _, prefix := range ociVendorPrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return strings.TrimPrefix(id, prefix)
+		}
+	}
+	return id
+}
+
+// Builtin returns the built-in capability entry for a model ID.
+func Builtin(id string) (ModelInfo, bool) {
+	normalized := NormalizeID(id)
+	for _, key := range builtinKeys {
+		if strings.HasPrefix(normalized, key) {
+			info := builtinModels[key]
+			info.ID = id
+			return info, true
+		}
+	}
+	return ModelInfo{ID: id}, false
+}
+
+// mergeBuiltin fills the zero fields of a remotely listed model from the
+// built-in table. Values reported by the provider take precedence.
+func mergeBuiltin(info ModelInfo) ModelInfo {
+	known, ok := Builtin(info.ID)
+	if !ok {
+		return info
+	}
+	if info.ContextWindow == 0 {
+		info.ContextWindow = known.ContextWindow
+	}
+	if info.MaxOutput == 0 {
+		info.MaxOutput = known.MaxOutput
+	}
+	if info.InputPrice == 0 && info.OutputPrice == 0 {
+		info.InputPrice = known.InputPrice
+		info.OutputPrice = known.OutputPrice
+	}
+	info.Vision = info.Vision || known.Vision
+	info.Tools = info.Tools || known.Tools
+	info.Reasoning = info.Reasoning || known.Reasoning
+	return info
+}
+
+// EstimateCost returns the list-price cost in USD of a call with the given
+// token counts, or 0 when the model's price is unknown.
+func (m ModelInfo) EstimateCost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*m.InputPrice + float64(completionTokens)*m.OutputPrice) / 1000000
+}
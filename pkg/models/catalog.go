@@ -0,0 +1,546 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/logger"
+)
+
+const (
+	defaultCacheTTL = time.Hour
+	errorCacheTTL   = 2 * time.Minute
+	fetchTimeout    = 10 * time.Second
+	maxListPerGroup = 25
+)
+
+// endpoint is one configured provider whose models can be listed.
+type endpoint struct {
+	name    string
+	kind    string // "openai", "openrouter", "ollama", "anthropic", "gemini"
+	apiBase string
+	apiKey  string
+}
+
+type listing struct {
+	models    []ModelInfo
+	err       error
+	fetchedAt time.Time
+}
+
+// Catalog merges the models each configured provider reports with the
+// built-in capability table. Listings are cached per provider for an hour,
+// and failed listings for a few minutes so an unreachable provider does not
+// cost a fetch timeout on every call.
+type Catalog struct {
+	cfg    *config.Config
+	client *http.Client
+	ttl    time.Duration
+
+	mu       sync.Mutex
+	listings map[string]listing
+}
+
+func NewCatalog(cfg *config.Config) *Catalog {
+	return &Catalog{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: fetchTimeout},
+		ttl:      defaultCacheTTL,
+		listings: make(map[string]listing),
+	}
+}
+
+// endpoints returns the providers that have enough configuration to be
+// queried, using the same default API bases as providers.CreateProvider.
+func (c *Catalog) endpoints() []endpoint {
+	p := c.cfg.Providers
+	candidates := []struct {
+		name, kind, defaultBase string
+		pc                      config.ProviderConfig
+		needsKey                bool
+	}{
+		{"anthropic", "anthropic", "https://api.anthropic.com/v1", p.Anthropic, true},
+		{"openai", "openai", "https://api.openai.com/v1", p.OpenAI, true},
+		{"openrouter", "openrouter", "https://openrouter.ai/api/v1", p.OpenRouter, true},
+		{"groq", "openai", "https://api.groq.com/openai/v1", p.Groq, true},
+		{"gemini", "gemini", "https://generativelanguage.googleapis.com/v1beta", p.Gemini, true},
+		{"nvidia", "openai", "https://integrate.api.nvidia.com/v1", p.Nvidia, true},
+		{"moonshot", "openai", "https://api.moonshot.cn/v1", p.Moonshot, true},
+		{"deepseek", "openai", "https://api.deepseek.com/v1", p.DeepSeek, true},
+		{"vllm", "openai", "", p.VLLM, false},
+		{"ollama", "ollama", "", p.Ollama, false},
+	}
+
+	var result []endpoint
+	for _, cand := range candidates {
+		if cand.needsKey && cand.pc.APIKey == "" {
+			continue
+		}
+		base := cand.pc.APIBase
+		if base == "" {
+			base = cand.defaultBase
+		}
+		if base == "" {
+			continue
+		}
+		result = append(result, endpoint{
+			name:    cand.name,
+			kind:    cand.kind,
+			apiBase: strings.TrimRight(base, "/"),
+			apiKey:  cand.pc.APIKey,
+		})
+	}
+	return result
+}
+
+// List returns the models of every configured provider, keyed by provider
+// name. Providers that could not be queried are reported in errs.
+func (c *Catalog) List(ctx context.Context) (map[string][]ModelInfo, map[string]error) {
+	endpoints := c.endpoints()
+	result := make(map[string][]ModelInfo, len(endpoints))
+	errs := make(map[string]error)
+
+	var wg sync.WaitGroup
+	var resMu sync.Mutex
+	for _, ep := range endpoints {
+		if cached, ok := c.cached(ep.name); ok {
+			if cached.err != nil {
+				errs[ep.name] = cached.err
+			} else {
+				result[ep.name] = cached.models
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(ep endpoint) {
+			defer wg.Done()
+			fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+			defer cancel()
+
+			models, err := c.fetch(fetchCtx, ep)
+			resMu.Lock()
+			defer resMu.Unlock()
+			if err != nil {
+				logger.DebugCF("models", "Model listing failed", map[string]interface{}{
+					"provider": ep.name,
+					"error":    err.Error(),
+				})
+				errs[ep.name] = err
+				// A cancelled caller says nothing about the provider.
+				if ctx.Err() == nil {
+					c.store(ep.name, nil, err)
+				}
+				return
+			}
+			result[ep.name] = models
+			c.store(ep.name, models, nil)
+		}(ep)
+	}
+	wg.Wait()
+
+	return result, errs
+}
+
+// Lookup returns what is known about a model, preferring live listings and
+// falling back to the built-in table.
+func (c *Catalog) Lookup(ctx context.Context, model string) (ModelInfo, bool) {
+	listings, _ := c.List(ctx)
+	if info, ok := findModel(listings, model); ok {
+		return info, true
+	}
+	return Builtin(model)
+}
+
+// Validate reports an error when every configured provider could be listed
+// and none of them offers model. When some provider could not be listed the
+// model may be one of its own, so it is accepted with a warning naming the
+// providers that could not be checked.
+func (c *Catalog) Validate(ctx context.Context, model string) (string, error) {
+	listings, errs := c.List(ctx)
+	if _, ok := findModel(listings, model); ok {
+		return "", nil
+	}
+	if len(errs) > 0 {
+		return fmt.Sprintf("could not verify %s: %s unavailable", model, strings.Join(sortedKeys(errs), ", ")), nil
+	}
+	if len(listings) == 0 {
+		return "", nil
+	}
+
+	var suggestions []string
+	family := strings.SplitN(NormalizeID(model), "-", 2)[0]
+	for _, name := range sortedKeys(listings) {
+		for _, m := range listings[name] {
+			if strings.HasPrefix(NormalizeID(m.ID), family) && len(suggestions) < 5 {
+				suggestions = append(suggestions, m.ID)
+			}
+		}
+	}
+	msg := fmt.Sprintf("model %q is not offered by any configured provider", model)
+	if len(suggestions) > 0 {
+		msg += "; similar: " + strings.Join(suggestions, ", ")
+	}
+	return "", errors.New(msg)
+}
+
+// Format renders the catalog for /list models.
+func (c *Catalog) Format(ctx context.Context, current string) string {
+	listings, errs := c.List(ctx)
+
+	var sb strings.Builder
+	info, ok := findModel(listings, current)
+	if !ok {
+		info, _ = Builtin(current)
+	}
+	sb.WriteString(fmt.Sprintf("Current model: %s%s\n", current, describe(info)))
+
+	for _, name := range sortedKeys(listings) {
+		models := listings[name]
+		sb.WriteString(fmt.Sprintf("\n%s (%d models):\n", name, len(models)))
+		for i, m := range models {
+			if i == maxListPerGroup {
+				sb.WriteString(fmt.Sprintf("  ... and %d more\n", len(models)-maxListPerGroup))
+				break
+			}
+			marker := "  "
+			if NormalizeID(m.ID) == NormalizeID(current) {
+				marker = "* "
+			}
+			sb.WriteString(fmt.Sprintf("%s%s%s\n", marker, m.ID, describe(m)))
+		}
+	}
+
+	for _, name := range sortedKeys(errs) {
+		sb.WriteString(fmt.Sprintf("\n%s: unavailable (%v)\n", name, errs[name]))
+	}
+	if len(listings) == 0 && len(errs) == 0 {
+		sb.WriteString("\nNo providers configured for model listing.\n")
+	}
+
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func describe(m ModelInfo) string {
+	var parts []string
+	if m.ContextWindow > 0 {
+		parts = append(parts, formatTokens(m.ContextWindow)+" ctx")
+	}
+	if m.InputPrice > 0 || m.OutputPrice > 0 {
+		parts = append(parts, fmt.Sprintf("$%.2f/$%.2f per 1M", m.InputPrice, m.OutputPrice))
+	}
+	var caps []string
+	if m.Tools {
+		caps = append(caps, "tools")
+	}
+	if m.Vision {
+		caps = append(caps, "vision")
+	}
+	if m.Reasoning {
+		caps = append(caps, "reasoning")
+	}
+	if len(caps) > 0 {
+		parts = append(parts, strings.Join(caps, ","))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+func formatTokens(n int) string {
+	if n >= 1000000 {
+		return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	}
+	if n >= 1000 {
+		return fmt.Sprintf("%dk", n/1000)
+	}
+	return strconv.Itoa(n)
+}
+
+func (c *Catalog) cached(name string) (listing, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.listings[name]
+	ttl := c.ttl
+	if l.err != nil {
+		ttl = min(ttl, errorCacheTTL)
+	}
+	if !ok || time.Since(l.fetchedAt) > ttl {
+		return listing{}, false
+	}
+	return l, true
+}
+
+func (c *Catalog) store(name string, models []ModelInfo, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listings[name] = listing{models: models, err: err, fetchedAt: time.Now()}
+}
+
+func findModel(listings map[string][]ModelInfo, model string) (ModelInfo, bool) {
+	// An explicit provider prefix ("groq/llama-3.3-70b") narrows the search.
+	if idx := strings.Index(model, "/"); idx != -1 {
+		if models, ok := listings[model[:idx]]; ok {
+			rest := model[idx+1:]
+			for _, m := range models {
+				if m.ID == rest || m.ID == model {
+					return m, true
+				}
+			}
+		}
+	}
+	for _, name := range sortedKeys(listings) {
+		for _, m := range listings[name] {
+			if m.ID == model {
+				return m, true
+			}
+		}
+	}
+	normalized := NormalizeID(model)
+	for _, name := range sortedKeys(listings) {
+		for _, m := range listings[name] {
+			if NormalizeID(m.ID) == normalized {
+				return m, true
+			}
+		}
+	}
+	return ModelInfo{}, false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Catalog) fetch(ctx context.Context, ep endpoint) ([]ModelInfo, error) {
+	var models []ModelInfo
+	var err error
+	switch ep.kind {
+	case "openrouter":
+		models, err = c.fetchOpenRouter(ctx, ep)
+	case "ollama":
+		models, err = c.fetchOllama(ctx, ep)
+	case "anthropic":
+		models, err = c.fetchAnthropic(ctx, ep)
+	case "gemini":
+		models, err = c.fetchGemini(ctx, ep)
+	default:
+		models, err = c.fetchOpenAI(ctx, ep)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range models {
+		models[i].Provider = ep.name
+		models[i] = mergeBuiltin(models[i])
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+func (c *Catalog) getJSON(ctx context.Context, url string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+func bearer(apiKey string) map[string]string {
+	if apiKey == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + apiKey}
+}
+
+// fetchOpenAI handles OpenAI and compatible /models endpoints. Groq reports
+// context_window and vLLM max_model_len; both are optional.
+func (c *Catalog) fetchOpenAI(ctx context.Context, ep endpoint) ([]ModelInfo, error) {
+	var resp struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextWindow int    `json:"context_window"`
+			MaxModelLen   int    `json:"max_model_len"`
+		} `json:"data"`
+	}
+	if err := c.getJSON(ctx, ep.apiBase+"/models", bearer(ep.apiKey), &resp); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		ctxWindow := m.ContextWindow
+		if ctxWindow == 0 {
+			ctxWindow = m.MaxModelLen
+		}
+		models = append(models, ModelInfo{ID: m.ID, ContextWindow: ctxWindow})
+	}
+	return models, nil
+}
+
+// fetchOpenRouter uses OpenRouter's richer listing, which includes per-token
+// pricing, context length, input modalities and supported parameters.
+func (c *Catalog) fetchOpenRouter(ctx context.Context, ep endpoint) ([]ModelInfo, error) {
+	var resp struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+			Pricing       struct {
+				Prompt     string `json:"prompt"`
+				Completion string `json:"completion"`
+			} `json:"pricing"`
+			Architecture struct {
+				InputModalities []string `json:"input_modalities"`
+			} `json:"architecture"`
+			TopProvider struct {
+				MaxCompletionTokens int `json:"max_completion_tokens"`
+			} `json:"top_provider"`
+			SupportedParameters []string `json:"supported_parameters"`
+		} `json:"data"`
+	}
+	if err := c.getJSON(ctx, ep.apiBase+"/models", bearer(ep.apiKey), &resp); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		info := ModelInfo{
+			ID:            m.ID,
+			ContextWindow: m.ContextLength,
+			MaxOutput:     m.TopProvider.MaxCompletionTokens,
+			InputPrice:    perMillion(m.Pricing.Prompt),
+			OutputPrice:   perMillion(m.Pricing.Completion),
+		}
+		for _, mod := range m.Architecture.InputModalities {
+			if mod == "image" {
+				info.Vision = true
+			}
+		}
+		for _, p := range m.SupportedParameters {
+			switch p {
+			case "tools":
+				info.Tools = true
+			case "reasoning":
+				info.Reasoning = true
+			}
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// perMillion converts OpenRouter's per-token USD price string.
+func perMillion(perToken string) float64 {
+	v, err := strconv.ParseFloat(perToken, 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return v * 1000000
+}
+
+// fetchOllama lists locally pulled models from the native /api/tags
+// endpoint, which sits next to the OpenAI-compatible /v1 base.
+func (c *Catalog) fetchOllama(ctx context.Context, ep endpoint) ([]ModelInfo, error) {
+	var resp struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	root := strings.TrimSuffix(ep.apiBase, "/v1")
+	if err := c.getJSON(ctx, root+"/api/tags", nil, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		models = append(models, ModelInfo{ID: m.Name})
+	}
+	return models, nil
+}
+
+func (c *Catalog) fetchAnthropic(ctx context.Context, ep endpoint) ([]ModelInfo, error) {
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	headers := map[string]string{
+		"x-api-key":         ep.apiKey,
+		"anthropic-version": "2023-06-01",
+	}
+	if err := c.getJSON(ctx, ep.apiBase+"/models?limit=1000", headers, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		models = append(models, ModelInfo{ID: m.ID})
+	}
+	return models, nil
+}
+
+func (c *Catalog) fetchGemini(ctx context.Context, ep endpoint) ([]ModelInfo, error) {
+	var resp struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			OutputTokenLimit           int      `json:"outputTokenLimit"`
+			Thinking                   bool     `json:"thinking"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	base := strings.TrimSuffix(ep.apiBase, "/openai")
+	if err := c.getJSON(ctx, base+"/models?pageSize=1000", map[string]string{"x-goog-api-key": ep.apiKey}, &resp); err != nil {
+		return nil, err
+	}
+	models := make([]ModelInfo, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		chat := false
+		for _, method := range m.SupportedGenerationMethods {
+			if method == "generateContent" {
+				chat = true
+			}
+		}
+		if !chat {
+			continue
+		}
+		models = append(models, ModelInfo{
+			ID:            strings.TrimPrefix(m.Name, "models/"),
+			ContextWindow: m.InputTokenLimit,
+			MaxOutput:     m.OutputTokenLimit,
+			Reasoning:     m.Thinking,
+		})
+	}
+	return models, nil
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+)
+
+func emptyProvidersConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.Providers = config.ProvidersConfig{}
+	return cfg
+}
+
+func TestBuiltin_LongestPrefixAndNormalization(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantCtx int
+		wantIn  float64
+	}{
+		{"gpt-4o-mini-2024-07-18", 128000, 0.15},
+		{"gpt-4o", 128000, 2.5},
+		{"openrouter/anthropic/claude-sonnet-4-5", 200000, 3},
+		{"xai.grok-4", 256000, 3},
+		{"ollama/qwen2.5:14b", 32768, 0},
+		{"groq/llama-3.3-70b-versatile", 131072, 0.59},
+	}
+	for _, tt := range tests {
+		info, ok := Builtin(tt.id)
+		if !ok {
+			t.Errorf("Builtin(%q) not found", tt.id)
+			continue
+		}
+		if info.ContextWindow != tt.wantCtx || info.InputPrice != tt.wantIn {
+			t.Errorf("Builtin(%q) = ctx %d price %v, want %d / %v", tt.id, info.ContextWindow, info.InputPrice, tt.wantCtx, tt.wantIn)
+		}
+	}
+	if _, ok := Builtin("test-model"); ok {
+		t.Error("Builtin(test-model) should be unknown")
+	}
+}
+
+func TestCatalog_ListMergesProviders(t *testing.T) {
+	var hits atomic.Int32
+	openrouter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path != "/models" || r.Header.Get("Authorization") != "Bearer or-key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data": [{
+			"id": "anthropic/claude-sonnet-4.5",
+			"context_length": 1000000,
+			"pricing": {"prompt": "0.000003", "completion": "0.000015"},
+			"architecture": {"input_modalities": ["text", "image"]},
+			"supported_parameters": ["tools", "reasoning"]
+		}]}`))
+	}))
+	defer openrouter.Close()
+
+	ollama := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"models": [{"name": "qwen2.5:14b"}, {"name": "llama3.2:3b"}]}`))
+	}))
+	defer ollama.Close()
+
+	cfg := emptyProvidersConfig()
+	cfg.Providers.OpenRouter = config.ProviderConfig{APIKey: "or-key", APIBase: openrouter.URL}
+	cfg.Providers.Ollama = config.ProviderConfig{APIBase: ollama.URL + "/v1"}
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "gsk", APIBase: "http://127.0.0.1:1"}
+
+	c := NewCatalog(cfg)
+	listings, errs := c.List(t.Context())
+
+	or := listings["openrouter"]
+	if len(or) != 1 {
+		t.Fatalf("openrouter models = %+v", or)
+	}
+	m := or[0]
+	if m.ContextWindow != 1000000 || m.InputPrice != 3 || m.OutputPrice != 15 || !m.Vision || !m.Tools || !m.Reasoning {
+		t.Errorf("openrouter model = %+v", m)
+	}
+
+	ol := listings["ollama"]
+	if len(ol) != 2 || ol[0].ID != "llama3.2:3b" || ol[1].ContextWindow != 32768 {
+		t.Errorf("ollama models = %+v, want sorted and merged with builtin", ol)
+	}
+
+	if errs["groq"] == nil {
+		t.Error("expected groq listing error")
+	}
+
+	c.List(t.Context())
+	if hits.Load() != 1 {
+		t.Errorf("openrouter hits = %d, want cached after first listing", hits.Load())
+	}
+}
+
+func TestCatalog_CachesFailedListings(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cfg := emptyProvidersConfig()
+	cfg.Providers.Ollama = config.ProviderConfig{APIBase: server.URL}
+	c := NewCatalog(cfg)
+
+	for i := 0; i < 3; i++ {
+		if _, errs := c.List(t.Context()); errs["ollama"] == nil {
+			t.Fatalf("List() call %d: expected ollama error", i+1)
+		}
+	}
+	if hits.Load() != 1 {
+		t.Errorf("ollama hits = %d, want the failure cached", hits.Load())
+	}
+
+	c.mu.Lock()
+	l := c.listings["ollama"]
+	l.fetchedAt = time.Now().Add(-errorCacheTTL - time.Second)
+	c.listings["ollama"] = l
+	c.mu.Unlock()
+	c.List(t.Context())
+	if hits.Load() != 2 {
+		t.Errorf("ollama hits = %d, want a retry once the error expires", hits.Load())
+	}
+}
+
+func TestCatalog_Validate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": "llama-3.3-70b-versatile", "context_window": 131072}, {"id": "llama-3.1-8b-instant"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := emptyProvidersConfig()
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "gsk", APIBase: server.URL}
+	c := NewCatalog(cfg)
+
+	if _, err := c.Validate(t.Context(), "groq/llama-3.3-70b-versatile"); err != nil {
+		t.Errorf("Validate(prefixed) error: %v", err)
+	}
+	if _, err := c.Validate(t.Context(), "llama-3.1-8b-instant"); err != nil {
+		t.Errorf("Validate(bare) error: %v", err)
+	}
+	_, err := c.Validate(t.Context(), "llama-4-maverick")
+	if err == nil || !strings.Contains(err.Error(), "llama-3.3-70b-versatile") {
+		t.Errorf("Validate(unknown) = %v, want error with suggestions", err)
+	}
+}
+
+func TestCatalog_ValidateWithoutListingsAccepts(t *testing.T) {
+	c := NewCatalog(emptyProvidersConfig())
+	if _, err := c.Validate(t.Context(), "anything"); err != nil {
+		t.Errorf("Validate() = %v, want nil when nothing can be listed", err)
+	}
+}
+
+func TestCatalog_ValidateWithFailedProviderWarns(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": "llama-3.3-70b-versatile"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := emptyProvidersConfig()
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "gsk", APIBase: server.URL}
+	cfg.Providers.DeepSeek = config.ProviderConfig{APIKey: "sk", APIBase: "http://127.0.0.1:1"}
+	c := NewCatalog(cfg)
+
+	warning, err := c.Validate(t.Context(), "deepseek-chat")
+	if err != nil {
+		t.Fatalf("Validate() = %v, want the model accepted while deepseek is unavailable", err)
+	}
+	if !strings.Contains(warning, "deepseek") {
+		t.Errorf("warning = %q, want it to name the unavailable provider", warning)
+	}
+	if warning, _ := c.Validate(t.Context(), "llama-3.3-70b-versatile"); warning != "" {
+		t.Errorf("listed model should not warn, got %q", warning)
+	}
+}
+
+func TestCatalog_Format(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data": [{"id": "gpt-4o"}, {"id": "gpt-4o-mini"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := emptyProvidersConfig()
+	cfg.Providers.OpenAI = config.ProviderConfig{APIKey: "sk", APIBase: server.URL}
+	out := NewCatalog(cfg).Format(t.Context(), "gpt-4o")
+
+	if !strings.Contains(out, "Current model: gpt-4o (128k ctx, $2.50/$10.00 per 1M, tools,vision)") {
+		t.Errorf("Format() header missing capabilities:\n%s", out)
+	}
+	if !strings.Contains(out, "* gpt-4o (") || !strings.Contains(out, "  gpt-4o-mini (") {
+		t.Errorf("Format() should mark the current model:\n%s", out)
+	}
+}
+
+func TestModelInfo_EstimateCost(t *testing.T) {
+	info, _ := Builtin("gpt-4o")
+	if got := info.EstimateCost(1000000, 100000); got != 3.5 {
+		t.Errorf("EstimateCost() = %v, want 3.5", got)
+	}
+}
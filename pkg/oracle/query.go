@@ -0,0 +1,411 @@
+package oracle
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// QueryResult is a bounded result set with every value rendered as text.
+type QueryResult struct {
+	Columns   []string
+	Rows      [][]string
+	Truncated bool // the query returned more rows than the limit
+}
+
+// ColumnInfo describes one column of a table or view.
+type ColumnInfo struct {
+	Name     string
+	Type     string // e.g. VARCHAR2(100), NUMBER(10,2)
+	Nullable bool
+	Comment  string
+}
+
+// TableInfo is the metadata returned by DescribeTable.
+type TableInfo struct {
+	Owner      string
+	Name       string
+	Kind       string // TABLE or VIEW
+	Comment    string
+	Columns    []ColumnInfo
+	PrimaryKey []string
+}
+
+// ReadOnlyQuerier runs agent queries on a connection that should belong to
+// a read-only database user. Every query is parsed by ParseReadOnlyQuery
+// and runs in a SET TRANSACTION READ ONLY transaction that is rolled back.
+type ReadOnlyQuerier struct {
+	db      *sql.DB
+	maxRows int
+	timeout time.Duration
+	schemas []string // upper-cased owners DescribeTable may look at
+}
+
+// maxCellChars caps a single rendered value.
+const maxCellChars = 500
+
+// NewReadOnlyQuerier wraps db. Zero limits default to 200 rows and 30
+// seconds.
+func NewReadOnlyQuerier(db *sql.DB, maxRows int, timeout time.Duration, schemas []string) *ReadOnlyQuerier {
+	if maxRows <= 0 {
+		maxRows = 200
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	q := &ReadOnlyQuerier{db: db, maxRows: maxRows, timeout: timeout}
+	for _, s := range schemas {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			q.schemas = append(q.schemas, s)
+		}
+	}
+	return q
+}
+
+// Schemas returns the schemas DescribeTable and ListTables accept.
+func (q *ReadOnlyQuerier) Schemas() []string {
+	return q.schemas
+}
+
+// Query runs a single SELECT or WITH statement. binds maps bind names to
+// values: "status" for :status, "1" for :1. maxRows <= 0 or above the configured limit uses the
+// configured limit.
+func (q *ReadOnlyQuerier) Query(ctx context.Context, stmt string, binds interface{}, maxRows int) (*QueryResult, error) {
+	parsed, err := ParseReadOnlyQuery(stmt)
+	if err != nil {
+		return nil, err
+	}
+	args, err := bindArgs(parsed.Binds, binds)
+	if err != nil {
+		return nil, err
+	}
+	if maxRows <= 0 || maxRows > q.maxRows {
+		maxRows = q.maxRows
+	}
+	return q.readOnly(ctx, parsed.SQL, args, maxRows)
+}
+
+// bindArgs matches the supplied values to the statement's bind variables.
+func bindArgs(names []string, binds interface{}) ([]interface{}, error) {
+	switch b := binds.(type) {
+	case nil:
+		if len(names) > 0 {
+			return nil, fmt.Errorf("missing values for bind variables: :%s", strings.Join(names, ", :"))
+		}
+		return nil, nil
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(b))
+		for k, v := range b {
+			values[strings.ToUpper(strings.TrimPrefix(k, ":"))] = v
+		}
+		args := make([]interface{}, 0, len(names))
+		var missing []string
+		for _, name := range names {
+			v, ok := values[strings.ToUpper(name)]
+			if !ok {
+				missing = append(missing, name)
+				continue
+			}
+			delete(values, strings.ToUpper(name))
+			if isNumericBind(name) {
+				args = append(args, bindValue(v))
+			} else {
+				args = append(args, sql.Named(name, bindValue(v)))
+			}
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("missing values for bind variables: :%s", strings.Join(missing, ", :"))
+		}
+		for k := range values {
+			return nil, fmt.Errorf("bind variable :%s is not used in the statement", k)
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("binds must be an object of bind names to values")
+}
+
+// bindValue converts a decoded JSON value for the driver. Whole numbers
+// become integers; booleans become 1 and 0 since Oracle SQL has no
+// boolean bind type before 23ai.
+func bindValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case float64:
+		if x == float64(int64(x)) {
+			return int64(x)
+		}
+		return x
+	case bool:
+		if x {
+			return int64(1)
+		}
+		return int64(0)
+	case string, nil:
+		return x
+	}
+	return fmt.Sprint(v)
+}
+
+// readOnly runs a query inside a read-only transaction and collects up to
+// maxRows rows.
+func (q *ReadOnlyQuerier) readOnly(ctx context.Context, query string, args []interface{}, maxRows int) (*QueryResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.timeout)
+	defer cancel()
+
+	tx, err := q.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "SET TRANSACTION READ ONLY"); err != nil {
+		return nil, fmt.Errorf("failed to make the transaction read-only: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, queryError(ctx, err, q.timeout)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{Columns: cols}
+	values := make([]interface{}, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if len(result.Rows) >= maxRows {
+			result.Truncated = true
+			break
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(cols))
+		for i, v := range values {
+			row[i] = formatValue(v)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err, q.timeout)
+	}
+	return result, nil
+}
+
+func queryError(ctx context.Context, err error, timeout time.Duration) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("query timed out after %s", timeout)
+	}
+	return err
+}
+
+// formatValue renders a scanned value for display.
+func formatValue(v interface{}) string {
+	var s string
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case time.Time:
+		if x.Hour() == 0 && x.Minute() == 0 && x.Second() == 0 && x.Nanosecond() == 0 {
+			return x.Format("2006-01-02")
+		}
+		return x.Format("2006-01-02 15:04:05")
+	case []byte:
+		if utf8.Valid(x) {
+			s = string(x)
+		} else {
+			n := min(len(x), 32)
+			s = "0x" + strings.ToUpper(hex.EncodeToString(x[:n]))
+			if n < len(x) {
+				s += fmt.Sprintf("... (%d bytes)", len(x))
+			}
+			return s
+		}
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case string:
+		s = x
+	default:
+		s = fmt.Sprint(x)
+	}
+	if utf8.RuneCountInString(s) > maxCellChars {
+		r := []rune(s)
+		s = string(r[:maxCellChars]) + "..."
+	}
+	return s
+}
+
+// splitTableName parses "OWNER.TABLE" or "TABLE". Unquoted names are
+// upper-cased like Oracle does; quoted names keep their case.
+func splitTableName(name string) (owner, table string, err error) {
+	parts, err := splitQualified(name)
+	if err != nil {
+		return "", "", err
+	}
+	switch len(parts) {
+	case 1:
+		return "", parts[0], nil
+	case 2:
+		return parts[0], parts[1], nil
+	}
+	return "", "", fmt.Errorf("invalid table name %q", name)
+}
+
+func splitQualified(name string) ([]string, error) {
+	toks, err := tokenizeSQL(name)
+	if err != nil {
+		return nil, err
+	}
+	var parts []string
+	for i, t := range toks {
+		if i%2 == 1 {
+			if t.kind != tokPunct || t.text != "." {
+				return nil, fmt.Errorf("invalid name %q", name)
+			}
+			continue
+		}
+		switch t.kind {
+		case tokWord:
+			if err := validateSQLIdentifier(strings.NewReplacer("$", "_", "#", "_").Replace(t.text)); err != nil {
+				return nil, err
+			}
+			parts = append(parts, t.text)
+		case tokQuoted:
+			parts = append(parts, strings.ReplaceAll(t.text, `""`, `"`))
+		default:
+			return nil, fmt.Errorf("invalid name %q", name)
+		}
+	}
+	if len(toks)%2 == 0 {
+		return nil, fmt.Errorf("invalid name %q", name)
+	}
+	return parts, nil
+}
+
+// resolveSchema picks the owner for a lookup: the given one, or the only
+// allowlisted schema. It fails for schemas outside the allowlist.
+func (q *ReadOnlyQuerier) resolveSchema(owner string) (string, error) {
+	if len(q.schemas) == 0 {
+		return "", fmt.Errorf("no schemas are allowlisted for describe_table (tools.sql.schemas)")
+	}
+	if owner == "" {
+		if len(q.schemas) > 1 {
+			return "", fmt.Errorf("qualify the table with one of the schemas: %s", strings.Join(q.schemas, ", "))
+		}
+		return q.schemas[0], nil
+	}
+	for _, s := range q.schemas {
+		if s == strings.ToUpper(owner) {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("schema %s is not allowlisted; allowed: %s", owner, strings.Join(q.schemas, ", "))
+}
+
+// ListTables lists the tables and views of an allowlisted schema.
+func (q *ReadOnlyQuerier) ListTables(ctx context.Context, schema string) (*QueryResult, error) {
+	if schema != "" {
+		parts, err := splitQualified(schema)
+		if err != nil || len(parts) != 1 {
+			return nil, fmt.Errorf("invalid schema name %q", schema)
+		}
+		schema = parts[0]
+	}
+	owner, err := q.resolveSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+	return q.readOnly(ctx, `SELECT table_name, table_type, comments FROM all_tab_comments
+		WHERE owner = :1 AND table_name NOT LIKE 'BIN$%' ORDER BY table_name`, []interface{}{owner}, q.maxRows)
+}
+
+// DescribeTable returns column, key and comment metadata for a table or
+// view in an allowlisted schema.
+func (q *ReadOnlyQuerier) DescribeTable(ctx context.Context, name string) (*TableInfo, error) {
+	owner, table, err := splitTableName(name)
+	if err != nil {
+		return nil, err
+	}
+	if owner, err = q.resolveSchema(owner); err != nil {
+		return nil, err
+	}
+
+	head, err := q.readOnly(ctx, `SELECT table_type, comments FROM all_tab_comments
+		WHERE owner = :1 AND table_name = :2`, []interface{}{owner, table}, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(head.Rows) == 0 {
+		return nil, fmt.Errorf("table %s.%s not found or not accessible", owner, table)
+	}
+	info := &TableInfo{Owner: owner, Name: table, Kind: head.Rows[0][0], Comment: nullToEmpty(head.Rows[0][1])}
+
+	cols, err := q.readOnly(ctx, `SELECT c.column_name, c.data_type, c.data_length, c.data_precision, c.data_scale,
+		c.char_length, c.nullable, cc.comments
+		FROM all_tab_columns c
+		LEFT JOIN all_col_comments cc
+			ON cc.owner = c.owner AND cc.table_name = c.table_name AND cc.column_name = c.column_name
+		WHERE c.owner = :1 AND c.table_name = :2
+		ORDER BY c.column_id`, []interface{}{owner, table}, 1000)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range cols.Rows {
+		info.Columns = append(info.Columns, ColumnInfo{
+			Name:     r[0],
+			Type:     columnType(r[1], r[2], r[3], r[4], r[5]),
+			Nullable: r[6] == "Y",
+			Comment:  nullToEmpty(r[7]),
+		})
+	}
+
+	pk, err := q.readOnly(ctx, `SELECT cols.column_name
+		FROM all_constraints cons
+		JOIN all_cons_columns cols ON cols.owner = cons.owner AND cols.constraint_name = cons.constraint_name
+		WHERE cons.constraint_type = 'P' AND cons.owner = :1 AND cons.table_name = :2
+		ORDER BY cols.position`, []interface{}{owner, table}, 100)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range pk.Rows {
+		info.PrimaryKey = append(info.PrimaryKey, r[0])
+	}
+	return info, nil
+}
+
+func nullToEmpty(s string) string {
+	if s == "NULL" {
+		return ""
+	}
+	return s
+}
+
+// columnType renders a data type the way it is written in DDL.
+func columnType(dataType, length, precision, scale, charLength string) string {
+	switch dataType {
+	case "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR":
+		return fmt.Sprintf("%s(%s)", dataType, charLength)
+	case "RAW":
+		return fmt.Sprintf("RAW(%s)", length)
+	case "NUMBER":
+		switch {
+		case precision == "NULL" && scale == "0":
+			return "INTEGER"
+		case precision == "NULL":
+			return "NUMBER"
+		case scale == "NULL" || scale == "0":
+			return fmt.Sprintf("NUMBER(%s)", precision)
+		}
+		return fmt.Sprintf("NUMBER(%s,%s)", precision, scale)
+	}
+	return dataType
+}
@@ -0,0 +1,149 @@
+package oracle
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestReadOnlyQuerier_Query(t *testing.T) {
+	db, mock, err := newMockDB(t)
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	q := NewReadOnlyQuerier(db, 2, time.Second, nil)
+
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectExec("SET TRANSACTION READ ONLY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, name, created, data FROM items WHERE kind = :kind").
+		WithArgs(sql.Named("kind", "lamp")).
+		WillReturnRows(sqlmock.NewRows([]string{"ID", "NAME", "CREATED", "DATA"}).
+			AddRow(int64(1), "desk", day, []byte{0xff, 0x00}).
+			AddRow(int64(2), nil, day.Add(90*time.Minute), []byte("text")).
+			AddRow(int64(3), "floor", day, nil))
+	mock.ExpectRollback()
+
+	res, err := q.Query(context.Background(), "SELECT id, name, created, data FROM items WHERE kind = :kind;",
+		map[string]interface{}{":KIND": "lamp"}, 0)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	want := [][]string{{"1", "desk", "2026-03-01", "0xFF00"}, {"2", "NULL", "2026-03-01 01:30:00", "text"}}
+	if !reflect.DeepEqual(res.Rows, want) || !res.Truncated {
+		t.Errorf("Query = %v truncated=%v, want %v truncated", res.Rows, res.Truncated, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReadOnlyQuerier_Binds(t *testing.T) {
+	db, mock, err := newMockDB(t)
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	q := NewReadOnlyQuerier(db, 0, 0, nil)
+	ctx := context.Background()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SET TRANSACTION READ ONLY").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT").WithArgs(int64(5), 2.5, int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"X"}).AddRow("ok"))
+	mock.ExpectRollback()
+	if _, err := q.Query(ctx, "SELECT :1 + :2 + :3 x FROM dual", map[string]interface{}{"1": 5.0, "2": 2.5, ":3": true}, 0); err != nil {
+		t.Errorf("numbered binds failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		binds interface{}
+		want  string
+	}{
+		{nil, "missing values for bind variables: :a"},
+		{map[string]interface{}{"b": 1.0}, "missing values"},
+		{map[string]interface{}{"a": 1.0, "b": 2.0}, ":B is not used"},
+		{[]interface{}{1.0}, "must be an object"},
+		{"x", "must be an object"},
+	} {
+		_, err := q.Query(ctx, "SELECT :a FROM dual", tc.binds, 0)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("binds %v: error = %v, want %q", tc.binds, err, tc.want)
+		}
+	}
+	if _, err := q.Query(ctx, "UPDATE t SET a = 1", nil, 0); err == nil {
+		t.Error("UPDATE should be rejected before reaching the database")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestReadOnlyQuerier_DescribeTable(t *testing.T) {
+	db, mock, err := newMockDB(t)
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	q := NewReadOnlyQuerier(db, 0, 0, []string{"app", " hr "})
+	ctx := context.Background()
+
+	if _, err := q.DescribeTable(ctx, "ORDERS"); err == nil || !strings.Contains(err.Error(), "APP, HR") {
+		t.Errorf("unqualified name with two schemas should be refused, got %v", err)
+	}
+	if _, err := q.DescribeTable(ctx, "sys.user$"); err == nil || !strings.Contains(err.Error(), "not allowlisted") {
+		t.Errorf("schema outside the allowlist should be refused, got %v", err)
+	}
+
+	expectTx := func(query string, args ...driver.Value) *sqlmock.ExpectedQuery {
+		mock.ExpectBegin()
+		mock.ExpectExec("SET TRANSACTION READ ONLY").WillReturnResult(sqlmock.NewResult(0, 0))
+		return mock.ExpectQuery(query).WithArgs(args...)
+	}
+	expectTx("FROM all_tab_comments", "APP", "ORDERS").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_TYPE", "COMMENTS"}).AddRow("TABLE", "Customer orders"))
+	mock.ExpectRollback()
+	expectTx("FROM all_tab_columns", "APP", "ORDERS").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "DATA_TYPE", "DATA_LENGTH", "DATA_PRECISION", "DATA_SCALE", "CHAR_LENGTH", "NULLABLE", "COMMENTS"}).
+			AddRow("ID", "NUMBER", 22, nil, 0, 0, "N", nil).
+			AddRow("TOTAL", "NUMBER", 22, 10, 2, 0, "Y", "In cents").
+			AddRow("NOTE", "VARCHAR2", 400, nil, nil, 100, "Y", nil))
+	mock.ExpectRollback()
+	expectTx("FROM all_constraints", "APP", "ORDERS").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME"}).AddRow("ID"))
+	mock.ExpectRollback()
+
+	info, err := q.DescribeTable(ctx, "app.orders")
+	if err != nil {
+		t.Fatalf("DescribeTable failed: %v", err)
+	}
+	want := &TableInfo{
+		Owner: "APP", Name: "ORDERS", Kind: "TABLE", Comment: "Customer orders",
+		Columns: []ColumnInfo{
+			{Name: "ID", Type: "INTEGER"},
+			{Name: "TOTAL", Type: "NUMBER(10,2)", Nullable: true, Comment: "In cents"},
+			{Name: "NOTE", Type: "VARCHAR2(100)", Nullable: true},
+		},
+		PrimaryKey: []string{"ID"},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("DescribeTable = %+v, want %+v", info, want)
+	}
+
+	expectTx("FROM all_tab_comments", "HR", "Mixed Case").
+		WillReturnRows(sqlmock.NewRows([]string{"TABLE_TYPE", "COMMENTS"}))
+	mock.ExpectRollback()
+	if _, err := q.DescribeTable(ctx, `hr."Mixed Case"`); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing table should be reported, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -81,6 +81,18 @@ var tableDDL = map[string]string{
         content      CLOB,
         created_at   TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     )`,
+
+	"PICO_SENSOR_READINGS": `CREATE TABLE PICO_SENSOR_READINGS (
+        id         NUMBER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+        agent_id   VARCHAR2(64) NOT NULL,
+        sensor     VARCHAR2(64) NOT NULL,
+        bus        VARCHAR2(16),
+        address    NUMBER(3),
+        quantity   VARCHAR2(64) NOT NULL,
+        value      BINARY_DOUBLE,
+        unit       VARCHAR2(16),
+        read_at    TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    )`,
 }
 
 // Regular index DDL
@@ -91,6 +103,7 @@ var indexDDL = []string{
 	"CREATE INDEX IDX_PICO_TRANSCRIPTS_SESSION ON PICO_TRANSCRIPTS(session_key)",
 	"CREATE INDEX IDX_PICO_STATE_AGENT ON PICO_STATE(agent_id)",
 	"CREATE INDEX IDX_PICO_MEMORIES_AGENT_CAT ON PICO_MEMORIES(agent_id, category)",
+	"CREATE INDEX IDX_PICO_SENSOR_READINGS_TIME ON PICO_SENSOR_READINGS(agent_id, sensor, quantity, read_at)",
 }
 
 // Vector index DDL
@@ -113,6 +126,7 @@ func InitSchema(db *sql.DB) error {
 	tableOrder := []string{
 		"PICO_META", "PICO_MEMORIES", "PICO_DAILY_NOTES", "PICO_SESSIONS",
 		"PICO_STATE", "PICO_CONFIG", "PICO_PROMPTS", "PICO_TRANSCRIPTS",
+		"PICO_SENSOR_READINGS",
 	}
 
 	for _, tableName := range tableOrder {
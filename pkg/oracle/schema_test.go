@@ -16,6 +16,7 @@ func TestInitSchema_CreatesAllTables(t *testing.T) {
 	expectedTables := []string{
 		"PICO_META", "PICO_MEMORIES", "PICO_DAILY_NOTES", "PICO_SESSIONS",
 		"PICO_STATE", "PICO_CONFIG", "PICO_PROMPTS", "PICO_TRANSCRIPTS",
+		"PICO_SENSOR_READINGS",
 	}
 
 	// Expect CREATE TABLE for each
@@ -57,7 +58,7 @@ func TestInitSchema_Idempotent(t *testing.T) {
 	}
 
 	// Simulate all tables already existing (ORA-00955)
-	for i := 0; i < 8; i++ {
+	for i := 0; i < 9; i++ {
 		mock.ExpectExec("CREATE TABLE").
 			WillReturnError(fmt.Errorf("ORA-00955: name is already used by an existing object"))
 	}
@@ -114,7 +115,7 @@ func TestTableDDL_AllTablesHavePICOPrefix(t *testing.T) {
 }
 
 func TestTableDDL_ExpectedTableCount(t *testing.T) {
-	if len(tableDDL) != 8 {
-		t.Errorf("expected 8 tables, got %d", len(tableDDL))
+	if len(tableDDL) != 9 {
+		t.Errorf("expected 9 tables, got %d", len(tableDDL))
 	}
 }
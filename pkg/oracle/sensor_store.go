@@ -0,0 +1,109 @@
+package oracle
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SensorReading is one logged sensor value.
+type SensorReading struct {
+	Sensor   string
+	Bus      string
+	Address  int
+	Quantity string
+	Value    float64
+	Unit     string
+	ReadAt   time.Time
+}
+
+// SensorStore logs sensor readings to PICO_SENSOR_READINGS for time-series
+// queries.
+type SensorStore struct {
+	db      *sql.DB
+	agentID string
+}
+
+// NewSensorStore creates a new Oracle-backed sensor reading store.
+func NewSensorStore(db *sql.DB, agentID string) *SensorStore {
+	return &SensorStore{
+		db:      db,
+		agentID: agentID,
+	}
+}
+
+// Log inserts readings in a single transaction.
+func (ss *SensorStore) Log(ctx context.Context, readings []SensorReading) error {
+	if len(readings) == 0 {
+		return nil
+	}
+	tx, err := ss.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range readings {
+		readAt := r.ReadAt
+		if readAt.IsZero() {
+			readAt = time.Now()
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO PICO_SENSOR_READINGS (agent_id, sensor, bus, address, quantity, value, unit, read_at)
+			VALUES (:1, :2, :3, :4, :5, :6, :7, :8)
+		`, ss.agentID, r.Sensor, r.Bus, r.Address, r.Quantity, r.Value, r.Unit, readAt)
+		if err != nil {
+			return fmt.Errorf("failed to log %s %s reading: %w", r.Sensor, r.Quantity, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sensor readings: %w", err)
+	}
+	return nil
+}
+
+// History returns readings taken since the given time, newest first. Empty
+// sensor or quantity match everything.
+func (ss *SensorStore) History(ctx context.Context, sensor, quantity string, since time.Time, limit int) ([]SensorReading, error) {
+	where := []string{"agent_id = :1", "read_at >= :2"}
+	args := []interface{}{ss.agentID, since}
+	if sensor != "" {
+		args = append(args, sensor)
+		where = append(where, fmt.Sprintf("sensor = :%d", len(args)))
+	}
+	if quantity != "" {
+		args = append(args, quantity)
+		where = append(where, fmt.Sprintf("quantity = :%d", len(args)))
+	}
+	args = append(args, limit)
+	query := fmt.Sprintf(`
+		SELECT sensor, bus, address, quantity, value, unit, read_at
+		FROM PICO_SENSOR_READINGS
+		WHERE %s
+		ORDER BY read_at DESC
+		FETCH FIRST :%d ROWS ONLY`, strings.Join(where, " AND "), len(args))
+
+	rows, err := ss.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sensor history: %w", err)
+	}
+	defer rows.Close()
+
+	var readings []SensorReading
+	for rows.Next() {
+		var r SensorReading
+		var bus, unit sql.NullString
+		var address sql.NullInt64
+		var value sql.NullFloat64
+		if err := rows.Scan(&r.Sensor, &bus, &address, &r.Quantity, &value, &unit, &r.ReadAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sensor reading: %w", err)
+		}
+		r.Bus, r.Unit = bus.String, unit.String
+		r.Address = int(address.Int64)
+		r.Value = value.Float64
+		readings = append(readings, r)
+	}
+	return readings, rows.Err()
+}
@@ -0,0 +1,76 @@
+package oracle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSensorStore_Log(t *testing.T) {
+	db, mock, err := newMockDB(t)
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	store := NewSensorStore(db, "test-agent")
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO PICO_SENSOR_READINGS").
+		WithArgs("test-agent", "bme280", "1", 0x76, "temperature", 21.5, "°C", at).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO PICO_SENSOR_READINGS").
+		WithArgs("test-agent", "bme280", "1", 0x76, "humidity", 40.2, "%RH", at).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	err = store.Log(context.Background(), []SensorReading{
+		{Sensor: "bme280", Bus: "1", Address: 0x76, Quantity: "temperature", Value: 21.5, Unit: "°C", ReadAt: at},
+		{Sensor: "bme280", Bus: "1", Address: 0x76, Quantity: "humidity", Value: 40.2, Unit: "%RH", ReadAt: at},
+	})
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if err := store.Log(context.Background(), nil); err != nil {
+		t.Errorf("logging nothing should not touch the database: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSensorStore_History(t *testing.T) {
+	db, mock, err := newMockDB(t)
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+	store := NewSensorStore(db, "test-agent")
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := since.Add(time.Hour)
+
+	mock.ExpectQuery(`WHERE agent_id = :1 AND read_at >= :2 AND quantity = :3\s+ORDER BY read_at DESC\s+FETCH FIRST :4 ROWS ONLY`).
+		WithArgs("test-agent", since, "temperature", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"sensor", "bus", "address", "quantity", "value", "unit", "read_at"}).
+			AddRow("sht3x", "1", 0x44, "temperature", 22.75, "°C", at).
+			AddRow("aht20", nil, nil, "temperature", nil, nil, at))
+
+	readings, err := store.History(context.Background(), "", "temperature", since, 10)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	if len(readings) != 2 {
+		t.Fatalf("expected 2 readings, got %d", len(readings))
+	}
+	if r := readings[0]; r.Sensor != "sht3x" || r.Address != 0x44 || r.Value != 22.75 || r.Unit != "°C" || !r.ReadAt.Equal(at) {
+		t.Errorf("unexpected reading: %+v", r)
+	}
+	if r := readings[1]; r.Bus != "" || r.Value != 0 {
+		t.Errorf("NULL columns should scan to zero values: %+v", r)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
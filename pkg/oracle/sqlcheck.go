@@ -0,0 +1,262 @@
+package oracle
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type sqlTokenKind int
+
+const (
+	tokWord   sqlTokenKind = iota // keyword or unquoted identifier, upper-cased
+	tokQuoted                     // "quoted identifier"
+	tokString                     // 'literal', q'[literal]', N'literal'
+	tokNumber
+	tokBind // :name or :1, text without the colon
+	tokPunct
+)
+
+type sqlToken struct {
+	kind sqlTokenKind
+	text string
+	pos  int
+}
+
+// tokenizeSQL splits an Oracle SQL statement into tokens, dropping
+// whitespace and comments (optimizer hints included).
+func tokenizeSQL(s string) ([]sqlToken, error) {
+	var toks []sqlToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case strings.HasPrefix(s[i:], "--"):
+			end := strings.IndexByte(s[i:], '\n')
+			if end < 0 {
+				return toks, nil
+			}
+			i += end + 1
+		case strings.HasPrefix(s[i:], "/*"):
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 4
+		case (c == 'q' || c == 'Q') && i+1 < len(s) && s[i+1] == '\'':
+			end, err := scanQQuote(s, i+1)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, sqlToken{tokString, s[i:end], i})
+			i = end
+		case (c == 'n' || c == 'N') && i+2 < len(s) && (s[i+2] == '\'' && (s[i+1] == 'q' || s[i+1] == 'Q')):
+			end, err := scanQQuote(s, i+2)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, sqlToken{tokString, s[i:end], i})
+			i = end
+		case (c == 'n' || c == 'N') && i+1 < len(s) && s[i+1] == '\'':
+			end, err := scanQuoted(s, i+1, '\'')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, sqlToken{tokString, s[i:end], i})
+			i = end
+		case c == '\'':
+			end, err := scanQuoted(s, i, '\'')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, sqlToken{tokString, s[i:end], i})
+			i = end
+		case c == '"':
+			end, err := scanQuoted(s, i, '"')
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, sqlToken{tokQuoted, s[i+1 : end-1], i})
+			i = end
+		case c == ':' && i+1 < len(s) && isIdentByte(s[i+1]):
+			j := i + 1
+			for j < len(s) && isIdentByte(s[j]) {
+				j++
+			}
+			toks = append(toks, sqlToken{tokBind, s[i+1 : j], i})
+			i = j
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				(s[j] == '+' || s[j] == '-') && (s[j-1] == 'e' || s[j-1] == 'E')) {
+				j++
+			}
+			toks = append(toks, sqlToken{tokNumber, s[i:j], i})
+			i = j
+		case isIdentByte(c) || c >= 0x80:
+			j := i
+			for j < len(s) && (isIdentByte(s[j]) || s[j] >= 0x80) {
+				j++
+			}
+			toks = append(toks, sqlToken{tokWord, strings.ToUpper(s[i:j]), i})
+			i = j
+		default:
+			n := 1
+			for _, op := range []string{"||", "<=", ">=", "<>", "!=", "^=", "=>", "**"} {
+				if strings.HasPrefix(s[i:], op) {
+					n = 2
+					break
+				}
+			}
+			toks = append(toks, sqlToken{tokPunct, s[i : i+n], i})
+			i += n
+		}
+	}
+	return toks, nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c == '#' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// scanQuoted returns the offset after the literal starting at s[start],
+// where a doubled quote stands for itself.
+func scanQuoted(s string, start int, quote byte) (int, error) {
+	for i := start + 1; i < len(s); i++ {
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted text at offset %d", start)
+}
+
+// scanQQuote handles q'<delim>...<delim>' literals; start is the offset of
+// the opening quote.
+func scanQQuote(s string, start int) (int, error) {
+	if start+1 >= len(s) {
+		return 0, fmt.Errorf("unterminated q-quoted literal at offset %d", start)
+	}
+	open := s[start+1]
+	closing := map[byte]byte{'[': ']', '(': ')', '{': '}', '<': '>'}[open]
+	if closing == 0 {
+		closing = open
+	}
+	end := strings.Index(s[start+2:], string(closing)+"'")
+	if end < 0 {
+		return 0, fmt.Errorf("unterminated q-quoted literal at offset %d", start)
+	}
+	return start + 2 + end + 2, nil
+}
+
+// writeKeywords may not appear unquoted in a read-only query. UPDATE also
+// covers SELECT ... FOR UPDATE and INTO covers SELECT INTO. A few are not
+// reserved in Oracle; columns with such names can still be used quoted.
+var writeKeywords = map[string]bool{
+	"INTO": true, "INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "UPSERT": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "LOCK": true, "COMMIT": true, "ROLLBACK": true,
+	"SAVEPOINT": true, "BEGIN": true, "DECLARE": true, "EXECUTE": true, "CALL": true,
+}
+
+// ParsedQuery is a statement accepted by ParseReadOnlyQuery.
+type ParsedQuery struct {
+	SQL   string   // the statement without a trailing semicolon
+	Binds []string // bind variable names in order of first use
+}
+
+// ParseReadOnlyQuery tokenizes stmt and accepts exactly one SELECT or
+// WITH ... SELECT query. It rejects other statements, several statements,
+// PL/SQL in WITH clauses, SELECT ... FOR UPDATE and database links. The
+// database session is read-only as well; this check gives clear errors
+// and keeps a second statement from being smuggled in.
+func ParseReadOnlyQuery(stmt string) (*ParsedQuery, error) {
+	toks, err := tokenizeSQL(stmt)
+	if err != nil {
+		return nil, err
+	}
+	end := len(stmt)
+	for len(toks) > 0 && toks[len(toks)-1].kind == tokPunct && (toks[len(toks)-1].text == ";" || toks[len(toks)-1].text == "/") {
+		end = toks[len(toks)-1].pos
+		toks = toks[:len(toks)-1]
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty statement")
+	}
+
+	first := 0
+	for first < len(toks) && toks[first].kind == tokPunct && toks[first].text == "(" {
+		first++
+	}
+	if first == len(toks) || toks[first].kind != tokWord || (toks[first].text != "SELECT" && toks[first].text != "WITH") {
+		return nil, fmt.Errorf("only SELECT and WITH queries are allowed, got %q", tokenText(toks, first))
+	}
+	if toks[first].text == "WITH" && first+1 < len(toks) && toks[first+1].kind == tokWord &&
+		(toks[first+1].text == "FUNCTION" || toks[first+1].text == "PROCEDURE") {
+		return nil, fmt.Errorf("PL/SQL declarations in WITH are not allowed")
+	}
+
+	q := &ParsedQuery{SQL: strings.TrimSpace(stmt[:end])}
+	seenBind := make(map[string]bool)
+	depth := 0
+	sawSelect := false
+	for _, t := range toks {
+		switch t.kind {
+		case tokPunct:
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+				if depth < 0 {
+					return nil, fmt.Errorf("unbalanced parentheses at offset %d", t.pos)
+				}
+			case ";":
+				return nil, fmt.Errorf("only one statement is allowed")
+			case "@":
+				return nil, fmt.Errorf("database links are not allowed")
+			}
+		case tokWord:
+			if writeKeywords[t.text] {
+				return nil, fmt.Errorf("%s is not allowed in a read-only query", t.text)
+			}
+			if t.text == "SELECT" {
+				sawSelect = true
+			}
+		case tokBind:
+			name := strings.ToUpper(t.text)
+			if !seenBind[name] {
+				seenBind[name] = true
+				q.Binds = append(q.Binds, t.text)
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+	if !sawSelect {
+		return nil, fmt.Errorf("no SELECT found")
+	}
+	return q, nil
+}
+
+func tokenText(toks []sqlToken, i int) string {
+	if i >= len(toks) {
+		return ""
+	}
+	return toks[i].text
+}
+
+// isNumericBind reports whether a bind name is positional (:1, :2).
+func isNumericBind(name string) bool {
+	for _, r := range name {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return name != ""
+}
@@ -0,0 +1,63 @@
+package oracle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReadOnlyQuery_Accepts(t *testing.T) {
+	tests := []struct {
+		stmt  string
+		sql   string
+		binds []string
+	}{
+		{"SELECT * FROM dual", "SELECT * FROM dual", nil},
+		{"select id from t where a = :a and b = :B and c = :a;", "select id from t where a = :a and b = :B and c = :a", []string{"a", "B"}},
+		{"(SELECT 1 FROM dual) UNION ALL (SELECT 2 FROM dual)\n/", "(SELECT 1 FROM dual) UNION ALL (SELECT 2 FROM dual)", nil},
+		{"WITH x AS (SELECT :1 v FROM dual) SELECT v FROM x", "WITH x AS (SELECT :1 v FROM dual) SELECT v FROM x", []string{"1"}},
+		{"SELECT 'insert; drop table t' FROM dual", "SELECT 'insert; drop table t' FROM dual", nil},
+		{"SELECT q'[it's; delete]' , n'x''y' FROM dual", "SELECT q'[it's; delete]' , n'x''y' FROM dual", nil},
+		{`SELECT "UPDATE" FROM t -- delete everything`, `SELECT "UPDATE" FROM t -- delete everything`, nil},
+		{"SELECT /*+ INDEX(t) */ 1.5e-3 FROM t /* ; drop */", "SELECT /*+ INDEX(t) */ 1.5e-3 FROM t /* ; drop */", nil},
+	}
+	for _, tt := range tests {
+		q, err := ParseReadOnlyQuery(tt.stmt)
+		if err != nil {
+			t.Errorf("ParseReadOnlyQuery(%q) error: %v", tt.stmt, err)
+			continue
+		}
+		if q.SQL != tt.sql || !reflect.DeepEqual(q.Binds, tt.binds) {
+			t.Errorf("ParseReadOnlyQuery(%q) = %q %v, want %q %v", tt.stmt, q.SQL, q.Binds, tt.sql, tt.binds)
+		}
+	}
+}
+
+func TestParseReadOnlyQuery_Rejects(t *testing.T) {
+	tests := []struct {
+		stmt string
+		want string
+	}{
+		{"", "empty"},
+		{"  ;", "empty"},
+		{"DELETE FROM t", "only SELECT"},
+		{"BEGIN NULL; END;", "only SELECT"},
+		{"SELECT 1 FROM dual; DROP TABLE t", "one statement"},
+		{"SELECT 1 FROM dual; SELECT 2 FROM dual", "one statement"},
+		{"SELECT * FROM t FOR UPDATE", "UPDATE is not allowed"},
+		{"SELECT id INTO :x FROM t", "INTO is not allowed"},
+		{"WITH FUNCTION f RETURN NUMBER IS BEGIN RETURN 1; END; SELECT f FROM dual", "PL/SQL"},
+		{"SELECT * FROM t@remote", "database links"},
+		{"SELECT (1 FROM dual", "unbalanced"},
+		{"SELECT 1) FROM dual", "unbalanced"},
+		{"SELECT 'open FROM dual", "unterminated"},
+		{"SELECT 1 /* open", "unterminated"},
+		{"WITH x AS (SELECT 1 FROM dual) DELETE FROM x", "DELETE is not allowed"},
+	}
+	for _, tt := range tests {
+		_, err := ParseReadOnlyQuery(tt.stmt)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseReadOnlyQuery(%q) error = %v, want %q", tt.stmt, err, tt.want)
+		}
+	}
+}
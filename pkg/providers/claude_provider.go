@@ -49,13 +49,28 @@ func (p *ClaudeProvider) Chat(ctx context.Context, messages []Message, tools []T
 		return nil, fmt.Errorf("claude API call: %w", err)
 	}
 
-	return parseClaudeResponse(resp), nil
+	result := parseClaudeResponse(resp)
+	if schema, name := responseSchemaOptions(options); schema != nil {
+		unwrapStructuredToolCall(result, name)
+	}
+	return result, nil
 }
 
 func (p *ClaudeProvider) GetDefaultModel() string {
 	return "claude-sonnet-4-5-20250929"
 }
 
+// claudeToolResult builds a tool_result block, with any images inside it.
+func claudeToolResult(msg Message) anthropic.ContentBlockParamUnion {
+	block := anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)
+	for _, img := range msg.Images {
+		image := anthropic.NewImageBlockBase64(img.MediaType, img.Data)
+		block.OfToolResult.Content = append(block.OfToolResult.Content,
+			anthropic.ToolResultBlockParamContentUnion{OfImage: image.OfImage})
+	}
+	return block
+}
+
 func buildClaudeParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (anthropic.MessageNewParams, error) {
 	var system []anthropic.TextBlockParam
 	var anthropicMessages []anthropic.MessageParam
@@ -67,12 +82,14 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 		case "user":
 			if msg.ToolCallID != "" {
 				anthropicMessages = append(anthropicMessages,
-					anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
+					anthropic.NewUserMessage(claudeToolResult(msg)),
 				)
 			} else {
-				anthropicMessages = append(anthropicMessages,
-					anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content)),
-				)
+				blocks := []anthropic.ContentBlockParamUnion{anthropic.NewTextBlock(msg.Content)}
+				for _, img := range msg.Images {
+					blocks = append(blocks, anthropic.NewImageBlockBase64(img.MediaType, img.Data))
+				}
+				anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(blocks...))
 			}
 		case "assistant":
 			if len(msg.ToolCalls) > 0 {
@@ -91,7 +108,7 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 			}
 		case "tool":
 			anthropicMessages = append(anthropicMessages,
-				anthropic.NewUserMessage(anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false)),
+				anthropic.NewUserMessage(claudeToolResult(msg)),
 			)
 		}
 	}
@@ -107,16 +124,44 @@ func buildClaudeParams(messages []Message, tools []ToolDefinition, model string,
 		MaxTokens: maxTokens,
 	}
 
+	// Cache breakpoints: the system prompt and tool schemas are identical on
+	// every iteration of a turn, and everything up to the latest message is
+	// the prefix of the next request. Anthropic allows up to four.
 	if len(system) > 0 {
+		system[len(system)-1].CacheControl = anthropic.NewCacheControlEphemeralParam()
 		params.System = system
 	}
+	if n := len(anthropicMessages); n > 0 {
+		if blocks := anthropicMessages[n-1].Content; len(blocks) > 0 {
+			if cc := blocks[len(blocks)-1].GetCacheControl(); cc != nil {
+				*cc = anthropic.NewCacheControlEphemeralParam()
+			}
+		}
+	}
 
 	if temp, ok := options["temperature"].(float64); ok {
 		params.Temperature = anthropic.Float(temp)
 	}
 
+	// Structured output: Claude has no response_format, so the schema becomes
+	// a tool the model is forced to call; its input is the reply.
+	if schema, name := responseSchemaOptions(options); schema != nil {
+		tools = append(tools, ToolDefinition{
+			Type: "function",
+			Function: ToolFunctionDefinition{
+				Name:        name,
+				Description: "Respond with the structured result.",
+				Parameters:  schema,
+			},
+		})
+		params.ToolChoice = anthropic.ToolChoiceParamOfTool(name)
+	}
+
 	if len(tools) > 0 {
 		params.Tools = translateToolsForClaude(tools)
+		if cc := params.Tools[len(params.Tools)-1].GetCacheControl(); cc != nil {
+			*cc = anthropic.NewCacheControlEphemeralParam()
+		}
 	}
 
 	return params, nil
@@ -148,6 +193,26 @@ func translateToolsForClaude(tools []ToolDefinition) []anthropic.ToolUnionParam
 	return result
 }
 
+// unwrapStructuredToolCall turns the forced structured-output tool call
+// back into plain JSON content.
+func unwrapStructuredToolCall(resp *LLMResponse, name string) {
+	remaining := resp.ToolCalls[:0]
+	for _, tc := range resp.ToolCalls {
+		if tc.Name != name {
+			remaining = append(remaining, tc)
+			continue
+		}
+		data, err := json.Marshal(tc.Arguments)
+		if err == nil {
+			resp.Content = string(data)
+		}
+	}
+	resp.ToolCalls = remaining
+	if len(remaining) == 0 && resp.FinishReason == "tool_calls" {
+		resp.FinishReason = "stop"
+	}
+}
+
 func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 	var content string
 	var toolCalls []ToolCall
@@ -181,14 +246,20 @@ func parseClaudeResponse(resp *anthropic.Message) *LLMResponse {
 		finishReason = "stop"
 	}
 
+	// Anthropic reports cached input separately from input_tokens; fold it
+	// back in so PromptTokens means the whole prompt for every provider.
+	promptTokens := resp.Usage.InputTokens + resp.Usage.CacheReadInputTokens + resp.Usage.CacheCreationInputTokens
+
 	return &LLMResponse{
 		Content:      content,
 		ToolCalls:    toolCalls,
 		FinishReason: finishReason,
 		Usage: &UsageInfo{
-			PromptTokens:     int(resp.Usage.InputTokens),
+			PromptTokens:     int(promptTokens),
 			CompletionTokens: int(resp.Usage.OutputTokens),
-			TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
+			TotalTokens:      int(promptTokens + resp.Usage.OutputTokens),
+			CacheReadTokens:  int(resp.Usage.CacheReadInputTokens),
+			CacheWriteTokens: int(resp.Usage.CacheCreationInputTokens),
 		},
 	}
 }
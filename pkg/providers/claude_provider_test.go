@@ -76,6 +76,25 @@ func TestBuildClaudeParams_ToolCallMessage(t *testing.T) {
 	}
 }
 
+func TestBuildClaudeParams_ToolResultImage(t *testing.T) {
+	messages := []Message{
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "read_file"}}},
+		{Role: "tool", Content: "Image a.png (attached)", ToolCallID: "call_1",
+			Images: []ImagePart{{MediaType: "image/png", Data: "iVBO"}}},
+	}
+	params, err := buildClaudeParams(messages, nil, "claude-sonnet-4-5-20250929", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+	result := params.Messages[1].Content[0].OfToolResult
+	if result == nil || len(result.Content) != 2 || result.Content[1].OfImage == nil {
+		t.Fatalf("tool result = %+v, want text and image content", result)
+	}
+	if src := result.Content[1].OfImage.Source.OfBase64; src == nil || src.Data != "iVBO" {
+		t.Errorf("image source = %+v", result.Content[1].OfImage.Source)
+	}
+}
+
 func TestBuildClaudeParams_WithTools(t *testing.T) {
 	tools := []ToolDefinition{
 		{
@@ -208,3 +227,59 @@ func createAnthropicTestClient(baseURL, token string) *anthropic.Client {
 	)
 	return &c
 }
+
+func TestBuildClaudeParams_CacheBreakpoints(t *testing.T) {
+	tools := []ToolDefinition{
+		{Type: "function", Function: ToolFunctionDefinition{Name: "read_file", Parameters: map[string]interface{}{"type": "object"}}},
+		{Type: "function", Function: ToolFunctionDefinition{Name: "write_file", Parameters: map[string]interface{}{"type": "object"}}},
+	}
+	messages := []Message{
+		{Role: "system", Content: "You are helpful"},
+		{Role: "user", Content: "Read a.txt"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Name: "read_file", Arguments: map[string]interface{}{"path": "a.txt"}}}},
+		{Role: "tool", Content: "contents", ToolCallID: "call_1"},
+	}
+	params, err := buildClaudeParams(messages, tools, "claude-sonnet-4-5-20250929", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+
+	if params.System[0].CacheControl.Type != "ephemeral" {
+		t.Error("system block should carry a cache breakpoint")
+	}
+	if params.Tools[0].GetCacheControl().Type != "" {
+		t.Error("only the last tool should carry a cache breakpoint")
+	}
+	if params.Tools[1].GetCacheControl().Type != "ephemeral" {
+		t.Error("last tool should carry a cache breakpoint")
+	}
+	last := params.Messages[len(params.Messages)-1].Content
+	if last[len(last)-1].GetCacheControl().Type != "ephemeral" {
+		t.Error("last history block should carry a cache breakpoint")
+	}
+	first := params.Messages[0].Content
+	if first[0].GetCacheControl().Type != "" {
+		t.Error("earlier history blocks should not carry a cache breakpoint")
+	}
+}
+
+func TestParseClaudeResponse_CacheUsage(t *testing.T) {
+	resp := &anthropic.Message{
+		Usage: anthropic.Usage{
+			InputTokens:              5,
+			CacheReadInputTokens:     1200,
+			CacheCreationInputTokens: 300,
+			OutputTokens:             40,
+		},
+	}
+	result := parseClaudeResponse(resp)
+	if result.Usage.PromptTokens != 1505 {
+		t.Errorf("PromptTokens = %d, want 1505 (input + cache read + cache write)", result.Usage.PromptTokens)
+	}
+	if result.Usage.CacheReadTokens != 1200 || result.Usage.CacheWriteTokens != 300 {
+		t.Errorf("cache tokens = read %d write %d, want 1200/300", result.Usage.CacheReadTokens, result.Usage.CacheWriteTokens)
+	}
+	if result.Usage.TotalTokens != 1545 {
+		t.Errorf("TotalTokens = %d, want 1545", result.Usage.TotalTokens)
+	}
+}
@@ -180,6 +180,26 @@ func resolveCodexModel(model string) (string, string) {
 	return codexDefaultModel, "unsupported model family"
 }
 
+func codexImageMessage(text string, images []ImagePart) responses.ResponseInputItemUnionParam {
+	content := responses.ResponseInputMessageContentListParam{
+		{OfInputText: &responses.ResponseInputTextParam{Text: text}},
+	}
+	for _, img := range images {
+		content = append(content, responses.ResponseInputContentUnionParam{
+			OfInputImage: &responses.ResponseInputImageParam{
+				Detail:   responses.ResponseInputImageDetailAuto,
+				ImageURL: openai.String(img.dataURL()),
+			},
+		})
+	}
+	return responses.ResponseInputItemUnionParam{
+		OfMessage: &responses.EasyInputMessageParam{
+			Role:    responses.EasyInputMessageRoleUser,
+			Content: responses.EasyInputMessageContentUnionParam{OfInputItemContentList: content},
+		},
+	}
+}
+
 func buildCodexParams(messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) responses.ResponseNewParams {
 	var inputItems responses.ResponseInputParam
 	var instructions string
@@ -196,6 +216,8 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 						Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)},
 					},
 				})
+			} else if len(msg.Images) > 0 {
+				inputItems = append(inputItems, codexImageMessage(msg.Content, msg.Images))
 			} else {
 				inputItems = append(inputItems, responses.ResponseInputItemUnionParam{
 					OfMessage: &responses.EasyInputMessageParam{
@@ -242,6 +264,10 @@ func buildCodexParams(messages []Message, tools []ToolDefinition, model string,
 					Output: responses.ResponseInputItemFunctionCallOutputOutputUnionParam{OfString: openai.Opt(msg.Content)},
 				},
 			})
+			// Function outputs are text only; images follow as a user message.
+			if len(msg.Images) > 0 {
+				inputItems = append(inputItems, codexImageMessage("Images from tool call "+msg.ToolCallID+":", msg.Images))
+			}
 		}
 	}
 
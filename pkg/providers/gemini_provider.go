@@ -0,0 +1,576 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const geminiDefaultAPIBase = "https://generativelanguage.googleapis.com/v1beta"
+
+// geminiSchemaKeys is the subset of JSON Schema understood by Gemini's
+// OpenAPI-style Schema object. Anything else is dropped before sending.
+var geminiSchemaKeys = map[string]bool{
+	"type":        true,
+	"format":      true,
+	"title":       true,
+	"description": true,
+	"nullable":    true,
+	"enum":        true,
+	"properties":  true,
+	"required":    true,
+	"items":       true,
+	"anyOf":       true,
+	"minItems":    true,
+	"maxItems":    true,
+	"minLength":   true,
+	"maxLength":   true,
+	"minimum":     true,
+	"maximum":     true,
+	"pattern":     true,
+}
+
+// maxThoughtSignatures bounds the per-provider thought signature cache.
+const maxThoughtSignatures = 1024
+
+// GeminiProvider talks to the native Gemini API (generateContent and
+// streamGenerateContent) instead of the OpenAI compatibility layer.
+type GeminiProvider struct {
+	apiKey         string
+	apiBase        string
+	httpClient     *http.Client
+	safetySettings []geminiSafetySetting
+
+	// Gemini 2.5+ attaches opaque thought signatures to function calls and
+	// rejects follow-up turns that omit them. The agent loop only keeps the
+	// tool call ID, so signatures are remembered here keyed by that ID.
+	sigMu      sync.Mutex
+	signatures map[string]string
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	ThoughtSignature string                  `json:"thoughtSignature,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *geminiInlineData       `json:"inlineData,omitempty"`
+}
+
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFunctionCall struct {
+	ID   string                 `json:"id,omitempty"`
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string                 `json:"id,omitempty"`
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiGenerationConfig struct {
+	MaxOutputTokens  int                    `json:"maxOutputTokens,omitempty"`
+	Temperature      *float64               `json:"temperature,omitempty"`
+	ResponseMimeType string                 `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]interface{} `json:"responseSchema,omitempty"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	SafetySettings    []geminiSafetySetting   `json:"safetySettings,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount        int `json:"promptTokenCount"`
+	CandidatesTokenCount    int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount      int `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int `json:"cachedContentTokenCount"`
+	TotalTokenCount         int `json:"totalTokenCount"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason"`
+	} `json:"promptFeedback"`
+	UsageMetadata *geminiUsageMetadata `json:"usageMetadata"`
+}
+
+// NewGeminiProvider creates a native Gemini provider. safetySettings maps
+// harm categories (e.g. "HARM_CATEGORY_HARASSMENT") to block thresholds
+// (e.g. "BLOCK_ONLY_HIGH"); nil keeps the API defaults.
+func NewGeminiProvider(apiKey, apiBase, proxy string, safetySettings map[string]string) *GeminiProvider {
+	client := &http.Client{
+		Timeout: 600 * time.Second,
+	}
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err == nil {
+			client.Transport = &http.Transport{
+				Proxy: http.ProxyURL(proxyURL),
+			}
+		}
+	}
+
+	if apiBase == "" {
+		apiBase = geminiDefaultAPIBase
+	}
+
+	categories := make([]string, 0, len(safetySettings))
+	for category := range safetySettings {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	settings := make([]geminiSafetySetting, 0, len(categories))
+	for _, category := range categories {
+		settings = append(settings, geminiSafetySetting{Category: category, Threshold: safetySettings[category]})
+	}
+
+	return &GeminiProvider{
+		apiKey:         apiKey,
+		apiBase:        strings.TrimRight(apiBase, "/"),
+		httpClient:     client,
+		safetySettings: settings,
+		signatures:     make(map[string]string),
+	}
+}
+
+func (p *GeminiProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	model = strings.TrimPrefix(strings.TrimPrefix(model, "google/"), "gemini/")
+	if model == "" {
+		model = p.GetDefaultModel()
+	}
+
+	reqBody := p.buildRequest(messages, tools, options)
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	streamCallback, streaming := options["stream_callback"].(StreamCallback)
+	endpoint := fmt.Sprintf("%s/models/%s:generateContent", p.apiBase, url.PathEscape(model))
+	if streaming {
+		endpoint = fmt.Sprintf("%s/models/%s:streamGenerateContent?alt=sse", p.apiBase, url.PathEscape(model))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("x-goog-api-key", p.apiKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed:\n  Status: %d\n  Body:   %s", resp.StatusCode, string(body))
+	}
+
+	if streaming {
+		return p.chatStream(resp.Body, streamCallback)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var apiResp geminiResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return p.parseResponse([]geminiResponse{apiResp})
+}
+
+func (p *GeminiProvider) GetDefaultModel() string {
+	return "gemini-2.5-flash"
+}
+
+func geminiImageParts(images []ImagePart) []geminiPart {
+	parts := make([]geminiPart, 0, len(images))
+	for _, img := range images {
+		parts = append(parts, geminiPart{InlineData: &geminiInlineData{MimeType: img.MediaType, Data: img.Data}})
+	}
+	return parts
+}
+
+// buildRequest translates provider-neutral messages and tools into a
+// generateContent request body.
+func (p *GeminiProvider) buildRequest(messages []Message, tools []ToolDefinition, options map[string]interface{}) *geminiRequest {
+	req := &geminiRequest{SafetySettings: p.safetySettings}
+
+	// Gemini matches function responses by name, but tool messages only
+	// carry the call ID, so remember which name each ID belonged to.
+	callNames := make(map[string]string)
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiContent{}
+			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, geminiPart{Text: msg.Content})
+		case "assistant":
+			var parts []geminiPart
+			if msg.Content != "" {
+				parts = append(parts, geminiPart{Text: msg.Content})
+			}
+			for _, tc := range msg.ToolCalls {
+				name, args := toolCallNameAndArgs(tc)
+				callNames[tc.ID] = name
+				parts = append(parts, geminiPart{
+					FunctionCall:     &geminiFunctionCall{Name: name, Args: args},
+					ThoughtSignature: p.thoughtSignature(tc.ID),
+				})
+			}
+			if len(parts) > 0 {
+				req.Contents = appendGeminiContent(req.Contents, "model", parts)
+			}
+		case "tool":
+			req.Contents = appendGeminiContent(req.Contents, "user", append([]geminiPart{{
+				FunctionResponse: &geminiFunctionResponse{
+					Name:     callNames[msg.ToolCallID],
+					Response: map[string]interface{}{"content": msg.Content},
+				},
+			}}, geminiImageParts(msg.Images)...))
+		default:
+			if msg.ToolCallID != "" {
+				req.Contents = appendGeminiContent(req.Contents, "user", []geminiPart{{
+					FunctionResponse: &geminiFunctionResponse{
+						Name:     callNames[msg.ToolCallID],
+						Response: map[string]interface{}{"content": msg.Content},
+					},
+				}})
+				continue
+			}
+			req.Contents = appendGeminiContent(req.Contents, "user", append([]geminiPart{{Text: msg.Content}}, geminiImageParts(msg.Images)...))
+		}
+	}
+
+	if len(tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(tools))
+		for _, t := range tools {
+			decl := geminiFunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+			}
+			if props, ok := t.Function.Parameters["properties"].(map[string]interface{}); ok && len(props) > 0 {
+				decl.Parameters = toGeminiSchema(t.Function.Parameters)
+			}
+			decls = append(decls, decl)
+		}
+		req.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+
+	genCfg := &geminiGenerationConfig{}
+	if maxTokens, ok := options["max_tokens"].(int); ok {
+		genCfg.MaxOutputTokens = maxTokens
+	}
+	if temperature, ok := options["temperature"].(float64); ok {
+		genCfg.Temperature = &temperature
+	}
+	if schema, _ := responseSchemaOptions(options); schema != nil {
+		genCfg.ResponseMimeType = "application/json"
+		genCfg.ResponseSchema = toGeminiSchema(schema)
+	}
+	if genCfg.MaxOutputTokens > 0 || genCfg.Temperature != nil || genCfg.ResponseSchema != nil {
+		req.GenerationConfig = genCfg
+	}
+
+	return req
+}
+
+// appendGeminiContent merges consecutive turns from the same role, since
+// Gemini expects all function responses for one model turn in a single
+// user content.
+func appendGeminiContent(contents []geminiContent, role string, parts []geminiPart) []geminiContent {
+	if n := len(contents); n > 0 && contents[n-1].Role == role {
+		contents[n-1].Parts = append(contents[n-1].Parts, parts...)
+		return contents
+	}
+	return append(contents, geminiContent{Role: role, Parts: parts})
+}
+
+// toolCallNameAndArgs handles both the flat Name/Arguments form and the
+// OpenAI-style Function form that the agent loop stores in history.
+func toolCallNameAndArgs(tc ToolCall) (string, map[string]interface{}) {
+	name := tc.Name
+	args := tc.Arguments
+	if tc.Function != nil {
+		if name == "" {
+			name = tc.Function.Name
+		}
+		if args == nil && tc.Function.Arguments != "" {
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				args = map[string]interface{}{"raw": tc.Function.Arguments}
+			}
+		}
+	}
+	return name, args
+}
+
+// toGeminiSchema converts a JSON Schema fragment into Gemini's OpenAPI
+// subset: unknown keywords are dropped, types are upper-cased and
+// ["string", "null"] unions become nullable.
+func toGeminiSchema(schema map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		if !geminiSchemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "type":
+			switch t := value.(type) {
+			case string:
+				out["type"] = strings.ToUpper(t)
+			case []interface{}:
+				for _, v := range t {
+					s, _ := v.(string)
+					if s == "null" {
+						out["nullable"] = true
+					} else if s != "" {
+						out["type"] = strings.ToUpper(s)
+					}
+				}
+			}
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			converted := make(map[string]interface{}, len(props))
+			for name, prop := range props {
+				if propSchema, ok := prop.(map[string]interface{}); ok {
+					converted[name] = toGeminiSchema(propSchema)
+				}
+			}
+			out["properties"] = converted
+		case "items":
+			if itemSchema, ok := value.(map[string]interface{}); ok {
+				out["items"] = toGeminiSchema(itemSchema)
+			}
+		case "anyOf":
+			variants, ok := value.([]interface{})
+			if !ok {
+				continue
+			}
+			converted := make([]interface{}, 0, len(variants))
+			for _, v := range variants {
+				if variant, ok := v.(map[string]interface{}); ok {
+					converted = append(converted, toGeminiSchema(variant))
+				}
+			}
+			out["anyOf"] = converted
+		case "enum":
+			// Gemini only accepts string enums.
+			values, ok := value.([]interface{})
+			if !ok {
+				if ss, ok := value.([]string); ok {
+					out["enum"] = ss
+				}
+				continue
+			}
+			enum := make([]string, 0, len(values))
+			for _, v := range values {
+				enum = append(enum, fmt.Sprintf("%v", v))
+			}
+			out["enum"] = enum
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// chatStream reads SSE events from streamGenerateContent, forwards deltas to
+// the callback and returns the accumulated response.
+func (p *GeminiProvider) chatStream(body io.Reader, callback StreamCallback) (*LLMResponse, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var chunks []geminiResponse
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &chunk); err != nil {
+			continue
+		}
+		chunks = append(chunks, chunk)
+
+		if len(chunk.Candidates) == 0 {
+			continue
+		}
+		for _, part := range chunk.Candidates[0].Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				callback(StreamChunk{ToolCallName: part.FunctionCall.Name})
+				if len(part.FunctionCall.Args) > 0 {
+					argsJSON, _ := json.Marshal(part.FunctionCall.Args)
+					callback(StreamChunk{ToolCallArgs: string(argsJSON)})
+				}
+			case part.Thought:
+				if part.Text != "" {
+					callback(StreamChunk{ReasoningContent: part.Text})
+				}
+			case part.Text != "":
+				callback(StreamChunk{Content: part.Text})
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("stream read error: %w", err)
+	}
+	callback(StreamChunk{Done: true})
+
+	return p.parseResponse(chunks)
+}
+
+// parseResponse folds one or more generateContent responses (a single
+// response, or every chunk of a stream) into an LLMResponse.
+func (p *GeminiProvider) parseResponse(responses []geminiResponse) (*LLMResponse, error) {
+	var content, reasoning strings.Builder
+	var toolCalls []ToolCall
+	var finishReason string
+	var usage *geminiUsageMetadata
+
+	for _, r := range responses {
+		if r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+			return nil, fmt.Errorf("gemini blocked the prompt: %s", r.PromptFeedback.BlockReason)
+		}
+		if r.UsageMetadata != nil {
+			usage = r.UsageMetadata
+		}
+		if len(r.Candidates) == 0 {
+			continue
+		}
+		candidate := r.Candidates[0]
+		if candidate.FinishReason != "" {
+			finishReason = candidate.FinishReason
+		}
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				id := part.FunctionCall.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%d_%d", time.Now().UnixNano(), len(toolCalls))
+				}
+				if part.ThoughtSignature != "" {
+					p.rememberThoughtSignature(id, part.ThoughtSignature)
+				}
+				args := part.FunctionCall.Args
+				if args == nil {
+					args = make(map[string]interface{})
+				}
+				toolCalls = append(toolCalls, ToolCall{
+					ID:        id,
+					Name:      part.FunctionCall.Name,
+					Arguments: args,
+				})
+			case part.Thought:
+				reasoning.WriteString(part.Text)
+			default:
+				content.WriteString(part.Text)
+			}
+		}
+	}
+
+	result := &LLMResponse{
+		Content:          content.String(),
+		ReasoningContent: reasoning.String(),
+		ToolCalls:        toolCalls,
+		FinishReason:     mapGeminiFinishReason(finishReason, len(toolCalls) > 0),
+	}
+	if usage != nil {
+		result.Usage = &UsageInfo{
+			PromptTokens:     usage.PromptTokenCount,
+			CompletionTokens: usage.CandidatesTokenCount + usage.ThoughtsTokenCount,
+			TotalTokens:      usage.TotalTokenCount,
+			CacheReadTokens:  usage.CachedContentTokenCount,
+			ReasoningTokens:  usage.ThoughtsTokenCount,
+		}
+	}
+	return result, nil
+}
+
+func mapGeminiFinishReason(reason string, hasToolCalls bool) string {
+	if hasToolCalls {
+		return "tool_calls"
+	}
+	switch reason {
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	default:
+		return "stop"
+	}
+}
+
+func (p *GeminiProvider) rememberThoughtSignature(id, signature string) {
+	p.sigMu.Lock()
+	defer p.sigMu.Unlock()
+	if len(p.signatures) >= maxThoughtSignatures {
+		p.signatures = make(map[string]string)
+	}
+	p.signatures[id] = signature
+}
+
+func (p *GeminiProvider) thoughtSignature(id string) string {
+	p.sigMu.Lock()
+	defer p.sigMu.Unlock()
+	return p.signatures[id]
+}
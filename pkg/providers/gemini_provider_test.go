@@ -0,0 +1,326 @@
+package providers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+)
+
+// newGeminiFixtureServer serves a recorded fixture from testdata/gemini and
+// captures the decoded request body and URL for assertions.
+func newGeminiFixtureServer(t *testing.T, fixture string, gotBody *map[string]interface{}, gotURL *string) *httptest.Server {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "gemini", fixture))
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if gotURL != nil {
+			*gotURL = r.URL.String()
+		}
+		if gotBody != nil {
+			raw, _ := io.ReadAll(r.Body)
+			json.Unmarshal(raw, gotBody)
+		}
+		if strings.HasSuffix(fixture, ".sse") {
+			w.Header().Set("Content-Type", "text/event-stream")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGeminiProvider_ChatText(t *testing.T) {
+	var body map[string]interface{}
+	var gotURL string
+	server := newGeminiFixtureServer(t, "text_response.json", &body, &gotURL)
+
+	p := NewGeminiProvider("test-key", server.URL, "", nil)
+	resp, err := p.Chat(t.Context(), []Message{
+		{Role: "system", Content: "You are helpful"},
+		{Role: "user", Content: "Hi"},
+	}, nil, "google/gemini-2.5-flash", map[string]interface{}{"max_tokens": 1024, "temperature": 0.5})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+
+	if gotURL != "/models/gemini-2.5-flash:generateContent" {
+		t.Errorf("URL = %q, want generateContent endpoint without provider prefix", gotURL)
+	}
+	if resp.Content != "Hello! How can I help you today?" {
+		t.Errorf("Content = %q", resp.Content)
+	}
+	if !strings.Contains(resp.ReasoningContent, "Checking the forecast") {
+		t.Errorf("ReasoningContent = %q, want thought part", resp.ReasoningContent)
+	}
+	if resp.FinishReason != "stop" {
+		t.Errorf("FinishReason = %q, want stop", resp.FinishReason)
+	}
+	if resp.Usage == nil || resp.Usage.PromptTokens != 12 || resp.Usage.CompletionTokens != 23 || resp.Usage.TotalTokens != 35 {
+		t.Errorf("Usage = %+v, want prompt=12 completion=23 total=35", resp.Usage)
+	}
+
+	sys, ok := body["systemInstruction"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("systemInstruction missing from request: %v", body)
+	}
+	parts := sys["parts"].([]interface{})
+	if parts[0].(map[string]interface{})["text"] != "You are helpful" {
+		t.Errorf("systemInstruction = %v", sys)
+	}
+	contents := body["contents"].([]interface{})
+	if len(contents) != 1 {
+		t.Fatalf("len(contents) = %d, want 1 (system must not be a content turn)", len(contents))
+	}
+	genCfg := body["generationConfig"].(map[string]interface{})
+	if genCfg["maxOutputTokens"] != float64(1024) || genCfg["temperature"] != 0.5 {
+		t.Errorf("generationConfig = %v", genCfg)
+	}
+}
+
+func TestGeminiProvider_FunctionCallRoundTrip(t *testing.T) {
+	server := newGeminiFixtureServer(t, "function_call_response.json", nil, nil)
+	p := NewGeminiProvider("test-key", server.URL, "", nil)
+
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Weather in Lisbon?"}}, nil, "gemini-2.5-flash", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want tool_calls", resp.FinishReason)
+	}
+	if len(resp.ToolCalls) != 1 {
+		t.Fatalf("len(ToolCalls) = %d, want 1", len(resp.ToolCalls))
+	}
+	tc := resp.ToolCalls[0]
+	if tc.ID == "" || tc.Name != "get_weather" || tc.Arguments["city"] != "Lisbon" {
+		t.Errorf("ToolCall = %+v", tc)
+	}
+
+	// Feed the call back the way the agent loop stores it in history.
+	argsJSON, _ := json.Marshal(tc.Arguments)
+	history := []Message{
+		{Role: "user", Content: "Weather in Lisbon?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{
+			ID:       tc.ID,
+			Type:     "function",
+			Function: &FunctionCall{Name: tc.Name, Arguments: string(argsJSON)},
+		}}},
+		{Role: "tool", Content: "21C, sunny", ToolCallID: tc.ID},
+	}
+	req := p.buildRequest(history, nil, nil)
+	if len(req.Contents) != 3 {
+		t.Fatalf("len(Contents) = %d, want 3", len(req.Contents))
+	}
+	call := req.Contents[1]
+	if call.Role != "model" || call.Parts[0].FunctionCall == nil {
+		t.Fatalf("assistant turn = %+v, want model functionCall", call)
+	}
+	if call.Parts[0].FunctionCall.Name != "get_weather" || call.Parts[0].FunctionCall.Args["unit"] != "celsius" {
+		t.Errorf("functionCall = %+v", call.Parts[0].FunctionCall)
+	}
+	if call.Parts[0].ThoughtSignature != "CiQB0e2Kb7kXc2lnbmF0dXJlLWZpeHR1cmU=" {
+		t.Errorf("ThoughtSignature = %q, want signature echoed back", call.Parts[0].ThoughtSignature)
+	}
+	result := req.Contents[2]
+	if result.Role != "user" || result.Parts[0].FunctionResponse == nil {
+		t.Fatalf("tool turn = %+v, want user functionResponse", result)
+	}
+	if result.Parts[0].FunctionResponse.Name != "get_weather" || result.Parts[0].FunctionResponse.Response["content"] != "21C, sunny" {
+		t.Errorf("functionResponse = %+v", result.Parts[0].FunctionResponse)
+	}
+}
+
+func TestGeminiProvider_ParallelToolResultsMerged(t *testing.T) {
+	p := NewGeminiProvider("test-key", "", "", nil)
+	req := p.buildRequest([]Message{
+		{Role: "user", Content: "Compare"},
+		{Role: "assistant", ToolCalls: []ToolCall{
+			{ID: "a", Name: "read_file", Arguments: map[string]interface{}{"path": "a.txt"}},
+			{ID: "b", Name: "list_dir", Arguments: map[string]interface{}{"path": "."}},
+		}},
+		{Role: "tool", Content: "A", ToolCallID: "a"},
+		{Role: "tool", Content: "B", ToolCallID: "b"},
+	}, nil, nil)
+
+	if len(req.Contents) != 3 {
+		t.Fatalf("len(Contents) = %d, want 3", len(req.Contents))
+	}
+	parts := req.Contents[2].Parts
+	if len(parts) != 2 {
+		t.Fatalf("len(parts) = %d, want both responses in one turn", len(parts))
+	}
+	if parts[0].FunctionResponse.Name != "read_file" || parts[1].FunctionResponse.Name != "list_dir" {
+		t.Errorf("response names = %q, %q", parts[0].FunctionResponse.Name, parts[1].FunctionResponse.Name)
+	}
+}
+
+func TestGeminiProvider_SafetyFinish(t *testing.T) {
+	server := newGeminiFixtureServer(t, "safety_response.json", nil, nil)
+	p := NewGeminiProvider("test-key", server.URL, "", nil)
+
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "x"}}, nil, "gemini-2.5-flash", nil)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if resp.FinishReason != "content_filter" {
+		t.Errorf("FinishReason = %q, want content_filter", resp.FinishReason)
+	}
+}
+
+func TestGeminiProvider_PromptBlocked(t *testing.T) {
+	server := newGeminiFixtureServer(t, "prompt_blocked_response.json", nil, nil)
+	p := NewGeminiProvider("test-key", server.URL, "", nil)
+
+	_, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "x"}}, nil, "gemini-2.5-flash", nil)
+	if err == nil || !strings.Contains(err.Error(), "PROHIBITED_CONTENT") {
+		t.Fatalf("Chat() error = %v, want block reason", err)
+	}
+}
+
+func TestGeminiProvider_Stream(t *testing.T) {
+	var gotURL string
+	server := newGeminiFixtureServer(t, "stream_response.sse", nil, &gotURL)
+	p := NewGeminiProvider("test-key", server.URL, "", nil)
+
+	var streamed strings.Builder
+	done := false
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gemini-2.5-flash", map[string]interface{}{
+		"stream_callback": StreamCallback(func(c StreamChunk) {
+			streamed.WriteString(c.Content)
+			if c.Done {
+				done = true
+			}
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if gotURL != "/models/gemini-2.5-flash:streamGenerateContent?alt=sse" {
+		t.Errorf("URL = %q, want streamGenerateContent endpoint", gotURL)
+	}
+	if resp.Content != "Hello there, friend!" || streamed.String() != resp.Content {
+		t.Errorf("Content = %q, streamed = %q", resp.Content, streamed.String())
+	}
+	if !done {
+		t.Error("expected a Done chunk")
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 11 {
+		t.Errorf("Usage = %+v, want total 11 from final chunk", resp.Usage)
+	}
+}
+
+func TestGeminiProvider_SafetySettingsSent(t *testing.T) {
+	var body map[string]interface{}
+	server := newGeminiFixtureServer(t, "text_response.json", &body, nil)
+	p := NewGeminiProvider("test-key", server.URL, "", map[string]string{
+		"HARM_CATEGORY_HARASSMENT":        "BLOCK_ONLY_HIGH",
+		"HARM_CATEGORY_DANGEROUS_CONTENT": "BLOCK_NONE",
+	})
+
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "Hi"}}, nil, "gemini-2.5-flash", nil); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	settings, ok := body["safetySettings"].([]interface{})
+	if !ok || len(settings) != 2 {
+		t.Fatalf("safetySettings = %v, want 2 entries", body["safetySettings"])
+	}
+	first := settings[0].(map[string]interface{})
+	if first["category"] != "HARM_CATEGORY_DANGEROUS_CONTENT" || first["threshold"] != "BLOCK_NONE" {
+		t.Errorf("safetySettings[0] = %v, want sorted by category", first)
+	}
+}
+
+func TestToGeminiSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{"type": "string", "description": "File path"},
+			"mode": map[string]interface{}{"type": []interface{}{"string", "null"}, "enum": []interface{}{"r", "w"}},
+			"tags": map[string]interface{}{
+				"type":  "array",
+				"items": map[string]interface{}{"type": "integer", "default": 1},
+			},
+		},
+		"required": []interface{}{"path"},
+	}
+
+	got := toGeminiSchema(schema)
+	if got["type"] != "OBJECT" {
+		t.Errorf("type = %v, want OBJECT", got["type"])
+	}
+	if _, ok := got["$schema"]; ok {
+		t.Error("$schema should be dropped")
+	}
+	if _, ok := got["additionalProperties"]; ok {
+		t.Error("additionalProperties should be dropped")
+	}
+	props := got["properties"].(map[string]interface{})
+	mode := props["mode"].(map[string]interface{})
+	if mode["type"] != "STRING" || mode["nullable"] != true {
+		t.Errorf("mode = %v, want nullable STRING", mode)
+	}
+	items := props["tags"].(map[string]interface{})["items"].(map[string]interface{})
+	if items["type"] != "INTEGER" {
+		t.Errorf("items.type = %v, want INTEGER", items["type"])
+	}
+	if _, ok := items["default"]; ok {
+		t.Error("nested default should be dropped")
+	}
+}
+
+func TestGeminiProvider_ToolsWithoutPropertiesOmitParameters(t *testing.T) {
+	p := NewGeminiProvider("test-key", "", "", nil)
+	req := p.buildRequest([]Message{{Role: "user", Content: "hi"}}, []ToolDefinition{
+		{Type: "function", Function: ToolFunctionDefinition{
+			Name:       "list_models",
+			Parameters: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		}},
+	}, nil)
+	if len(req.Tools) != 1 || len(req.Tools[0].FunctionDeclarations) != 1 {
+		t.Fatalf("Tools = %+v", req.Tools)
+	}
+	if req.Tools[0].FunctionDeclarations[0].Parameters != nil {
+		t.Error("empty object schema should be omitted, Gemini rejects OBJECT without properties")
+	}
+}
+
+func TestCreateProvider_GeminiNative(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Agents.Defaults.Provider = "gemini"
+	cfg.Agents.Defaults.Model = "gemini-2.5-flash"
+	cfg.Providers.Gemini.APIKey = "test-key"
+
+	provider, err := CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider() error: %v", err)
+	}
+	if _, ok := provider.(*GeminiProvider); !ok {
+		t.Fatalf("provider = %T, want *GeminiProvider", provider)
+	}
+
+	cfg.Providers.Gemini.APIBase = "https://generativelanguage.googleapis.com/v1beta/openai/"
+	provider, err = CreateProvider(cfg)
+	if err != nil {
+		t.Fatalf("CreateProvider() error: %v", err)
+	}
+	if _, ok := provider.(*HTTPProvider); !ok {
+		t.Fatalf("provider = %T, want *HTTPProvider for OpenAI-compatible base", provider)
+	}
+}
@@ -26,6 +26,44 @@ type HTTPProvider struct {
 	apiKey     string
 	apiBase    string
 	httpClient *http.Client
+
+	// supportsPromptCacheKey is set for backends known to accept the
+	// prompt_cache_key request field; others may reject unknown fields.
+	supportsPromptCacheKey bool
+}
+
+// openAIUsage is the usage object returned by OpenAI-compatible APIs,
+// including the cached-token breakdowns reported by OpenAI and DeepSeek.
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails *struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+	PromptCacheHitTokens int `json:"prompt_cache_hit_tokens"`
+}
+
+func (u *openAIUsage) toUsageInfo() *UsageInfo {
+	if u == nil {
+		return nil
+	}
+	info := &UsageInfo{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		CacheReadTokens:  u.PromptCacheHitTokens,
+	}
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		info.CacheReadTokens = u.PromptTokensDetails.CachedTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		info.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return info
 }
 
 func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
@@ -43,10 +81,61 @@ func NewHTTPProvider(apiKey, apiBase, proxy string) *HTTPProvider {
 	}
 
 	return &HTTPProvider{
-		apiKey:     apiKey,
-		apiBase:    strings.TrimRight(apiBase, "/"),
-		httpClient: client,
+		apiKey:                 apiKey,
+		apiBase:                strings.TrimRight(apiBase, "/"),
+		httpClient:             client,
+		supportsPromptCacheKey: strings.Contains(apiBase, "api.openai.com"),
+	}
+}
+
+// openAIMessages converts messages with images to content parts. Tool
+// messages can only hold text, so their images are sent in a user message
+// after the run of tool results; messages without images pass unchanged.
+func openAIMessages(messages []Message) interface{} {
+	hasImages := false
+	for _, m := range messages {
+		hasImages = hasImages || len(m.Images) > 0
+	}
+	if !hasImages {
+		return messages
+	}
+
+	out := make([]interface{}, 0, len(messages)+1)
+	var pending []interface{}
+	flush := func() {
+		if len(pending) > 0 {
+			out = append(out, map[string]interface{}{"role": "user", "content": pending})
+			pending = nil
+		}
+	}
+	for i, m := range messages {
+		switch {
+		case len(m.Images) == 0:
+			out = append(out, m)
+		case m.Role == "tool":
+			out = append(out, Message{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID})
+			pending = append(pending, map[string]interface{}{"type": "text", "text": "Images from tool call " + m.ToolCallID + ":"})
+			pending = append(pending, openAIImageParts(m.Images)...)
+		default:
+			parts := append([]interface{}{map[string]interface{}{"type": "text", "text": m.Content}}, openAIImageParts(m.Images)...)
+			out = append(out, map[string]interface{}{"role": m.Role, "content": parts})
+		}
+		if i+1 == len(messages) || messages[i+1].Role != "tool" {
+			flush()
+		}
+	}
+	return out
+}
+
+func openAIImageParts(images []ImagePart) []interface{} {
+	parts := make([]interface{}, 0, len(images))
+	for _, img := range images {
+		parts = append(parts, map[string]interface{}{
+			"type":      "image_url",
+			"image_url": map[string]interface{}{"url": img.dataURL()},
+		})
 	}
+	return parts
 }
 
 func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
@@ -64,7 +153,7 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 
 	requestBody := map[string]interface{}{
 		"model":    model,
-		"messages": messages,
+		"messages": openAIMessages(messages),
 	}
 
 	if len(tools) > 0 {
@@ -72,6 +161,21 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 		requestBody["tool_choice"] = "auto"
 	}
 
+	if schema, name := responseSchemaOptions(options); schema != nil {
+		// Ollama's /v1/chat/completions takes response_format too; its native
+		// "format" field only applies to /api/chat. Strict mode requires a
+		// closed schema, so only ask for it then.
+		additional, hasAdditional := schema["additionalProperties"].(bool)
+		requestBody["response_format"] = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   name,
+				"schema": schema,
+				"strict": hasAdditional && !additional,
+			},
+		}
+	}
+
 	if maxTokens, ok := options["max_tokens"].(int); ok {
 		lowerModel := strings.ToLower(model)
 		if strings.Contains(lowerModel, "o1") {
@@ -91,6 +195,11 @@ func (p *HTTPProvider) Chat(ctx context.Context, messages []Message, tools []Too
 		}
 	}
 
+	// Route requests sharing a prefix to the same cache shard
+	if cacheKey, ok := options["prompt_cache_key"].(string); ok && cacheKey != "" && p.supportsPromptCacheKey {
+		requestBody["prompt_cache_key"] = cacheKey
+	}
+
 	// Check for streaming callback
 	var streamCallback StreamCallback
 	if cb, ok := options["stream_callback"].(StreamCallback); ok {
@@ -185,7 +294,7 @@ func (p *HTTPProvider) chatStream(body io.Reader, callback StreamCallback) (*LLM
 				} `json:"delta"`
 				FinishReason *string `json:"finish_reason"`
 			} `json:"choices"`
-			Usage *UsageInfo `json:"usage"`
+			Usage *openAIUsage `json:"usage"`
 		}
 
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
@@ -193,7 +302,7 @@ func (p *HTTPProvider) chatStream(body io.Reader, callback StreamCallback) (*LLM
 		}
 
 		if chunk.Usage != nil {
-			usage = chunk.Usage
+			usage = chunk.Usage.toUsageInfo()
 		}
 
 		if len(chunk.Choices) == 0 {
@@ -288,7 +397,7 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
-		Usage *UsageInfo `json:"usage"`
+		Usage *openAIUsage `json:"usage"`
 	}
 
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
@@ -339,7 +448,7 @@ func (p *HTTPProvider) parseResponse(body []byte) (*LLMResponse, error) {
 		ReasoningContent: choice.Message.ReasoningContent,
 		ToolCalls:        toolCalls,
 		FinishReason:     choice.FinishReason,
-		Usage:            apiResponse.Usage,
+		Usage:            apiResponse.Usage.toUsageInfo(),
 	}, nil
 }
 
@@ -369,7 +478,59 @@ func createCodexAuthProvider() (LLMProvider, error) {
 	return NewCodexProviderWithTokenSource(cred.AccessToken, cred.AccountID, createCodexTokenSource()), nil
 }
 
+// createGeminiProvider returns the native Gemini provider, or nil when the
+// configured API base points at Gemini's OpenAI-compatible endpoint, in
+// which case the generic HTTP provider is used instead.
+func createGeminiProvider(pc config.ProviderConfig) LLMProvider {
+	if strings.HasSuffix(strings.TrimRight(pc.APIBase, "/"), "/openai") {
+		return nil
+	}
+	return NewGeminiProvider(pc.APIKey, pc.APIBase, pc.Proxy, pc.SafetySettings)
+}
+
+// CreateProvider builds the provider for the configured default model and
+// wraps it in a RateLimitedProvider when that provider has a rate_limit.
 func CreateProvider(cfg *config.Config) (LLMProvider, error) {
+	provider, err := createProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if pc, ok := resolveProviderConfig(cfg); ok {
+		provider = NewRateLimitedProvider(provider, pc.RateLimit)
+	}
+	return provider, nil
+}
+
+// resolveProviderConfig finds the provider section the default model is
+// served from: the explicit provider name, else the model's routing prefix
+// ("groq/llama-3.3-70b"), else the model family.
+func resolveProviderConfig(cfg *config.Config) (config.ProviderConfig, bool) {
+	if pc, ok := cfg.Providers.ByName(cfg.Agents.Defaults.Provider); ok {
+		return pc, true
+	}
+	model := strings.ToLower(cfg.Agents.Defaults.Model)
+	if idx := strings.Index(model, "/"); idx > 0 {
+		if pc, ok := cfg.Providers.ByName(model[:idx]); ok {
+			return pc, true
+		}
+	}
+	families := []struct{ keyword, provider string }{
+		{"claude", "anthropic"},
+		{"gpt", "openai"},
+		{"gemini", "gemini"},
+		{"kimi", "moonshot"},
+		{"moonshot", "moonshot"},
+		{"deepseek", "deepseek"},
+	}
+	for _, f := range families {
+		if strings.Contains(model, f.keyword) {
+			return cfg.Providers.ByName(f.provider)
+		}
+	}
+	return config.ProviderConfig{}, false
+}
+
+func createProvider(cfg *config.Config) (LLMProvider, error) {
 	model := cfg.Agents.Defaults.Model
 	providerName := strings.ToLower(cfg.Agents.Defaults.Provider)
 
@@ -424,11 +585,11 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			}
 		case "gemini", "google":
 			if cfg.Providers.Gemini.APIKey != "" {
+				if p := createGeminiProvider(cfg.Providers.Gemini); p != nil {
+					return p, nil
+				}
 				apiKey = cfg.Providers.Gemini.APIKey
 				apiBase = cfg.Providers.Gemini.APIBase
-				if apiBase == "" {
-					apiBase = "https://generativelanguage.googleapis.com/v1beta"
-				}
 			}
 		case "ollama":
 			apiBase = cfg.Providers.Ollama.APIBase
@@ -522,12 +683,12 @@ func CreateProvider(cfg *config.Config) (LLMProvider, error) {
 			}
 
 		case (strings.Contains(lowerModel, "gemini") || strings.HasPrefix(model, "google/")) && cfg.Providers.Gemini.APIKey != "":
+			if p := createGeminiProvider(cfg.Providers.Gemini); p != nil {
+				return p, nil
+			}
 			apiKey = cfg.Providers.Gemini.APIKey
 			apiBase = cfg.Providers.Gemini.APIBase
 			proxy = cfg.Providers.Gemini.Proxy
-			if apiBase == "" {
-				apiBase = "https://generativelanguage.googleapis.com/v1beta"
-			}
 
 		case (strings.Contains(lowerModel, "groq") || strings.HasPrefix(model, "groq/")) && cfg.Providers.Groq.APIKey != "":
 			apiKey = cfg.Providers.Groq.APIKey
@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPProvider_PromptCacheKey(t *testing.T) {
+	var reqBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{
+			"choices": [{"message": {"content": "ok"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 2048, "completion_tokens": 10, "total_tokens": 2058,
+				"prompt_tokens_details": {"cached_tokens": 1920}}
+		}`))
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	opts := map[string]interface{}{"prompt_cache_key": "telegram:42"}
+
+	resp, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", opts)
+	if err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if _, ok := reqBody["prompt_cache_key"]; ok {
+		t.Error("prompt_cache_key should not be sent to backends that are not known to support it")
+	}
+	if resp.Usage.CacheReadTokens != 1920 {
+		t.Errorf("CacheReadTokens = %d, want 1920", resp.Usage.CacheReadTokens)
+	}
+
+	p.supportsPromptCacheKey = true
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", opts); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if reqBody["prompt_cache_key"] != "telegram:42" {
+		t.Errorf("prompt_cache_key = %v, want telegram:42", reqBody["prompt_cache_key"])
+	}
+}
+
+func TestOpenAIUsage_DeepSeekCacheHits(t *testing.T) {
+	var u openAIUsage
+	if err := json.Unmarshal([]byte(`{"prompt_tokens": 100, "completion_tokens": 5, "total_tokens": 105, "prompt_cache_hit_tokens": 64}`), &u); err != nil {
+		t.Fatal(err)
+	}
+	if got := u.toUsageInfo(); got.CacheReadTokens != 64 || got.PromptTokens != 100 {
+		t.Errorf("toUsageInfo() = %+v, want 64 cache read of 100 prompt", got)
+	}
+}
+
+func TestOpenAIMessages_Images(t *testing.T) {
+	img := []ImagePart{{MediaType: "image/png", Data: "iVBO"}}
+	plain := []Message{{Role: "user", Content: "hi"}}
+	if _, ok := openAIMessages(plain).([]Message); !ok {
+		t.Error("messages without images should be sent unchanged")
+	}
+
+	msgs := []Message{
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "a"}, {ID: "b"}}},
+		{Role: "tool", ToolCallID: "a", Content: "Image x.png (attached)", Images: img},
+		{Role: "tool", ToolCallID: "b", Content: "done"},
+		{Role: "user", Content: "look", Images: img},
+	}
+	data, _ := json.Marshal(openAIMessages(msgs))
+	var got []map[string]interface{}
+	json.Unmarshal(data, &got)
+
+	roles := ""
+	for _, m := range got {
+		roles += m["role"].(string) + " "
+		if _, ok := m["images"]; ok {
+			t.Errorf("images field leaked into the request: %v", m)
+		}
+	}
+	// The image of tool call a must wait until both tool results are sent.
+	if roles != "assistant tool tool user user " {
+		t.Fatalf("roles = %q", roles)
+	}
+	parts := got[3]["content"].([]interface{})
+	url := parts[1].(map[string]interface{})["image_url"].(map[string]interface{})["url"]
+	if len(parts) != 2 || url != "data:image/png;base64,iVBO" {
+		t.Errorf("tool image message = %v", got[3])
+	}
+	if parts := got[4]["content"].([]interface{}); len(parts) != 2 {
+		t.Errorf("user message with image = %v", got[4])
+	}
+}
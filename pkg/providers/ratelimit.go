@@ -0,0 +1,258 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/logger"
+)
+
+// Priority orders callers waiting on a rate-limited provider. Higher values
+// are admitted first; callers of equal priority are served in arrival order.
+type Priority int
+
+const (
+	PriorityBackground  Priority = iota // heartbeat, cron, spawned subagents
+	PriorityNormal                      // default when nothing is set
+	PriorityInteractive                 // turns a user is waiting on
+)
+
+type priorityKey struct{}
+
+// WithPriority tags ctx so rate-limited providers can order its requests.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or
+// PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// tokenBucket refills continuously at capacity per minute. Its level may
+// go negative when actual usage exceeds what was reserved up front.
+type tokenBucket struct {
+	capacity float64
+	level    float64
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{capacity: float64(perMinute), level: float64(perMinute), last: now}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Minutes()
+	b.last = now
+	b.level += elapsed * b.capacity
+	if b.level > b.capacity {
+		b.level = b.capacity
+	}
+}
+
+// wait returns how long until n units are available (0 if they are now).
+func (b *tokenBucket) wait(n float64) time.Duration {
+	if n > b.capacity {
+		n = b.capacity
+	}
+	if b.level >= n {
+		return 0
+	}
+	return time.Duration((n - b.level) / b.capacity * float64(time.Minute))
+}
+
+type rateWaiter struct {
+	priority Priority
+	tokens   float64
+	ready    chan struct{}
+}
+
+// RateLimitedProvider wraps an LLMProvider with a requests-per-minute and a
+// tokens-per-minute bucket plus a concurrency cap. Waiting callers are
+// admitted strictly by priority, so interactive turns overtake queued
+// heartbeat, cron and subagent calls.
+type RateLimitedProvider struct {
+	inner          LLMProvider
+	maxConcurrency int
+
+	mu       sync.Mutex
+	requests *tokenBucket
+	tokens   *tokenBucket
+	inFlight int
+	queue    []*rateWaiter
+	timer    *time.Timer
+	now      func() time.Time
+}
+
+// NewRateLimitedProvider wraps inner with the limits in rl. Zero limits are
+// not enforced; if all are zero inner is returned unchanged.
+func NewRateLimitedProvider(inner LLMProvider, rl config.RateLimitConfig) LLMProvider {
+	if rl.RequestsPerMinute <= 0 && rl.TokensPerMinute <= 0 && rl.MaxConcurrency <= 0 {
+		return inner
+	}
+	now := time.Now()
+	return &RateLimitedProvider{
+		inner:          inner,
+		maxConcurrency: rl.MaxConcurrency,
+		requests:       newTokenBucket(rl.RequestsPerMinute, now),
+		tokens:         newTokenBucket(rl.TokensPerMinute, now),
+		now:            time.Now,
+	}
+}
+
+func (p *RateLimitedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	reserved := estimateRequestTokens(messages, options)
+	if err := p.acquire(ctx, PriorityFromContext(ctx), reserved); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.inner.Chat(ctx, messages, tools, model, options)
+	p.release(resp, err, reserved)
+	return resp, err
+}
+
+func (p *RateLimitedProvider) GetDefaultModel() string {
+	return p.inner.GetDefaultModel()
+}
+
+// estimateRequestTokens reserves prompt tokens (about four characters per
+// token) plus the requested completion budget against the TPM bucket.
+func estimateRequestTokens(messages []Message, options map[string]interface{}) float64 {
+	chars := 0
+	for _, m := range messages {
+		chars += len(m.Content) + 16
+	}
+	estimate := float64(chars) / 4
+	if maxTokens, ok := options["max_tokens"].(int); ok {
+		estimate += float64(maxTokens)
+	}
+	return estimate
+}
+
+func (p *RateLimitedProvider) acquire(ctx context.Context, priority Priority, tokens float64) error {
+	w := &rateWaiter{priority: priority, tokens: tokens, ready: make(chan struct{})}
+
+	p.mu.Lock()
+	// Insert after every waiter of equal or higher priority.
+	idx := len(p.queue)
+	for i, q := range p.queue {
+		if q.priority < priority {
+			idx = i
+			break
+		}
+	}
+	p.queue = append(p.queue, nil)
+	copy(p.queue[idx+1:], p.queue[idx:])
+	p.queue[idx] = w
+	p.dispatchLocked()
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		select {
+		case <-w.ready:
+			// Admitted concurrently with cancellation; hand the slot back.
+			p.inFlight--
+			p.dispatchLocked()
+		default:
+			p.removeLocked(w)
+			p.dispatchLocked()
+		}
+		return ctx.Err()
+	}
+}
+
+func (p *RateLimitedProvider) release(resp *LLMResponse, err error, reserved float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.inFlight--
+	if p.tokens != nil && resp != nil && resp.Usage != nil && resp.Usage.TotalTokens > 0 {
+		// Settle the reservation against what was actually used.
+		p.tokens.level += reserved - float64(resp.Usage.TotalTokens)
+	}
+	if err != nil && p.requests != nil && isRateLimitError(err) {
+		// The provider is already throttling us: stop sending until the
+		// request bucket has refilled instead of hammering it.
+		p.requests.level = 0
+		logger.WarnCF("provider.ratelimit", "Upstream rate limit hit, pausing requests", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+	p.dispatchLocked()
+}
+
+func isRateLimitError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "429") || strings.Contains(msg, "rate limit")
+}
+
+func (p *RateLimitedProvider) removeLocked(w *rateWaiter) {
+	for i, q := range p.queue {
+		if q == w {
+			p.queue = append(p.queue[:i], p.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatchLocked admits waiters from the head of the queue while limits
+// allow, and arms a timer for when the head can next be admitted.
+func (p *RateLimitedProvider) dispatchLocked() {
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+
+	for len(p.queue) > 0 {
+		if p.maxConcurrency > 0 && p.inFlight >= p.maxConcurrency {
+			return // release will dispatch again
+		}
+
+		head := p.queue[0]
+		now := p.now()
+		var delay time.Duration
+		if p.requests != nil {
+			p.requests.refill(now)
+			delay = p.requests.wait(1)
+		}
+		if p.tokens != nil {
+			p.tokens.refill(now)
+			if d := p.tokens.wait(head.tokens); d > delay {
+				delay = d
+			}
+		}
+		if delay > 0 {
+			p.timer = time.AfterFunc(delay, func() {
+				p.mu.Lock()
+				defer p.mu.Unlock()
+				p.dispatchLocked()
+			})
+			return
+		}
+
+		if p.requests != nil {
+			p.requests.level--
+		}
+		if p.tokens != nil {
+			p.tokens.level -= head.tokens
+		}
+		p.inFlight++
+		p.queue = p.queue[1:]
+		close(head.ready)
+	}
+}
@@ -0,0 +1,167 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+)
+
+// gatedProvider blocks each Chat call until release is signalled and
+// records the order in which calls started.
+type gatedProvider struct {
+	mu      sync.Mutex
+	started []string
+	release chan struct{}
+	err     error
+}
+
+func (g *gatedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	g.mu.Lock()
+	g.started = append(g.started, messages[0].Content)
+	g.mu.Unlock()
+	if g.release != nil {
+		<-g.release
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &LLMResponse{Content: "ok", Usage: &UsageInfo{TotalTokens: 10}}, nil
+}
+
+func (g *gatedProvider) GetDefaultModel() string { return "test-model" }
+
+func (g *gatedProvider) startedCalls() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.started...)
+}
+
+func waitForQueue(t *testing.T, p *RateLimitedProvider, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		p.mu.Lock()
+		l := len(p.queue)
+		p.mu.Unlock()
+		if l == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("queue never reached %d waiters", n)
+}
+
+func TestNewRateLimitedProvider_NoLimitsReturnsInner(t *testing.T) {
+	inner := &gatedProvider{}
+	if got := NewRateLimitedProvider(inner, config.RateLimitConfig{}); got != inner {
+		t.Errorf("NewRateLimitedProvider() with no limits should return inner provider")
+	}
+}
+
+func TestRateLimitedProvider_InteractiveOvertakesBackground(t *testing.T) {
+	inner := &gatedProvider{release: make(chan struct{})}
+	p := NewRateLimitedProvider(inner, config.RateLimitConfig{MaxConcurrency: 1}).(*RateLimitedProvider)
+
+	call := func(ctx context.Context, name string, wg *sync.WaitGroup) {
+		defer wg.Done()
+		if _, err := p.Chat(ctx, []Message{{Role: "user", Content: name}}, nil, "", nil); err != nil {
+			t.Errorf("Chat(%s) error: %v", name, err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	go call(context.Background(), "first", &wg)
+	waitForQueue(t, p, 0)
+	for len(inner.startedCalls()) == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	bg := WithPriority(context.Background(), PriorityBackground)
+	go call(bg, "cron-1", &wg)
+	waitForQueue(t, p, 1)
+	go call(bg, "cron-2", &wg)
+	waitForQueue(t, p, 2)
+	go call(WithPriority(context.Background(), PriorityInteractive), "user", &wg)
+	waitForQueue(t, p, 3)
+
+	for i := 0; i < 4; i++ {
+		inner.release <- struct{}{}
+	}
+	wg.Wait()
+
+	got := inner.startedCalls()
+	want := []string{"first", "user", "cron-1", "cron-2"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("call order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRateLimitedProvider_RequestsPerMinute(t *testing.T) {
+	inner := &gatedProvider{}
+	p := NewRateLimitedProvider(inner, config.RateLimitConfig{RequestsPerMinute: 2}).(*RateLimitedProvider)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := p.Chat(ctx, []Message{{Content: "hi"}}, nil, "", nil); err != nil {
+			t.Fatalf("Chat() error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Chat(ctx, []Message{{Content: "hi"}}, nil, "", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third Chat() = %v, want it to wait past the deadline", err)
+	}
+	p.mu.Lock()
+	queued := len(p.queue)
+	p.mu.Unlock()
+	if queued != 0 {
+		t.Errorf("cancelled waiter left in queue (%d)", queued)
+	}
+
+	// Thirty seconds later one request's worth has refilled.
+	p.mu.Lock()
+	p.now = func() time.Time { return time.Now().Add(31 * time.Second) }
+	p.mu.Unlock()
+	if _, err := p.Chat(context.Background(), []Message{{Content: "hi"}}, nil, "", nil); err != nil {
+		t.Fatalf("Chat() after refill error: %v", err)
+	}
+}
+
+func TestRateLimitedProvider_TokensSettledAgainstUsage(t *testing.T) {
+	inner := &gatedProvider{}
+	p := NewRateLimitedProvider(inner, config.RateLimitConfig{TokensPerMinute: 1000}).(*RateLimitedProvider)
+
+	if _, err := p.Chat(context.Background(), []Message{{Content: "hi"}}, nil, "", map[string]interface{}{"max_tokens": 500}); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	p.mu.Lock()
+	level := p.tokens.level
+	p.mu.Unlock()
+	// 500 reserved for completion, but only 10 tokens were used.
+	if level < 985 {
+		t.Errorf("token bucket level = %v, want reservation refunded to ~990", level)
+	}
+}
+
+func TestRateLimitedProvider_UpstreamRateLimitPauses(t *testing.T) {
+	inner := &gatedProvider{err: errors.New("API error: status 429: Too Many Requests")}
+	p := NewRateLimitedProvider(inner, config.RateLimitConfig{RequestsPerMinute: 60}).(*RateLimitedProvider)
+
+	if _, err := p.Chat(context.Background(), []Message{{Content: "hi"}}, nil, "", nil); err == nil {
+		t.Fatal("expected upstream error")
+	}
+	p.mu.Lock()
+	level := p.requests.level
+	p.mu.Unlock()
+	if level > 0 {
+		t.Errorf("request bucket level = %v, want drained after 429", level)
+	}
+}
@@ -0,0 +1,232 @@
+package providers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ValidateJSONSchema checks a decoded JSON value (as produced by
+// encoding/json into interface{}) against a JSON Schema. It covers the
+// subset used by tool parameters and structured outputs: type, properties,
+// required, additionalProperties, items, enum, anyOf and the numeric,
+// length and size bounds. The returned error names the offending path.
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) error {
+	return validateSchemaAt("$", schema, value)
+}
+
+func validateSchemaAt(path string, schema map[string]interface{}, value interface{}) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	if variants, ok := schema["anyOf"].([]interface{}); ok && len(variants) > 0 {
+		var firstErr error
+		for _, v := range variants {
+			variant, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			err := validateSchemaAt(path, variant, value)
+			if err == nil {
+				firstErr = nil
+				break
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr != nil {
+			return fmt.Errorf("%s: does not match any allowed schema (%v)", path, firstErr)
+		}
+	}
+
+	if t, ok := schema["type"]; ok {
+		types := schemaTypes(t)
+		if len(types) > 0 && !matchesAnyType(types, value) {
+			return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(types, " or "), jsonTypeName(value))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprintf("%v", e) == fmt.Sprintf("%v", value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed := make([]string, 0, len(enum))
+			for _, e := range enum {
+				allowed = append(allowed, fmt.Sprintf("%v", e))
+			}
+			return fmt.Errorf("%s: %v is not one of [%s]", path, value, strings.Join(allowed, ", "))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return validateObject(path, schema, v)
+	case []interface{}:
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < min {
+			return fmt.Errorf("%s: expected at least %v items, got %d", path, min, len(v))
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > max {
+			return fmt.Errorf("%s: expected at most %v items, got %d", path, max, len(v))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				if err := validateSchemaAt(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		n := float64(len([]rune(v)))
+		if min, ok := schemaNumber(schema["minLength"]); ok && n < min {
+			return fmt.Errorf("%s: expected at least %v characters, got %v", path, min, n)
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && n > max {
+			return fmt.Errorf("%s: expected at most %v characters, got %v", path, max, n)
+		}
+	case float64:
+		if min, ok := schemaNumber(schema["minimum"]); ok && v < min {
+			return fmt.Errorf("%s: %v is less than minimum %v", path, v, min)
+		}
+		if max, ok := schemaNumber(schema["maximum"]); ok && v > max {
+			return fmt.Errorf("%s: %v is greater than maximum %v", path, v, max)
+		}
+	}
+
+	return nil
+}
+
+func validateObject(path string, schema map[string]interface{}, obj map[string]interface{}) error {
+	for _, name := range schemaRequired(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			return fmt.Errorf("%s: missing required property %q", path, name)
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		propSchema, known := props[k].(map[string]interface{})
+		if !known {
+			if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				return fmt.Errorf("%s: unexpected property %q", path, k)
+			}
+			continue
+		}
+		if err := validateSchemaAt(path+"."+k, propSchema, obj[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaRequired(r interface{}) []string {
+	switch v := r.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func matchesAnyType(types []string, value interface{}) bool {
+	for _, t := range types {
+		if matchesType(t, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, value interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,116 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Structured output is requested through Chat options:
+//
+//	"response_schema"      map[string]interface{} JSON Schema the reply must match
+//	"response_schema_name" string                 optional schema name (default "response")
+//
+// OpenAI-compatible providers (Ollama included) send it as response_format
+// json_schema, Claude forces a single tool whose input is the schema,
+// and Gemini sets responseSchema. Providers that cannot enforce a schema
+// ignore the options; ChatJSON validates the reply either way.
+
+const defaultResponseSchemaName = "response"
+
+// responseSchemaOptions extracts the structured output options, if any.
+func responseSchemaOptions(options map[string]interface{}) (map[string]interface{}, string) {
+	schema, ok := options["response_schema"].(map[string]interface{})
+	if !ok || len(schema) == 0 {
+		return nil, ""
+	}
+	name, _ := options["response_schema_name"].(string)
+	if name == "" {
+		name = defaultResponseSchemaName
+	}
+	return schema, name
+}
+
+// ChatJSON asks the provider for a reply matching schema and decodes it into
+// T. If the reply is not valid JSON or does not validate, the model is told
+// what was wrong and given one chance to repair it.
+func ChatJSON[T any](ctx context.Context, provider LLMProvider, messages []Message, schema map[string]interface{}, model string, options map[string]interface{}) (T, error) {
+	var result T
+
+	opts := make(map[string]interface{}, len(options)+1)
+	for k, v := range options {
+		opts[k] = v
+	}
+	opts["response_schema"] = schema
+
+	conversation := append([]Message(nil), messages...)
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		resp, err := provider.Chat(ctx, conversation, nil, model, opts)
+		if err != nil {
+			return result, err
+		}
+
+		raw := extractJSONText(resp.Content)
+		lastErr = decodeStructured(raw, schema, &result)
+		if lastErr == nil {
+			return result, nil
+		}
+
+		conversation = append(conversation,
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: fmt.Sprintf(
+				"Your previous reply was not valid: %v. Reply again with only a JSON value that matches the requested schema, no prose or code fences.", lastErr)},
+		)
+	}
+
+	return result, fmt.Errorf("structured output invalid after repair attempt: %w", lastErr)
+}
+
+func decodeStructured(raw string, schema map[string]interface{}, out interface{}) error {
+	if raw == "" {
+		return fmt.Errorf("empty response")
+	}
+	var generic interface{}
+	if err := json.Unmarshal([]byte(raw), &generic); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := ValidateJSONSchema(schema, generic); err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return fmt.Errorf("decoding into result type: %w", err)
+	}
+	return nil
+}
+
+// extractJSONText strips surrounding prose and markdown code fences that
+// models add despite instructions, returning the outermost JSON value.
+func extractJSONText(content string) string {
+	s := strings.TrimSpace(content)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```json")
+		s = strings.TrimPrefix(s, "```")
+		if idx := strings.LastIndex(s, "```"); idx != -1 {
+			s = s[:idx]
+		}
+		s = strings.TrimSpace(s)
+	}
+	if json.Valid([]byte(s)) {
+		return s
+	}
+
+	start := strings.IndexAny(s, "{[")
+	if start == -1 {
+		return s
+	}
+	closer := byte('}')
+	if s[start] == '[' {
+		closer = ']'
+	}
+	if end := strings.LastIndexByte(s, closer); end > start {
+		return s[start : end+1]
+	}
+	return s
+}
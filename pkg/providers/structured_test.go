@@ -0,0 +1,193 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type scriptedProvider struct {
+	replies  []string
+	calls    [][]Message
+	lastOpts map[string]interface{}
+}
+
+func (s *scriptedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string, options map[string]interface{}) (*LLMResponse, error) {
+	s.calls = append(s.calls, messages)
+	s.lastOpts = options
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	return &LLMResponse{Content: reply, FinishReason: "stop"}, nil
+}
+
+func (s *scriptedProvider) GetDefaultModel() string { return "scripted" }
+
+var heartbeatSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"ok":     map[string]interface{}{"type": "boolean"},
+		"reason": map[string]interface{}{"type": "string"},
+	},
+	"required":             []interface{}{"ok"},
+	"additionalProperties": false,
+}
+
+type heartbeatResult struct {
+	OK     bool   `json:"ok"`
+	Reason string `json:"reason"`
+}
+
+func TestChatJSON_DecodesFencedReply(t *testing.T) {
+	p := &scriptedProvider{replies: []string{"```json\n{\"ok\": true, \"reason\": \"all quiet\"}\n```"}}
+
+	got, err := ChatJSON[heartbeatResult](t.Context(), p, []Message{{Role: "user", Content: "status?"}}, heartbeatSchema, "m", nil)
+	if err != nil {
+		t.Fatalf("ChatJSON() error: %v", err)
+	}
+	if !got.OK || got.Reason != "all quiet" {
+		t.Errorf("ChatJSON() = %+v", got)
+	}
+	if _, ok := p.lastOpts["response_schema"]; !ok {
+		t.Error("response_schema option not passed to provider")
+	}
+}
+
+func TestChatJSON_RepairsOnce(t *testing.T) {
+	p := &scriptedProvider{replies: []string{`{"ok": "yes"}`, `{"ok": false}`}}
+
+	got, err := ChatJSON[heartbeatResult](t.Context(), p, []Message{{Role: "user", Content: "status?"}}, heartbeatSchema, "m", nil)
+	if err != nil {
+		t.Fatalf("ChatJSON() error: %v", err)
+	}
+	if got.OK {
+		t.Errorf("ChatJSON() = %+v, want repaired ok=false", got)
+	}
+	if len(p.calls) != 2 {
+		t.Fatalf("calls = %d, want 2", len(p.calls))
+	}
+	repair := p.calls[1][len(p.calls[1])-1]
+	if repair.Role != "user" || !strings.Contains(repair.Content, "$.ok: expected boolean") {
+		t.Errorf("repair prompt = %q, want validation error", repair.Content)
+	}
+}
+
+func TestChatJSON_FailsAfterRepair(t *testing.T) {
+	p := &scriptedProvider{replies: []string{"not json", "still not json"}}
+
+	_, err := ChatJSON[heartbeatResult](t.Context(), p, []Message{{Role: "user", Content: "status?"}}, heartbeatSchema, "m", nil)
+	if err == nil || !strings.Contains(err.Error(), "after repair attempt") {
+		t.Fatalf("ChatJSON() error = %v, want repair failure", err)
+	}
+}
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer", "minimum": 1},
+			"mode":  map[string]interface{}{"type": "string", "enum": []interface{}{"fast", "slow"}},
+			"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required": []interface{}{"count"},
+	}
+	tests := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{"valid", `{"count": 2, "mode": "fast", "tags": ["a"]}`, ""},
+		{"missing required", `{"mode": "fast"}`, `missing required property "count"`},
+		{"not integer", `{"count": 1.5}`, "$.count: expected integer"},
+		{"below minimum", `{"count": 0}`, "less than minimum"},
+		{"bad enum", `{"count": 1, "mode": "medium"}`, "is not one of"},
+		{"bad item", `{"count": 1, "tags": ["a", 2]}`, "$.tags[1]: expected string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v interface{}
+			json.Unmarshal([]byte(tt.value), &v)
+			err := ValidateJSONSchema(schema, v)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPProvider_ResponseFormat(t *testing.T) {
+	var reqBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody = nil
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		w.Write([]byte(`{"choices": [{"message": {"content": "{\"ok\": true}"}, "finish_reason": "stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewHTTPProvider("key", server.URL, "")
+	opts := map[string]interface{}{"response_schema": heartbeatSchema, "response_schema_name": "heartbeat"}
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "gpt-4o", opts); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	rf, ok := reqBody["response_format"].(map[string]interface{})
+	if !ok || rf["type"] != "json_schema" {
+		t.Fatalf("response_format = %v", reqBody["response_format"])
+	}
+	js := rf["json_schema"].(map[string]interface{})
+	if js["name"] != "heartbeat" || js["strict"] != true {
+		t.Errorf("json_schema = %v, want name heartbeat and strict for closed schema", js)
+	}
+
+	if _, err := p.Chat(t.Context(), []Message{{Role: "user", Content: "hi"}}, nil, "ollama/qwen2.5:14b", opts); err != nil {
+		t.Fatalf("Chat() error: %v", err)
+	}
+	if _, ok := reqBody["response_format"].(map[string]interface{}); !ok {
+		t.Errorf("response_format = %v, want it for Ollama's OpenAI-compatible endpoint", reqBody["response_format"])
+	}
+	if _, ok := reqBody["format"]; ok {
+		t.Error("Ollama's native format field does not apply to /v1/chat/completions")
+	}
+}
+
+func TestBuildClaudeParams_ResponseSchemaForcesTool(t *testing.T) {
+	params, err := buildClaudeParams([]Message{{Role: "user", Content: "hi"}}, nil, "claude-sonnet-4-5-20250929", map[string]interface{}{
+		"response_schema": heartbeatSchema,
+	})
+	if err != nil {
+		t.Fatalf("buildClaudeParams() error: %v", err)
+	}
+	if len(params.Tools) != 1 || params.Tools[0].OfTool.Name != "response" {
+		t.Fatalf("Tools = %+v, want forced response tool", params.Tools)
+	}
+	if params.ToolChoice.OfTool == nil || params.ToolChoice.OfTool.Name != "response" {
+		t.Errorf("ToolChoice = %+v, want tool response", params.ToolChoice)
+	}
+
+	resp := &LLMResponse{
+		ToolCalls:    []ToolCall{{ID: "t1", Name: "response", Arguments: map[string]interface{}{"ok": true}}},
+		FinishReason: "tool_calls",
+	}
+	unwrapStructuredToolCall(resp, "response")
+	if resp.Content != `{"ok":true}` || len(resp.ToolCalls) != 0 || resp.FinishReason != "stop" {
+		t.Errorf("unwrapped = %+v", resp)
+	}
+}
+
+func TestGeminiProvider_ResponseSchema(t *testing.T) {
+	p := NewGeminiProvider("k", "", "", nil)
+	req := p.buildRequest([]Message{{Role: "user", Content: "hi"}}, nil, map[string]interface{}{"response_schema": heartbeatSchema})
+	if req.GenerationConfig == nil || req.GenerationConfig.ResponseMimeType != "application/json" {
+		t.Fatalf("GenerationConfig = %+v", req.GenerationConfig)
+	}
+	if req.GenerationConfig.ResponseSchema["type"] != "OBJECT" {
+		t.Errorf("ResponseSchema = %v", req.GenerationConfig.ResponseSchema)
+	}
+}
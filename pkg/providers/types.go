@@ -23,10 +23,17 @@ type LLMResponse struct {
 	Usage            *UsageInfo `json:"usage,omitempty"`
 }
 
+// UsageInfo reports token usage for one LLM call. PromptTokens always
+// includes cached input; CacheReadTokens and CacheWriteTokens break out the
+// portion served from or written to the provider's prompt cache. Likewise
+// CompletionTokens includes ReasoningTokens.
 type UsageInfo struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+	ReasoningTokens  int `json:"reasoning_tokens,omitempty"`
 }
 
 // StreamChunk represents a single streaming chunk from the LLM.
@@ -42,10 +49,22 @@ type StreamChunk struct {
 type StreamCallback func(chunk StreamChunk)
 
 type Message struct {
-	Role       string     `json:"role"`
-	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Role       string      `json:"role"`
+	Content    string      `json:"content"`
+	ToolCalls  []ToolCall  `json:"tool_calls,omitempty"`
+	ToolCallID string      `json:"tool_call_id,omitempty"`
+	Images     []ImagePart `json:"images,omitempty"`
+}
+
+// ImagePart is an image attached to a message for vision models. Providers
+// that cannot take images ignore it; Content should describe the image.
+type ImagePart struct {
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"` // base64
+}
+
+func (img ImagePart) dataURL() string {
+	return "data:" + img.MediaType + ";base64," + img.Data
 }
 
 type LLMProvider interface {
@@ -0,0 +1,221 @@
+package tools
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/jasperan/picooraclaw/pkg/providers"
+)
+
+// validateToolArgs checks args against a tool's parameter schema. Values
+// of the wrong JSON type are coerced first when the intent is clear: "5"
+// for an integer, 5 for a string, "true" for a boolean, a JSON-encoded
+// object or array in a string, or a single value where an array is
+// expected. It returns the coerced copy of args.
+func validateToolArgs(params map[string]interface{}, args map[string]interface{}) (map[string]interface{}, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	if len(params) == 0 {
+		return args, nil
+	}
+	// Tool schemas are Go literals ([]string enums, int bounds); the
+	// validator works on decoded JSON.
+	schema, err := normalizeSchema(params)
+	if err != nil {
+		return args, nil
+	}
+	coerced, _ := coerceValue(schema, args).(map[string]interface{})
+	if coerced == nil {
+		coerced = args
+	}
+	if err := providers.ValidateJSONSchema(schema, coerced); err != nil {
+		return nil, err
+	}
+	return coerced, nil
+}
+
+func normalizeSchema(params map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// coerceValue converts v towards the schema's type, recursing into object
+// properties and array items. It never fails; the validator reports what
+// could not be converted.
+func coerceValue(schema map[string]interface{}, v interface{}) interface{} {
+	types := schemaTypeList(schema["type"])
+	if len(types) > 0 && !hasJSONType(types, v) {
+		for _, t := range types {
+			if c, ok := coerceScalar(t, v); ok {
+				v = c
+				break
+			}
+		}
+	}
+
+	if str, ok := v.(string); ok {
+		v = matchEnumCase(schema["enum"], str)
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		if len(props) == 0 {
+			return val
+		}
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if propSchema, ok := props[k].(map[string]interface{}); ok {
+				item = coerceValue(propSchema, item)
+			}
+			out[k] = item
+		}
+		return out
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return val
+		}
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = coerceValue(items, item)
+		}
+		return out
+	}
+	return v
+}
+
+func coerceScalar(t string, v interface{}) (interface{}, bool) {
+	switch t {
+	case "integer", "number":
+		if s, ok := v.(string); ok {
+			s = strings.TrimSpace(s)
+			// Decimal unless explicitly hex: a leading zero is not octal,
+			// so "010" stays 10.
+			digits := strings.TrimLeft(s, "+-")
+			if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+				if n, err := strconv.ParseInt(s[:len(s)-len(digits)]+digits[2:], 16, 64); err == nil {
+					return float64(n), true
+				}
+				break
+			}
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return float64(n), true
+			}
+			if f, err := strconv.ParseFloat(s, 64); err == nil {
+				return f, true
+			}
+		}
+	case "boolean":
+		if s, ok := v.(string); ok {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b, true
+			}
+		}
+	case "string":
+		switch x := v.(type) {
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64), true
+		case bool:
+			return strconv.FormatBool(x), true
+		}
+	case "array":
+		if s, ok := v.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var arr []interface{}
+			if json.Unmarshal([]byte(s), &arr) == nil {
+				return arr, true
+			}
+		}
+		if _, isObject := v.(map[string]interface{}); v != nil && !isObject {
+			return []interface{}{v}, true
+		}
+	case "object":
+		if s, ok := v.(string); ok && strings.HasPrefix(strings.TrimSpace(s), "{") {
+			var obj map[string]interface{}
+			if json.Unmarshal([]byte(s), &obj) == nil {
+				return obj, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// matchEnumCase returns the enum value equal to s ignoring case, so "post"
+// is accepted for "POST". Exact matches and non-matches return s.
+func matchEnumCase(enum interface{}, s string) string {
+	values, _ := enum.([]interface{})
+	for _, e := range values {
+		if e == s {
+			return s
+		}
+	}
+	for _, e := range values {
+		if es, ok := e.(string); ok && strings.EqualFold(es, s) {
+			return es
+		}
+	}
+	return s
+}
+
+func schemaTypeList(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, s := range v {
+			if str, ok := s.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func hasJSONType(types []string, v interface{}) bool {
+	for _, t := range types {
+		switch t {
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := v.(float64); ok && f == float64(int64(f)) {
+				return true
+			}
+		case "null":
+			if v == nil {
+				return true
+			}
+		default:
+			return true
+		}
+	}
+	return false
+}
@@ -20,6 +20,8 @@ type toolCtxKey struct{ name string }
 var (
 	ctxKeyChannel = &toolCtxKey{"channel"}
 	ctxKeyChatID  = &toolCtxKey{"chatID"}
+	ctxKeySender  = &toolCtxKey{"sender"}
+	ctxKeyVision  = &toolCtxKey{"vision"}
 )
 
 // WithToolContext returns a child context carrying channel and chatID.
@@ -41,6 +43,30 @@ func ToolChatID(ctx context.Context) string {
 	return v
 }
 
+// WithToolSender returns a child context carrying the sender of the message
+// being handled.
+func WithToolSender(ctx context.Context, senderID string) context.Context {
+	return context.WithValue(ctx, ctxKeySender, senderID)
+}
+
+// ToolSender extracts the sender ID from ctx, or "" if unset.
+func ToolSender(ctx context.Context) string {
+	v, _ := ctx.Value(ctxKeySender).(string)
+	return v
+}
+
+// WithToolVision returns a child context recording whether the model the
+// tool result goes to accepts images.
+func WithToolVision(ctx context.Context, vision bool) context.Context {
+	return context.WithValue(ctx, ctxKeyVision, vision)
+}
+
+// ToolVision reports whether tool results may carry images.
+func ToolVision(ctx context.Context) bool {
+	v, _ := ctx.Value(ctxKeyVision).(bool)
+	return v
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 type AsyncCallback func(ctx context.Context, result *ToolResult)
 
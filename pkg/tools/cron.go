@@ -7,6 +7,7 @@ import (
 
 	"github.com/jasperan/picooraclaw/pkg/bus"
 	"github.com/jasperan/picooraclaw/pkg/cron"
+	"github.com/jasperan/picooraclaw/pkg/providers"
 	"github.com/jasperan/picooraclaw/pkg/utils"
 )
 
@@ -36,6 +37,17 @@ func NewCronTool(cronService *cron.CronService, executor JobExecutor, msgBus *bu
 	}
 }
 
+// SetExecPolicy applies the exec policy to scheduled commands. They are
+// checked as agent "cron" in the channel and chat the job reports to.
+func (t *CronTool) SetExecPolicy(policy *ExecPolicySet) {
+	t.execTool.SetPolicy(policy, "cron")
+}
+
+// SetExecSandbox runs scheduled shell commands through the exec sandbox.
+func (t *CronTool) SetExecSandbox(sandbox *Sandbox) {
+	t.execTool.SetSandbox(sandbox)
+}
+
 // Name returns the tool name
 func (t *CronTool) Name() string {
 	return "cron"
@@ -268,7 +280,7 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 			"command": job.Payload.Command,
 		}
 
-		result := t.execTool.Execute(ctx, args)
+		result := t.execTool.Execute(WithToolContext(ctx, channel, chatID), args)
 		var output string
 		if result.IsError {
 			output = fmt.Sprintf("Error executing scheduled command: %s", result.ForLLM)
@@ -299,7 +311,7 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 
 	// Call agent with job's message
 	response, err := t.executor.ProcessDirectWithChannel(
-		ctx,
+		providers.WithPriority(ctx, providers.PriorityBackground),
 		job.Payload.Message,
 		sessionKey,
 		channel,
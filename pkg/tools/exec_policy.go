@@ -0,0 +1,512 @@
+package tools
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ExecRule matches commands by exactly one of Prefix, Regex or Binary.
+type ExecRule struct {
+	Prefix string // whole leading words, e.g. "git status"
+	Regex  string
+	Binary string // program name, also found behind wrappers like env
+
+	re *regexp.Regexp
+}
+
+func (r ExecRule) String() string {
+	switch {
+	case r.Prefix != "":
+		return fmt.Sprintf("prefix %q", r.Prefix)
+	case r.Regex != "":
+		return fmt.Sprintf("regex %q", r.Regex)
+	}
+	return fmt.Sprintf("binary %q", r.Binary)
+}
+
+func (r *ExecRule) compile() error {
+	set := 0
+	for _, v := range []string{r.Prefix, r.Regex, r.Binary} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("rule must set exactly one of prefix, regex or binary")
+	}
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %w", r.Regex, err)
+		}
+		r.re = re
+	}
+	return nil
+}
+
+// allows reports whether the rule permits one command of a pipeline or
+// list. Only the first program counts, so "env df" does not pass a
+// binary "df" rule.
+func (r ExecRule) allows(seg commandSegment) bool {
+	switch {
+	case r.Prefix != "":
+		return seg.hasPrefix(r.Prefix)
+	case r.re != nil:
+		return r.re.MatchString(seg.text)
+	}
+	bins := seg.binaries()
+	return len(bins) > 0 && bins[0] == r.Binary
+}
+
+// denies reports whether the rule matches the command or any part of it.
+func (r ExecRule) denies(command string, segs []commandSegment) bool {
+	if r.re != nil {
+		return r.re.MatchString(command)
+	}
+	for _, seg := range segs {
+		if r.Prefix != "" && seg.hasPrefix(r.Prefix) {
+			return true
+		}
+		if r.Binary != "" {
+			for _, b := range seg.binaries() {
+				if b == r.Binary {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// ExecPolicy is one layer of exec rules (see config.ExecPolicyConfig).
+type ExecPolicy struct {
+	Allow       []ExecRule
+	Deny        []ExecRule
+	BuiltinDeny *bool // nil keeps the built-in deny patterns on
+	AllowedDirs []string
+	EnvAllow    []string
+	EnvDeny     []string
+	Timeout     time.Duration
+
+	layers []string
+}
+
+// Layers names the policy layers merged into a resolved policy.
+func (p *ExecPolicy) Layers() []string {
+	return p.layers
+}
+
+// ExecPolicyOverride applies Policy to callers matching every non-empty
+// match field.
+type ExecPolicyOverride struct {
+	Agent   string
+	Channel string
+	ChatID  string
+	Sender  string
+	Policy  ExecPolicy
+}
+
+func (o ExecPolicyOverride) matches(c ExecCaller) bool {
+	return (o.Agent == "" || o.Agent == c.Agent) &&
+		(o.Channel == "" || o.Channel == c.Channel) &&
+		(o.ChatID == "" || o.ChatID == c.ChatID) &&
+		(o.Sender == "" || o.Sender == c.Sender)
+}
+
+func (o ExecPolicyOverride) describe(i int) string {
+	var parts []string
+	for _, kv := range [][2]string{{"agent", o.Agent}, {"channel", o.Channel}, {"chat_id", o.ChatID}, {"sender", o.Sender}} {
+		if kv[1] != "" {
+			parts = append(parts, kv[0]+"="+kv[1])
+		}
+	}
+	return fmt.Sprintf("override %d (%s)", i+1, strings.Join(parts, " "))
+}
+
+// ExecCaller identifies who is running a command.
+type ExecCaller struct {
+	Agent   string
+	Channel string
+	ChatID  string
+	Sender  string
+}
+
+// ExecPolicySet holds the base policy and its per-caller overrides.
+type ExecPolicySet struct {
+	workspace string
+	base      ExecPolicy
+	overrides []ExecPolicyOverride
+}
+
+// NewExecPolicySet validates and compiles the rules. Relative allowed
+// directories are resolved against workspace.
+func NewExecPolicySet(workspace string, base ExecPolicy, overrides []ExecPolicyOverride) (*ExecPolicySet, error) {
+	if err := compileRules("base", &base); err != nil {
+		return nil, err
+	}
+	for i := range overrides {
+		if err := compileRules(overrides[i].describe(i), &overrides[i].Policy); err != nil {
+			return nil, err
+		}
+	}
+	return &ExecPolicySet{workspace: workspace, base: base, overrides: overrides}, nil
+}
+
+func compileRules(layer string, p *ExecPolicy) error {
+	for _, rules := range [][]ExecRule{p.Allow, p.Deny} {
+		for i := range rules {
+			if err := rules[i].compile(); err != nil {
+				return fmt.Errorf("exec policy %s: %w", layer, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Resolve merges the overrides matching caller into the base policy.
+// Allow, BuiltinDeny, AllowedDirs, EnvAllow and Timeout are replaced when
+// an override sets them; Deny and EnvDeny accumulate.
+func (s *ExecPolicySet) Resolve(caller ExecCaller) *ExecPolicy {
+	p := s.base
+	p.Deny = append([]ExecRule(nil), p.Deny...)
+	p.EnvDeny = append([]string(nil), p.EnvDeny...)
+	p.layers = []string{"base"}
+
+	for i, o := range s.overrides {
+		if !o.matches(caller) {
+			continue
+		}
+		op := o.Policy
+		if len(op.Allow) > 0 {
+			p.Allow = op.Allow
+		}
+		if op.BuiltinDeny != nil {
+			p.BuiltinDeny = op.BuiltinDeny
+		}
+		if len(op.AllowedDirs) > 0 {
+			p.AllowedDirs = op.AllowedDirs
+		}
+		if len(op.EnvAllow) > 0 {
+			p.EnvAllow = op.EnvAllow
+		}
+		if op.Timeout > 0 {
+			p.Timeout = op.Timeout
+		}
+		p.Deny = append(p.Deny, op.Deny...)
+		p.EnvDeny = append(p.EnvDeny, op.EnvDeny...)
+		p.layers = append(p.layers, o.describe(i))
+	}
+
+	dirs := make([]string, 0, len(p.AllowedDirs))
+	for _, d := range p.AllowedDirs {
+		if !filepath.IsAbs(d) {
+			d = filepath.Join(s.workspace, d)
+		}
+		dirs = append(dirs, filepath.Clean(d))
+	}
+	p.AllowedDirs = dirs
+	return &p
+}
+
+// ExecDecision explains whether a command may run.
+type ExecDecision struct {
+	Allowed bool
+	Rule    string // the rule that decided, empty when nothing matched
+	Reason  string // why the command was blocked
+	Source  string // "safety guard" or "exec policy"
+}
+
+// Check evaluates command against the policy and the built-in deny
+// patterns. It does not look at the working directory.
+func (p *ExecPolicy) Check(command string) ExecDecision {
+	return p.check(command, defaultDenyPatterns, nil)
+}
+
+func (p *ExecPolicy) check(command string, builtin, legacyAllow []*regexp.Regexp) ExecDecision {
+	d := ExecDecision{}
+	block := func(source, rule, reason string) ExecDecision {
+		d.Source, d.Rule, d.Reason = source, rule, reason
+		return d
+	}
+
+	lower := strings.ToLower(strings.TrimSpace(command))
+	if p.BuiltinDeny == nil || *p.BuiltinDeny {
+		for _, re := range builtin {
+			if re.MatchString(lower) {
+				return block("safety guard", "built-in deny pattern `"+re.String()+"`", "dangerous pattern detected")
+			}
+		}
+	}
+	if len(legacyAllow) > 0 {
+		allowed := false
+		for _, re := range legacyAllow {
+			if re.MatchString(lower) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return block("safety guard", "", "not in allowlist")
+		}
+	}
+
+	segs := splitCommand(command)
+	for _, rule := range p.Deny {
+		if rule.denies(command, segs) {
+			return block("exec policy", "deny "+rule.String(), "denied by "+rule.String())
+		}
+	}
+
+	if len(p.Allow) > 0 {
+		for _, sub := range []string{"$(", "`", "<(", ">("} {
+			if strings.Contains(command, sub) {
+				return block("exec policy", "", "command substitution is not allowed with an allow list")
+			}
+		}
+		var matched []string
+		seen := map[string]bool{}
+		for _, seg := range segs {
+			rule := ""
+			for _, r := range p.Allow {
+				if r.allows(seg) {
+					rule = r.String()
+					break
+				}
+			}
+			if rule == "" {
+				return block("exec policy", "", fmt.Sprintf("%q is not in the allow list", seg.text))
+			}
+			for _, target := range seg.redirects {
+				if target != "/dev/null" {
+					return block("exec policy", "", fmt.Sprintf("redirecting output to %q is not allowed with an allow list", target))
+				}
+			}
+			if !seen[rule] {
+				seen[rule] = true
+				matched = append(matched, rule)
+			}
+		}
+		d.Rule = "allow " + strings.Join(matched, ", ")
+	}
+
+	d.Allowed = true
+	return d
+}
+
+// CheckDir returns a reason when dir is outside the allowed directories.
+func (p *ExecPolicy) CheckDir(dir string) string {
+	if len(p.AllowedDirs) == 0 {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "working directory cannot be resolved"
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	for _, allowed := range p.AllowedDirs {
+		if resolved, err := filepath.EvalSymlinks(allowed); err == nil {
+			allowed = resolved
+		}
+		if rel, err := filepath.Rel(allowed, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("working directory %s is not allowed", dir)
+}
+
+// Environ scrubs env ("KEY=value" entries). It returns nil when the policy
+// leaves the environment alone.
+func (p *ExecPolicy) Environ(env []string) []string {
+	if len(p.EnvAllow) == 0 && len(p.EnvDeny) == 0 {
+		return nil
+	}
+	out := []string{}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		if len(p.EnvAllow) > 0 && !matchEnvName(p.EnvAllow, name) {
+			continue
+		}
+		if matchEnvName(p.EnvDeny, name) {
+			continue
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func matchEnvName(patterns []string, name string) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(pat, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// commandSegment is one simple command of a shell pipeline or list.
+type commandSegment struct {
+	text      string   // source text, trimmed
+	argv      []string // words with quotes removed
+	redirects []string // files the command's output is redirected to
+}
+
+// execWrappers run another program given as an argument.
+var execWrappers = map[string]bool{
+	"env": true, "nice": true, "nohup": true, "timeout": true, "time": true, "command": true,
+	"exec": true, "xargs": true, "stdbuf": true, "setsid": true, "sudo": true, "doas": true, "busybox": true,
+}
+
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// binaries returns the program names the segment runs: the first program
+// and, when that is a wrapper such as env or timeout, the wrapped ones.
+func (s commandSegment) binaries() []string {
+	var out []string
+	wrapped := false
+	for _, w := range s.argv {
+		switch {
+		case w == "" || w == "{" || w == "!":
+			continue
+		case envAssignment.MatchString(w) && (len(out) == 0 || wrapped):
+			continue
+		case wrapped && (strings.HasPrefix(w, "-") || (w[0] >= '0' && w[0] <= '9')):
+			continue // wrapper options and durations/priorities
+		}
+		name := filepath.Base(w)
+		out = append(out, name)
+		if !execWrappers[name] {
+			break
+		}
+		wrapped = true
+	}
+	return out
+}
+
+// hasPrefix matches whole leading words, ignoring extra whitespace.
+func (s commandSegment) hasPrefix(prefix string) bool {
+	want := strings.Fields(prefix)
+	if len(want) > len(s.argv) {
+		return false
+	}
+	for i, w := range want {
+		if s.argv[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// splitCommand breaks a shell command into simple commands at ;, &, |,
+// newlines and parentheses outside quotes. It is not a full shell parser;
+// it only has to be precise enough that every program a command would run
+// and every file its output is redirected to shows up in some segment.
+func splitCommand(command string) []commandSegment {
+	var (
+		segs      []commandSegment
+		raw       strings.Builder
+		word      strings.Builder
+		argv      []string
+		redirects []string
+		inTok     bool
+		quote     rune
+		prev      rune
+
+		redirect    bool // an output redirection is waiting for its target
+		redirectDup bool // the redirection was >&, so a digit or - is a fd
+		targetAt    int  // where the target starts in word
+	)
+	flushWord := func() {
+		if !inTok {
+			return
+		}
+		w := word.String()
+		argv = append(argv, w)
+		if redirect && len(w) > targetAt {
+			target := w[targetAt:]
+			if !redirectDup || strings.Trim(target, "0123456789-") != "" {
+				redirects = append(redirects, target)
+			}
+			redirect = false
+		}
+		targetAt = 0
+		word.Reset()
+		inTok = false
+	}
+	flushSeg := func() {
+		flushWord()
+		if len(argv) > 0 {
+			segs = append(segs, commandSegment{text: strings.TrimSpace(raw.String()), argv: argv, redirects: redirects})
+		}
+		raw.Reset()
+		argv, redirects = nil, nil
+		redirect = false
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			raw.WriteRune(c)
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				raw.WriteRune(runes[i])
+				word.WriteRune(runes[i])
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inTok = true
+			raw.WriteRune(c)
+		case c == '\\' && i+1 < len(runes):
+			i++
+			raw.WriteRune(c)
+			raw.WriteRune(runes[i])
+			word.WriteRune(runes[i])
+			inTok = true
+		case c == '&' && (prev == '>' || prev == '<' || (i+1 < len(runes) && runes[i+1] == '>')):
+			// Redirections such as 2>&1 and &>file, not a list operator.
+			raw.WriteRune(c)
+			word.WriteRune(c)
+			inTok = true
+		case c == '>':
+			// >, >>, >| and >& (also after &, as in &> and &>>).
+			raw.WriteRune(c)
+			word.WriteRune(c)
+			inTok = true
+			redirectDup = false
+			for i+1 < len(runes) && (runes[i+1] == '>' || runes[i+1] == '|' || runes[i+1] == '&') {
+				i++
+				redirectDup = runes[i] == '&'
+				raw.WriteRune(runes[i])
+				word.WriteRune(runes[i])
+			}
+			redirect = true
+			targetAt = word.Len()
+			prev = runes[i]
+			continue
+		case c == ';' || c == '&' || c == '|' || c == '\n' || c == '(' || c == ')':
+			flushSeg()
+		case unicode.IsSpace(c):
+			flushWord()
+			raw.WriteRune(c)
+		default:
+			raw.WriteRune(c)
+			word.WriteRune(c)
+			inTok = true
+		}
+		prev = c
+	}
+	flushSeg()
+	return segs
+}
@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    [][]string
+	}{
+		{"df -h", [][]string{{"df", "-h"}}},
+		{"uptime && df -h 2>&1 | grep '/ ;x'", [][]string{{"uptime"}, {"df", "-h", "2>&1"}, {"grep", "/ ;x"}}},
+		{"a; (b) || c & d\ne", [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{`echo "a\"b" c\ d`, [][]string{{"echo", `a"b`, "c d"}}},
+	}
+	for _, tt := range tests {
+		var got [][]string
+		for _, seg := range splitCommand(tt.command) {
+			got = append(got, seg.argv)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommand(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	redirects := map[string][]string{
+		"df > a 2>&1":         {"a"},
+		"df >>b 2>/dev/null":  {"b", "/dev/null"},
+		"df &> c; uptime >|d": {"c", "d"},
+		"df >&e 1>&-":         {"e"},
+		`echo ">" "f g"`:      nil,
+	}
+	for command, want := range redirects {
+		var got []string
+		for _, seg := range splitCommand(command) {
+			got = append(got, seg.redirects...)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("splitCommand(%q) redirects = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func TestCommandSegment_Binaries(t *testing.T) {
+	tests := map[string][]string{
+		"/bin/rm -rf x":              {"rm"},
+		"FOO=1 ls":                   {"ls"},
+		"env -i A=b rm x":            {"env", "rm"},
+		"timeout 5s nice -n 10 wget": {"timeout", "nice", "wget"},
+	}
+	for command, want := range tests {
+		if got := splitCommand(command)[0].binaries(); !reflect.DeepEqual(got, want) {
+			t.Errorf("binaries(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+func familyPolicy(t *testing.T, workspace string) *ExecPolicySet {
+	t.Helper()
+	off := false
+	set, err := NewExecPolicySet(workspace,
+		ExecPolicy{
+			Deny:    []ExecRule{{Binary: "curl"}, {Prefix: "git push"}},
+			EnvDeny: []string{"*_TOKEN"},
+			Timeout: 30 * time.Second,
+		},
+		[]ExecPolicyOverride{
+			{Channel: "telegram", ChatID: "family", Policy: ExecPolicy{
+				Allow: []ExecRule{{Binary: "uptime"}, {Binary: "df"}},
+			}},
+			{Channel: "telegram", Sender: "admin", Policy: ExecPolicy{
+				BuiltinDeny: &off,
+				Timeout:     5 * time.Minute,
+			}},
+		})
+	if err != nil {
+		t.Fatalf("NewExecPolicySet() error: %v", err)
+	}
+	return set
+}
+
+func TestExecPolicy_Overrides(t *testing.T) {
+	set := familyPolicy(t, t.TempDir())
+	family := set.Resolve(ExecCaller{Agent: "main", Channel: "telegram", ChatID: "family", Sender: "kid"})
+	admin := set.Resolve(ExecCaller{Agent: "main", Channel: "telegram", ChatID: "dm", Sender: "admin"})
+
+	tests := []struct {
+		name    string
+		policy  *ExecPolicy
+		command string
+		allowed bool
+		rule    string
+	}{
+		{"family allowed", family, "uptime && df -h", true, `allow binary "uptime", binary "df"`},
+		{"family pipeline", family, "df -h | sh", false, ""},
+		{"family other binary", family, "ls", false, ""},
+		{"family wrapper", family, "env df", false, ""},
+		{"family substitution", family, "df $(ls)", false, ""},
+		{"family redirect", family, "uptime > AGENTS.md", false, ""},
+		{"family append", family, "df >> ~/.bashrc", false, ""},
+		{"family redirect both", family, "df &>out.txt", false, ""},
+		{"family clobber", family, "uptime >| out.txt", false, ""},
+		{"family redirect fd", family, "df 2>&1", true, ""},
+		{"family redirect null", family, "df -h 2>/dev/null | uptime >/dev/null", true, ""},
+		{"family quoted >", family, "uptime '>' x", true, ""},
+		{"family base deny", family, "curl x", false, `deny binary "curl"`},
+		{"admin full shell", admin, "ls -la | wc -l", true, ""},
+		{"admin builtin off", admin, "kill 1", true, ""},
+		{"admin base deny", admin, "cd repo && git push origin", false, `deny prefix "git push"`},
+		{"admin deny behind wrapper", admin, "nohup /usr/bin/curl x", false, `deny binary "curl"`},
+	}
+	for _, tt := range tests {
+		d := tt.policy.Check(tt.command)
+		if d.Allowed != tt.allowed {
+			t.Errorf("%s: Check(%q).Allowed = %v (%s)", tt.name, tt.command, d.Allowed, d.Reason)
+		}
+		if tt.rule != "" && d.Rule != tt.rule {
+			t.Errorf("%s: rule = %q, want %q", tt.name, d.Rule, tt.rule)
+		}
+	}
+
+	if admin.Timeout != 5*time.Minute || family.Timeout != 30*time.Second {
+		t.Errorf("timeouts = %v / %v", admin.Timeout, family.Timeout)
+	}
+	if got := strings.Join(family.Layers(), "; "); got != "base; override 1 (channel=telegram chat_id=family)" {
+		t.Errorf("layers = %q", got)
+	}
+}
+
+func TestExecPolicy_InvalidRule(t *testing.T) {
+	if _, err := NewExecPolicySet("", ExecPolicy{Allow: []ExecRule{{Regex: "("}}}, nil); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+	if _, err := NewExecPolicySet("", ExecPolicy{Deny: []ExecRule{{Prefix: "a", Binary: "b"}}}, nil); err == nil {
+		t.Error("expected an error for a rule with two matchers")
+	}
+}
+
+func TestExecPolicy_DirsAndEnv(t *testing.T) {
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, "projects", "a"), 0755)
+	set, err := NewExecPolicySet(workspace, ExecPolicy{
+		AllowedDirs: []string{"projects"},
+		EnvAllow:    []string{"PATH", "HOME", "GH_*"},
+		EnvDeny:     []string{"*_TOKEN"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := set.Resolve(ExecCaller{})
+
+	if reason := p.CheckDir(filepath.Join(workspace, "projects", "a")); reason != "" {
+		t.Errorf("subdirectory of an allowed dir rejected: %s", reason)
+	}
+	if reason := p.CheckDir(workspace); reason == "" {
+		t.Error("workspace root should be outside allowed_dirs")
+	}
+	if reason := p.CheckDir(filepath.Join(workspace, "projects-evil")); reason == "" {
+		t.Error("sibling with a shared prefix should be rejected")
+	}
+
+	env := p.Environ([]string{"PATH=/bin", "HOME=/h", "GH_HOST=x", "GH_TOKEN=secret", "AWS_KEY=k"})
+	if want := []string{"PATH=/bin", "HOME=/h", "GH_HOST=x"}; !reflect.DeepEqual(env, want) {
+		t.Errorf("Environ() = %q, want %q", env, want)
+	}
+	if (&ExecPolicy{}).Environ(os.Environ()) != nil {
+		t.Error("a policy without env rules should leave the environment alone")
+	}
+}
+
+func TestExecTool_PolicyFromContext(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewExecTool(workspace, false)
+	tool.SetPolicy(familyPolicy(t, workspace), "main")
+
+	family := WithToolContext(context.Background(), "telegram", "family")
+	if result := tool.Execute(family, map[string]interface{}{"command": "echo hi"}); !result.IsError || !strings.Contains(result.ForLLM, "exec policy") {
+		t.Errorf("expected echo to be blocked in the family chat, got: %s", result.ForLLM)
+	}
+
+	t.Setenv("PICOORACLAW_TEST_TOKEN", "secret")
+	dm := WithToolSender(WithToolContext(context.Background(), "telegram", "dm"), "admin")
+	result := tool.Execute(dm, map[string]interface{}{"command": "echo hi; env"})
+	if result.IsError || !strings.Contains(result.ForLLM, "hi") {
+		t.Fatalf("expected the admin DM to run the command, got: %s", result.ForLLM)
+	}
+	if strings.Contains(result.ForLLM, "PICOORACLAW_TEST_TOKEN") {
+		t.Error("expected *_TOKEN variables to be scrubbed")
+	}
+}
@@ -1,11 +1,24 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/jasperan/picooraclaw/pkg/docs"
+	"github.com/jasperan/picooraclaw/pkg/providers"
 )
 
 // validatePath ensures the given path is within the workspace if restrict is true.
@@ -91,7 +104,7 @@ func (t *ReadFileTool) Name() string {
 }
 
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file"
+	return "Read a file. Text is returned in pages of lines (use offset/limit for large files); byte_offset/byte_length read a raw byte range, shown as a hex dump for binary data. PDF, DOCX and XLSX files are converted to text (use pages to pick PDF pages or sheets); set extract for CSV and HTML. Images are returned as images when the model can see them."
 }
 
 func (t *ReadFileTool) Parameters() map[string]interface{} {
@@ -102,6 +115,30 @@ func (t *ReadFileTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Path to the file to read",
 			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Line number to start reading from (1-based)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum number of lines to read. Default: %d", readFileMaxLines),
+			},
+			"byte_offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Read raw bytes starting at this offset instead of lines",
+			},
+			"byte_length": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of bytes to read with byte_offset. Default: %d, max: %d", readFileDefaultByteLength, readFileMaxBytes),
+			},
+			"pages": map[string]interface{}{
+				"type":        "string",
+				"description": "PDF pages or XLSX sheets to extract, e.g. \"1-3,5\" (1-based). Default: all",
+			},
+			"extract": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Convert CSV to a markdown table and HTML to markdown instead of returning the raw text",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -118,12 +155,292 @@ func (t *ReadFileTool) Execute(ctx context.Context, args map[string]interface{})
 		return ErrorResult(err.Error())
 	}
 
-	content, err := os.ReadFile(resolvedPath)
+	f, err := os.Open(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	defer f.Close()
+	info, err := f.Stat()
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
 	}
+	if info.IsDir() {
+		return ErrorResult(fmt.Sprintf("failed to read file: %s is a directory, use list_dir", path))
+	}
+
+	_, hasByteOffset := args["byte_offset"].(float64)
+	_, hasByteLength := args["byte_length"].(float64)
+	if hasByteOffset || hasByteLength {
+		return readByteRange(f, info.Size(), args)
+	}
+
+	head := make([]byte, 8000)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	switch format := docs.Detect(path, head); format {
+	case docs.FormatPDF, docs.FormatDOCX, docs.FormatXLSX:
+		return readDocument(resolvedPath, path, format, info.Size(), args)
+	case docs.FormatCSV, docs.FormatHTML:
+		if extract, _ := args["extract"].(bool); extract {
+			return readDocument(resolvedPath, path, format, info.Size(), args)
+		}
+	}
+	if kind := http.DetectContentType(head); strings.HasPrefix(kind, "image/") {
+		return readImage(ctx, f, path, kind, info.Size())
+	}
+	if isBinary(head) {
+		return NewToolResult(fmt.Sprintf("%s is a binary file (%s, %s). First bytes:\n%s\nUse byte_offset/byte_length to inspect other parts.",
+			path, http.DetectContentType(head), formatByteSize(info.Size()), hexDump(head[:min(len(head), 256)], 0)))
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	return readLines(f, args)
+}
+
+const (
+	readFileMaxLines          = 2000
+	readFileMaxBytes          = 100 * 1024
+	readFileDefaultByteLength = 4096
+	readFileMaxImageBytes     = 3750000 // 5 MB once base64 encoded, the common API limit
+)
+
+// readFileMaxDocumentBytes caps the documents read_file converts to text.
+const readFileMaxDocumentBytes = 50 << 20
 
-	return NewToolResult(string(content))
+// readDocument extracts the text of a PDF, Office, CSV or HTML file and
+// pages through it like a text file, after a one-line summary.
+func readDocument(resolvedPath, path, format string, size int64, args map[string]interface{}) *ToolResult {
+	if size > readFileMaxDocumentBytes {
+		return ErrorResult(fmt.Sprintf("%s is too large to extract (%s, max %s)", path, formatByteSize(size), formatByteSize(readFileMaxDocumentBytes)))
+	}
+	data, err := os.ReadFile(resolvedPath)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	pages, _ := args["pages"].(string)
+	doc, err := docs.Extract(data, format, docs.Options{Pages: pages})
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to extract %s: %v", path, err))
+	}
+
+	result := readLines(strings.NewReader(doc.Text), args)
+	if result.IsError {
+		return result
+	}
+	header := fmt.Sprintf("[%s: %s]\n", path, doc.Summary())
+	if doc.Title != "" {
+		header = fmt.Sprintf("[%s: %s, title %q]\n", path, doc.Summary(), doc.Title)
+	}
+	result.ForLLM = header + result.ForLLM
+	return result
+}
+
+// readLines returns a page of lines and, when the file was not read to the
+// end, where it stopped.
+func readLines(r io.Reader, args map[string]interface{}) *ToolResult {
+	offset, limit := 1, readFileMaxLines
+	_, paged := args["offset"].(float64)
+	if v, ok := args["offset"].(float64); ok && v > 1 {
+		offset = int(v)
+	}
+	if v, ok := args["limit"].(float64); ok && v > 0 {
+		limit = int(v)
+		paged = true
+	}
+
+	br := bufio.NewReaderSize(r, 64*1024)
+	var out strings.Builder
+	total, last, clipped, full := 0, 0, false, false
+	for {
+		line, err := readLine(br, readFileMaxBytes)
+		if len(line.text) > 0 || !line.eof {
+			total++
+			full = full || out.Len()+len(line.text) > readFileMaxBytes
+			if total >= offset && total < offset+limit && !full {
+				out.Write(line.text)
+				if line.clipped {
+					clipped = true
+					out.WriteString("…[line truncated]")
+				}
+				if !line.eof {
+					out.WriteByte('\n')
+				}
+				last = total
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	if offset > total && total > 0 {
+		return ErrorResult(fmt.Sprintf("offset %d is past the end of the file (%d lines)", offset, total))
+	}
+	if !paged && last == total && !clipped {
+		return NewToolResult(out.String())
+	}
+	notice := fmt.Sprintf("[Lines %d-%d of %d total.", min(offset, last), last, total)
+	if last < total {
+		notice += fmt.Sprintf(" Use offset=%d to read more.", last+1)
+	}
+	if clipped {
+		notice += " Long lines were truncated; use byte_offset/byte_length to read them."
+	}
+	return NewToolResult(strings.TrimSuffix(out.String(), "\n") + "\n" + notice + "]")
+}
+
+type fileLine struct {
+	text    []byte
+	clipped bool
+	eof     bool // the line had no trailing newline
+}
+
+// readLine reads one line, keeping at most max bytes of it.
+func readLine(br *bufio.Reader, max int) (fileLine, error) {
+	var line fileLine
+	size := 0
+	for {
+		chunk, err := br.ReadSlice('\n')
+		size += len(chunk)
+		if room := max - len(line.text); room > 0 {
+			line.text = append(line.text, chunk[:min(len(chunk), room)]...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == nil {
+			size--
+			line.text = bytes.TrimSuffix(line.text, []byte("\n"))
+			line.text = bytes.TrimSuffix(line.text, []byte("\r"))
+		} else {
+			line.eof = true
+		}
+		line.clipped = size > max
+		return line, err
+	}
+}
+
+// readByteRange returns raw bytes, as text when they are text.
+func readByteRange(f *os.File, size int64, args map[string]interface{}) *ToolResult {
+	var offset int64
+	if v, ok := args["byte_offset"].(float64); ok && v > 0 {
+		offset = int64(v)
+	}
+	length := readFileDefaultByteLength
+	if v, ok := args["byte_length"].(float64); ok && v > 0 {
+		length = min(int(v), readFileMaxBytes)
+	}
+	if offset >= size {
+		return ErrorResult(fmt.Sprintf("byte_offset %d is past the end of the file (%d bytes)", offset, size))
+	}
+	data := make([]byte, min(int64(length), size-offset))
+	n, err := f.ReadAt(data, offset)
+	if err != nil && err != io.EOF {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	data = data[:n]
+
+	notice := fmt.Sprintf("[Bytes %d-%d of %d.", offset, offset+int64(n), size)
+	if end := offset + int64(n); end < size {
+		notice += fmt.Sprintf(" Use byte_offset=%d to read more.", end)
+	}
+	if isBinary(data) {
+		return NewToolResult(hexDump(data, offset) + notice + "]")
+	}
+	return NewToolResult(string(data) + "\n" + notice + "]")
+}
+
+// attachableImageTypes are the image types every vision provider accepts.
+// Others, such as BMP and ICO, are described instead.
+var attachableImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// readImage attaches an image for vision models, or describes it.
+func readImage(ctx context.Context, f *os.File, path, mediaType string, size int64) *ToolResult {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+	}
+	desc := fmt.Sprintf("Image %s: %s, %s", path, mediaType, formatByteSize(size))
+	if cfg, _, err := image.DecodeConfig(f); err == nil {
+		desc = fmt.Sprintf("Image %s: %s, %dx%d, %s", path, mediaType, cfg.Width, cfg.Height, formatByteSize(size))
+	}
+
+	switch {
+	case !ToolVision(ctx):
+		desc += ". The current model cannot view images."
+	case !attachableImageTypes[mediaType]:
+		desc += ". Models only accept JPEG, PNG, GIF and WebP images."
+	case size > readFileMaxImageBytes:
+		desc += fmt.Sprintf(". Too large to attach (limit %s).", formatByteSize(readFileMaxImageBytes))
+	default:
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read file: %v", err))
+		}
+		result := NewToolResult(desc + " (attached)")
+		result.Images = []providers.ImagePart{{MediaType: mediaType, Data: base64.StdEncoding.EncodeToString(data)}}
+		return result
+	}
+
+	head := make([]byte, 256)
+	f.Seek(0, io.SeekStart)
+	n, _ := io.ReadFull(f, head)
+	return NewToolResult(desc + " First bytes:\n" + hexDump(head[:n], 0))
+}
+
+// isBinary reports whether data looks like binary rather than text: it
+// has NUL bytes or is not UTF-8 (ignoring a rune cut off at the end).
+func isBinary(data []byte) bool {
+	if bytes.IndexByte(data, 0) >= 0 {
+		return true
+	}
+	for i := 0; i < utf8.UTFMax && len(data) > 0 && !utf8.Valid(data); i++ {
+		data = data[:len(data)-1]
+	}
+	return !utf8.Valid(data)
+}
+
+// hexDump formats data like hexdump -C, numbering from base.
+func hexDump(data []byte, base int64) string {
+	var sb strings.Builder
+	for i := 0; i < len(data); i += 16 {
+		row := data[i:min(i+16, len(data))]
+		fmt.Fprintf(&sb, "%08x ", base+int64(i))
+		for j := 0; j < 16; j++ {
+			if j == 8 {
+				sb.WriteByte(' ')
+			}
+			if j < len(row) {
+				fmt.Fprintf(&sb, " %02x", row[j])
+			} else {
+				sb.WriteString("   ")
+			}
+		}
+		sb.WriteString("  |")
+		for _, c := range row {
+			if c < 32 || c > 126 {
+				c = '.'
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteString("|\n")
+	}
+	return sb.String()
+}
+
+func formatByteSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
 }
 
 type WriteFileTool struct {
@@ -1,7 +1,12 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
@@ -79,6 +84,126 @@ func TestFilesystemTool_ReadFile_MissingPath(t *testing.T) {
 	}
 }
 
+// TestFilesystemTool_ReadFile_Paging verifies line offset/limit and notices
+func TestFilesystemTool_ReadFile_Paging(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "big.log")
+	var sb strings.Builder
+	for i := 1; i <= readFileMaxLines+500; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	os.WriteFile(testFile, []byte(sb.String()), 0644)
+	tool := &ReadFileTool{}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": testFile})
+	if !strings.HasSuffix(result.ForLLM, "line 2000\n[Lines 1-2000 of 2500 total. Use offset=2001 to read more.]") {
+		t.Errorf("Expected a truncation notice after line 2000, got tail: %q", result.ForLLM[len(result.ForLLM)-120:])
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": testFile, "offset": float64(10), "limit": float64(2)})
+	if result.ForLLM != "line 10\nline 11\n[Lines 10-11 of 2500 total. Use offset=12 to read more.]" {
+		t.Errorf("Unexpected page: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": testFile, "offset": float64(2499)})
+	if result.ForLLM != "line 2499\nline 2500\n[Lines 2499-2500 of 2500 total.]" {
+		t.Errorf("Unexpected last page: %q", result.ForLLM)
+	}
+
+	if result := tool.Execute(ctx, map[string]interface{}{"path": testFile, "offset": float64(3000)}); !result.IsError {
+		t.Errorf("Expected an error for an offset past the end, got: %s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ReadFile_ByteRange verifies raw byte reads and hex dumps
+func TestFilesystemTool_ReadFile_ByteRange(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "data.bin")
+	os.WriteFile(testFile, append([]byte("hello world"), 0, 1, 2, 0xff), 0644)
+	tool := &ReadFileTool{}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": testFile, "byte_offset": float64(6), "byte_length": float64(5)})
+	if result.ForLLM != "world\n[Bytes 6-11 of 15. Use byte_offset=11 to read more.]" {
+		t.Errorf("Unexpected text range: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": testFile, "byte_offset": float64(8)})
+	if !strings.HasPrefix(result.ForLLM, "00000008  72 6c 64 00 01 02 ff") || !strings.Contains(result.ForLLM, "|rld....|") {
+		t.Errorf("Expected a hex dump, got: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"path": testFile})
+	if !strings.Contains(result.ForLLM, "is a binary file") || !strings.Contains(result.ForLLM, "00000000  68 65 6c 6c") {
+		t.Errorf("Expected a binary summary, got: %s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ReadFile_Image verifies images are attached only for vision models
+func TestFilesystemTool_ReadFile_Image(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "dot.png")
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 3, 2)))
+	os.WriteFile(testFile, buf.Bytes(), 0644)
+	tool := &ReadFileTool{}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"path": testFile})
+	if len(result.Images) != 0 || !strings.Contains(result.ForLLM, "image/png, 3x2") || !strings.Contains(result.ForLLM, "cannot view images") {
+		t.Errorf("Expected a description without attachment, got: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(WithToolVision(context.Background(), true), map[string]interface{}{"path": testFile})
+	if len(result.Images) != 1 || result.Images[0].MediaType != "image/png" || !strings.HasSuffix(result.ForLLM, "(attached)") {
+		t.Fatalf("Expected an attached image, got: %s", result.ForLLM)
+	}
+	if data, _ := base64.StdEncoding.DecodeString(result.Images[0].Data); !bytes.Equal(data, buf.Bytes()) {
+		t.Error("Attached image data does not match the file")
+	}
+
+	bmpFile := filepath.Join(t.TempDir(), "dot.bmp")
+	os.WriteFile(bmpFile, append([]byte("BM"), make([]byte, 64)...), 0644)
+	result = tool.Execute(WithToolVision(context.Background(), true), map[string]interface{}{"path": bmpFile})
+	if len(result.Images) != 0 || !strings.Contains(result.ForLLM, "image/bmp") || !strings.Contains(result.ForLLM, "First bytes") {
+		t.Errorf("Expected a BMP described rather than attached, got: %s", result.ForLLM)
+	}
+}
+
+// TestFilesystemTool_ReadFile_Document verifies documents are converted to text
+func TestFilesystemTool_ReadFile_Document(t *testing.T) {
+	dir := t.TempDir()
+	pdf := "%PDF-1.4\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+		"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj\n" +
+		"3 0 obj << /Type /Page /Contents 5 0 R >> endobj\n4 0 obj << /Type /Page /Contents 6 0 R >> endobj\n" +
+		"5 0 obj << /Length 27 >>\nstream\nBT (First page text) Tj ET\nendstream endobj\n" +
+		"6 0 obj << /Length 28 >>\nstream\nBT (Second page text) Tj ET\nendstream endobj\n" +
+		"trailer << /Root 1 0 R >>\n%%EOF\n"
+	pdfFile := filepath.Join(dir, "report.pdf")
+	os.WriteFile(pdfFile, []byte(pdf), 0644)
+	tool := &ReadFileTool{}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"path": pdfFile, "pages": "2"})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if !strings.HasPrefix(result.ForLLM, "[") || !strings.Contains(result.ForLLM, "PDF, 2 pages (extracted pages 2)]") ||
+		!strings.Contains(result.ForLLM, "Second page text") || strings.Contains(result.ForLLM, "First page") {
+		t.Errorf("Unexpected PDF extraction: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"path": pdfFile, "pages": "5"}); !result.IsError {
+		t.Errorf("Expected an error for a page past the end, got: %s", result.ForLLM)
+	}
+
+	csvFile := filepath.Join(dir, "data.csv")
+	os.WriteFile(csvFile, []byte("a,b\n1,2\n"), 0644)
+	if result := tool.Execute(ctx, map[string]interface{}{"path": csvFile}); result.ForLLM != "a,b\n1,2\n" {
+		t.Errorf("CSV should be returned raw by default, got: %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"path": csvFile, "extract": true})
+	if !strings.Contains(result.ForLLM, "| a | b |\n| --- | --- |\n| 1 | 2 |") {
+		t.Errorf("Expected a markdown table, got: %s", result.ForLLM)
+	}
+}
+
 // TestFilesystemTool_WriteFile_Success verifies successful file writing
 func TestFilesystemTool_WriteFile_Success(t *testing.T) {
 	tmpDir := t.TempDir()
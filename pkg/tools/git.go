@@ -0,0 +1,934 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// gitMaxOutput caps diff, log and show output.
+const gitMaxOutput = 40000
+
+// GitRemote allows pushing to remotes whose URL matches URL: exactly (a
+// trailing ".git" or "/" is ignored), or as a prefix when URL ends in "/".
+// Username and Token, when set, are sent as HTTP basic auth.
+type GitRemote struct {
+	URL      string
+	Username string
+	Token    string
+}
+
+// GitTool works with git repositories in the workspace through go-git, so
+// no git binary is needed. With restrict set, the repository's worktree and
+// every path argument must be inside the workspace.
+type GitTool struct {
+	workspace   string
+	restrict    bool
+	authorName  string
+	authorEmail string
+	pushRemotes []GitRemote
+}
+
+func NewGitTool(workspace string, restrict bool) *GitTool {
+	return &GitTool{workspace: workspace, restrict: restrict}
+}
+
+// SetAuthor sets the commit author. When unset, user.name and user.email
+// from the repository or global git config are used.
+func (t *GitTool) SetAuthor(name, email string) {
+	t.authorName = name
+	t.authorEmail = email
+}
+
+// SetPushRemotes sets the remotes push may write to. push is refused when
+// the list is empty.
+func (t *GitTool) SetPushRemotes(remotes []GitRemote) {
+	t.pushRemotes = remotes
+}
+
+func (t *GitTool) Name() string {
+	return "git"
+}
+
+func (t *GitTool) Description() string {
+	return "Work with git repositories in the workspace. Actions: status, diff (unstaged, or staged=true), " +
+		"log, show, add, commit, branch (list, create, delete), checkout, and push to allowed remotes. " +
+		"'repo' is the repository directory (default: the workspace)."
+}
+
+func (t *GitTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"status", "diff", "log", "show", "add", "commit", "branch", "checkout", "push"},
+				"description": "Action to perform",
+			},
+			"repo": map[string]interface{}{
+				"type":        "string",
+				"description": "Repository directory or a path inside it (default: workspace)",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Files or directories relative to the repository (add; limits diff, log and show; show prints them at rev). Use [\".\"] with add for all changes",
+			},
+			"staged": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Diff the index against HEAD instead of the worktree against the index (diff)",
+			},
+			"rev": map[string]interface{}{
+				"type":        "string",
+				"description": "Revision such as HEAD~2, a branch, tag or hash (log, show: default HEAD; checkout: detach at it; branch: start point)",
+			},
+			"max_count": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum commits to list, default 20 (log)",
+			},
+			"message": map[string]interface{}{
+				"type":        "string",
+				"description": "Commit message (commit)",
+			},
+			"all": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Stage modified and deleted tracked files before committing (commit)",
+			},
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Branch name (branch: create or delete; checkout)",
+			},
+			"create": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Create the branch before checking it out (checkout)",
+			},
+			"delete": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Delete the named branch (branch)",
+			},
+			"remote": map[string]interface{}{
+				"type":        "string",
+				"description": "Remote name, default origin (push)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *GitTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	repo, root, err := t.open(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	paths, err := t.repoPaths(root, args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	var out string
+	switch action {
+	case "status":
+		out, err = gitStatus(repo)
+	case "diff":
+		staged, _ := args["staged"].(bool)
+		out, err = gitDiff(repo, paths, staged)
+	case "log":
+		out, err = gitLog(repo, args, paths)
+	case "show":
+		out, err = gitShow(repo, args, paths)
+	case "add":
+		out, err = gitAdd(repo, paths)
+	case "commit":
+		out, err = t.commit(repo, args)
+	case "branch":
+		out, err = gitBranch(repo, args)
+	case "checkout":
+		out, err = gitCheckout(repo, args)
+	case "push":
+		out, err = t.push(ctx, repo, args)
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+	}
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("git %s: %v", action, err))
+	}
+	return NewToolResult(truncateGitOutput(out))
+}
+
+// open finds the repository containing args["repo"] and returns it with
+// its worktree root.
+func (t *GitTool) open(args map[string]interface{}) (*git.Repository, string, error) {
+	dir, _ := args["repo"].(string)
+	if dir == "" {
+		dir = "."
+	}
+	dir, err := validatePath(dir, t.workspace, t.restrict)
+	if err != nil {
+		return nil, "", err
+	}
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		if errors.Is(err, git.ErrRepositoryNotExists) {
+			return nil, "", fmt.Errorf("%s is not inside a git repository", dir)
+		}
+		return nil, "", err
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return nil, "", fmt.Errorf("bare repositories are not supported: %w", err)
+	}
+	root := wt.Filesystem.Root()
+	// A repository found above the workspace would let add and checkout
+	// touch files outside it.
+	if _, err := validatePath(root, t.workspace, t.restrict); err != nil {
+		return nil, "", fmt.Errorf("access denied: repository %s is outside the workspace", root)
+	}
+	return repo, root, nil
+}
+
+// repoPaths resolves args["paths"] against the workspace and returns them
+// relative to the repository root, in slash form.
+func (t *GitTool) repoPaths(root string, args map[string]interface{}) ([]string, error) {
+	raw, _ := args["paths"].([]interface{})
+	rootReal := root
+	if r, err := filepath.EvalSymlinks(root); err == nil {
+		rootReal = r
+	}
+	var paths []string
+	for _, v := range raw {
+		p, ok := v.(string)
+		if !ok || p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		abs, err := validatePath(p, t.workspace, t.restrict)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || !isWithinWorkspace(abs, root) {
+			if real, err2 := filepath.EvalSymlinks(abs); err2 == nil && isWithinWorkspace(real, rootReal) {
+				rel, _ = filepath.Rel(rootReal, real)
+			} else {
+				return nil, fmt.Errorf("%s is outside the repository %s", v, root)
+			}
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+	return paths, nil
+}
+
+// matchesPaths reports whether file is one of paths or below one of them.
+// No paths match everything.
+func matchesPaths(file string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		if p == "." || file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func truncateGitOutput(s string) string {
+	if len(s) <= gitMaxOutput {
+		return s
+	}
+	return s[:gitMaxOutput] + fmt.Sprintf("\n... (output truncated, %d more bytes; narrow it with paths or max_count)", len(s)-gitMaxOutput)
+}
+
+func gitStatus(repo *git.Repository) (string, error) {
+	var sb strings.Builder
+	head, err := repo.Head()
+	switch {
+	case err == nil && head.Name().IsBranch():
+		fmt.Fprintf(&sb, "On branch %s\n", head.Name().Short())
+	case err == nil:
+		fmt.Fprintf(&sb, "HEAD detached at %s\n", head.Hash().String()[:8])
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		sb.WriteString("No commits yet\n")
+	default:
+		return "", err
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return "", err
+	}
+	files := make([]string, 0, len(status))
+	for f := range status {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	var staged, unstaged, untracked []string
+	for _, f := range files {
+		s := status[f]
+		if s.Worktree == git.Untracked {
+			untracked = append(untracked, f)
+			continue
+		}
+		if s.Staging != git.Unmodified {
+			staged = append(staged, fmt.Sprintf("%s %s", statusWord(s.Staging), f))
+		}
+		if s.Worktree != git.Unmodified {
+			unstaged = append(unstaged, fmt.Sprintf("%s %s", statusWord(s.Worktree), f))
+		}
+	}
+	if len(staged)+len(unstaged)+len(untracked) == 0 {
+		sb.WriteString("nothing to commit, working tree clean\n")
+		return sb.String(), nil
+	}
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"Changes to be committed", staged},
+		{"Changes not staged for commit", unstaged},
+		{"Untracked files", untracked},
+	} {
+		if len(section.lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n%s:\n", section.title)
+		for _, l := range section.lines {
+			fmt.Fprintf(&sb, "  %s\n", l)
+		}
+	}
+	return sb.String(), nil
+}
+
+func statusWord(c git.StatusCode) string {
+	switch c {
+	case git.Added:
+		return "new file:"
+	case git.Modified:
+		return "modified:"
+	case git.Deleted:
+		return "deleted: "
+	case git.Renamed:
+		return "renamed: "
+	case git.Copied:
+		return "copied:  "
+	case git.UpdatedButUnmerged:
+		return "unmerged:"
+	}
+	return string(c) + ":"
+}
+
+// gitDiff diffs the worktree against the index, or with staged the index
+// against HEAD, like git diff and git diff --cached. Untracked files are
+// not included.
+func gitDiff(repo *git.Repository, paths []string, staged bool) (string, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	status, err := wt.Status()
+	if err != nil {
+		return "", err
+	}
+	idx, err := repo.Storer.Index()
+	if err != nil {
+		return "", err
+	}
+	var headTree *object.Tree
+	if staged {
+		if head, err := repo.Head(); err == nil {
+			commit, err := repo.CommitObject(head.Hash())
+			if err != nil {
+				return "", err
+			}
+			if headTree, err = commit.Tree(); err != nil {
+				return "", err
+			}
+		} else if !errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return "", err
+		}
+	}
+
+	files := make([]string, 0, len(status))
+	for f := range status {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	patch := &gitPatch{}
+	for _, f := range files {
+		s := status[f]
+		if !matchesPaths(f, paths) || s.Worktree == git.Untracked {
+			continue
+		}
+		var from, to *gitBlob
+		if staged {
+			if s.Staging == git.Unmodified {
+				continue
+			}
+			if from, err = treeBlob(headTree, f); err != nil {
+				return "", err
+			}
+			if to, err = indexBlob(repo, idx.Entries, f); err != nil {
+				return "", err
+			}
+		} else {
+			if s.Worktree == git.Unmodified {
+				continue
+			}
+			if from, err = indexBlob(repo, idx.Entries, f); err != nil {
+				return "", err
+			}
+			if to, err = worktreeBlob(wt.Filesystem.Root(), f); err != nil {
+				return "", err
+			}
+		}
+		patch.add(from, to)
+	}
+	if len(patch.files) == 0 {
+		if staged {
+			return "No staged changes", nil
+		}
+		return "No unstaged changes", nil
+	}
+	return patch.String()
+}
+
+func gitLog(repo *git.Repository, args map[string]interface{}, paths []string) (string, error) {
+	from, err := resolveCommit(repo, args)
+	if err != nil {
+		return "", err
+	}
+	maxCount := 20
+	if v, ok := args["max_count"].(float64); ok && v > 0 {
+		maxCount = int(v)
+	}
+	opts := &git.LogOptions{From: from.Hash}
+	if len(paths) > 0 {
+		opts.PathFilter = func(p string) bool { return matchesPaths(p, paths) }
+	}
+	iter, err := repo.Log(opts)
+	if err != nil {
+		return "", err
+	}
+	defer iter.Close()
+
+	var lines []string
+	err = iter.ForEach(func(c *object.Commit) error {
+		if len(lines) >= maxCount {
+			return io.EOF
+		}
+		subject, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+		lines = append(lines, fmt.Sprintf("%s %s %s: %s",
+			c.Hash.String()[:8], c.Author.When.Format("2006-01-02 15:04"), c.Author.Name, subject))
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "No commits", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// gitShow prints a commit with its diff against the first parent, or with
+// paths the contents of those files at the commit.
+func gitShow(repo *git.Repository, args map[string]interface{}, paths []string) (string, error) {
+	commit, err := resolveCommit(repo, args)
+	if err != nil {
+		return "", err
+	}
+	if len(paths) > 0 {
+		tree, err := commit.Tree()
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		for _, p := range paths {
+			file, err := tree.File(p)
+			if err != nil {
+				return "", fmt.Errorf("%s not found at %s", p, commit.Hash.String()[:8])
+			}
+			content, err := file.Contents()
+			if err != nil {
+				return "", err
+			}
+			if len(paths) > 1 {
+				fmt.Fprintf(&sb, "==> %s <==\n", p)
+			}
+			if isBinary([]byte(content[:min(len(content), 8000)])) {
+				fmt.Fprintf(&sb, "(binary file, %d bytes)\n", len(content))
+				continue
+			}
+			sb.WriteString(content)
+		}
+		return sb.String(), nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "commit %s\nAuthor: %s <%s>\nDate:   %s\n\n",
+		commit.Hash, commit.Author.Name, commit.Author.Email, commit.Author.When.Format(time.RFC1123Z))
+	for _, line := range strings.Split(strings.TrimRight(commit.Message, "\n"), "\n") {
+		fmt.Fprintf(&sb, "    %s\n", line)
+	}
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return "", err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return "", err
+		}
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	changes, err := object.DiffTree(parentTree, tree)
+	if err != nil {
+		return "", err
+	}
+	patch, err := changes.Patch()
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString("\n")
+	sb.WriteString(patch.String())
+	return sb.String(), nil
+}
+
+func gitAdd(repo *git.Repository, paths []string) (string, error) {
+	if len(paths) == 0 {
+		return "", errors.New("paths is required; use [\".\"] to stage all changes")
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range paths {
+		if p == "." {
+			err = wt.AddWithOptions(&git.AddOptions{All: true})
+		} else {
+			err = wt.AddWithOptions(&git.AddOptions{Path: p})
+		}
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", p, err)
+		}
+	}
+	return gitStatus(repo)
+}
+
+func (t *GitTool) commit(repo *git.Repository, args map[string]interface{}) (string, error) {
+	message, _ := args["message"].(string)
+	if strings.TrimSpace(message) == "" {
+		return "", errors.New("message is required")
+	}
+	all, _ := args["all"].(bool)
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	opts := &git.CommitOptions{All: all}
+	if t.authorName != "" && t.authorEmail != "" {
+		opts.Author = &object.Signature{Name: t.authorName, Email: t.authorEmail, When: time.Now()}
+	}
+	hash, err := wt.Commit(message, opts)
+	if errors.Is(err, git.ErrEmptyCommit) {
+		return "", errors.New("nothing to commit; stage changes with add first")
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "author") {
+			return "", fmt.Errorf("%w (set tools.git.author_name and author_email)", err)
+		}
+		return "", err
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return "", err
+	}
+	stats, _ := commit.Stats()
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	branch := "HEAD"
+	if head, err := repo.Head(); err == nil && head.Name().IsBranch() {
+		branch = head.Name().Short()
+	}
+	return fmt.Sprintf("[%s %s] %s\n %d file(s) changed", branch, hash.String()[:8], subject, len(stats)), nil
+}
+
+func gitBranch(repo *git.Repository, args map[string]interface{}) (string, error) {
+	name, _ := args["name"].(string)
+	del, _ := args["delete"].(bool)
+	if name == "" {
+		if del {
+			return "", errors.New("name is required to delete a branch")
+		}
+		return listBranches(repo)
+	}
+
+	ref := plumbing.NewBranchReferenceName(name)
+	if del {
+		if head, err := repo.Head(); err == nil && head.Name() == ref {
+			return "", fmt.Errorf("cannot delete the checked-out branch %s", name)
+		}
+		if _, err := repo.Reference(ref, false); err != nil {
+			return "", fmt.Errorf("branch %s not found", name)
+		}
+		if err := repo.Storer.RemoveReference(ref); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted branch %s", name), nil
+	}
+
+	if err := ref.Validate(); err != nil {
+		return "", fmt.Errorf("invalid branch name %q", name)
+	}
+	if _, err := repo.Reference(ref, false); err == nil {
+		return "", fmt.Errorf("branch %s already exists", name)
+	}
+	start, err := resolveCommit(repo, args)
+	if err != nil {
+		return "", err
+	}
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(ref, start.Hash)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Created branch %s at %s", name, start.Hash.String()[:8]), nil
+}
+
+func listBranches(repo *git.Repository) (string, error) {
+	var current plumbing.ReferenceName
+	if head, err := repo.Head(); err == nil {
+		current = head.Name()
+	}
+	iter, err := repo.Branches()
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		marker := "  "
+		if ref.Name() == current {
+			marker = "* "
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s", marker, ref.Name().Short(), ref.Hash().String()[:8]))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(lines) == 0 {
+		return "No branches yet", nil
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+	return strings.Join(lines, "\n"), nil
+}
+
+func gitCheckout(repo *git.Repository, args map[string]interface{}) (string, error) {
+	name, _ := args["name"].(string)
+	rev, _ := args["rev"].(string)
+	create, _ := args["create"].(bool)
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	opts := &git.CheckoutOptions{}
+	switch {
+	case name != "":
+		opts.Branch = plumbing.NewBranchReferenceName(name)
+		opts.Create = create
+		if create {
+			start, err := resolveCommit(repo, args)
+			if err != nil {
+				return "", err
+			}
+			opts.Hash = start.Hash
+		}
+	case rev != "":
+		commit, err := resolveCommit(repo, args)
+		if err != nil {
+			return "", err
+		}
+		opts.Hash = commit.Hash
+	default:
+		return "", errors.New("name or rev is required")
+	}
+
+	if err := wt.Checkout(opts); err != nil {
+		if errors.Is(err, git.ErrUnstagedChanges) {
+			return "", errors.New("the worktree has uncommitted changes; commit them first")
+		}
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return "", fmt.Errorf("branch %s not found (use create=true to create it)", name)
+		}
+		return "", err
+	}
+	if name != "" {
+		if create {
+			return fmt.Sprintf("Switched to a new branch %s", name), nil
+		}
+		return fmt.Sprintf("Switched to branch %s", name), nil
+	}
+	return fmt.Sprintf("HEAD is now detached at %s", opts.Hash.String()[:8]), nil
+}
+
+func (t *GitTool) push(ctx context.Context, repo *git.Repository, args map[string]interface{}) (string, error) {
+	remoteName, _ := args["remote"].(string)
+	if remoteName == "" {
+		remoteName = "origin"
+	}
+	remote, err := repo.Remote(remoteName)
+	if err != nil {
+		return "", fmt.Errorf("remote %s not found", remoteName)
+	}
+	remoteURL := remote.Config().URLs[0]
+	allowed := t.allowedRemote(remoteURL)
+	if allowed == nil {
+		return "", fmt.Errorf("pushing to %s is not allowed; add it to tools.git.push_remotes", remoteURL)
+	}
+
+	branch, _ := args["name"].(string)
+	if branch == "" {
+		head, err := repo.Head()
+		if err != nil || !head.Name().IsBranch() {
+			return "", errors.New("HEAD is not on a branch; pass name")
+		}
+		branch = head.Name().Short()
+	}
+	ref := plumbing.NewBranchReferenceName(branch)
+	if _, err := repo.Reference(ref, false); err != nil {
+		return "", fmt.Errorf("branch %s not found", branch)
+	}
+
+	opts := &git.PushOptions{
+		RemoteName: remoteName,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(ref + ":" + ref)},
+	}
+	if allowed.Token != "" {
+		username := allowed.Username
+		if username == "" {
+			username = "git"
+		}
+		opts.Auth = &githttp.BasicAuth{Username: username, Password: allowed.Token}
+	}
+	err = repo.PushContext(ctx, opts)
+	switch {
+	case errors.Is(err, git.NoErrAlreadyUpToDate):
+		return fmt.Sprintf("Everything up-to-date (%s -> %s)", branch, remoteName), nil
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrAuthorizationFailed):
+		return "", fmt.Errorf("%w; set a token for this remote in tools.git.push_remotes", err)
+	case err != nil:
+		return "", err
+	}
+	return fmt.Sprintf("Pushed %s to %s (%s)", branch, remoteName, remoteURL), nil
+}
+
+func (t *GitTool) allowedRemote(remoteURL string) *GitRemote {
+	normalize := func(s string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(s), "/"), ".git")
+	}
+	target := normalize(remoteURL)
+	for i, r := range t.pushRemotes {
+		if r.URL == "" {
+			continue
+		}
+		if strings.HasSuffix(r.URL, "/") && strings.HasPrefix(remoteURL, r.URL) {
+			return &t.pushRemotes[i]
+		}
+		if normalize(r.URL) == target {
+			return &t.pushRemotes[i]
+		}
+	}
+	return nil
+}
+
+// resolveCommit resolves args["rev"], defaulting to HEAD.
+func resolveCommit(repo *git.Repository, args map[string]interface{}) (*object.Commit, error) {
+	rev, _ := args["rev"].(string)
+	if rev == "" {
+		rev = "HEAD"
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		if rev == "HEAD" {
+			return nil, errors.New("the repository has no commits yet")
+		}
+		return nil, fmt.Errorf("unknown revision %s", rev)
+	}
+	return repo.CommitObject(*hash)
+}
+
+// gitBlob is one side of a file diff.
+type gitBlob struct {
+	path    string
+	mode    filemode.FileMode
+	hash    plumbing.Hash
+	content []byte
+}
+
+func (b *gitBlob) Hash() plumbing.Hash     { return b.hash }
+func (b *gitBlob) Mode() filemode.FileMode { return b.mode }
+func (b *gitBlob) Path() string            { return b.path }
+
+func treeBlob(tree *object.Tree, path string) (*gitBlob, error) {
+	if tree == nil {
+		return nil, nil
+	}
+	file, err := tree.File(path)
+	if errors.Is(err, object.ErrFileNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return &gitBlob{path: path, mode: file.Mode, hash: file.Hash, content: []byte(content)}, nil
+}
+
+func indexBlob(repo *git.Repository, entries []*index.Entry, path string) (*gitBlob, error) {
+	for _, e := range entries {
+		if e.Name != path {
+			continue
+		}
+		blob, err := repo.BlobObject(e.Hash)
+		if err != nil {
+			return nil, err
+		}
+		r, err := blob.Reader()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return &gitBlob{path: path, mode: e.Mode, hash: e.Hash, content: content}, nil
+	}
+	return nil, nil
+}
+
+func worktreeBlob(root, path string) (*gitBlob, error) {
+	full := filepath.Join(root, filepath.FromSlash(path))
+	info, err := os.Lstat(full)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var content []byte
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(full)
+		if err != nil {
+			return nil, err
+		}
+		content = []byte(target)
+	} else if content, err = os.ReadFile(full); err != nil {
+		return nil, err
+	}
+	mode, err := filemode.NewFromOSFileMode(info.Mode())
+	if err != nil {
+		return nil, err
+	}
+	return &gitBlob{path: path, mode: mode, hash: plumbing.ComputeHash(plumbing.BlobObject, content), content: content}, nil
+}
+
+// gitPatch adapts worktree and index diffs to go-git's unified encoder.
+type gitPatch struct {
+	files []fdiff.FilePatch
+}
+
+type gitFilePatch struct {
+	from, to *gitBlob
+	binary   bool
+	chunks   []fdiff.Chunk
+}
+
+type gitChunk struct {
+	content string
+	op      fdiff.Operation
+}
+
+func (p *gitPatch) FilePatches() []fdiff.FilePatch { return p.files }
+func (p *gitPatch) Message() string                { return "" }
+
+func (p *gitPatch) add(from, to *gitBlob) {
+	fp := &gitFilePatch{from: from, to: to}
+	var src, dst []byte
+	if from != nil {
+		src = from.content
+	}
+	if to != nil {
+		dst = to.content
+	}
+	if isBinary(src) || isBinary(dst) {
+		fp.binary = true
+	} else {
+		for _, d := range diff.Do(string(src), string(dst)) {
+			op := fdiff.Equal
+			switch d.Type {
+			case diffmatchpatch.DiffInsert:
+				op = fdiff.Add
+			case diffmatchpatch.DiffDelete:
+				op = fdiff.Delete
+			}
+			fp.chunks = append(fp.chunks, &gitChunk{content: d.Text, op: op})
+		}
+	}
+	p.files = append(p.files, fp)
+}
+
+func (p *gitPatch) String() (string, error) {
+	var sb strings.Builder
+	if err := fdiff.NewUnifiedEncoder(&sb, fdiff.DefaultContextLines).Encode(p); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (f *gitFilePatch) IsBinary() bool        { return f.binary }
+func (f *gitFilePatch) Chunks() []fdiff.Chunk { return f.chunks }
+
+// Files returns untyped nils for missing sides, as the encoder expects.
+func (f *gitFilePatch) Files() (from, to fdiff.File) {
+	if f.from != nil {
+		from = f.from
+	}
+	if f.to != nil {
+		to = f.to
+	}
+	return from, to
+}
+
+func (c *gitChunk) Content() string       { return c.content }
+func (c *gitChunk) Type() fdiff.Operation { return c.op }
@@ -0,0 +1,184 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+)
+
+func runGit(t *testing.T, tool *GitTool, args map[string]interface{}) *ToolResult {
+	t.Helper()
+	return tool.Execute(context.Background(), args)
+}
+
+func mustGit(t *testing.T, tool *GitTool, args map[string]interface{}) string {
+	t.Helper()
+	result := runGit(t, tool, args)
+	if result.IsError {
+		t.Fatalf("git %v failed: %s", args["action"], result.ForLLM)
+	}
+	return result.ForLLM
+}
+
+func newGitWorkspace(t *testing.T) (string, *GitTool) {
+	t.Helper()
+	workspace := t.TempDir()
+	repoDir := filepath.Join(workspace, "notes")
+	if _, err := git.PlainInit(repoDir, false); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewGitTool(workspace, true)
+	tool.SetAuthor("Test Bot", "bot@example.com")
+	return repoDir, tool
+}
+
+func TestGitTool_CommitDiffLogShow(t *testing.T) {
+	repoDir, tool := newGitWorkspace(t)
+	os.WriteFile(filepath.Join(repoDir, "todo.md"), []byte("one\ntwo\n"), 0644)
+
+	out := mustGit(t, tool, map[string]interface{}{"action": "status", "repo": "notes"})
+	if !strings.Contains(out, "No commits yet") || !strings.Contains(out, "Untracked files:\n  todo.md") {
+		t.Errorf("unexpected status:\n%s", out)
+	}
+	if result := runGit(t, tool, map[string]interface{}{"action": "commit", "repo": "notes", "message": "empty"}); !result.IsError {
+		t.Error("commit with nothing staged should fail")
+	}
+
+	mustGit(t, tool, map[string]interface{}{"action": "add", "repo": "notes", "paths": []interface{}{"todo.md"}})
+	out = mustGit(t, tool, map[string]interface{}{"action": "diff", "repo": "notes", "staged": true})
+	if !strings.Contains(out, "+++ b/todo.md") || !strings.Contains(out, "+two") {
+		t.Errorf("unexpected staged diff:\n%s", out)
+	}
+	out = mustGit(t, tool, map[string]interface{}{"action": "commit", "repo": "notes", "message": "Add todo"})
+	if !strings.Contains(out, "Add todo") || !strings.Contains(out, "1 file(s) changed") {
+		t.Errorf("unexpected commit output: %s", out)
+	}
+
+	os.WriteFile(filepath.Join(repoDir, "todo.md"), []byte("one\n2\n"), 0644)
+	out = mustGit(t, tool, map[string]interface{}{"action": "diff", "repo": "notes/todo.md"})
+	if !strings.Contains(out, "-two\n+2") {
+		t.Errorf("unexpected unstaged diff:\n%s", out)
+	}
+	if out = mustGit(t, tool, map[string]interface{}{"action": "diff", "repo": "notes", "staged": true}); out != "No staged changes" {
+		t.Errorf("expected no staged changes, got:\n%s", out)
+	}
+	mustGit(t, tool, map[string]interface{}{"action": "commit", "repo": "notes", "message": "Rename item\n\nDetails.", "all": true})
+
+	out = mustGit(t, tool, map[string]interface{}{"action": "log", "repo": "notes"})
+	lines := strings.Split(out, "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "Test Bot: Rename item") || !strings.Contains(lines[1], "Add todo") {
+		t.Errorf("unexpected log:\n%s", out)
+	}
+	out = mustGit(t, tool, map[string]interface{}{"action": "show", "repo": "notes"})
+	if !strings.Contains(out, "Author: Test Bot <bot@example.com>") || !strings.Contains(out, "+2") {
+		t.Errorf("unexpected show:\n%s", out)
+	}
+	out = mustGit(t, tool, map[string]interface{}{"action": "show", "repo": "notes", "rev": "HEAD~1", "paths": []interface{}{"todo.md"}})
+	if out != "one\ntwo\n" {
+		t.Errorf("unexpected file at HEAD~1: %q", out)
+	}
+}
+
+func TestGitTool_BranchCheckout(t *testing.T) {
+	repoDir, tool := newGitWorkspace(t)
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("a\n"), 0644)
+	mustGit(t, tool, map[string]interface{}{"action": "add", "repo": "notes", "paths": []interface{}{"."}})
+	mustGit(t, tool, map[string]interface{}{"action": "commit", "repo": "notes", "message": "init"})
+
+	mustGit(t, tool, map[string]interface{}{"action": "checkout", "repo": "notes", "name": "feature", "create": true})
+	os.WriteFile(filepath.Join(repoDir, "b.txt"), []byte("b\n"), 0644)
+	mustGit(t, tool, map[string]interface{}{"action": "add", "repo": "notes", "paths": []interface{}{"b.txt"}})
+	mustGit(t, tool, map[string]interface{}{"action": "commit", "repo": "notes", "message": "add b"})
+
+	out := mustGit(t, tool, map[string]interface{}{"action": "branch", "repo": "notes"})
+	if !strings.Contains(out, "* feature") || !strings.Contains(out, "  master") {
+		t.Errorf("unexpected branch list:\n%s", out)
+	}
+	if result := runGit(t, tool, map[string]interface{}{"action": "branch", "repo": "notes", "name": "feature", "delete": true}); !result.IsError {
+		t.Error("deleting the current branch should fail")
+	}
+
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("changed\n"), 0644)
+	if result := runGit(t, tool, map[string]interface{}{"action": "checkout", "repo": "notes", "name": "master"}); !result.IsError {
+		t.Error("checkout over uncommitted changes should fail")
+	}
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("a\n"), 0644)
+	mustGit(t, tool, map[string]interface{}{"action": "checkout", "repo": "notes", "name": "master"})
+	if _, err := os.Stat(filepath.Join(repoDir, "b.txt")); !os.IsNotExist(err) {
+		t.Error("b.txt should be gone after switching to master")
+	}
+	out = mustGit(t, tool, map[string]interface{}{"action": "branch", "repo": "notes", "name": "feature", "delete": true})
+	if !strings.Contains(out, "Deleted branch feature") {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
+
+func TestGitTool_WorkspaceRestriction(t *testing.T) {
+	outer := t.TempDir()
+	if _, err := git.PlainInit(outer, false); err != nil {
+		t.Fatal(err)
+	}
+	workspace := filepath.Join(outer, "workspace")
+	os.MkdirAll(workspace, 0755)
+	tool := NewGitTool(workspace, true)
+
+	// The only repository is the one enclosing the workspace.
+	result := runGit(t, tool, map[string]interface{}{"action": "status"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the workspace") {
+		t.Errorf("enclosing repository should be refused, got: %s", result.ForLLM)
+	}
+	if result := runGit(t, tool, map[string]interface{}{"action": "status", "repo": outer}); !result.IsError {
+		t.Error("repo outside the workspace should be refused")
+	}
+
+	repoDir, tool := newGitWorkspace(t)
+	result = runGit(t, tool, map[string]interface{}{"action": "add", "repo": "notes", "paths": []interface{}{"../../etc/passwd"}})
+	if !result.IsError {
+		t.Errorf("path outside the workspace should be refused")
+	}
+	os.WriteFile(filepath.Join(filepath.Dir(repoDir), "loose.txt"), []byte("x"), 0644)
+	result = runGit(t, tool, map[string]interface{}{"action": "add", "repo": "notes", "paths": []interface{}{"../loose.txt"}})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the repository") {
+		t.Errorf("path outside the repository should be refused, got: %s", result.ForLLM)
+	}
+}
+
+func TestGitTool_Push(t *testing.T) {
+	repoDir, tool := newGitWorkspace(t)
+	remoteDir := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainInit(remoteDir, true); err != nil {
+		t.Fatal(err)
+	}
+	repo, _ := git.PlainOpen(repoDir)
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remoteDir}}); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("a\n"), 0644)
+	mustGit(t, tool, map[string]interface{}{"action": "add", "repo": "notes", "paths": []interface{}{"a.txt"}})
+	mustGit(t, tool, map[string]interface{}{"action": "commit", "repo": "notes", "message": "init"})
+
+	result := runGit(t, tool, map[string]interface{}{"action": "push", "repo": "notes"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "not allowed") {
+		t.Fatalf("push without an allowlist should be refused, got: %s", result.ForLLM)
+	}
+
+	tool.SetPushRemotes([]GitRemote{{URL: remoteDir + ".git/"}, {URL: remoteDir}})
+	out := mustGit(t, tool, map[string]interface{}{"action": "push", "repo": "notes"})
+	if !strings.Contains(out, "Pushed master to origin") {
+		t.Errorf("unexpected push output: %s", out)
+	}
+	remote, _ := git.PlainOpen(remoteDir)
+	local, _ := repo.Head()
+	if ref, err := remote.Reference("refs/heads/master", false); err != nil || ref.Hash() != local.Hash() {
+		t.Errorf("remote master = %v, %v; want %s", ref, err, local.Hash())
+	}
+	out = mustGit(t, tool, map[string]interface{}{"action": "push", "repo": "notes"})
+	if !strings.Contains(out, "up-to-date") {
+		t.Errorf("expected up-to-date, got: %s", out)
+	}
+}
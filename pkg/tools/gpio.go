@@ -0,0 +1,671 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gpioConsumer is the label the kernel shows for lines the tool holds.
+const gpioConsumer = "picooraclaw"
+
+type gpioChip struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Lines int    `json:"lines"`
+}
+
+type gpioLine struct {
+	Offset    int    `json:"offset"`
+	Name      string `json:"name,omitempty"`
+	Consumer  string `json:"consumer,omitempty"`
+	Used      bool   `json:"used"`
+	Output    bool   `json:"output"`
+	ActiveLow bool   `json:"active_low,omitempty"`
+}
+
+// gpioLineConfig describes how a line is requested. An empty Direction
+// keeps the line's current direction, so reading an output does not turn
+// it into an input.
+type gpioLineConfig struct {
+	Direction  string // "", "input" or "output"
+	Value      int    // initial value for outputs
+	Bias       string // "", "pull-up", "pull-down" or "disabled"
+	ActiveLow  bool
+	Edge       string // "", "rising", "falling" or "both"
+	DebounceUs int
+}
+
+type gpioEvent struct {
+	Edge        string `json:"edge"`
+	TimestampNs uint64 `json:"timestamp_ns"`
+}
+
+// gpioBackend gives access to GPIO chips. The Linux implementation uses
+// the /dev/gpiochipN v2 character device; tests use a fake.
+type gpioBackend interface {
+	Chips() ([]gpioChip, error)
+	Lines(chip string) ([]gpioLine, error)
+	Request(chip string, offset int, cfg gpioLineConfig) (gpioRequest, error)
+}
+
+// gpioRequest is a requested line. The line stays claimed until Close.
+type gpioRequest interface {
+	Value() (int, error)
+	SetValue(v int) error
+	// WaitEdge waits up to timeout for an edge; nil without error means
+	// none arrived.
+	WaitEdge(timeout time.Duration) (*gpioEvent, error)
+	Close() error
+}
+
+// GPIOTool reads and drives GPIO lines and PWM channels. Lines it sets
+// stay requested (held) so that their level persists, until released.
+// Lines reserved in config, and lines claimed by kernel drivers or other
+// programs, are never touched.
+type GPIOTool struct {
+	mu       sync.Mutex
+	backend  gpioBackend
+	pwmRoot  string
+	reserved []string // "chip:line" entries, where line is an offset or name and chip may be "*"
+	held     map[string]gpioRequest
+}
+
+// NewGPIOTool creates the gpio tool.
+func NewGPIOTool() *GPIOTool {
+	return &GPIOTool{
+		backend: newGPIOBackend(),
+		pwmRoot: "/sys/class/pwm",
+		held:    make(map[string]gpioRequest),
+	}
+}
+
+// Configure takes the "reserved" option: lines (and PWM channels) the
+// tool must not touch, as "gpiochip0:23", "0:23", "gpiochip0:SD_PWR",
+// a bare line name such as "SD_PWR" for any chip, or "pwmchip0:1".
+func (t *GPIOTool) Configure(options map[string]interface{}) error {
+	if err := checkOptions(options, "reserved"); err != nil {
+		return err
+	}
+	entries, err := optionStrings(options, "reserved")
+	if err != nil {
+		return err
+	}
+	reserved := make([]string, 0, len(entries))
+	for _, e := range entries {
+		chip, line, found := strings.Cut(strings.TrimSpace(e), ":")
+		if !found {
+			chip, line = "*", chip
+			if _, err := strconv.Atoi(line); err == nil {
+				return fmt.Errorf("reserved: %q needs a chip, e.g. gpiochip0:%s", e, line)
+			}
+		}
+		if _, err := strconv.Atoi(chip); err == nil {
+			chip = "gpiochip" + chip
+		}
+		if chip == "" || line == "" {
+			return fmt.Errorf("reserved: invalid entry %q", e)
+		}
+		reserved = append(reserved, chip+":"+line)
+	}
+	t.reserved = reserved
+	return nil
+}
+
+func (t *GPIOTool) Name() string {
+	return "gpio"
+}
+
+func (t *GPIOTool) Description() string {
+	return "Control GPIO pins and PWM outputs. Actions: list (chips, or the lines of one chip, plus PWM chips), read (line level), set (drive a line high or low; it stays held until release), release, wait (wait for a rising/falling edge with timeout), pwm (set period/frequency and duty cycle on /sys/class/pwm). Lines are given by offset or name. set and pwm require confirm: true. Reserved lines and lines used by drivers are refused. Linux only."
+}
+
+func (t *GPIOTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"list", "read", "set", "release", "wait", "pwm"},
+				"description": "Action to perform",
+			},
+			"chip": map[string]interface{}{
+				"type":        "string",
+				"description": "GPIO chip: name (gpiochip0), number (0) or label. For pwm: PWM chip (pwmchip0 or 0).",
+			},
+			"line": map[string]interface{}{
+				"type":        "string",
+				"description": "Line offset (e.g. \"17\") or line name (e.g. \"GPIO17\")",
+			},
+			"value": map[string]interface{}{
+				"type":        "integer",
+				"enum":        []int{0, 1},
+				"description": "For set: level to drive",
+			},
+			"bias": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"pull-up", "pull-down", "disabled"},
+				"description": "For read and wait: input bias (setting it makes the line an input)",
+			},
+			"active_low": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Treat low as 1 (default false)",
+			},
+			"edge": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"rising", "falling", "both"},
+				"description": "For wait: edge to wait for (default both)",
+			},
+			"timeout_ms": map[string]interface{}{
+				"type":        "integer",
+				"description": "For wait: how long to wait (default 5000, max 60000)",
+			},
+			"debounce_us": map[string]interface{}{
+				"type":        "integer",
+				"description": "For wait: debounce period in microseconds, for buttons",
+			},
+			"channel": map[string]interface{}{
+				"type":        "integer",
+				"description": "For pwm: channel number on the PWM chip",
+			},
+			"frequency_hz": map[string]interface{}{
+				"type":        "number",
+				"description": "For pwm: frequency in Hz (alternative to period_ns)",
+			},
+			"period_ns": map[string]interface{}{
+				"type":        "integer",
+				"description": "For pwm: period in nanoseconds",
+			},
+			"duty_percent": map[string]interface{}{
+				"type":        "number",
+				"description": "For pwm: duty cycle 0-100 (alternative to duty_ns)",
+			},
+			"duty_ns": map[string]interface{}{
+				"type":        "integer",
+				"description": "For pwm: active time in nanoseconds",
+			},
+			"polarity": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"normal", "inversed"},
+				"description": "For pwm: output polarity",
+			},
+			"enable": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For pwm: enable (default true) or disable the output",
+			},
+			"confirm": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Must be true for set and pwm. Safety guard to prevent accidental writes.",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *GPIOTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	action, _ := args["action"].(string)
+	switch action {
+	case "list":
+		return t.list(args)
+	case "pwm":
+		return t.pwm(args)
+	case "read", "set", "release", "wait":
+	default:
+		return ErrorResult(fmt.Sprintf("unknown action: %s (valid: list, read, set, release, wait, pwm)", action))
+	}
+
+	line, errResult := t.resolveLine(args)
+	if errResult != nil {
+		return errResult
+	}
+	switch action {
+	case "read":
+		return t.read(line, args)
+	case "set":
+		return t.set(line, args)
+	case "release":
+		if req, ok := t.held[line.key]; ok {
+			req.Close()
+			delete(t.held, line.key)
+			return SilentResult(fmt.Sprintf("Released %s", line))
+		}
+		return SilentResult(fmt.Sprintf("%s was not held", line))
+	default:
+		return t.wait(ctx, line, args)
+	}
+}
+
+// gpioTarget is a resolved line.
+type gpioTarget struct {
+	chip string
+	info gpioLine
+	key  string // chip:offset
+}
+
+func (l gpioTarget) String() string {
+	if l.info.Name != "" {
+		return fmt.Sprintf("%s (%s)", l.key, l.info.Name)
+	}
+	return l.key
+}
+
+func (t *GPIOTool) resolveChip(name string) (string, error) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "/dev/")
+	if name == "" {
+		return "", fmt.Errorf("chip is required (e.g. gpiochip0); use action list to find chips")
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		name = "gpiochip" + name
+	}
+	chips, err := t.backend.Chips()
+	if err != nil {
+		return "", err
+	}
+	for _, c := range chips {
+		if c.Name == name || c.Label == name {
+			return c.Name, nil
+		}
+	}
+	return "", fmt.Errorf("GPIO chip %s not found; use action list to find chips", name)
+}
+
+// resolveLine finds the line named by args and refuses reserved lines.
+func (t *GPIOTool) resolveLine(args map[string]interface{}) (*gpioTarget, *ToolResult) {
+	chipArg, _ := args["chip"].(string)
+	chip, err := t.resolveChip(chipArg)
+	if err != nil {
+		return nil, ErrorResult(err.Error())
+	}
+	lineArg, _ := args["line"].(string)
+	lineArg = strings.TrimSpace(lineArg)
+	if lineArg == "" {
+		return nil, ErrorResult("line is required (offset or name)")
+	}
+	lines, err := t.backend.Lines(chip)
+	if err != nil {
+		return nil, ErrorResult(fmt.Sprintf("failed to read lines of %s: %v", chip, err))
+	}
+
+	var found *gpioLine
+	if offset, err := strconv.Atoi(lineArg); err == nil {
+		if offset < 0 || offset >= len(lines) {
+			return nil, ErrorResult(fmt.Sprintf("line %d out of range: %s has %d lines", offset, chip, len(lines)))
+		}
+		found = &lines[offset]
+	} else {
+		for i := range lines {
+			if lines[i].Name == lineArg {
+				found = &lines[i]
+				break
+			}
+		}
+		for i := range lines {
+			if found == nil && strings.EqualFold(lines[i].Name, lineArg) {
+				found = &lines[i]
+			}
+		}
+		if found == nil {
+			return nil, ErrorResult(fmt.Sprintf("no line named %s on %s", lineArg, chip))
+		}
+	}
+
+	line := &gpioTarget{chip: chip, info: *found, key: fmt.Sprintf("%s:%d", chip, found.Offset)}
+	if t.isReserved(chip, found.Offset, found.Name) {
+		return nil, ErrorResult(fmt.Sprintf("%s is reserved in config (tools.gpio.options.reserved) and cannot be used", line))
+	}
+	return line, nil
+}
+
+func (t *GPIOTool) isReserved(chip string, offset int, name string) bool {
+	for _, r := range t.reserved {
+		rc, rl, _ := strings.Cut(r, ":")
+		if rc != "*" && rc != chip {
+			continue
+		}
+		if rl == strconv.Itoa(offset) || (name != "" && rl == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkFree refuses lines claimed by drivers or other programs.
+func (t *GPIOTool) checkFree(line *gpioTarget) *ToolResult {
+	if _, ok := t.held[line.key]; ok || !line.info.Used {
+		return nil
+	}
+	consumer := line.info.Consumer
+	if consumer == "" {
+		consumer = "another consumer"
+	}
+	return ErrorResult(fmt.Sprintf("%s is in use by %s", line, consumer))
+}
+
+func (t *GPIOTool) list(args map[string]interface{}) *ToolResult {
+	if chipArg, _ := args["chip"].(string); chipArg != "" {
+		chip, err := t.resolveChip(chipArg)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		lines, err := t.backend.Lines(chip)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to read lines of %s: %v", chip, err))
+		}
+		type lineEntry struct {
+			gpioLine
+			Reserved bool `json:"reserved,omitempty"`
+			Held     bool `json:"held,omitempty"`
+		}
+		entries := make([]lineEntry, len(lines))
+		for i, l := range lines {
+			_, held := t.held[fmt.Sprintf("%s:%d", chip, l.Offset)]
+			entries[i] = lineEntry{gpioLine: l, Reserved: t.isReserved(chip, l.Offset, l.Name), Held: held}
+		}
+		result, _ := json.MarshalIndent(map[string]interface{}{"chip": chip, "lines": entries}, "", "  ")
+		return SilentResult(string(result))
+	}
+
+	chips, err := t.backend.Chips()
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to list GPIO chips: %v", err))
+	}
+	out := map[string]interface{}{"gpio_chips": chips}
+	if pwm := t.pwmChips(); len(pwm) > 0 {
+		out["pwm_chips"] = pwm
+	}
+	if len(t.held) > 0 {
+		held := make([]string, 0, len(t.held))
+		for k := range t.held {
+			held = append(held, k)
+		}
+		sort.Strings(held)
+		out["held"] = held
+	}
+	if len(chips) == 0 {
+		return SilentResult("No GPIO chips found (/dev/gpiochip*). Check that the GPIO character device is enabled in the kernel.")
+	}
+	result, _ := json.MarshalIndent(out, "", "  ")
+	return SilentResult(string(result))
+}
+
+func (t *GPIOTool) read(line *gpioTarget, args map[string]interface{}) *ToolResult {
+	req, ok := t.held[line.key]
+	if !ok {
+		if r := t.checkFree(line); r != nil {
+			return r
+		}
+		cfg := gpioLineConfig{ActiveLow: line.info.ActiveLow}
+		if v, ok := args["active_low"].(bool); ok {
+			cfg.ActiveLow = v
+		}
+		if bias, _ := args["bias"].(string); bias != "" {
+			if line.info.Output {
+				return ErrorResult(fmt.Sprintf("%s is an output; a bias would switch it to input", line))
+			}
+			cfg.Direction, cfg.Bias = "input", bias
+		}
+		var err error
+		if req, err = t.backend.Request(line.chip, line.info.Offset, cfg); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to request %s: %v", line, err))
+		}
+		defer req.Close()
+	}
+	v, err := req.Value()
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read %s: %v", line, err))
+	}
+	return SilentResult(fmt.Sprintf("%s = %d", line, v))
+}
+
+func (t *GPIOTool) set(line *gpioTarget, args map[string]interface{}) *ToolResult {
+	if confirm, _ := args["confirm"].(bool); !confirm {
+		return ErrorResult("set requires confirm: true. Please confirm with the user before driving GPIO lines, as the wrong pin can power off or damage attached hardware.")
+	}
+	fv, ok := args["value"].(float64)
+	if !ok || (fv != 0 && fv != 1) {
+		return ErrorResult("value must be 0 or 1")
+	}
+	value := int(fv)
+
+	if req, ok := t.held[line.key]; ok {
+		if err := req.SetValue(value); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to set %s: %v", line, err))
+		}
+		return SilentResult(fmt.Sprintf("Set %s to %d", line, value))
+	}
+	if r := t.checkFree(line); r != nil {
+		return r
+	}
+	cfg := gpioLineConfig{Direction: "output", Value: value, ActiveLow: line.info.ActiveLow}
+	if v, ok := args["active_low"].(bool); ok {
+		cfg.ActiveLow = v
+	}
+	req, err := t.backend.Request(line.chip, line.info.Offset, cfg)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to request %s as output: %v", line, err))
+	}
+	t.held[line.key] = req
+	return SilentResult(fmt.Sprintf("Set %s to %d (held as output until released)", line, value))
+}
+
+func (t *GPIOTool) wait(ctx context.Context, line *gpioTarget, args map[string]interface{}) *ToolResult {
+	if _, ok := t.held[line.key]; ok {
+		return ErrorResult(fmt.Sprintf("%s is held as an output; release it before waiting for edges", line))
+	}
+	if r := t.checkFree(line); r != nil {
+		return r
+	}
+	if line.info.Output {
+		return ErrorResult(fmt.Sprintf("%s is an output; waiting for edges would switch it to input", line))
+	}
+	edge, _ := args["edge"].(string)
+	if edge == "" {
+		edge = "both"
+	}
+	timeout := 5 * time.Second
+	if ms, ok := args["timeout_ms"].(float64); ok && ms > 0 {
+		timeout = min(time.Duration(ms)*time.Millisecond, time.Minute)
+	}
+	cfg := gpioLineConfig{Direction: "input", Edge: edge, ActiveLow: line.info.ActiveLow}
+	cfg.Bias, _ = args["bias"].(string)
+	if v, ok := args["active_low"].(bool); ok {
+		cfg.ActiveLow = v
+	}
+	if us, ok := args["debounce_us"].(float64); ok && us > 0 {
+		cfg.DebounceUs = int(us)
+	}
+
+	req, err := t.backend.Request(line.chip, line.info.Offset, cfg)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to request %s for edge events: %v", line, err))
+	}
+	defer req.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return SilentResult(fmt.Sprintf("No %s edge on %s within %s", edge, line, timeout))
+		}
+		if ctx.Err() != nil {
+			return ErrorResult(fmt.Sprintf("wait on %s cancelled", line))
+		}
+		ev, err := req.WaitEdge(min(remaining, 100*time.Millisecond))
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to wait for edge on %s: %v", line, err))
+		}
+		if ev != nil {
+			result, _ := json.MarshalIndent(map[string]interface{}{
+				"line": line.String(), "edge": ev.Edge, "timestamp_ns": ev.TimestampNs,
+			}, "", "  ")
+			return SilentResult(string(result))
+		}
+	}
+}
+
+type pwmChannel struct {
+	Channel  int    `json:"channel"`
+	PeriodNs int64  `json:"period_ns"`
+	DutyNs   int64  `json:"duty_ns"`
+	Enabled  bool   `json:"enabled"`
+	Polarity string `json:"polarity,omitempty"`
+}
+
+type pwmChipInfo struct {
+	Name     string       `json:"name"`
+	Channels int          `json:"channels"`
+	Exported []pwmChannel `json:"exported,omitempty"`
+}
+
+func readSysfs(path string) string {
+	data, _ := os.ReadFile(path)
+	return strings.TrimSpace(string(data))
+}
+
+func (t *GPIOTool) pwmChips() []pwmChipInfo {
+	dirs, _ := filepath.Glob(filepath.Join(t.pwmRoot, "pwmchip*"))
+	var chips []pwmChipInfo
+	for _, dir := range dirs {
+		n, _ := strconv.Atoi(readSysfs(filepath.Join(dir, "npwm")))
+		info := pwmChipInfo{Name: filepath.Base(dir), Channels: n}
+		for ch := 0; ch < n; ch++ {
+			chDir := filepath.Join(dir, fmt.Sprintf("pwm%d", ch))
+			if _, err := os.Stat(chDir); err != nil {
+				continue
+			}
+			period, _ := strconv.ParseInt(readSysfs(filepath.Join(chDir, "period")), 10, 64)
+			duty, _ := strconv.ParseInt(readSysfs(filepath.Join(chDir, "duty_cycle")), 10, 64)
+			info.Exported = append(info.Exported, pwmChannel{
+				Channel: ch, PeriodNs: period, DutyNs: duty,
+				Enabled:  readSysfs(filepath.Join(chDir, "enable")) == "1",
+				Polarity: readSysfs(filepath.Join(chDir, "polarity")),
+			})
+		}
+		chips = append(chips, info)
+	}
+	return chips
+}
+
+func (t *GPIOTool) pwm(args map[string]interface{}) *ToolResult {
+	if confirm, _ := args["confirm"].(bool); !confirm {
+		return ErrorResult("pwm requires confirm: true. Please confirm with the user before changing PWM outputs, as they may drive motors, heaters or backlights.")
+	}
+	chip, _ := args["chip"].(string)
+	chip = strings.TrimSpace(chip)
+	if _, err := strconv.Atoi(chip); err == nil {
+		chip = "pwmchip" + chip
+	}
+	if !strings.HasPrefix(chip, "pwmchip") || strings.ContainsAny(chip, "/.") {
+		return ErrorResult("chip must be a PWM chip such as pwmchip0; use action list to find PWM chips")
+	}
+	chipDir := filepath.Join(t.pwmRoot, chip)
+	npwm, err := strconv.Atoi(readSysfs(filepath.Join(chipDir, "npwm")))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("PWM chip %s not found", chip))
+	}
+	chf, ok := args["channel"].(float64)
+	channel := int(chf)
+	if !ok || channel < 0 || channel >= npwm {
+		return ErrorResult(fmt.Sprintf("channel must be between 0 and %d for %s", npwm-1, chip))
+	}
+	if t.isReserved(chip, channel, "") {
+		return ErrorResult(fmt.Sprintf("%s:%d is reserved in config (tools.gpio.options.reserved) and cannot be used", chip, channel))
+	}
+
+	chDir := filepath.Join(chipDir, fmt.Sprintf("pwm%d", channel))
+	if _, err := os.Stat(chDir); err != nil {
+		if err := os.WriteFile(filepath.Join(chipDir, "export"), []byte(strconv.Itoa(channel)), 0); err != nil {
+			return ErrorResult(fmt.Sprintf("failed to export %s channel %d: %v", chip, channel, err))
+		}
+		// udev may need a moment to create the files and fix permissions.
+		for i := 0; i < 20; i++ {
+			if _, err := os.Stat(filepath.Join(chDir, "period")); err == nil {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	write := func(name string, v string) error {
+		if err := os.WriteFile(filepath.Join(chDir, name), []byte(v), 0); err != nil {
+			return fmt.Errorf("failed to write %s: %v", name, err)
+		}
+		return nil
+	}
+
+	enable := true
+	if v, ok := args["enable"].(bool); ok {
+		enable = v
+	}
+	if !enable {
+		if err := write("enable", "0"); err != nil {
+			return ErrorResult(err.Error())
+		}
+		return SilentResult(fmt.Sprintf("Disabled %s channel %d", chip, channel))
+	}
+
+	period, _ := strconv.ParseInt(readSysfs(filepath.Join(chDir, "period")), 10, 64)
+	if v, ok := args["period_ns"].(float64); ok {
+		period = int64(v)
+	} else if hz, ok := args["frequency_hz"].(float64); ok {
+		if hz <= 0 {
+			return ErrorResult("frequency_hz must be positive")
+		}
+		period = int64(1e9/hz + 0.5)
+	}
+	if period <= 0 {
+		return ErrorResult("period_ns or frequency_hz is required")
+	}
+	oldDuty, _ := strconv.ParseInt(readSysfs(filepath.Join(chDir, "duty_cycle")), 10, 64)
+	duty := min(oldDuty, period)
+	if v, ok := args["duty_ns"].(float64); ok {
+		duty = int64(v)
+	} else if pct, ok := args["duty_percent"].(float64); ok {
+		if pct < 0 || pct > 100 {
+			return ErrorResult("duty_percent must be between 0 and 100")
+		}
+		duty = int64(float64(period)*pct/100 + 0.5)
+	}
+	if duty < 0 || duty > period {
+		return ErrorResult("duty cycle must be between 0 and the period")
+	}
+
+	// Polarity can only change while disabled.
+	if polarity, _ := args["polarity"].(string); polarity != "" && polarity != readSysfs(filepath.Join(chDir, "polarity")) {
+		if err := write("enable", "0"); err != nil {
+			return ErrorResult(err.Error())
+		}
+		if err := write("polarity", polarity); err != nil {
+			return ErrorResult(err.Error())
+		}
+	}
+	// The kernel rejects a duty cycle longer than the period, so shrink
+	// whichever one would break that first.
+	steps := []string{"period", "duty_cycle"}
+	if period < oldDuty {
+		steps = []string{"duty_cycle", "period"}
+	}
+	for _, name := range steps {
+		v := period
+		if name == "duty_cycle" {
+			v = duty
+		}
+		if err := write(name, strconv.FormatInt(v, 10)); err != nil {
+			return ErrorResult(err.Error())
+		}
+	}
+	if err := write("enable", "1"); err != nil {
+		return ErrorResult(err.Error())
+	}
+	return SilentResult(fmt.Sprintf("PWM %s channel %d: period %d ns (%.6g Hz), duty %d ns (%.1f%%), enabled",
+		chip, channel, period, 1e9/float64(period), duty, float64(duty)*100/float64(period)))
+}
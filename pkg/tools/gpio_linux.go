@@ -0,0 +1,287 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// GPIO v2 character device structures and flags from <linux/gpio.h>.
+const (
+	gpioMaxNameSize  = 32
+	gpioV2LinesMax   = 64
+	gpioV2AttrsMax   = 10
+	gpioIoctlType    = 0xB4
+	gpioEventSize    = 48
+	gpioIocRead      = 2
+	gpioIocReadWrite = 3
+
+	gpioV2LineFlagUsed         = 1 << 0
+	gpioV2LineFlagActiveLow    = 1 << 1
+	gpioV2LineFlagInput        = 1 << 2
+	gpioV2LineFlagOutput       = 1 << 3
+	gpioV2LineFlagEdgeRising   = 1 << 4
+	gpioV2LineFlagEdgeFalling  = 1 << 5
+	gpioV2LineFlagBiasPullUp   = 1 << 8
+	gpioV2LineFlagBiasPullDown = 1 << 9
+	gpioV2LineFlagBiasDisabled = 1 << 10
+
+	gpioV2LineAttrIDOutputValues = 2
+	gpioV2LineAttrIDDebounce     = 3
+
+	gpioV2LineEventRisingEdge = 1
+)
+
+type gpioChipInfo struct {
+	name  [gpioMaxNameSize]byte
+	label [gpioMaxNameSize]byte
+	lines uint32
+}
+
+type gpioV2LineAttribute struct {
+	id      uint32
+	padding uint32
+	value   uint64 // flags, output values or debounce period
+}
+
+type gpioV2LineConfigAttribute struct {
+	attr gpioV2LineAttribute
+	mask uint64
+}
+
+type gpioV2LineConfig struct {
+	flags    uint64
+	numAttrs uint32
+	padding  [5]uint32
+	attrs    [gpioV2AttrsMax]gpioV2LineConfigAttribute
+}
+
+type gpioV2LineRequest struct {
+	offsets         [gpioV2LinesMax]uint32
+	consumer        [gpioMaxNameSize]byte
+	config          gpioV2LineConfig
+	numLines        uint32
+	eventBufferSize uint32
+	padding         [5]uint32
+	fd              int32
+}
+
+type gpioV2LineValues struct {
+	bits uint64
+	mask uint64
+}
+
+type gpioV2LineInfo struct {
+	name     [gpioMaxNameSize]byte
+	consumer [gpioMaxNameSize]byte
+	offset   uint32
+	numAttrs uint32
+	flags    uint64
+	attrs    [gpioV2AttrsMax]gpioV2LineAttribute
+	padding  [4]uint32
+}
+
+func gpioIoctlNumber(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | gpioIoctlType<<8 | nr
+}
+
+var (
+	gpioGetChipInfo     = gpioIoctlNumber(gpioIocRead, 0x01, unsafe.Sizeof(gpioChipInfo{}))
+	gpioV2GetLineInfo   = gpioIoctlNumber(gpioIocReadWrite, 0x05, unsafe.Sizeof(gpioV2LineInfo{}))
+	gpioV2GetLine       = gpioIoctlNumber(gpioIocReadWrite, 0x07, unsafe.Sizeof(gpioV2LineRequest{}))
+	gpioV2GetLineValues = gpioIoctlNumber(gpioIocReadWrite, 0x0E, unsafe.Sizeof(gpioV2LineValues{}))
+	gpioV2SetLineValues = gpioIoctlNumber(gpioIocReadWrite, 0x0F, unsafe.Sizeof(gpioV2LineValues{}))
+)
+
+func gpioIoctl(fd int, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func cString(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		return string(b[:i])
+	}
+	return string(b)
+}
+
+// gpioCdev is the gpioBackend for the /dev/gpiochipN character devices.
+type gpioCdev struct{}
+
+func newGPIOBackend() gpioBackend {
+	return gpioCdev{}
+}
+
+func openGPIOChip(chip string) (int, error) {
+	if strings.ContainsAny(chip, "/.") {
+		return -1, fmt.Errorf("invalid chip name %q", chip)
+	}
+	fd, err := unix.Open("/dev/"+chip, unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open /dev/%s: %v (check permissions)", chip, err)
+	}
+	return fd, nil
+}
+
+func (gpioCdev) Chips() ([]gpioChip, error) {
+	paths, _ := filepath.Glob("/dev/gpiochip*")
+	sort.Slice(paths, func(i, j int) bool {
+		a, _ := strconv.Atoi(strings.TrimPrefix(paths[i], "/dev/gpiochip"))
+		b, _ := strconv.Atoi(strings.TrimPrefix(paths[j], "/dev/gpiochip"))
+		return a < b
+	})
+	chips := make([]gpioChip, 0, len(paths))
+	for _, path := range paths {
+		fd, err := unix.Open(path, unix.O_RDONLY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			continue
+		}
+		var info gpioChipInfo
+		err = gpioIoctl(fd, gpioGetChipInfo, unsafe.Pointer(&info))
+		unix.Close(fd)
+		if err != nil {
+			continue
+		}
+		chips = append(chips, gpioChip{Name: cString(info.name[:]), Label: cString(info.label[:]), Lines: int(info.lines)})
+	}
+	return chips, nil
+}
+
+func (gpioCdev) Lines(chip string) ([]gpioLine, error) {
+	fd, err := openGPIOChip(chip)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+	var chipInfo gpioChipInfo
+	if err := gpioIoctl(fd, gpioGetChipInfo, unsafe.Pointer(&chipInfo)); err != nil {
+		return nil, err
+	}
+	lines := make([]gpioLine, chipInfo.lines)
+	for i := range lines {
+		info := gpioV2LineInfo{offset: uint32(i)}
+		if err := gpioIoctl(fd, gpioV2GetLineInfo, unsafe.Pointer(&info)); err != nil {
+			return nil, fmt.Errorf("line %d: %v", i, err)
+		}
+		lines[i] = gpioLine{
+			Offset:    i,
+			Name:      cString(info.name[:]),
+			Consumer:  cString(info.consumer[:]),
+			Used:      info.flags&gpioV2LineFlagUsed != 0,
+			Output:    info.flags&gpioV2LineFlagOutput != 0,
+			ActiveLow: info.flags&gpioV2LineFlagActiveLow != 0,
+		}
+	}
+	return lines, nil
+}
+
+func (gpioCdev) Request(chip string, offset int, cfg gpioLineConfig) (gpioRequest, error) {
+	fd, err := openGPIOChip(chip)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+
+	var req gpioV2LineRequest
+	req.offsets[0] = uint32(offset)
+	req.numLines = 1
+	copy(req.consumer[:gpioMaxNameSize-1], gpioConsumer)
+
+	c := &req.config
+	switch cfg.Direction {
+	case "input":
+		c.flags |= gpioV2LineFlagInput
+	case "output":
+		c.flags |= gpioV2LineFlagOutput
+		c.attrs[c.numAttrs] = gpioV2LineConfigAttribute{
+			attr: gpioV2LineAttribute{id: gpioV2LineAttrIDOutputValues, value: uint64(cfg.Value & 1)},
+			mask: 1,
+		}
+		c.numAttrs++
+	}
+	if cfg.ActiveLow {
+		c.flags |= gpioV2LineFlagActiveLow
+	}
+	switch cfg.Bias {
+	case "pull-up":
+		c.flags |= gpioV2LineFlagBiasPullUp
+	case "pull-down":
+		c.flags |= gpioV2LineFlagBiasPullDown
+	case "disabled":
+		c.flags |= gpioV2LineFlagBiasDisabled
+	}
+	switch cfg.Edge {
+	case "rising":
+		c.flags |= gpioV2LineFlagEdgeRising
+	case "falling":
+		c.flags |= gpioV2LineFlagEdgeFalling
+	case "both":
+		c.flags |= gpioV2LineFlagEdgeRising | gpioV2LineFlagEdgeFalling
+	}
+	if cfg.DebounceUs > 0 {
+		attr := gpioV2LineAttribute{id: gpioV2LineAttrIDDebounce}
+		// debounce_period_us is the first 32 bits of the union.
+		*(*uint32)(unsafe.Pointer(&attr.value)) = uint32(cfg.DebounceUs)
+		c.attrs[c.numAttrs] = gpioV2LineConfigAttribute{attr: attr, mask: 1}
+		c.numAttrs++
+	}
+
+	if err := gpioIoctl(fd, gpioV2GetLine, unsafe.Pointer(&req)); err != nil {
+		if err == unix.EBUSY {
+			return nil, fmt.Errorf("line is busy (claimed by a driver or another program)")
+		}
+		return nil, err
+	}
+	return &gpioCdevLine{fd: int(req.fd)}, nil
+}
+
+// gpioCdevLine is a line request file descriptor.
+type gpioCdevLine struct {
+	fd int
+}
+
+func (l *gpioCdevLine) Value() (int, error) {
+	vals := gpioV2LineValues{mask: 1}
+	if err := gpioIoctl(l.fd, gpioV2GetLineValues, unsafe.Pointer(&vals)); err != nil {
+		return 0, err
+	}
+	return int(vals.bits & 1), nil
+}
+
+func (l *gpioCdevLine) SetValue(v int) error {
+	vals := gpioV2LineValues{bits: uint64(v & 1), mask: 1}
+	return gpioIoctl(l.fd, gpioV2SetLineValues, unsafe.Pointer(&vals))
+}
+
+func (l *gpioCdevLine) WaitEdge(timeout time.Duration) (*gpioEvent, error) {
+	fds := []unix.PollFd{{Fd: int32(l.fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if err == unix.EINTR || n == 0 {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// struct gpio_v2_line_event: timestamp_ns, id, offset, seqno, line_seqno, padding.
+	buf := make([]byte, gpioEventSize)
+	if _, err := unix.Read(l.fd, buf); err != nil {
+		return nil, err
+	}
+	ev := &gpioEvent{Edge: "falling", TimestampNs: *(*uint64)(unsafe.Pointer(&buf[0]))}
+	if *(*uint32)(unsafe.Pointer(&buf[8])) == gpioV2LineEventRisingEdge {
+		ev.Edge = "rising"
+	}
+	return ev, nil
+}
+
+func (l *gpioCdevLine) Close() error {
+	return unix.Close(l.fd)
+}
@@ -0,0 +1,27 @@
+package tools
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestGPIOCdev_StructLayout(t *testing.T) {
+	for name, got := range map[string]uintptr{
+		"gpiochip_info":        unsafe.Sizeof(gpioChipInfo{}),
+		"gpio_v2_line_info":    unsafe.Sizeof(gpioV2LineInfo{}),
+		"gpio_v2_line_request": unsafe.Sizeof(gpioV2LineRequest{}),
+		"gpio_v2_line_values":  unsafe.Sizeof(gpioV2LineValues{}),
+		"gpio_v2_line_config":  unsafe.Sizeof(gpioV2LineConfig{}),
+	} {
+		want := map[string]uintptr{
+			"gpiochip_info": 68, "gpio_v2_line_info": 256, "gpio_v2_line_request": 592,
+			"gpio_v2_line_values": 16, "gpio_v2_line_config": 272,
+		}[name]
+		if got != want {
+			t.Errorf("struct %s is %d bytes, kernel expects %d", name, got, want)
+		}
+	}
+	if gpioV2GetLine != 0xC250B407 || gpioGetChipInfo != 0x8044B401 {
+		t.Errorf("ioctl numbers: GET_LINE %#x, GET_CHIPINFO %#x", gpioV2GetLine, gpioGetChipInfo)
+	}
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package tools
+
+import "fmt"
+
+var errGPIOUnsupported = fmt.Errorf("GPIO is only supported on Linux")
+
+// gpioUnsupported is the gpioBackend for non-Linux platforms.
+type gpioUnsupported struct{}
+
+func newGPIOBackend() gpioBackend {
+	return gpioUnsupported{}
+}
+
+func (gpioUnsupported) Chips() ([]gpioChip, error) {
+	return nil, errGPIOUnsupported
+}
+
+func (gpioUnsupported) Lines(chip string) ([]gpioLine, error) {
+	return nil, errGPIOUnsupported
+}
+
+func (gpioUnsupported) Request(chip string, offset int, cfg gpioLineConfig) (gpioRequest, error) {
+	return nil, errGPIOUnsupported
+}
@@ -0,0 +1,233 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGPIO is an in-memory gpioBackend with one chip.
+type fakeGPIO struct {
+	lines    []gpioLine
+	values   map[int]int
+	requests map[int]gpioLineConfig
+	events   chan gpioEvent
+}
+
+func newFakeGPIO() *fakeGPIO {
+	return &fakeGPIO{
+		lines: []gpioLine{
+			{Offset: 0, Name: "SD_PWR", Output: true},
+			{Offset: 1, Name: "LED"},
+			{Offset: 2, Name: "BUTTON"},
+			{Offset: 3, Name: "spi-cs", Used: true, Consumer: "spi0 CS0", Output: true},
+			{Offset: 4},
+		},
+		values:   map[int]int{0: 1, 2: 1},
+		requests: make(map[int]gpioLineConfig),
+		events:   make(chan gpioEvent, 1),
+	}
+}
+
+func (f *fakeGPIO) Chips() ([]gpioChip, error) {
+	return []gpioChip{{Name: "gpiochip0", Label: "pinctrl-test", Lines: len(f.lines)}}, nil
+}
+
+func (f *fakeGPIO) Lines(chip string) ([]gpioLine, error) {
+	if chip != "gpiochip0" {
+		return nil, fmt.Errorf("no such chip")
+	}
+	lines := append([]gpioLine(nil), f.lines...)
+	for offset, cfg := range f.requests {
+		lines[offset].Used, lines[offset].Consumer = true, gpioConsumer
+		lines[offset].Output = lines[offset].Output || cfg.Direction == "output"
+	}
+	return lines, nil
+}
+
+func (f *fakeGPIO) Request(chip string, offset int, cfg gpioLineConfig) (gpioRequest, error) {
+	if _, busy := f.requests[offset]; busy || f.lines[offset].Used {
+		return nil, fmt.Errorf("line is busy")
+	}
+	if cfg.Direction == "output" {
+		f.values[offset] = cfg.Value
+	}
+	f.requests[offset] = cfg
+	return &fakeGPIORequest{gpio: f, offset: offset}, nil
+}
+
+type fakeGPIORequest struct {
+	gpio   *fakeGPIO
+	offset int
+}
+
+func (r *fakeGPIORequest) Value() (int, error) { return r.gpio.values[r.offset], nil }
+func (r *fakeGPIORequest) SetValue(v int) error {
+	r.gpio.values[r.offset] = v
+	return nil
+}
+func (r *fakeGPIORequest) WaitEdge(timeout time.Duration) (*gpioEvent, error) {
+	select {
+	case ev := <-r.gpio.events:
+		return &ev, nil
+	case <-time.After(timeout):
+		return nil, nil
+	}
+}
+func (r *fakeGPIORequest) Close() error {
+	delete(r.gpio.requests, r.offset)
+	return nil
+}
+
+func newFakeGPIOTool(t *testing.T) (*GPIOTool, *fakeGPIO) {
+	t.Helper()
+	fake := newFakeGPIO()
+	tool := NewGPIOTool()
+	tool.backend = fake
+	tool.pwmRoot = t.TempDir()
+	if err := tool.Configure(map[string]interface{}{"reserved": []interface{}{"SD_PWR", "0:4", "pwmchip0:1"}}); err != nil {
+		t.Fatal(err)
+	}
+	return tool, fake
+}
+
+func TestGPIOTool_ReadSetRelease(t *testing.T) {
+	tool, fake := newFakeGPIOTool(t)
+	ctx := context.Background()
+	run := func(args map[string]interface{}) *ToolResult {
+		return tool.Execute(ctx, args)
+	}
+
+	result := run(map[string]interface{}{"action": "read", "chip": "0", "line": "button"})
+	if result.IsError || result.ForLLM != "gpiochip0:2 (BUTTON) = 1" {
+		t.Errorf("read by name: %s", result.ForLLM)
+	}
+	if len(fake.requests) != 0 {
+		t.Error("a read should release the line afterwards")
+	}
+
+	result = run(map[string]interface{}{"action": "set", "chip": "gpiochip0", "line": "1", "value": 1.0})
+	if !result.IsError || !strings.Contains(result.ForLLM, "confirm: true") {
+		t.Errorf("set without confirm should be refused: %s", result.ForLLM)
+	}
+	result = run(map[string]interface{}{"action": "set", "chip": "pinctrl-test", "line": "LED", "value": 1.0, "confirm": true})
+	if result.IsError || fake.values[1] != 1 || fake.requests[1].Direction != "output" {
+		t.Fatalf("set failed: %s", result.ForLLM)
+	}
+	run(map[string]interface{}{"action": "set", "chip": "0", "line": "1", "value": 0.0, "confirm": true})
+	if fake.values[1] != 0 {
+		t.Error("setting a held line should reuse its request")
+	}
+	if r := run(map[string]interface{}{"action": "read", "chip": "0", "line": "1"}); r.ForLLM != "gpiochip0:1 (LED) = 0" {
+		t.Errorf("reading a held line: %s", r.ForLLM)
+	}
+	if r := run(map[string]interface{}{"action": "list", "chip": "0"}); !strings.Contains(r.ForLLM, `"held": true`) || !strings.Contains(r.ForLLM, `"reserved": true`) {
+		t.Errorf("list should mark held and reserved lines:\n%s", r.ForLLM)
+	}
+	if r := run(map[string]interface{}{"action": "release", "chip": "0", "line": "1"}); r.IsError || len(fake.requests) != 0 {
+		t.Errorf("release: %s", r.ForLLM)
+	}
+}
+
+func TestGPIOTool_Ownership(t *testing.T) {
+	tool, fake := newFakeGPIOTool(t)
+	ctx := context.Background()
+
+	for _, line := range []string{"SD_PWR", "sd_pwr", "0", "4"} {
+		for _, action := range []string{"set", "read", "wait"} {
+			result := tool.Execute(ctx, map[string]interface{}{"action": action, "chip": "0", "line": line, "value": 0.0, "confirm": true})
+			if !result.IsError || !strings.Contains(result.ForLLM, "reserved in config") {
+				t.Errorf("%s on reserved line %s should be refused: %s", action, line, result.ForLLM)
+			}
+		}
+	}
+	result := tool.Execute(ctx, map[string]interface{}{"action": "set", "chip": "0", "line": "spi-cs", "value": 1.0, "confirm": true})
+	if !result.IsError || !strings.Contains(result.ForLLM, "in use by spi0 CS0") {
+		t.Errorf("line claimed by a driver should be refused: %s", result.ForLLM)
+	}
+	if fake.values[0] != 1 || len(fake.requests) != 0 {
+		t.Error("refused lines must not be touched")
+	}
+	if r := tool.Execute(ctx, map[string]interface{}{"action": "read", "chip": "0", "line": "9"}); !r.IsError || !strings.Contains(r.ForLLM, "out of range") {
+		t.Errorf("unknown offset: %s", r.ForLLM)
+	}
+
+	for _, reserved := range []interface{}{"23", ":5", []interface{}{"0:"}} {
+		if err := NewGPIOTool().Configure(map[string]interface{}{"reserved": reserved}); err == nil {
+			t.Errorf("reserved %v should be rejected", reserved)
+		}
+	}
+}
+
+func TestGPIOTool_Wait(t *testing.T) {
+	tool, fake := newFakeGPIOTool(t)
+	ctx := context.Background()
+
+	fake.events <- gpioEvent{Edge: "falling", TimestampNs: 42}
+	result := tool.Execute(ctx, map[string]interface{}{"action": "wait", "chip": "0", "line": "BUTTON", "edge": "falling", "bias": "pull-up", "debounce_us": 5000.0})
+	if result.IsError || !strings.Contains(result.ForLLM, `"edge": "falling"`) || !strings.Contains(result.ForLLM, `"timestamp_ns": 42`) {
+		t.Errorf("wait: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "wait", "chip": "0", "line": "2", "timeout_ms": 150.0})
+	if result.IsError || result.ForLLM != "No both edge on gpiochip0:2 (BUTTON) within 150ms" {
+		t.Errorf("timeout: %s", result.ForLLM)
+	}
+	if len(fake.requests) != 0 {
+		t.Error("wait should release the line")
+	}
+
+	tool.Execute(ctx, map[string]interface{}{"action": "set", "chip": "0", "line": "LED", "value": 1.0, "confirm": true})
+	result = tool.Execute(ctx, map[string]interface{}{"action": "wait", "chip": "0", "line": "LED"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "held as an output") {
+		t.Errorf("waiting on a held output should be refused: %s", result.ForLLM)
+	}
+}
+
+func TestGPIOTool_PWM(t *testing.T) {
+	tool, _ := newFakeGPIOTool(t)
+	ctx := context.Background()
+	chip := filepath.Join(tool.pwmRoot, "pwmchip0")
+	channel := filepath.Join(chip, "pwm0")
+	os.MkdirAll(channel, 0755)
+	for name, v := range map[string]string{"npwm": "2", "export": ""} {
+		os.WriteFile(filepath.Join(chip, name), []byte(v), 0644)
+	}
+	for name, v := range map[string]string{"period": "1000000", "duty_cycle": "500000", "enable": "0", "polarity": "normal"} {
+		os.WriteFile(filepath.Join(channel, name), []byte(v), 0644)
+	}
+	read := func(name string) string { return readSysfs(filepath.Join(channel, name)) }
+
+	args := map[string]interface{}{"action": "pwm", "chip": "0", "channel": 0.0, "frequency_hz": 50.0, "duty_percent": 7.5}
+	if r := tool.Execute(ctx, args); !r.IsError || !strings.Contains(r.ForLLM, "confirm: true") {
+		t.Errorf("pwm without confirm should be refused: %s", r.ForLLM)
+	}
+	args["confirm"] = true
+	result := tool.Execute(ctx, args)
+	if result.IsError || read("period") != "20000000" || read("duty_cycle") != "1500000" || read("enable") != "1" {
+		t.Errorf("servo pulse not applied: %s (period=%s duty=%s)", result.ForLLM, read("period"), read("duty_cycle"))
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "pwm", "chip": "pwmchip0", "channel": 0.0, "duty_percent": 150.0, "confirm": true})
+	if !result.IsError {
+		t.Error("duty over 100% should be refused")
+	}
+	tool.Execute(ctx, map[string]interface{}{"action": "pwm", "chip": "pwmchip0", "channel": 0.0, "enable": false, "confirm": true})
+	if read("enable") != "0" {
+		t.Error("pwm should be disabled")
+	}
+	if r := tool.Execute(ctx, map[string]interface{}{"action": "list"}); !strings.Contains(r.ForLLM, `"period_ns": 20000000`) {
+		t.Errorf("list should show PWM channels:\n%s", r.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "pwm", "chip": "0", "channel": 1.0, "period_ns": 1000.0, "confirm": true})
+	if !result.IsError || !strings.Contains(result.ForLLM, "reserved in config") {
+		t.Errorf("reserved PWM channel should be refused: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "pwm", "chip": "../../etc", "channel": 0.0, "confirm": true})
+	if !result.IsError {
+		t.Error("chip outside the PWM class should be refused")
+	}
+}
@@ -0,0 +1,438 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// HTTPProfile is a named set of credentials for http_request. The model
+// only ever sees the profile name; headers and tokens are added to the
+// request here. Requests using a profile may only go to AllowHosts (host
+// names and IPs, matching subdomains too), which may be internal addresses.
+type HTTPProfile struct {
+	Name        string
+	BaseURL     string            // relative request URLs are joined to it
+	Headers     map[string]string // e.g. X-API-Key
+	BearerToken string
+	Username    string // HTTP basic auth
+	Password    string
+	AllowHosts  []string // defaults to the BaseURL host
+}
+
+type httpProfile struct {
+	HTTPProfile
+	hosts   []string
+	secrets []string
+	policy  *NetPolicy
+	client  *http.Client
+}
+
+// HTTPRequestTool calls REST APIs. Requests without a profile follow the
+// network policy of the web tools; requests with one follow its host
+// allowlist.
+type HTTPRequestTool struct {
+	timeout        time.Duration
+	maxBytes       int64
+	policy         *NetPolicy
+	client         *http.Client
+	profiles       map[string]*httpProfile
+	requireProfile bool
+}
+
+var httpMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// NewHTTPRequestTool creates the tool. Zero values default to a 30s
+// timeout and a 1 MiB response limit.
+func NewHTTPRequestTool(timeout time.Duration, maxBytes int64) *HTTPRequestTool {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	if maxBytes <= 0 {
+		maxBytes = 1 << 20
+	}
+	return &HTTPRequestTool{
+		timeout:  timeout,
+		maxBytes: maxBytes,
+		client:   &http.Client{Timeout: timeout},
+		profiles: make(map[string]*httpProfile),
+	}
+}
+
+// SetNetPolicy restricts requests made without a profile.
+func (t *HTTPRequestTool) SetNetPolicy(policy *NetPolicy) {
+	t.policy = policy
+	t.client = policy.Client(t.timeout, 5)
+}
+
+// SetRequireProfile refuses requests that do not name a profile.
+func (t *HTTPRequestTool) SetRequireProfile(require bool) {
+	t.requireProfile = require
+}
+
+// SetProfiles validates and installs the credential profiles.
+func (t *HTTPRequestTool) SetProfiles(profiles []HTTPProfile) error {
+	compiled := make(map[string]*httpProfile, len(profiles))
+	for _, p := range profiles {
+		if p.Name == "" {
+			return fmt.Errorf("http profile without a name")
+		}
+		hosts := append([]string(nil), p.AllowHosts...)
+		if len(hosts) == 0 && p.BaseURL != "" {
+			u, err := url.Parse(p.BaseURL)
+			if err != nil || u.Hostname() == "" {
+				return fmt.Errorf("http profile %s: invalid base_url %q", p.Name, p.BaseURL)
+			}
+			hosts = []string{u.Hostname()}
+		}
+		if len(hosts) == 0 {
+			return fmt.Errorf("http profile %s: allow_hosts or base_url is required", p.Name)
+		}
+		for i, h := range hosts {
+			if strings.Contains(h, "/") {
+				return fmt.Errorf("http profile %s: allow_hosts takes host names or IPs, not %q", p.Name, h)
+			}
+			hosts[i] = hostOnly(h)
+		}
+		policy, err := NewNetPolicy(NetPolicyOptions{AllowDomains: hosts, AllowPrivate: hosts})
+		if err != nil {
+			return fmt.Errorf("http profile %s: %w", p.Name, err)
+		}
+		cp := &httpProfile{HTTPProfile: p, hosts: hosts, policy: policy, client: policy.Client(t.timeout, 5)}
+		for _, s := range []string{p.BearerToken, p.Password} {
+			cp.addSecret(s)
+		}
+		if p.Username != "" {
+			cp.addSecret(base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + p.Password)))
+		}
+		for _, v := range p.Headers {
+			cp.addSecret(v)
+			// Also hide the credential of "Bearer xyz"-style values.
+			if _, cred, ok := strings.Cut(v, " "); ok {
+				cp.addSecret(cred)
+			}
+		}
+		compiled[p.Name] = cp
+	}
+	t.profiles = compiled
+	return nil
+}
+
+func (p *httpProfile) addSecret(s string) {
+	if len(s) >= 6 {
+		p.secrets = append(p.secrets, s)
+	}
+}
+
+func (t *HTTPRequestTool) Name() string {
+	return "http_request"
+}
+
+func (t *HTTPRequestTool) Description() string {
+	desc := "Call an HTTP API with any method, headers and a JSON, form or raw body. " +
+		"Use 'select' (e.g. \"data.items[0].name\") to return only part of a JSON response. " +
+		"Use web_fetch instead to read web pages."
+	if len(t.profiles) > 0 {
+		names := make([]string, 0, len(t.profiles))
+		for name, p := range t.profiles {
+			names = append(names, fmt.Sprintf("%s (%s)", name, strings.Join(p.hosts, ", ")))
+		}
+		sort.Strings(names)
+		desc += " Credential profiles, which add authentication for their hosts: " + strings.Join(names, "; ") + "."
+	}
+	if t.requireProfile {
+		desc += " A profile is required."
+	}
+	return desc
+}
+
+func (t *HTTPRequestTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "Request URL; with a profile that has a base URL, a path relative to it",
+			},
+			"method": map[string]interface{}{
+				"type":        "string",
+				"enum":        httpMethods,
+				"description": "HTTP method (default GET)",
+			},
+			"profile": map[string]interface{}{
+				"type":        "string",
+				"description": "Credential profile to authenticate with",
+			},
+			"headers": map[string]interface{}{
+				"type":        "object",
+				"description": "Extra request headers",
+			},
+			"query": map[string]interface{}{
+				"type":        "object",
+				"description": "Query parameters added to the URL",
+			},
+			"json": map[string]interface{}{
+				"description": "JSON request body",
+			},
+			"form": map[string]interface{}{
+				"type":        "object",
+				"description": "URL-encoded form body",
+			},
+			"body": map[string]interface{}{
+				"type":        "string",
+				"description": "Raw request body; set Content-Type in headers",
+			},
+			"select": map[string]interface{}{
+				"type":        "string",
+				"description": "JSON path into the response, e.g. \"results[*].id\"",
+			},
+			"max_chars": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum characters of response body to return (default 20000)",
+				"minimum":     100.0,
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	rawURL, _ := args["url"].(string)
+	if rawURL == "" {
+		return ErrorResult("url is required")
+	}
+	method := "GET"
+	if m, ok := args["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+	known := false
+	for _, m := range httpMethods {
+		known = known || m == method
+	}
+	if !known {
+		return ErrorResult(fmt.Sprintf("unsupported method %s", method))
+	}
+
+	var profile *httpProfile
+	if name, _ := args["profile"].(string); name != "" {
+		profile = t.profiles[name]
+		if profile == nil {
+			return ErrorResult(fmt.Sprintf("unknown profile %q", name))
+		}
+	} else if t.requireProfile {
+		return ErrorResult("a profile is required for http_request")
+	}
+
+	if profile != nil && profile.BaseURL != "" && !strings.Contains(rawURL, "://") {
+		rawURL = strings.TrimRight(profile.BaseURL, "/") + "/" + strings.TrimLeft(rawURL, "/")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid URL: %v", err))
+	}
+	if q, ok := args["query"].(map[string]interface{}); ok {
+		values := u.Query()
+		for k, v := range q {
+			values.Set(k, formValue(v))
+		}
+		u.RawQuery = values.Encode()
+	}
+
+	client := t.client
+	if profile != nil {
+		if err := profile.policy.CheckURL(u); err != nil {
+			return ErrorResult(fmt.Sprintf("%s is not allowed for profile %s", u.Hostname(), profile.Name))
+		}
+		client = profile.client
+	} else if err := t.policy.CheckURL(u); err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	body, contentType, err := requestBody(args)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to create request: %v", err))
+	}
+	req.Header.Set("User-Agent", "picooraclaw")
+	req.Header.Set("Accept", "application/json, text/*;q=0.9, */*;q=0.8")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := args["headers"].(map[string]interface{}); ok {
+		for k, v := range headers {
+			req.Header.Set(k, formValue(v))
+		}
+	}
+	// Profile credentials go last so the model cannot replace them.
+	if profile != nil {
+		for k, v := range profile.Headers {
+			req.Header.Set(k, v)
+		}
+		if profile.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+profile.BearerToken)
+		} else if profile.Username != "" {
+			req.SetBasicAuth(profile.Username, profile.Password)
+		}
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return ErrorResult(redactSecrets(fmt.Sprintf("request failed: %v", err), profile))
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBytes+1))
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
+	}
+	truncated := int64(len(data)) > t.maxBytes
+	if truncated {
+		data = data[:t.maxBytes]
+	}
+
+	maxChars := 20000
+	if v, ok := args["max_chars"].(float64); ok && int(v) >= 100 {
+		maxChars = int(v)
+	}
+	selector, _ := args["select"].(string)
+	text, err := formatHTTPBody(resp.Header.Get("Content-Type"), data, selector, truncated)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	text = redactSecrets(text, profile)
+	if len(text) > maxChars {
+		text = text[:maxChars] + fmt.Sprintf("\n... (%d more characters; use select or max_chars)", len(text)-maxChars)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s -> %s (%d bytes", method, u.Redacted(), resp.Status, len(data))
+	if truncated {
+		fmt.Fprintf(&sb, ", cut at the %d byte limit", t.maxBytes)
+	}
+	fmt.Fprintf(&sb, ", %dms)\n", time.Since(start).Milliseconds())
+	for _, h := range []string{"Content-Type", "Location", "Retry-After"} {
+		if v := resp.Header.Get(h); v != "" {
+			fmt.Fprintf(&sb, "%s: %s\n", h, v)
+		}
+	}
+	if text != "" {
+		sb.WriteString("\n")
+		sb.WriteString(text)
+	}
+	result := NewToolResult(sb.String())
+	result.IsError = resp.StatusCode >= 400
+	return result
+}
+
+// requestBody builds the body from exactly one of json, form or body.
+func requestBody(args map[string]interface{}) (io.Reader, string, error) {
+	jsonBody, hasJSON := args["json"]
+	form, hasForm := args["form"].(map[string]interface{})
+	raw, hasRaw := args["body"].(string)
+	n := 0
+	for _, has := range []bool{hasJSON && jsonBody != nil, hasForm, hasRaw} {
+		if has {
+			n++
+		}
+	}
+	switch {
+	case n > 1:
+		return nil, "", fmt.Errorf("use only one of json, form and body")
+	case hasJSON && jsonBody != nil:
+		// A JSON body sent as a string that already holds JSON is passed
+		// through as-is rather than being quoted again.
+		if s, ok := jsonBody.(string); ok && json.Valid([]byte(s)) {
+			return strings.NewReader(s), "application/json", nil
+		}
+		data, err := json.Marshal(jsonBody)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid json body: %v", err)
+		}
+		return bytes.NewReader(data), "application/json", nil
+	case hasForm:
+		values := url.Values{}
+		for k, v := range form {
+			values.Set(k, formValue(v))
+		}
+		return strings.NewReader(values.Encode()), "application/x-www-form-urlencoded", nil
+	case hasRaw:
+		return strings.NewReader(raw), "", nil
+	}
+	return nil, "", nil
+}
+
+func formValue(v interface{}) string {
+	if s := jsonString(v); s != "" || v == "" {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// formatHTTPBody renders a response body: JSON is optionally narrowed by
+// the selector, text is returned as is and binary content is summarized.
+func formatHTTPBody(contentType string, data []byte, selector string, truncated bool) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isJSON := strings.HasSuffix(mediaType, "json") || (mediaType == "" && json.Valid(data))
+	if selector != "" {
+		if truncated {
+			return "", fmt.Errorf("response exceeds the size limit, cannot apply select")
+		}
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return "", fmt.Errorf("select needs a JSON response: %v", err)
+		}
+		v, ok := lookupJSONPath(doc, selector)
+		if !ok {
+			return "", fmt.Errorf("select %q matched nothing", selector)
+		}
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	if len(data) == 0 {
+		return "", nil
+	}
+	if !isJSON && !strings.HasPrefix(mediaType, "text/") && (isBinary(data) || !utf8.Valid(data)) {
+		return fmt.Sprintf("(binary content, %s)", formatByteSize(int64(len(data)))), nil
+	}
+	return string(data), nil
+}
+
+// redactSecrets hides profile credentials that an API echoes back.
+func redactSecrets(s string, profile *httpProfile) string {
+	if profile == nil {
+		return s
+	}
+	for _, secret := range profile.secrets {
+		s = strings.ReplaceAll(s, secret, "[REDACTED]")
+	}
+	return s
+}
+
+// hostOnly strips a port from an allow_hosts entry.
+func hostOnly(h string) string {
+	if host, _, err := net.SplitHostPort(h); err == nil {
+		return host
+	}
+	return h
+}
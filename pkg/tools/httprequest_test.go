@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPRequestTool_JSONAndSelect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != "POST" || r.Header.Get("Content-Type") != "application/json" || r.URL.Query().Get("dry") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "bad request"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{"items": []interface{}{
+				map[string]interface{}{"name": in["name"], "id": 1},
+				map[string]interface{}{"name": "other", "id": 2},
+			}},
+		})
+	}))
+	defer server.Close()
+	tool := NewHTTPRequestTool(0, 0)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{
+		"method": "post",
+		"url":    server.URL + "/items",
+		"query":  map[string]interface{}{"dry": true},
+		"json":   map[string]interface{}{"name": "lamp"},
+		"select": "data.items[0].name",
+	})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "POST "+server.URL+"/items?dry=true -> 200 OK") ||
+		!strings.HasSuffix(result.ForLLM, "\n\nlamp") {
+		t.Errorf("unexpected result: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{
+		"method": "POST", "url": server.URL, "query": map[string]interface{}{"dry": "true"},
+		"json": `{"name": "raw"}`, "select": "data.items[*].id",
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "[\n  1,\n  2\n]") {
+		t.Errorf("unexpected wildcard result: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"url": server.URL})
+	if !result.IsError || !strings.Contains(result.ForLLM, "400 Bad Request") || !strings.Contains(result.ForLLM, "bad request") {
+		t.Errorf("error status should be reported with its body: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"url": server.URL, "json": map[string]interface{}{}, "body": "x"}); !result.IsError {
+		t.Error("json and body together should be refused")
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"url": server.URL, "method": "TRACE"}); !result.IsError {
+		t.Error("unsupported method should be refused")
+	}
+}
+
+func TestHTTPRequestTool_FormAndLimits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/big" {
+			w.Write([]byte(strings.Repeat("x", 5000)))
+			return
+		}
+		r.ParseForm()
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.Header.Get("Content-Type") + " " + r.PostForm.Get("state") + " " + r.PostForm.Get("level")))
+	}))
+	defer server.Close()
+	tool := NewHTTPRequestTool(0, 1000)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{
+		"method": "PUT", "url": server.URL, "form": map[string]interface{}{"state": "on", "level": 3.0},
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "application/x-www-form-urlencoded on 3") {
+		t.Errorf("unexpected form result: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/big", "max_chars": 200.0})
+	if result.IsError || !strings.Contains(result.ForLLM, "cut at the 1000 byte limit") || !strings.Contains(result.ForLLM, "800 more characters") {
+		t.Errorf("unexpected limited result: %s", result.ForLLM)
+	}
+}
+
+func TestHTTPRequestTool_Profiles(t *testing.T) {
+	var gotAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		// Echo the headers back, like httpbin does.
+		data, _ := json.Marshal(map[string]interface{}{"path": r.URL.Path, "auth": gotAuth, "key": r.Header.Get("X-Api-Key")})
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	policy, _ := NewNetPolicy(NetPolicyOptions{})
+	tool := NewHTTPRequestTool(0, 0)
+	tool.SetNetPolicy(policy)
+	err := tool.SetProfiles([]HTTPProfile{
+		{Name: "ha", BaseURL: server.URL + "/api/", BearerToken: "secret-token-123", Headers: map[string]string{"X-Api-Key": "key-abcdef"}},
+		{Name: "other", AllowHosts: []string{"example.com"}, Username: "u", Password: "hunter22"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(tool.Description(), "ha (127.0.0.1)") || strings.Contains(tool.Description(), "secret") {
+		t.Errorf("unexpected description: %s", tool.Description())
+	}
+	ctx := context.Background()
+
+	// Loopback is refused without a profile ...
+	if result := tool.Execute(ctx, map[string]interface{}{"url": server.URL}); !result.IsError {
+		t.Errorf("loopback request without a profile should be blocked: %s", result.ForLLM)
+	}
+	// ... but allowed for a profile that lists it, with credentials injected
+	// and not replaceable by the model.
+	result := tool.Execute(ctx, map[string]interface{}{
+		"url": "states/light.kitchen", "profile": "ha",
+		"headers": map[string]interface{}{"Authorization": "Bearer stolen"},
+	})
+	if result.IsError {
+		t.Fatalf("profile request failed: %s", result.ForLLM)
+	}
+	if gotAuth != "Bearer secret-token-123" {
+		t.Errorf("server saw Authorization %q", gotAuth)
+	}
+	if !strings.Contains(result.ForLLM, `"path":"/api/states/light.kitchen"`) ||
+		strings.Contains(result.ForLLM, "secret-token-123") || strings.Contains(result.ForLLM, "key-abcdef") {
+		t.Errorf("credentials should be redacted from the response: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"url": server.URL, "profile": "other"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "not allowed for profile other") {
+		t.Errorf("host outside the profile allowlist should be refused: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"url": server.URL, "profile": "missing"}); !result.IsError {
+		t.Error("unknown profile should be refused")
+	}
+
+	tool.SetRequireProfile(true)
+	if result := tool.Execute(ctx, map[string]interface{}{"url": "https://example.com"}); !result.IsError {
+		t.Error("request without a profile should be refused when one is required")
+	}
+
+	if err := tool.SetProfiles([]HTTPProfile{{Name: "bad"}}); err == nil {
+		t.Error("profile without hosts should be rejected")
+	}
+	if err := tool.SetProfiles([]HTTPProfile{{Name: "bad", AllowHosts: []string{"10.0.0.0/8"}}}); err == nil {
+		t.Error("CIDR allow_hosts should be rejected")
+	}
+}
+
+func TestHTTPRequestTool_BinaryResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, "\x89PNG\r\n\x1a\n\x00\x00\x00")
+	}))
+	defer server.Close()
+	result := NewHTTPRequestTool(0, 0).Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	if result.IsError || !strings.Contains(result.ForLLM, "(binary content, 11 bytes)") {
+		t.Errorf("unexpected binary result: %s", result.ForLLM)
+	}
+}
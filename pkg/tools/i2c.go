@@ -7,15 +7,50 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
 )
 
 // I2CTool provides I2C bus interaction for reading sensors and controlling peripherals.
-type I2CTool struct{}
+type I2CTool struct {
+	buses []string // buses the agent may use; all when empty
+}
 
 func NewI2CTool() *I2CTool {
 	return &I2CTool{}
 }
 
+// Configure takes the "buses" option, limiting the tool to the listed bus
+// numbers.
+func (t *I2CTool) Configure(options map[string]interface{}) error {
+	if err := checkOptions(options, "buses"); err != nil {
+		return err
+	}
+	buses, err := optionStrings(options, "buses")
+	if err != nil {
+		return err
+	}
+	for _, b := range buses {
+		if !isValidBusID(b) {
+			return fmt.Errorf("buses: invalid bus %q", b)
+		}
+	}
+	t.buses = buses
+	return nil
+}
+
+// allowedBus reports whether the configured buses include bus.
+func (t *I2CTool) allowedBus(bus string) bool {
+	if len(t.buses) == 0 {
+		return true
+	}
+	for _, b := range t.buses {
+		if b == bus {
+			return true
+		}
+	}
+	return false
+}
+
 func (t *I2CTool) Name() string {
 	return "i2c"
 }
@@ -73,6 +108,10 @@ func (t *I2CTool) Execute(ctx context.Context, args map[string]interface{}) *Too
 		return ErrorResult("action is required")
 	}
 
+	if bus, _ := args["bus"].(string); bus != "" && action != "detect" && !t.allowedBus(bus) {
+		return ErrorResult(fmt.Sprintf("bus %s is not enabled for the i2c tool (allowed: %s)", bus, strings.Join(t.buses, ", ")))
+	}
+
 	switch action {
 	case "detect":
 		return t.detect()
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseJSONPath splits a path like "data.items[0].title", "$.results" or
+// "items[*].name" into its segments. "*" matches every element of an
+// array or every value of an object.
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$")
+	var segs []string
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			j := strings.IndexByte(path[i:], ']')
+			if j < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", path)
+			}
+			segs = append(segs, strings.Trim(path[i+1:i+j], `'"`))
+			i += j + 1
+		default:
+			j := strings.IndexAny(path[i:], ".[")
+			if j < 0 {
+				j = len(path) - i
+			}
+			segs = append(segs, path[i:i+j])
+			i += j
+		}
+	}
+	return segs, nil
+}
+
+// lookupJSONPath walks decoded JSON along a path (see parseJSONPath).
+// Wildcards collect their matches into a slice. Negative array indexes
+// count from the end. An empty path returns v.
+func lookupJSONPath(v interface{}, path string) (interface{}, bool) {
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, false
+	}
+	return walkJSONPath(v, segs)
+}
+
+func walkJSONPath(v interface{}, segs []string) (interface{}, bool) {
+	if len(segs) == 0 {
+		return v, true
+	}
+	seg, rest := segs[0], segs[1:]
+	switch x := v.(type) {
+	case map[string]interface{}:
+		if seg == "*" {
+			keys := make([]string, 0, len(x))
+			for k := range x {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			out := []interface{}{}
+			for _, k := range keys {
+				if r, ok := walkJSONPath(x[k], rest); ok {
+					out = append(out, r)
+				}
+			}
+			return out, true
+		}
+		child, ok := x[seg]
+		if !ok {
+			return nil, false
+		}
+		return walkJSONPath(child, rest)
+	case []interface{}:
+		if seg == "*" {
+			out := []interface{}{}
+			for _, e := range x {
+				if r, ok := walkJSONPath(e, rest); ok {
+					out = append(out, r)
+				}
+			}
+			return out, true
+		}
+		idx, err := strconv.Atoi(seg)
+		if err != nil {
+			return nil, false
+		}
+		if idx < 0 {
+			idx += len(x)
+		}
+		if idx < 0 || idx >= len(x) {
+			return nil, false
+		}
+		return walkJSONPath(x[idx], rest)
+	}
+	return nil, false
+}
+
+// jsonString renders a scalar JSON value as text; objects and arrays
+// give "".
+func jsonString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return ""
+}
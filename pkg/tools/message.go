@@ -61,6 +61,15 @@ func (t *MessageTool) SetSendCallback(callback SendCallback) {
 	t.sendCallback = callback
 }
 
+// Send delivers content through the tool's send path without going through
+// the LLM, for notifications raised by the agent itself.
+func (t *MessageTool) Send(channel, chatID, content string) error {
+	if t.sendCallback == nil {
+		return fmt.Errorf("message sending not configured")
+	}
+	return t.sendCallback(channel, chatID, content)
+}
+
 func (t *MessageTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	content, ok := args["content"].(string)
 	if !ok {
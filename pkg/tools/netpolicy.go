@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NetPolicy decides which hosts the web tools may reach. Private, loopback,
+// link-local and other internal addresses are refused unless the host or
+// address is in AllowPrivate; the check runs on every resolved address
+// right before dialing, so redirects and DNS tricks cannot get around it.
+type NetPolicy struct {
+	allowDomains []string
+	denyDomains  []string
+	privateHosts []string
+	privateNets  []*net.IPNet
+}
+
+// NetPolicyOptions configures a NetPolicy. Domain entries match the domain
+// and its subdomains ("example.com" also matches "docs.example.com").
+type NetPolicyOptions struct {
+	AllowDomains []string // when set, only these domains may be fetched
+	DenyDomains  []string // never fetched; wins over AllowDomains
+	AllowPrivate []string // host names, IPs or CIDRs allowed to be internal
+}
+
+// internalNets are ranges that are not covered by the net.IP predicates but
+// still must not be reachable from a fetch tool.
+var internalNets = mustParseCIDRs(
+	"100.64.0.0/10", // carrier-grade NAT, used by some cloud metadata services
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"64:ff9b::/96",  // NAT64, can embed internal IPv4 addresses
+)
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// NewNetPolicy validates the options and builds a policy.
+func NewNetPolicy(opts NetPolicyOptions) (*NetPolicy, error) {
+	p := &NetPolicy{
+		allowDomains: normalizeDomains(opts.AllowDomains),
+		denyDomains:  normalizeDomains(opts.DenyDomains),
+	}
+	for _, entry := range opts.AllowPrivate {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			_, n, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allow_private entry %q: %w", entry, err)
+			}
+			p.privateNets = append(p.privateNets, n)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			p.privateNets = append(p.privateNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		default:
+			p.privateHosts = append(p.privateHosts, strings.ToLower(strings.TrimSuffix(entry, ".")))
+		}
+	}
+	return p, nil
+}
+
+func normalizeDomains(list []string) []string {
+	out := make([]string, 0, len(list))
+	for _, d := range list {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(d, "*.")
+		d = strings.Trim(d, ".")
+		if d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func domainMatches(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL applies the scheme and domain rules to a URL. Addresses are
+// checked later, when connecting.
+func (p *NetPolicy) CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("only http/https URLs are allowed")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("missing domain in URL")
+	}
+	if p == nil {
+		return nil
+	}
+	if domainMatches(host, p.denyDomains) {
+		return fmt.Errorf("access to %s is denied by tools.web.deny_domains", host)
+	}
+	if len(p.allowDomains) > 0 && !domainMatches(host, p.allowDomains) {
+		return fmt.Errorf("%s is not in tools.web.allow_domains", host)
+	}
+	return nil
+}
+
+// isInternalIP reports whether ip is loopback, private, link-local,
+// multicast, unspecified or in another internal range.
+func isInternalIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	if ip[0] == 0 || (len(ip) == net.IPv4len && ip[0] >= 240) { // "this network" and reserved
+		return true
+	}
+	for _, n := range internalNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAddr decides whether host may be reached at ip.
+func (p *NetPolicy) checkAddr(host string, ip net.IP) error {
+	if !isInternalIP(ip) {
+		return nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range p.privateHosts {
+		if h == host {
+			return nil
+		}
+	}
+	for _, n := range p.privateNets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	if host == ip.String() {
+		return fmt.Errorf("%s is an internal address; add it to tools.web.allow_private to allow it", ip)
+	}
+	return fmt.Errorf("%s resolves to internal address %s; add it to tools.web.allow_private to allow it", host, ip)
+}
+
+// DialContext resolves the host itself, refuses internal addresses and
+// connects to an address it checked, so a second DNS answer cannot swap
+// in a different one.
+func (p *NetPolicy) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: 15 * time.Second, KeepAlive: 30 * time.Second}
+	var firstErr error
+	for _, ip := range ips {
+		if err := p.checkAddr(host, ip); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		firstErr = err
+	}
+	if firstErr == nil {
+		firstErr = fmt.Errorf("no addresses found for %s", host)
+	}
+	return nil, firstErr
+}
+
+// Client returns an HTTP client that enforces the policy on every request
+// and redirect. It does not use proxies from the environment, since the
+// policy could only check the proxy's address.
+func (p *NetPolicy) Client(timeout time.Duration, maxRedirects int) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         p.DialContext,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+			TLSHandshakeTimeout: 15 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if err := p.CheckURL(req.URL); err != nil {
+				return fmt.Errorf("redirect to %s blocked: %w", req.URL.Redacted(), err)
+			}
+			return nil
+		},
+	}
+}
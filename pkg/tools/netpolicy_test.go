@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	internal := []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.100.1.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1", "224.0.0.1", "255.255.255.255"}
+	for _, s := range internal {
+		if !isInternalIP(net.ParseIP(s)) {
+			t.Errorf("%s should be internal", s)
+		}
+	}
+	for _, s := range []string{"8.8.8.8", "140.91.1.1", "2606:4700::1111"} {
+		if isInternalIP(net.ParseIP(s)) {
+			t.Errorf("%s should be public", s)
+		}
+	}
+}
+
+func TestNetPolicy_Domains(t *testing.T) {
+	p, err := NewNetPolicy(NetPolicyOptions{
+		AllowDomains: []string{"example.com", "*.oracle.com"},
+		DenyDomains:  []string{"ads.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(raw string) error {
+		u, _ := url.Parse(raw)
+		return p.CheckURL(u)
+	}
+	for _, ok := range []string{"https://example.com/a", "http://docs.example.com", "https://docs.oracle.com/x"} {
+		if err := check(ok); err != nil {
+			t.Errorf("%s: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"https://ads.example.com", "https://x.ads.example.com", "https://notexample.com", "ftp://example.com", "https://"} {
+		if err := check(bad); err == nil {
+			t.Errorf("%s should be refused", bad)
+		}
+	}
+
+	if _, err := NewNetPolicy(NetPolicyOptions{AllowPrivate: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("expected an invalid CIDR error")
+	}
+}
+
+func TestWebFetch_NetPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("internal data"))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	policy, _ := NewNetPolicy(NetPolicyOptions{})
+	tool := NewWebFetchTool(50000)
+	tool.SetNetPolicy(policy)
+	result := tool.Execute(ctx, map[string]interface{}{"url": server.URL})
+	if !result.IsError || !strings.Contains(result.ForLLM, "internal address") {
+		t.Errorf("loopback fetch should be blocked, got: %s", result.ForLLM)
+	}
+	localhostURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	if result := tool.Execute(ctx, map[string]interface{}{"url": localhostURL}); !result.IsError {
+		t.Errorf("localhost should be blocked after resolution, got: %s", result.ForLLM)
+	}
+
+	policy, _ = NewNetPolicy(NetPolicyOptions{AllowPrivate: []string{"127.0.0.0/8"}})
+	tool.SetNetPolicy(policy)
+	result = tool.Execute(ctx, map[string]interface{}{"url": server.URL})
+	if result.IsError || !strings.Contains(result.ForLLM, "internal data") {
+		t.Errorf("allowlisted fetch failed: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/redirect"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "169.254.169.254") {
+		t.Errorf("redirect to the metadata service should be blocked, got: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,639 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	patchDefaultFuzz = 2
+	patchMaxFuzz     = 3
+)
+
+// ApplyPatchTool applies unified diffs, possibly touching several files.
+// Hunks are located by their context, so line numbers may be off; a patch
+// is applied completely or not at all.
+type ApplyPatchTool struct {
+	workspace string
+	restrict  bool
+}
+
+func NewApplyPatchTool(workspace string, restrict bool) *ApplyPatchTool {
+	return &ApplyPatchTool{workspace: workspace, restrict: restrict}
+}
+
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+func (t *ApplyPatchTool) Description() string {
+	return "Apply a unified diff (as produced by 'diff -u' or 'git diff') to one or more files. Use '--- /dev/null' to create a file and '+++ /dev/null' to delete one; different old and new paths rename the file. Hunks are found by their context lines, so line numbers may be approximate. Either every hunk applies or no file is changed; failures report which hunk did not match and the closest lines in the file."
+}
+
+func (t *ApplyPatchTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"patch": map[string]interface{}{
+				"type":        "string",
+				"description": "The unified diff to apply",
+			},
+			"fuzz": map[string]interface{}{
+				"type":        "integer",
+				"description": "How many leading/trailing context lines of a hunk may fail to match (0-3). Default: 2",
+			},
+			"ignore_whitespace": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Match context and removed lines ignoring whitespace differences when no exact match exists. Default: true",
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Only check that the patch applies; do not change any file",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+func (t *ApplyPatchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	text, ok := args["patch"].(string)
+	if !ok || strings.TrimSpace(text) == "" {
+		return ErrorResult("patch is required")
+	}
+	opts := patchOptions{fuzz: patchDefaultFuzz, ignoreWhitespace: true}
+	if v, ok := args["fuzz"].(float64); ok {
+		opts.fuzz = max(0, min(int(v), patchMaxFuzz))
+	}
+	if v, ok := args["ignore_whitespace"].(bool); ok {
+		opts.ignoreWhitespace = v
+	}
+	dryRun, _ := args["dry_run"].(bool)
+
+	files, err := parsePatch(text)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("invalid patch: %v", err))
+	}
+
+	// Apply everything in memory first.
+	states := make(map[string]*patchedFile)
+	var order []*patchedFile
+	stateFor := func(name string) (*patchedFile, error) {
+		resolved, err := validatePath(name, t.workspace, t.restrict)
+		if err != nil {
+			return nil, err
+		}
+		if state, ok := states[resolved]; ok {
+			return state, nil
+		}
+		state, err := loadPatchedFile(resolved, name)
+		if err != nil {
+			return nil, err
+		}
+		states[resolved] = state
+		order = append(order, state)
+		return state, nil
+	}
+	var failures, notes []string
+	for _, fp := range files {
+		name := fp.newPath
+		if fp.isDelete() {
+			name = fp.oldPath
+		}
+		state, err := stateFor(name)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		// Different old and new paths rename the file when the old one
+		// exists and the new one does not; otherwise the new path is
+		// patched, as with "diff -u file.orig file".
+		var from *patchedFile
+		if !fp.isCreate() && !fp.isDelete() && fp.oldPath != fp.newPath && !state.exists {
+			if from, err = stateFor(fp.oldPath); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", fp.oldPath, err))
+				continue
+			}
+			if !from.exists {
+				from = nil
+			}
+		}
+		if from != nil {
+			fileNotes, fileFailures := from.apply(fp, opts)
+			if len(fileFailures) > 0 {
+				failures = append(failures, fileFailures...)
+				continue
+			}
+			notes = append(notes, fileNotes...)
+			state.renameFrom(from)
+			continue
+		}
+		if fileNotes, fileFailures := state.apply(fp, opts); len(fileFailures) > 0 {
+			failures = append(failures, fileFailures...)
+		} else {
+			notes = append(notes, fileNotes...)
+		}
+	}
+	if len(failures) > 0 {
+		return ErrorResult(fmt.Sprintf("Patch not applied, no files were changed.\n\n%s", strings.Join(failures, "\n\n")))
+	}
+
+	summary := make([]string, 0, len(order))
+	for _, s := range order {
+		if line := s.summary(); line != "" {
+			summary = append(summary, line)
+		}
+	}
+	if dryRun {
+		return SilentResult(fmt.Sprintf("Patch applies cleanly (dry run): %s%s", strings.Join(summary, ", "), formatPatchNotes(notes)))
+	}
+	if err := commitPatchedFiles(order); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to write patch, changes were rolled back: %v", err))
+	}
+	return SilentResult(fmt.Sprintf("Patch applied: %s%s", strings.Join(summary, ", "), formatPatchNotes(notes)))
+}
+
+func formatPatchNotes(notes []string) string {
+	if len(notes) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(notes, "\n")
+}
+
+type patchOptions struct {
+	fuzz             int
+	ignoreWhitespace bool
+}
+
+// filePatch is the diff of one file.
+type filePatch struct {
+	oldPath, newPath string
+	hunks            []*hunk
+}
+
+func (fp *filePatch) isCreate() bool { return fp.oldPath == "/dev/null" }
+func (fp *filePatch) isDelete() bool { return fp.newPath == "/dev/null" }
+
+// hunk is one @@ section. lines keep their ' ', '-' or '+' prefix.
+type hunk struct {
+	header     string
+	oldStart   int
+	lines      []string
+	noEOLOld   bool // "\ No newline at end of file" after the old side
+	noEOLNew   bool
+	sourceLine int // line of the header in the patch, for error messages
+}
+
+func (h *hunk) side(keep byte) []string {
+	var out []string
+	for _, l := range h.lines {
+		if l[0] == ' ' || l[0] == keep {
+			out = append(out, l[1:])
+		}
+	}
+	return out
+}
+
+// parsePatch splits a unified diff into per-file patches. Headers other
+// than ---/+++/@@ (diff --git, index, mode lines) are ignored.
+func parsePatch(text string) ([]*filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var files []*filePatch
+	var cur *filePatch
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			cur = &filePatch{
+				oldPath: patchPath(line[4:], "a/"),
+				newPath: patchPath(lines[i+1][4:], "b/"),
+			}
+			if cur.oldPath == "/dev/null" && cur.newPath == "/dev/null" {
+				return nil, fmt.Errorf("line %d: both sides are /dev/null", i+1)
+			}
+			files = append(files, cur)
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+			}
+			h := &hunk{header: line, oldStart: parseHunkStart(line), sourceLine: i + 1}
+			for i+1 < len(lines) {
+				next := lines[i+1]
+				if strings.HasPrefix(next, "@@") || strings.HasPrefix(next, "diff ") || strings.HasPrefix(next, "```") ||
+					(strings.HasPrefix(next, "--- ") && i+2 < len(lines) && strings.HasPrefix(lines[i+2], "+++ ")) {
+					break
+				}
+				i++
+				switch {
+				case next == "":
+					// Editors and models often strip the space of empty context lines.
+					h.lines = append(h.lines, " ")
+				case next[0] == ' ' || next[0] == '-' || next[0] == '+':
+					h.lines = append(h.lines, next)
+				case next[0] == '\\':
+					if len(h.lines) > 0 {
+						switch h.lines[len(h.lines)-1][0] {
+						case '-':
+							h.noEOLOld = true
+						case '+':
+							h.noEOLNew = true
+						default:
+							h.noEOLOld, h.noEOLNew = true, true
+						}
+					}
+				default:
+					return nil, fmt.Errorf("line %d: unexpected line in hunk: %q", i+1, next)
+				}
+			}
+			for len(h.lines) > 0 && h.lines[len(h.lines)-1] == " " {
+				h.lines = h.lines[:len(h.lines)-1]
+			}
+			if len(h.lines) == 0 {
+				return nil, fmt.Errorf("line %d: empty hunk", h.sourceLine)
+			}
+			cur.hunks = append(cur.hunks, h)
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no ---/+++ file headers found")
+	}
+	for _, fp := range files {
+		if len(fp.hunks) == 0 {
+			return nil, fmt.Errorf("%s: no hunks", fp.newPath)
+		}
+	}
+	return files, nil
+}
+
+// patchPath strips the timestamp and the git a/ or b/ prefix from a file
+// header.
+func patchPath(s, prefix string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if unquoted, err := strconv.Unquote(s); err == nil && strings.HasPrefix(s, `"`) {
+		s = unquoted
+	}
+	if s == "/dev/null" {
+		return s
+	}
+	return strings.TrimPrefix(s, prefix)
+}
+
+// parseHunkStart returns the old start line of "@@ -l,s +l,s @@", or 0 if
+// the header has no line numbers.
+func parseHunkStart(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 2 || !strings.HasPrefix(fields[1], "-") {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.SplitN(fields[1][1:], ",", 2)[0])
+	return n
+}
+
+// patchedFile is the in-memory state of a file while a patch is applied.
+type patchedFile struct {
+	path, name string
+	renamed    string // old name when this file was created by a rename
+	movedTo    string // new name when this file was renamed away
+	existed    bool
+	original   []byte
+	mode       os.FileMode
+	lines      []string
+	eol        string
+	finalEOL   bool
+	exists     bool
+	hunks      int
+}
+
+func loadPatchedFile(path, name string) (*patchedFile, error) {
+	f := &patchedFile{path: path, name: name, mode: 0644, eol: "\n", finalEOL: true}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("is a directory")
+	}
+	f.existed, f.exists, f.original, f.mode = true, true, data, info.Mode().Perm()
+	text := string(data)
+	if strings.Contains(text, "\r\n") {
+		f.eol = "\r\n"
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	f.finalEOL = text == "" || strings.HasSuffix(text, "\n")
+	text = strings.TrimSuffix(text, "\n")
+	if text != "" {
+		f.lines = strings.Split(text, "\n")
+	}
+	return f, nil
+}
+
+// apply applies one file's hunks, returning notes about offsets and fuzz,
+// or a failure report per hunk that did not apply.
+func (f *patchedFile) apply(fp *filePatch, opts patchOptions) (notes, failures []string) {
+	switch {
+	case fp.isCreate() && f.exists:
+		return nil, []string{fmt.Sprintf("%s: cannot create, file already exists", f.name)}
+	case fp.isCreate():
+		for _, h := range fp.hunks {
+			f.lines = append(f.lines, h.side('+')...)
+			f.finalEOL = !h.noEOLNew
+		}
+		f.exists = true
+		f.hunks += len(fp.hunks)
+		return nil, nil
+	case !f.exists:
+		return nil, []string{fmt.Sprintf("%s: file not found", f.name)}
+	}
+
+	lines := f.lines
+	finalEOL := f.finalEOL
+	offset, minPos := 0, 0
+	for n, h := range fp.hunks {
+		label := fmt.Sprintf("%s: hunk %d (%s)", f.name, n+1, h.header)
+		m, ok := findHunk(lines, h, h.oldStart-1+offset, minPos, opts)
+		if !ok {
+			failures = append(failures, label+" failed: "+describeMismatch(lines, h, h.oldStart-1+offset))
+			continue
+		}
+		var parts []string
+		if shift := m.pos - m.trimmed - (h.oldStart - 1 + offset); h.oldStart > 0 && shift != 0 {
+			parts = append(parts, fmt.Sprintf("offset %+d lines", shift))
+		}
+		if m.fuzz > 0 {
+			parts = append(parts, fmt.Sprintf("fuzz %d", m.fuzz))
+		}
+		if m.whitespace {
+			parts = append(parts, "ignoring whitespace")
+		}
+		if len(parts) > 0 {
+			notes = append(notes, fmt.Sprintf("%s applied with %s", label, strings.Join(parts, ", ")))
+		}
+
+		// Context lines keep the file's text; only +/- lines change.
+		var replacement []string
+		at := m.pos
+		for _, l := range m.lines {
+			switch l[0] {
+			case ' ':
+				replacement = append(replacement, lines[at])
+				at++
+			case '-':
+				at++
+			case '+':
+				replacement = append(replacement, l[1:])
+			}
+		}
+		end := m.pos + m.oldLen
+		if end == len(lines) && (h.noEOLOld || h.noEOLNew) {
+			finalEOL = !h.noEOLNew
+		}
+		lines = append(append(append([]string(nil), lines[:m.pos]...), replacement...), lines[end:]...)
+		minPos = m.pos + len(replacement)
+		offset += len(replacement) - m.oldLen
+	}
+	if len(failures) > 0 {
+		return nil, failures
+	}
+
+	if fp.isDelete() {
+		if len(lines) > 0 {
+			return nil, []string{fmt.Sprintf("%s: cannot delete, %d lines would remain after removing the hunks", f.name, len(lines))}
+		}
+		f.exists = false
+	}
+	f.lines, f.finalEOL = lines, finalEOL
+	f.hunks += len(fp.hunks)
+	return notes, nil
+}
+
+// hunkMatch is where a hunk matched. lines are the hunk lines left after
+// dropping unmatched edge context.
+type hunkMatch struct {
+	pos, oldLen int
+	lines       []string
+	trimmed     int // leading context lines dropped
+	fuzz        int
+	whitespace  bool
+}
+
+// findHunk looks for the hunk's old side nearest to hint, not before
+// minPos. It tries exact matches before whitespace-insensitive ones and
+// fuzzier ones.
+func findHunk(lines []string, h *hunk, hint, minPos int, opts patchOptions) (hunkMatch, bool) {
+	lead, trail := 0, 0
+	for lead < len(h.lines) && h.lines[lead][0] == ' ' {
+		lead++
+	}
+	for trail < len(h.lines)-lead && h.lines[len(h.lines)-1-trail][0] == ' ' {
+		trail++
+	}
+	for fuzz := 0; fuzz <= opts.fuzz; fuzz++ {
+		dropLead, dropTrail := min(fuzz, lead), min(fuzz, trail)
+		if fuzz > 0 && dropLead+dropTrail == 0 {
+			break
+		}
+		trimmed := h.lines[dropLead : len(h.lines)-dropTrail]
+		var old []string
+		for _, l := range trimmed {
+			if l[0] != '+' {
+				old = append(old, l[1:])
+			}
+		}
+		for _, ws := range []bool{false, true} {
+			if ws && !opts.ignoreWhitespace {
+				continue
+			}
+			if pos, ok := nearestMatch(lines, old, hint+dropLead, minPos, ws); ok {
+				return hunkMatch{pos: pos, oldLen: len(old), lines: trimmed, trimmed: dropLead, fuzz: max(dropLead, dropTrail), whitespace: ws}, true
+			}
+		}
+	}
+	return hunkMatch{}, false
+}
+
+func nearestMatch(lines, old []string, hint, minPos int, ignoreWS bool) (int, bool) {
+	last := len(lines) - len(old)
+	if last < minPos {
+		return 0, false
+	}
+	hint = max(minPos, min(hint, last))
+	for d := 0; hint-d >= minPos || hint+d <= last; d++ {
+		if p := hint - d; p >= minPos && linesMatch(lines[p:p+len(old)], old, ignoreWS) {
+			return p, true
+		}
+		if p := hint + d; d > 0 && p <= last && linesMatch(lines[p:p+len(old)], old, ignoreWS) {
+			return p, true
+		}
+	}
+	return 0, false
+}
+
+func linesMatch(a, b []string, ignoreWS bool) bool {
+	for i := range b {
+		if a[i] != b[i] && (!ignoreWS || !sameIgnoringWhitespace(a[i], b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameIgnoringWhitespace(a, b string) bool {
+	return strings.Join(strings.Fields(a), " ") == strings.Join(strings.Fields(b), " ")
+}
+
+// describeMismatch reports what the hunk expected and the closest lines
+// found in the file, so the patch can be corrected.
+func describeMismatch(lines []string, h *hunk, hint int) string {
+	old := h.side('-')
+	var sb strings.Builder
+	sb.WriteString("context not found")
+	if h.oldStart > 0 {
+		fmt.Fprintf(&sb, " near line %d", h.oldStart)
+	}
+	sb.WriteString(".\nExpected:\n")
+	for _, l := range old {
+		sb.WriteString("  |" + l + "\n")
+	}
+
+	best, bestScore := -1, 0
+	for p := 0; p < max(1, len(lines)-len(old)+1) && len(lines) > 0; p++ {
+		score := 0
+		for i := range old {
+			if p+i < len(lines) && sameIgnoringWhitespace(lines[p+i], old[i]) {
+				score++
+			}
+		}
+		if score > bestScore || (score == bestScore && score > 0 && abs(p-hint) < abs(best-hint)) {
+			best, bestScore = p, score
+		}
+	}
+	if best < 0 {
+		sb.WriteString("No similar lines in the file.")
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "Closest match at line %d (%d of %d lines match):\n", best+1, bestScore, len(old))
+	for i := range old {
+		if best+i >= len(lines) {
+			break
+		}
+		mark := " "
+		if !sameIgnoringWhitespace(lines[best+i], old[i]) {
+			mark = "!"
+		}
+		fmt.Fprintf(&sb, "%s %d|%s\n", mark, best+i+1, lines[best+i])
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func (f *patchedFile) content() []byte {
+	if len(f.lines) == 0 {
+		return nil
+	}
+	text := strings.Join(f.lines, f.eol)
+	if f.finalEOL {
+		text += f.eol
+	}
+	return []byte(text)
+}
+
+// renameFrom moves the patched content of from to f and removes from.
+func (f *patchedFile) renameFrom(from *patchedFile) {
+	f.lines, f.eol, f.finalEOL, f.mode = from.lines, from.eol, from.finalEOL, from.mode
+	f.exists, f.hunks, f.renamed = true, f.hunks+from.hunks, from.name
+	from.lines, from.exists, from.hunks, from.movedTo = nil, false, 0, f.name
+}
+
+func (f *patchedFile) summary() string {
+	if f.movedTo != "" && !f.exists {
+		return "" // reported under the new name
+	}
+	kind := "M"
+	switch {
+	case f.renamed != "" && !f.existed:
+		kind = "R " + f.renamed + " ->"
+	case !f.existed && f.exists:
+		kind = "A"
+	case f.existed && !f.exists:
+		kind = "D"
+	}
+	plural := "s"
+	if f.hunks == 1 {
+		plural = ""
+	}
+	return fmt.Sprintf("%s %s (%d hunk%s)", kind, f.name, f.hunks, plural)
+}
+
+// commitPatchedFiles writes every file through a temp file and rename,
+// restoring the originals if any write fails.
+func commitPatchedFiles(files []*patchedFile) error {
+	var done []*patchedFile
+	rollback := func() {
+		for _, f := range done {
+			if f.existed {
+				os.WriteFile(f.path, f.original, f.mode)
+			} else {
+				os.Remove(f.path)
+			}
+		}
+	}
+	for _, f := range files {
+		var err error
+		switch {
+		case !f.exists && !f.existed:
+			continue
+		case !f.exists:
+			err = os.Remove(f.path)
+		default:
+			err = writeFileAtomic(f.path, f.content(), f.mode)
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+		done = append(done, f)
+	}
+	return nil
+}
+
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".patch-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
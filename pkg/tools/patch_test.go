@@ -0,0 +1,171 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func applyPatch(t *testing.T, workspace, patch string, extra map[string]interface{}) *ToolResult {
+	t.Helper()
+	args := map[string]interface{}{"patch": patch}
+	for k, v := range extra {
+		args[k] = v
+	}
+	return NewApplyPatchTool(workspace, true).Execute(context.Background(), args)
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestApplyPatch_MultiFile(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "main.go"), []byte("package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n\nfunc helper() {\n\treturn\n}\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "old.txt"), []byte("bye\n"), 0644)
+
+	// Line numbers are off by two and one context line has drifted.
+	patch := "diff --git a/main.go b/main.go\n" +
+		"--- a/main.go\n+++ b/main.go\n" +
+		"@@ -3,5 +3,5 @@\n import \"fmt\"\n \n func main() {\n-\tfmt.Println(\"hi\")\n+\tfmt.Println(\"hello\")\n }\n" +
+		"@@ -11,3 +11,4 @@\n func helper() {\n+\t// nothing to do\n   return\n }\n" +
+		"--- /dev/null\n+++ b/docs/new.md\n@@ -0,0 +1,2 @@\n+# New\n+text\n" +
+		"--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"
+
+	result := applyPatch(t, ws, patch, nil)
+	if result.IsError {
+		t.Fatalf("patch failed: %s", result.ForLLM)
+	}
+	for _, want := range []string{"M main.go (2 hunks)", "A docs/new.md (1 hunk)", "D old.txt (1 hunk)", "hunk 2", "ignoring whitespace"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("result missing %q: %s", want, result.ForLLM)
+		}
+	}
+
+	want := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n\nfunc helper() {\n\t// nothing to do\n\treturn\n}\n"
+	if got := readFile(t, filepath.Join(ws, "main.go")); got != want {
+		t.Errorf("main.go =\n%s", got)
+	}
+	if got := readFile(t, filepath.Join(ws, "docs", "new.md")); got != "# New\ntext\n" {
+		t.Errorf("new.md = %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt should have been deleted")
+	}
+}
+
+func TestApplyPatch_AtomicFailureReport(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "a.txt"), []byte("one\ntwo\nthree\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "b.txt"), []byte("alpha\nbeta\ngamma\ndelta\n"), 0644)
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -2,2 +2,2 @@\n beta\n-GAMMA RAY\n+gamma\n"
+
+	result := applyPatch(t, ws, patch, nil)
+	if !result.IsError {
+		t.Fatalf("expected failure, got: %s", result.ForLLM)
+	}
+	for _, want := range []string{"no files were changed", "b.txt: hunk 1 (@@ -2,2 +2,2 @@) failed", "Closest match at line 2 (1 of 2 lines match)", "! 3|gamma"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("report missing %q:\n%s", want, result.ForLLM)
+		}
+	}
+	if got := readFile(t, filepath.Join(ws, "a.txt")); got != "one\ntwo\nthree\n" {
+		t.Errorf("a.txt was modified despite the failure: %q", got)
+	}
+}
+
+func TestApplyPatch_FuzzAndOptions(t *testing.T) {
+	ws := t.TempDir()
+	path := filepath.Join(ws, "f.txt")
+	original := "a\nb\nc\nd\ne\n"
+	os.WriteFile(path, []byte(original), 0644)
+
+	// The first context line does not exist in the file.
+	patch := "--- f.txt\n+++ f.txt\n@@ -1,3 +1,3 @@\n zzz\n b\n-c\n+C\n d\n"
+	if result := applyPatch(t, ws, patch, map[string]interface{}{"fuzz": float64(0)}); !result.IsError {
+		t.Errorf("fuzz 0 should reject a mismatched context line: %s", result.ForLLM)
+	}
+	result := applyPatch(t, ws, patch, map[string]interface{}{"dry_run": true})
+	if result.IsError || !strings.Contains(result.ForLLM, "dry run") || !strings.Contains(result.ForLLM, "fuzz 1") {
+		t.Errorf("dry run = %s", result.ForLLM)
+	}
+	if got := readFile(t, path); got != original {
+		t.Errorf("dry run modified the file: %q", got)
+	}
+
+	if result := applyPatch(t, ws, patch, nil); result.IsError {
+		t.Fatalf("fuzzy apply failed: %s", result.ForLLM)
+	}
+	if got := readFile(t, path); got != "a\nb\nC\nd\ne\n" {
+		t.Errorf("f.txt = %q", got)
+	}
+
+	if result := applyPatch(t, ws, "--- /dev/null\n+++ f.txt\n@@ -0,0 +1 @@\n+x\n", nil); !result.IsError {
+		t.Error("creating an existing file should fail")
+	}
+	if result := applyPatch(t, ws, "--- a/../x\n+++ b/../x\n@@ -1 +1 @@\n-a\n+b\n", nil); !result.IsError || !strings.Contains(result.ForLLM, "outside the workspace") {
+		t.Errorf("expected a workspace violation: %s", result.ForLLM)
+	}
+}
+
+func TestApplyPatch_LineEndings(t *testing.T) {
+	ws := t.TempDir()
+	path := filepath.Join(ws, "win.txt")
+	os.WriteFile(path, []byte("one\r\ntwo\r\n"), 0644)
+	if result := applyPatch(t, ws, "--- win.txt\n+++ win.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+three\n\\ No newline at end of file\n", nil); result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	if got := readFile(t, path); got != "one\r\nthree" {
+		t.Errorf("win.txt = %q", got)
+	}
+}
+
+func TestApplyPatch_Rename(t *testing.T) {
+	ws := t.TempDir()
+	os.WriteFile(filepath.Join(ws, "old.go"), []byte("package a\n\nfunc Old() {}\n"), 0600)
+	os.WriteFile(filepath.Join(ws, "conf.yaml"), []byte("a: 1\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "conf.yaml.orig"), []byte("a: 1\n"), 0644)
+
+	patch := "diff --git a/old.go b/pkg/new.go\n--- a/old.go\n+++ b/pkg/new.go\n" +
+		"@@ -1,3 +1,3 @@\n package a\n \n-func Old() {}\n+func New() {}\n" +
+		"--- conf.yaml.orig\n+++ conf.yaml\n@@ -1 +1 @@\n-a: 1\n+a: 2\n"
+
+	result := applyPatch(t, ws, patch, map[string]interface{}{"dry_run": true})
+	if result.IsError || !strings.Contains(result.ForLLM, "R old.go -> pkg/new.go (1 hunk)") {
+		t.Fatalf("dry run: %s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "pkg", "new.go")); !os.IsNotExist(err) {
+		t.Fatal("dry run should not write")
+	}
+
+	result = applyPatch(t, ws, patch, nil)
+	if result.IsError {
+		t.Fatalf("rename failed: %s", result.ForLLM)
+	}
+	if got := readFile(t, filepath.Join(ws, "pkg", "new.go")); got != "package a\n\nfunc New() {}\n" {
+		t.Errorf("new.go = %q", got)
+	}
+	if info, err := os.Stat(filepath.Join(ws, "pkg", "new.go")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("renamed file should keep its mode: %v", info)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "old.go")); !os.IsNotExist(err) {
+		t.Error("old.go should be gone after the rename")
+	}
+	if readFile(t, filepath.Join(ws, "conf.yaml")) != "a: 2\n" || readFile(t, filepath.Join(ws, "conf.yaml.orig")) != "a: 1\n" {
+		t.Error("a diff against a backup file should patch the new path only")
+	}
+
+	result = applyPatch(t, ws, "--- a/missing.go\n+++ b/other.go\n@@ -1 +1 @@\n-x\n+y\n", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "other.go: file not found") {
+		t.Errorf("rename of a missing file should fail: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,589 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProcessManagerOptions bounds background processes. Zero values pick the
+// defaults.
+type ProcessManagerOptions struct {
+	MaxPerSession int           // running processes per session, default 4
+	BufferSize    int           // output bytes kept per process, default 256 KiB
+	IdleTimeout   time.Duration // sessions unused this long are ended, default 1h
+}
+
+// ProcessManager runs background commands for the process tool. Processes
+// belong to the session (channel and chat) that started them. Chats have no
+// explicit end, so processes are killed after IdleTimeout without process
+// tool calls, or when the manager is closed at shutdown; EndSession kills
+// them sooner.
+type ProcessManager struct {
+	opts ProcessManagerOptions
+
+	mu       sync.Mutex
+	sessions map[string]*processSession
+	nextID   int
+	closed   bool
+	stop     chan struct{}
+}
+
+type processSession struct {
+	procs    map[string]*managedProcess
+	lastUsed time.Time
+}
+
+type managedProcess struct {
+	id      string
+	command string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	output  *ringBuffer
+	started time.Time
+	done    chan struct{}
+
+	mu       sync.Mutex
+	ended    time.Time
+	exitCode int
+	exitErr  error
+	readPos  int64 // where the next read without an offset starts
+}
+
+// NewProcessManager creates a manager and starts its idle-session reaper.
+func NewProcessManager(opts ProcessManagerOptions) *ProcessManager {
+	if opts.MaxPerSession <= 0 {
+		opts.MaxPerSession = 4
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 256 * 1024
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = time.Hour
+	}
+	m := &ProcessManager{
+		opts:     opts,
+		sessions: make(map[string]*processSession),
+		stop:     make(chan struct{}),
+	}
+	go m.reapIdle()
+	return m
+}
+
+func (m *ProcessManager) reapIdle() {
+	interval := m.opts.IdleTimeout / 4
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			var idle []string
+			m.mu.Lock()
+			for key, s := range m.sessions {
+				if now.Sub(s.lastUsed) > m.opts.IdleTimeout {
+					idle = append(idle, key)
+				}
+			}
+			m.mu.Unlock()
+			for _, key := range idle {
+				m.EndSession(key)
+			}
+		}
+	}
+}
+
+// session returns the session, creating it and marking it used.
+func (m *ProcessManager) session(key string) *processSession {
+	s, ok := m.sessions[key]
+	if !ok {
+		s = &processSession{procs: make(map[string]*managedProcess)}
+		m.sessions[key] = s
+	}
+	s.lastUsed = time.Now()
+	return s
+}
+
+// start launches cmd in the background. onExit, if set, runs once the
+// process has exited.
+func (m *ProcessManager) start(sessionKey, command string, cmd *exec.Cmd, onExit func(*managedProcess)) (*managedProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, errors.New("process manager is shut down")
+	}
+
+	s := m.session(sessionKey)
+	running := 0
+	for _, p := range s.procs {
+		if !p.exited() {
+			running++
+		}
+	}
+	if running >= m.opts.MaxPerSession {
+		return nil, fmt.Errorf("limit of %d running processes reached; kill one first", m.opts.MaxPerSession)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	output := newRingBuffer(m.opts.BufferSize)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// Don't let a grandchild holding the pipes open keep Wait blocked.
+	cmd.WaitDelay = 5 * time.Second
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	m.nextID++
+	p := &managedProcess{
+		id:      fmt.Sprintf("p%d", m.nextID),
+		command: command,
+		cmd:     cmd,
+		stdin:   stdin,
+		output:  output,
+		started: time.Now(),
+		done:    make(chan struct{}),
+	}
+	m.evictExited(s)
+	s.procs[p.id] = p
+
+	go func() {
+		err := cmd.Wait()
+		p.mu.Lock()
+		p.ended = time.Now()
+		p.exitErr = err
+		p.exitCode = cmd.ProcessState.ExitCode()
+		p.mu.Unlock()
+		close(p.done)
+		if onExit != nil {
+			onExit(p)
+		}
+	}()
+	return p, nil
+}
+
+// evictExited keeps the number of finished processes a session remembers
+// bounded, dropping the oldest first.
+func (m *ProcessManager) evictExited(s *processSession) {
+	var exited []*managedProcess
+	for _, p := range s.procs {
+		if p.exited() {
+			exited = append(exited, p)
+		}
+	}
+	keep := m.opts.MaxPerSession * 2
+	if len(exited) < keep {
+		return
+	}
+	sort.Slice(exited, func(i, j int) bool { return exited[i].started.Before(exited[j].started) })
+	for _, p := range exited[:len(exited)-keep+1] {
+		delete(s.procs, p.id)
+	}
+}
+
+// get returns a process of the session.
+func (m *ProcessManager) get(sessionKey, id string) (*managedProcess, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.session(sessionKey).procs[id]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("no process %q in this session", id)
+}
+
+// list returns the session's processes, oldest first.
+func (m *ProcessManager) list(sessionKey string) []*managedProcess {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*managedProcess
+	for _, p := range m.session(sessionKey).procs {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].started.Before(out[j].started) })
+	return out
+}
+
+// EndSession kills the session's processes and forgets them.
+func (m *ProcessManager) EndSession(sessionKey string) {
+	m.mu.Lock()
+	s := m.sessions[sessionKey]
+	delete(m.sessions, sessionKey)
+	m.mu.Unlock()
+	if s == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, p := range s.procs {
+		wg.Add(1)
+		go func(p *managedProcess) {
+			defer wg.Done()
+			p.kill(killSignalTerm)
+		}(p)
+	}
+	wg.Wait()
+}
+
+// Close kills every process and stops the reaper.
+func (m *ProcessManager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.stop)
+	keys := make([]string, 0, len(m.sessions))
+	for key := range m.sessions {
+		keys = append(keys, key)
+	}
+	m.mu.Unlock()
+	for _, key := range keys {
+		m.EndSession(key)
+	}
+}
+
+func (p *managedProcess) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// kill signals the process group and escalates to SIGKILL if it is still
+// running after a grace period.
+func (p *managedProcess) kill(sig killSignal) {
+	if p.exited() {
+		return
+	}
+	signalProcessGroup(p.cmd, sig)
+	if sig == killSignalKill {
+		return
+	}
+	select {
+	case <-p.done:
+	case <-time.After(3 * time.Second):
+		signalProcessGroup(p.cmd, killSignalKill)
+	}
+}
+
+// status describes the process in one line.
+func (p *managedProcess) status() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state := fmt.Sprintf("running for %s", time.Since(p.started).Round(time.Second))
+	if !p.ended.IsZero() {
+		state = fmt.Sprintf("exited with code %d after %s", p.exitCode, p.ended.Sub(p.started).Round(time.Second))
+	}
+	return fmt.Sprintf("%s: %s, pid %d, %d bytes of output, command: %s",
+		p.id, state, p.cmd.Process.Pid, p.output.Total(), p.command)
+}
+
+// ringBuffer keeps the last size bytes written to it. Offsets count every
+// byte ever written, so readers can resume where they stopped and learn
+// how much was dropped.
+type ringBuffer struct {
+	mu    sync.Mutex
+	data  []byte
+	size  int
+	total int64
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{size: size}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := len(p)
+	b.total += int64(n)
+	if n >= b.size {
+		b.data = append(b.data[:0], p[n-b.size:]...)
+		return n, nil
+	}
+	if over := len(b.data) + n - b.size; over > 0 {
+		b.data = append(b.data[:0], b.data[over:]...)
+	}
+	b.data = append(b.data, p...)
+	return n, nil
+}
+
+// Total returns the number of bytes ever written.
+func (b *ringBuffer) Total() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
+// ReadAt returns up to max bytes starting at offset, the offset after them
+// and how many bytes before the returned data were already dropped.
+func (b *ringBuffer) ReadAt(offset int64, max int) (data []byte, next int64, dropped int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	start := b.total - int64(len(b.data))
+	if offset < start {
+		dropped = start - offset
+		offset = start
+	}
+	if offset > b.total {
+		offset = b.total
+	}
+	chunk := b.data[offset-start:]
+	if max > 0 && len(chunk) > max {
+		chunk = chunk[:max]
+	}
+	return append([]byte(nil), chunk...), offset + int64(len(chunk)), dropped
+}
+
+// ProcessTool starts and manages background commands. Commands go through
+// the exec tool's guards, policy and sandbox.
+type ProcessTool struct {
+	manager *ProcessManager
+	exec    *ExecTool
+}
+
+// NewProcessTool creates the tool. runner is the registry's exec tool.
+func NewProcessTool(manager *ProcessManager, runner *ExecTool) *ProcessTool {
+	return &ProcessTool{manager: manager, exec: runner}
+}
+
+func (t *ProcessTool) Name() string {
+	return "process"
+}
+
+func (t *ProcessTool) Description() string {
+	return "Run long-running shell commands in the background (builds, dev servers, tail -f). " +
+		"'start' returns a process id right away and you are notified when it exits; " +
+		"'read' returns new output since the last read (or from 'offset'); 'write' sends stdin; " +
+		"'status', 'list' and 'kill' manage processes. Use exec instead for quick commands."
+}
+
+func (t *ProcessTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"start", "status", "read", "write", "kill", "list"},
+				"description": "Action to perform",
+			},
+			"command": map[string]interface{}{
+				"type":        "string",
+				"description": "Shell command to start (start)",
+			},
+			"working_dir": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional working directory (start)",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Process id returned by start (status, read, write, kill)",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Byte offset to read from; omit to continue after the previous read (read)",
+			},
+			"max_bytes": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum bytes to return, default 8000 (read)",
+			},
+			"input": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to send to stdin; include \\n to end a line (write)",
+			},
+			"eof": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Close stdin after writing (write)",
+			},
+			"signal": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"TERM", "KILL", "INT"},
+				"description": "Signal to send, default TERM (kill)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ProcessTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	return t.ExecuteAsync(ctx, args, nil)
+}
+
+// ExecuteAsync implements AsyncExecutor; cb is told when a started
+// process exits.
+func (t *ProcessTool) ExecuteAsync(ctx context.Context, args map[string]interface{}, cb AsyncCallback) *ToolResult {
+	action, _ := args["action"].(string)
+	session := ToolChannel(ctx) + ":" + ToolChatID(ctx)
+
+	if action == "start" {
+		return t.start(ctx, session, args, cb)
+	}
+	if action == "list" {
+		procs := t.manager.list(session)
+		if len(procs) == 0 {
+			return SilentResult("No background processes in this session")
+		}
+		lines := make([]string, 0, len(procs))
+		for _, p := range procs {
+			lines = append(lines, p.status())
+		}
+		return SilentResult(strings.Join(lines, "\n"))
+	}
+
+	id, _ := args["id"].(string)
+	if id == "" {
+		if action != "status" && action != "read" && action != "write" && action != "kill" {
+			return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+		}
+		return ErrorResult("id is required")
+	}
+	p, err := t.manager.get(session, id)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+
+	switch action {
+	case "status":
+		return SilentResult(p.status())
+	case "read":
+		return t.read(p, args)
+	case "write":
+		input, _ := args["input"].(string)
+		eof, _ := args["eof"].(bool)
+		if p.exited() {
+			return ErrorResult(fmt.Sprintf("process %s has exited", p.id))
+		}
+		if input != "" {
+			if _, err := io.WriteString(p.stdin, input); err != nil {
+				return ErrorResult(fmt.Sprintf("writing to %s: %v", p.id, err))
+			}
+		}
+		if eof {
+			p.stdin.Close()
+		}
+		return SilentResult(fmt.Sprintf("Wrote %d bytes to %s", len(input), p.id))
+	case "kill":
+		sig := killSignalTerm
+		switch args["signal"] {
+		case "KILL":
+			sig = killSignalKill
+		case "INT":
+			sig = killSignalInt
+		}
+		p.kill(sig)
+		return SilentResult(p.status())
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+}
+
+func (t *ProcessTool) start(ctx context.Context, session string, args map[string]interface{}, cb AsyncCallback) *ToolResult {
+	command, _ := args["command"].(string)
+	if command == "" {
+		return ErrorResult("command is required for start")
+	}
+	cwd := t.exec.resolveWorkingDir(args)
+	policy := t.exec.resolvePolicy(ctx)
+	if guardError := t.exec.guardCommand(command, cwd, policy); guardError != "" {
+		return ErrorResult(guardError)
+	}
+
+	// The process outlives this tool call; the manager cancels it.
+	procCtx, cancel := context.WithCancel(context.Background())
+	cmd, err := t.exec.buildCommand(procCtx, command, cwd, policy)
+	if err != nil {
+		cancel()
+		return ErrorResult(fmt.Sprintf("Sandbox unavailable: %v", err))
+	}
+	cmd.Cancel = func() error {
+		signalProcessGroup(cmd, killSignalKill)
+		return nil
+	}
+
+	p, err := t.manager.start(session, command, cmd, func(p *managedProcess) {
+		cancel()
+		if cb != nil {
+			cb(ctx, processExitResult(p))
+		}
+	})
+	if err != nil {
+		cancel()
+		return ErrorResult(fmt.Sprintf("failed to start process: %v", err))
+	}
+	return AsyncResult(fmt.Sprintf("Started %s (pid %d): %s\nUse process read/status with id %q; you will be notified when it exits.",
+		p.id, p.cmd.Process.Pid, command, p.id))
+}
+
+func (t *ProcessTool) read(p *managedProcess, args map[string]interface{}) *ToolResult {
+	maxBytes := 8000
+	if v, ok := args["max_bytes"].(float64); ok && v > 0 {
+		maxBytes = int(v)
+	}
+	p.mu.Lock()
+	offset := p.readPos
+	p.mu.Unlock()
+	if v, ok := args["offset"].(float64); ok && v >= 0 {
+		offset = int64(v)
+	}
+
+	data, next, dropped := p.output.ReadAt(offset, maxBytes)
+	p.mu.Lock()
+	p.readPos = next
+	p.mu.Unlock()
+
+	var sb strings.Builder
+	if dropped > 0 {
+		fmt.Fprintf(&sb, "[%d earlier bytes were dropped from the buffer]\n", dropped)
+	}
+	if len(data) == 0 {
+		sb.WriteString("(no new output)\n")
+	} else {
+		sb.Write(data)
+		if data[len(data)-1] != '\n' {
+			sb.WriteByte('\n')
+		}
+	}
+	total := p.output.Total()
+	state := "running"
+	if p.exited() {
+		state = "exited"
+	}
+	fmt.Fprintf(&sb, "[%s, next_offset %d of %d", state, next, total)
+	if next < total {
+		fmt.Fprintf(&sb, ", %d more bytes available", total-next)
+	}
+	sb.WriteString("]")
+	return SilentResult(sb.String())
+}
+
+// processExitResult is what the agent is told when a process exits.
+func processExitResult(p *managedProcess) *ToolResult {
+	const tail = 2000
+	total := p.output.Total()
+	from := total - tail
+	if from < 0 {
+		from = 0
+	}
+	data, _, _ := p.output.ReadAt(from, tail)
+
+	msg := fmt.Sprintf("Background process %s finished. %s", p.id, p.status())
+	if len(data) > 0 {
+		msg += fmt.Sprintf("\nLast output:\n%s", strings.TrimRight(string(data), "\n"))
+	}
+	return &ToolResult{ForLLM: msg, Silent: true}
+}
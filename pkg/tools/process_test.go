@@ -0,0 +1,152 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRingBuffer_ReadAt(t *testing.T) {
+	b := newRingBuffer(8)
+	b.Write([]byte("hello "))
+	b.Write([]byte("world"))
+
+	data, next, dropped := b.ReadAt(0, 0)
+	if string(data) != "lo world" || next != 11 || dropped != 3 {
+		t.Errorf("ReadAt(0) = %q, %d, %d", data, next, dropped)
+	}
+	data, next, dropped = b.ReadAt(6, 3)
+	if string(data) != "wor" || next != 9 || dropped != 0 {
+		t.Errorf("ReadAt(6, 3) = %q, %d, %d", data, next, dropped)
+	}
+	if data, next, _ = b.ReadAt(11, 0); len(data) != 0 || next != 11 {
+		t.Errorf("ReadAt(end) = %q, %d", data, next)
+	}
+
+	b.Write([]byte("0123456789"))
+	if data, _, _ = b.ReadAt(0, 0); string(data) != "23456789" || b.Total() != 21 {
+		t.Errorf("after an oversized write: %q, total %d", data, b.Total())
+	}
+}
+
+func newTestProcessTool(t *testing.T, maxPerSession int) *ProcessTool {
+	t.Helper()
+	manager := NewProcessManager(ProcessManagerOptions{MaxPerSession: maxPerSession})
+	t.Cleanup(manager.Close)
+	return NewProcessTool(manager, NewExecTool(t.TempDir(), false))
+}
+
+// startedID extracts the process id from a start result.
+func startedID(t *testing.T, result *ToolResult) string {
+	t.Helper()
+	if result.IsError || !result.Async {
+		t.Fatalf("start failed: %s", result.ForLLM)
+	}
+	return strings.Fields(result.ForLLM)[1]
+}
+
+func waitForOutput(t *testing.T, tool *ProcessTool, ctx context.Context, id, want string) string {
+	t.Helper()
+	var seen strings.Builder
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		result := tool.Execute(ctx, map[string]interface{}{"action": "read", "id": id})
+		seen.WriteString(result.ForLLM)
+		if strings.Contains(seen.String(), want) {
+			return seen.String()
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q, got: %s", want, seen.String())
+	return ""
+}
+
+func TestProcessTool_StartReadExit(t *testing.T) {
+	tool := newTestProcessTool(t, 4)
+	ctx := WithToolContext(context.Background(), "telegram", "chat1")
+
+	exited := make(chan *ToolResult, 1)
+	result := tool.ExecuteAsync(ctx, map[string]interface{}{
+		"action":  "start",
+		"command": "echo first; sleep 0.3; echo second",
+	}, func(_ context.Context, r *ToolResult) { exited <- r })
+	id := startedID(t, result)
+
+	waitForOutput(t, tool, ctx, id, "first")
+	out := waitForOutput(t, tool, ctx, id, "second")
+	if strings.Contains(out, "first") {
+		t.Errorf("incremental reads repeated output: %s", out)
+	}
+
+	select {
+	case r := <-exited:
+		if !strings.Contains(r.ForLLM, "exited with code 0") || !strings.Contains(r.ForLLM, "second") {
+			t.Errorf("exit notification = %s", r.ForLLM)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no exit notification")
+	}
+
+	full := tool.Execute(ctx, map[string]interface{}{"action": "read", "id": id, "offset": float64(0)})
+	if !strings.Contains(full.ForLLM, "first\nsecond") || !strings.Contains(full.ForLLM, "[exited, next_offset 13 of 13]") {
+		t.Errorf("read from offset 0 = %s", full.ForLLM)
+	}
+}
+
+func TestProcessTool_WriteAndKill(t *testing.T) {
+	tool := newTestProcessTool(t, 4)
+	ctx := WithToolContext(context.Background(), "cli", "direct")
+
+	id := startedID(t, tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "cat"}))
+	tool.Execute(ctx, map[string]interface{}{"action": "write", "id": id, "input": "ping\n"})
+	waitForOutput(t, tool, ctx, id, "ping")
+	tool.Execute(ctx, map[string]interface{}{"action": "write", "id": id, "eof": true})
+	waitForOutput(t, tool, ctx, id, "[exited")
+
+	id = startedID(t, tool.Execute(ctx, map[string]interface{}{"action": "start", "command": "sleep 30"}))
+	result := tool.Execute(ctx, map[string]interface{}{"action": "kill", "id": id})
+	if !strings.Contains(result.ForLLM, "exited with code -1") {
+		t.Errorf("kill result = %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "write", "id": id, "input": "x"}); !result.IsError {
+		t.Error("expected writing to an exited process to fail")
+	}
+}
+
+func TestProcessTool_SessionLimitsAndIsolation(t *testing.T) {
+	tool := newTestProcessTool(t, 2)
+	chat1 := WithToolContext(context.Background(), "telegram", "chat1")
+	chat2 := WithToolContext(context.Background(), "telegram", "chat2")
+	start := map[string]interface{}{"action": "start", "command": "sleep 30"}
+
+	id := startedID(t, tool.Execute(chat1, start))
+	startedID(t, tool.Execute(chat1, start))
+	if result := tool.Execute(chat1, start); !result.IsError || !strings.Contains(result.ForLLM, "limit of 2") {
+		t.Errorf("expected the per-session limit, got: %s", result.ForLLM)
+	}
+	startedID(t, tool.Execute(chat2, start))
+
+	if result := tool.Execute(chat2, map[string]interface{}{"action": "status", "id": id}); !result.IsError {
+		t.Errorf("chat2 could see chat1's process: %s", result.ForLLM)
+	}
+	list := tool.Execute(chat2, map[string]interface{}{"action": "list"})
+	if strings.Count(list.ForLLM, "sleep 30") != 1 {
+		t.Errorf("chat2 list = %s", list.ForLLM)
+	}
+
+	tool.manager.EndSession("telegram:chat1")
+	if result := tool.Execute(chat1, map[string]interface{}{"action": "list"}); !strings.Contains(result.ForLLM, "No background processes") {
+		t.Errorf("list after EndSession = %s", result.ForLLM)
+	}
+}
+
+func TestProcessTool_UsesExecGuard(t *testing.T) {
+	tool := newTestProcessTool(t, 4)
+	result := tool.Execute(context.Background(), map[string]interface{}{"action": "start", "command": "rm -rf /"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "blocked") {
+		t.Errorf("expected a dangerous command to be blocked, got: %s", result.ForLLM)
+	}
+}
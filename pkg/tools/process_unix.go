@@ -0,0 +1,34 @@
+//go:build !windows
+
+package tools
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+type killSignal = syscall.Signal
+
+const (
+	killSignalTerm = syscall.SIGTERM
+	killSignalKill = syscall.SIGKILL
+	killSignalInt  = syscall.SIGINT
+)
+
+// setProcessGroup puts the command in its own process group so signals
+// reach everything it spawned.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+func signalProcessGroup(cmd *exec.Cmd, sig killSignal) {
+	if cmd.Process == nil {
+		return
+	}
+	if err := syscall.Kill(-cmd.Process.Pid, sig); err != nil {
+		cmd.Process.Signal(sig)
+	}
+}
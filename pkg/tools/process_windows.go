@@ -0,0 +1,23 @@
+package tools
+
+import (
+	"os"
+	"os/exec"
+)
+
+type killSignal int
+
+// Windows has no signals to speak of; every kill terminates the process.
+const (
+	killSignalTerm killSignal = iota
+	killSignalKill
+	killSignalInt
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcessGroup(cmd *exec.Cmd, sig killSignal) {
+	if cmd.Process != nil {
+		cmd.Process.Signal(os.Kill)
+	}
+}
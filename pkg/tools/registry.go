@@ -3,29 +3,161 @@ package tools
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jasperan/picooraclaw/pkg/logger"
 	"github.com/jasperan/picooraclaw/pkg/providers"
 )
 
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools    map[string]Tool
+	outputs  *ToolOutputStore
+	settings map[string]ToolSettings
+	limits   map[string]toolLimits
+	known    map[string]bool           // names registered or asked about, enabled or not
+	pending  map[string][]*pendingCall // calls with a timeout that have not returned yet
+	mu       sync.RWMutex
+}
+
+// pendingCall is a call executeWithTimeout started. Guarded by mu.
+type pendingCall struct {
+	deadline  time.Time
+	abandoned bool // the caller stopped waiting before the tool returned
+}
+
+// overdue reports whether the call is past its deadline or abandoned.
+func (c *pendingCall) overdue(now time.Time) bool {
+	return c.abandoned || !now.Before(c.deadline)
+}
+
+type toolLimits struct {
+	timeout   time.Duration // enforced by the registry
+	maxOutput int
+}
+
+// ToolSettings configures a tool by name. Enabled false keeps the tool
+// from being registered. Timeout bounds each call: tools with their own
+// SetTimeout get it, others are cut off by the registry. A tool cut off
+// that way only stops if it honours its context; until the call returns,
+// further calls to the tool are refused so that, say, a retried hardware
+// write cannot overlap one still in flight. MaxOutput caps the result sent
+// to the model. Options go to tools implementing ConfigurableTool.
+type ToolSettings struct {
+	Enabled   *bool
+	Timeout   time.Duration
+	MaxOutput int
+	Options   map[string]interface{}
+}
+
+// ConfigurableTool is a tool that takes tool-specific options from config.
+// A tool whose options are invalid is not registered.
+type ConfigurableTool interface {
+	Tool
+	Configure(options map[string]interface{}) error
 }
 
 func NewToolRegistry() *ToolRegistry {
 	return &ToolRegistry{
-		tools: make(map[string]Tool),
+		tools:   make(map[string]Tool),
+		known:   make(map[string]bool),
+		pending: make(map[string][]*pendingCall),
 	}
 }
 
+// optionStrings reads a list option given as strings or numbers, e.g.
+// "buses": [1, "3"].
+func optionStrings(options map[string]interface{}, key string) ([]string, error) {
+	v, ok := options[key]
+	if !ok {
+		return nil, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		list = []interface{}{v}
+	}
+	out := make([]string, 0, len(list))
+	for _, item := range list {
+		switch x := item.(type) {
+		case string:
+			out = append(out, x)
+		case float64:
+			out = append(out, strconv.FormatFloat(x, 'f', -1, 64))
+		default:
+			return nil, fmt.Errorf("%s: expected strings or numbers, got %v", key, item)
+		}
+	}
+	return out, nil
+}
+
+// checkOptions rejects option names a tool does not know.
+func checkOptions(options map[string]interface{}, known ...string) error {
+	for k := range options {
+		found := false
+		for _, name := range known {
+			found = found || k == name
+		}
+		if !found {
+			return fmt.Errorf("unknown option %q (known: %s)", k, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// Configure sets per-tool settings. It applies to tools registered
+// afterwards, so call it before registering any.
+func (r *ToolRegistry) Configure(settings map[string]ToolSettings) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.settings = settings
+}
+
+// Enabled reports whether a tool is enabled, or def when its settings
+// leave that open.
+func (r *ToolRegistry) Enabled(name string, def bool) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.known[name] = true
+	if s, ok := r.settings[name]; ok && s.Enabled != nil {
+		return *s.Enabled
+	}
+	return def
+}
+
 func (r *ToolRegistry) Register(tool Tool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	name := tool.Name()
+	r.known[name] = true
+	if s, ok := r.settings[name]; ok {
+		if s.Enabled != nil && !*s.Enabled {
+			logger.InfoCF("tools", "Tool disabled by config", map[string]interface{}{"name": name})
+			return
+		}
+		if len(s.Options) > 0 {
+			ct, ok := tool.(ConfigurableTool)
+			if !ok {
+				logger.WarnCF("tools", "Tool takes no options; ignoring them", map[string]interface{}{"name": name})
+			} else if err := ct.Configure(s.Options); err != nil {
+				logger.ErrorCF("tools", "Tool disabled: invalid options", map[string]interface{}{"name": name, "error": err.Error()})
+				return
+			}
+		}
+		limits := toolLimits{timeout: s.Timeout, maxOutput: s.MaxOutput}
+		if ts, ok := tool.(interface{ SetTimeout(time.Duration) }); ok && s.Timeout > 0 {
+			ts.SetTimeout(s.Timeout)
+			limits.timeout = 0
+		}
+		if r.limits == nil {
+			r.limits = make(map[string]toolLimits)
+		}
+		r.limits[name] = limits
+	}
 	if _, exists := r.tools[name]; exists {
 		logger.WarnCF("tools", "Tool registration overwrites existing tool",
 			map[string]interface{}{"name": name})
@@ -33,6 +165,33 @@ func (r *ToolRegistry) Register(tool Tool) {
 	r.tools[name] = tool
 }
 
+// Known reports whether a tool of this name was registered or checked with
+// Enabled, even if config disabled it.
+func (r *ToolRegistry) Known(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.known[name]
+}
+
+// Unregister removes a tool. It reports whether the tool was registered.
+func (r *ToolRegistry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.tools[name]
+	delete(r.tools, name)
+	delete(r.limits, name)
+	return ok
+}
+
+// SetOutputStore makes the registry spill results larger than the store's
+// threshold to scratch files and registers read_tool_output to read them.
+func (r *ToolRegistry) SetOutputStore(store *ToolOutputStore) {
+	r.mu.Lock()
+	r.outputs = store
+	r.mu.Unlock()
+	r.Register(NewReadToolOutputTool(store))
+}
+
 func (r *ToolRegistry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -64,9 +223,26 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 		return ErrorResult(fmt.Sprintf("tool %q not found", name)).WithError(fmt.Errorf("tool not found"))
 	}
 
+	// Check the arguments against the tool's schema, after coercing values
+	// models commonly get wrong (numbers as strings and the like).
+	args, err := validateToolArgs(tool.Parameters(), args)
+	if err != nil {
+		logger.WarnCF("tool", "Invalid tool arguments",
+			map[string]interface{}{
+				"tool":  name,
+				"error": err.Error(),
+			})
+		return ErrorResult(fmt.Sprintf("invalid arguments for %s: %v. Fix the arguments to match the tool's parameter schema and call it again.", name, err))
+	}
+
 	// Inject channel/chatID into ctx so tools read them via ToolChannel(ctx)/ToolChatID(ctx).
 	ctx = WithToolContext(ctx, channel, chatID)
 
+	r.mu.RLock()
+	outputs := r.outputs
+	limits := r.limits[name]
+	r.mu.RUnlock()
+
 	// If tool implements AsyncExecutor and callback is provided, use ExecuteAsync.
 	var result *ToolResult
 	start := time.Now()
@@ -76,11 +252,26 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 				"tool": name,
 			})
 		result = asyncExec.ExecuteAsync(ctx, args, asyncCallback)
+	} else if limits.timeout > 0 {
+		result = r.executeWithTimeout(ctx, name, tool, args, limits.timeout)
 	} else {
 		result = tool.Execute(ctx, args)
 	}
 	duration := time.Since(start)
 
+	if limits.maxOutput > 0 && len(result.ForLLM) > limits.maxOutput {
+		limited := *result
+		cut := limits.maxOutput
+		for cut > 0 && !utf8.RuneStart(result.ForLLM[cut]) {
+			cut--
+		}
+		limited.ForLLM = result.ForLLM[:cut] + fmt.Sprintf("\n... [output cut at %d of %d bytes by tools.%s.max_output_bytes]", cut, len(result.ForLLM), name)
+		result = &limited
+	}
+	if outputs != nil && !result.Async && name != "read_tool_output" {
+		result = outputs.Spill(ToolChannel(ctx)+":"+ToolChatID(ctx), name, result)
+	}
+
 	// Log based on result type
 	if result.IsError {
 		logger.ErrorCF("tool", "Tool execution failed",
@@ -107,6 +298,64 @@ func (r *ToolRegistry) ExecuteWithContext(ctx context.Context, name string, args
 	return result
 }
 
+// executeWithTimeout runs a tool with a deadline. A tool that ignores its
+// context is left to finish in the background and its result dropped; the
+// tool refuses new calls until that happens.
+func (r *ToolRegistry) executeWithTimeout(ctx context.Context, name string, tool Tool, args map[string]interface{}, timeout time.Duration) *ToolResult {
+	// Checking for overdue calls and registering this one in one locked
+	// step keeps concurrent calls from slipping past a call that has just
+	// timed out but has not been marked abandoned yet.
+	call := &pendingCall{deadline: time.Now().Add(timeout)}
+	r.mu.Lock()
+	for _, c := range r.pending[name] {
+		if c.overdue(time.Now()) {
+			r.mu.Unlock()
+			return ErrorResult(fmt.Sprintf("%s is still finishing a call that timed out; wait and try again later", name))
+		}
+	}
+	r.pending[name] = append(r.pending[name], call)
+	r.mu.Unlock()
+
+	ctx, cancel := context.WithDeadline(ctx, call.deadline)
+	defer cancel()
+	done := make(chan *ToolResult, 1)
+	go func() {
+		result := tool.Execute(ctx, args)
+		r.mu.Lock()
+		r.removePending(name, call)
+		r.mu.Unlock()
+		done <- result
+	}()
+	select {
+	case result := <-done:
+		return result
+	case <-ctx.Done():
+	}
+
+	r.mu.Lock()
+	abandoned := slices.Contains(r.pending[name], call)
+	call.abandoned = abandoned
+	r.mu.Unlock()
+	msg := fmt.Sprintf("%s was canceled", name)
+	if ctx.Err() == context.DeadlineExceeded {
+		msg = fmt.Sprintf("%s timed out after %s", name, timeout)
+	}
+	if abandoned {
+		msg += fmt.Sprintf(". The call may still be running and %s refuses new calls until it returns.", name)
+	}
+	return ErrorResult(msg).WithError(ctx.Err())
+}
+
+// removePending forgets a call that returned. The caller holds r.mu.
+func (r *ToolRegistry) removePending(name string, call *pendingCall) {
+	calls := slices.DeleteFunc(r.pending[name], func(c *pendingCall) bool { return c == call })
+	if len(calls) == 0 {
+		delete(r.pending, name)
+	} else {
+		r.pending[name] = calls
+	}
+}
+
 // sortedToolNames returns tool names in sorted order for deterministic iteration.
 func (r *ToolRegistry) sortedToolNames() []string {
 	names := make([]string, 0, len(r.tools))
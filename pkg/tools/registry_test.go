@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type argsEchoTool struct {
+	got map[string]interface{}
+}
+
+func (t *argsEchoTool) Name() string        { return "echo" }
+func (t *argsEchoTool) Description() string { return "echoes its arguments" }
+func (t *argsEchoTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action":  map[string]interface{}{"type": "string", "enum": []string{"read", "write"}},
+			"address": map[string]interface{}{"type": "integer", "minimum": 3, "maximum": 0x77},
+			"bus":     map[string]interface{}{"type": "string"},
+			"confirm": map[string]interface{}{"type": "boolean"},
+			"data":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}},
+			"headers": map[string]interface{}{"type": "object"},
+		},
+		"required": []string{"action"},
+	}
+}
+func (t *argsEchoTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.got = args
+	return NewToolResult(strings.Repeat("x", 100))
+}
+
+func TestToolRegistry_ValidatesAndCoercesArgs(t *testing.T) {
+	tool := &argsEchoTool{}
+	registry := NewToolRegistry()
+	registry.Register(tool)
+	ctx := context.Background()
+
+	result := registry.Execute(ctx, "echo", map[string]interface{}{
+		"action": "WRITE", "address": "0x38", "bus": 1.0, "confirm": "true",
+		"data": "[1, \"2\"]", "headers": `{"a": "b"}`,
+	})
+	if result.IsError {
+		t.Fatalf("coercible arguments were refused: %s", result.ForLLM)
+	}
+	got := tool.got
+	if got["action"] != "write" || got["address"] != 56.0 || got["bus"] != "1" || got["confirm"] != true {
+		t.Errorf("scalars not coerced: %#v", got)
+	}
+	if data, _ := got["data"].([]interface{}); len(data) != 2 || data[1] != 2.0 {
+		t.Errorf("array not coerced: %#v", got["data"])
+	}
+	if headers, _ := got["headers"].(map[string]interface{}); headers["a"] != "b" {
+		t.Errorf("object not coerced: %#v", got["headers"])
+	}
+	registry.Execute(ctx, "echo", map[string]interface{}{"action": "read", "address": "010", "data": []interface{}{"08", "0X1f", "-0x2"}})
+	if got := tool.got; got["address"] != 10.0 {
+		t.Errorf("leading zero should be decimal, got %#v", got["address"])
+	}
+	if data, _ := tool.got["data"].([]interface{}); len(data) != 3 || data[0] != 8.0 || data[1] != 31.0 || data[2] != -2.0 {
+		t.Errorf("integers not parsed as decimal or hex: %#v", tool.got["data"])
+	}
+	registry.Execute(ctx, "echo", map[string]interface{}{"action": "read", "data": 7.0})
+	if data, _ := tool.got["data"].([]interface{}); len(data) != 1 || data[0] != 7.0 {
+		t.Errorf("single value should become an array: %#v", tool.got["data"])
+	}
+
+	for _, tc := range []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{nil, `missing required property "action"`},
+		{map[string]interface{}{"action": "erase"}, "$.action: erase is not one of [read, write]"},
+		{map[string]interface{}{"action": "read", "address": "fifty"}, "$.address: expected integer, got string"},
+		{map[string]interface{}{"action": "read", "address": 200.0}, "greater than maximum"},
+		{map[string]interface{}{"action": "read", "data": []interface{}{1.5}}, "$.data[0]: expected integer"},
+	} {
+		tool.got = nil
+		result := registry.Execute(ctx, "echo", tc.args)
+		if !result.IsError || !strings.Contains(result.ForLLM, "invalid arguments for echo") || !strings.Contains(result.ForLLM, tc.want) {
+			t.Errorf("args %v: got %q, want error containing %q", tc.args, result.ForLLM, tc.want)
+		}
+		if tool.got != nil {
+			t.Errorf("args %v: tool should not run with invalid arguments", tc.args)
+		}
+	}
+}
+
+type slowTool struct{}
+
+func (t *slowTool) Name() string                       { return "slow" }
+func (t *slowTool) Description() string                { return "waits for its context" }
+func (t *slowTool) Parameters() map[string]interface{} { return nil }
+func (t *slowTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	<-ctx.Done()
+	return NewToolResult("stopped")
+}
+
+// stuckTool ignores its context until released.
+type stuckTool struct {
+	release chan struct{}
+}
+
+func (t *stuckTool) Name() string                       { return "stuck" }
+func (t *stuckTool) Description() string                { return "ignores its context" }
+func (t *stuckTool) Parameters() map[string]interface{} { return nil }
+func (t *stuckTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	<-t.release
+	return NewToolResult("done")
+}
+
+func TestToolRegistry_TimeoutRefusesOverlappingCalls(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Configure(map[string]ToolSettings{"stuck": {Timeout: 20 * time.Millisecond}})
+	stuck := &stuckTool{release: make(chan struct{})}
+	registry.Register(stuck)
+	ctx := context.Background()
+
+	result := registry.Execute(ctx, "stuck", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "timed out after 20ms") || !strings.Contains(result.ForLLM, "may still be running") {
+		t.Fatalf("timeout should say the call may still be running: %s", result.ForLLM)
+	}
+	result = registry.Execute(ctx, "stuck", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "still finishing") {
+		t.Errorf("a call while the timed-out one runs should be refused: %s", result.ForLLM)
+	}
+
+	close(stuck.release)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		registry.mu.RLock()
+		n := len(registry.pending["stuck"])
+		registry.mu.RUnlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("overdue call never cleared")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if result = registry.Execute(ctx, "stuck", nil); result.IsError || result.ForLLM != "done" {
+		t.Errorf("tool should accept calls once the overdue one returns: %s", result.ForLLM)
+	}
+
+	// A call past its deadline counts as overdue before its caller has
+	// noticed the timeout and marked it abandoned.
+	registry.mu.Lock()
+	registry.pending["stuck"] = []*pendingCall{{deadline: time.Now().Add(-time.Millisecond)}}
+	registry.mu.Unlock()
+	if result = registry.Execute(ctx, "stuck", nil); !result.IsError || !strings.Contains(result.ForLLM, "still finishing") {
+		t.Errorf("a call past its deadline should block new calls: %s", result.ForLLM)
+	}
+}
+
+func TestToolRegistry_Settings(t *testing.T) {
+	disabled, enabled := false, true
+	registry := NewToolRegistry()
+	registry.Configure(map[string]ToolSettings{
+		"echo": {MaxOutput: 10},
+		"slow": {Timeout: 20 * time.Millisecond},
+		"i2c":  {Enabled: &enabled, Options: map[string]interface{}{"buses": []interface{}{1.0, "3"}}},
+		"spi":  {Options: map[string]interface{}{"devices": []interface{}{"nope"}}},
+		"exec": {Enabled: &disabled},
+	})
+	registry.Register(&argsEchoTool{})
+	registry.Register(&slowTool{})
+	registry.Register(NewI2CTool())
+	registry.Register(NewSPITool())
+	registry.Register(NewExecTool(t.TempDir(), true))
+
+	if names := strings.Join(registry.List(), ","); names != "echo,i2c,slow" {
+		t.Errorf("registered tools = %s; want disabled exec and misconfigured spi left out", names)
+	}
+	if !registry.Enabled("i2c", false) || registry.Enabled("exec", true) || !registry.Enabled("git", true) {
+		t.Error("Enabled should follow the settings and fall back to the default")
+	}
+
+	ctx := context.Background()
+	result := registry.Execute(ctx, "echo", map[string]interface{}{"action": "read"})
+	if !strings.HasPrefix(result.ForLLM, "xxxxxxxxxx\n... [output cut at 10 of 100 bytes") {
+		t.Errorf("output should be capped: %s", result.ForLLM)
+	}
+	result = registry.Execute(ctx, "slow", nil)
+	if !result.IsError || !strings.Contains(result.ForLLM, "slow timed out after 20ms") {
+		t.Errorf("slow tool should time out: %s", result.ForLLM)
+	}
+	tool, _ := registry.Get("i2c")
+	if i2c := tool.(*I2CTool); strings.Join(i2c.buses, ",") != "1,3" {
+		t.Errorf("i2c options not applied: %v", i2c.buses)
+	}
+	result = registry.Execute(ctx, "i2c", map[string]interface{}{"action": "scan", "bus": 5.0})
+	if !result.IsError || !strings.Contains(result.ForLLM, "bus 5 is not enabled") {
+		t.Errorf("bus outside the option list should be refused: %s", result.ForLLM)
+	}
+	if err := NewI2CTool().Configure(map[string]interface{}{"bus": "1"}); err == nil {
+		t.Error("unknown option should be rejected")
+	}
+}
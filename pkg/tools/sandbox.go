@@ -0,0 +1,36 @@
+package tools
+
+// SandboxOptions configures the namespace sandbox exec commands run in.
+type SandboxOptions struct {
+	Workspace     string   // mounted read-write
+	ReadOnlyPaths []string // host paths visible read-only; defaultSandboxReadOnlyPaths when empty
+	Network       bool     // keep the host network namespace
+	CPUSeconds    int      // RLIMIT_CPU, 0 for no limit
+	MemoryMB      int      // RLIMIT_AS, 0 for no limit
+	MaxProcesses  int      // RLIMIT_NPROC, 0 for no limit
+}
+
+// defaultSandboxReadOnlyPaths is enough of the host for a shell and the
+// usual command line tools. Home directories, /root, /var and the host's
+// /tmp stay hidden.
+var defaultSandboxReadOnlyPaths = []string{
+	"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/libx32", "/etc",
+}
+
+// Sandbox runs shell commands in new user, mount, pid, ipc, uts and
+// (unless Network is set) network namespaces. Inside, the workspace is the
+// only writable host directory, /tmp is a private tmpfs and /dev holds just
+// the harmless device nodes. It complements the regex guard in ExecTool
+// rather than replacing it. Only Linux is supported; elsewhere Command
+// returns an error.
+type Sandbox struct {
+	opts SandboxOptions
+}
+
+// NewSandbox creates a sandbox with the given options.
+func NewSandbox(opts SandboxOptions) *Sandbox {
+	if len(opts.ReadOnlyPaths) == 0 {
+		opts.ReadOnlyPaths = defaultSandboxReadOnlyPaths
+	}
+	return &Sandbox{opts: opts}
+}
@@ -0,0 +1,398 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// sandboxInitArg0 marks a re-exec of the current binary as the sandbox's
+// setup stage. Go cannot run code between clone and exec, so the child
+// starts as a copy of this program, prepares its mounts and limits, then
+// execs the shell.
+const sandboxInitArg0 = "picooraclaw-sandbox-init"
+
+// Constants missing from package syscall (<linux/capability.h>,
+// <linux/prctl.h>, <sys/resource.h>).
+const (
+	capSysAdmin            = 21
+	prSetNoNewPrivs        = 38
+	prCapAmbient           = 47
+	prCapAmbientClearAll   = 4
+	rlimitNproc            = 6
+	sandboxFallbackID      = 65534 // "nobody", used when running as host root
+	sandboxPreservedMntFlg = syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC |
+		syscall.MS_NOATIME | syscall.MS_NODIRATIME | syscall.MS_RELATIME
+)
+
+// sandboxSpec is handed to the setup stage as its only argument.
+type sandboxSpec struct {
+	Root         string   `json:"root"`
+	Workspace    string   `json:"workspace"`
+	Dir          string   `json:"dir"`
+	ReadOnly     []string `json:"read_only"`
+	CPUSeconds   int      `json:"cpu_seconds"`
+	MemoryMB     int      `json:"memory_mb"`
+	MaxProcesses int      `json:"max_processes"`
+	Command      string   `json:"command"`
+}
+
+func init() {
+	if len(os.Args) == 2 && os.Args[0] == sandboxInitArg0 {
+		sandboxInit(os.Args[1])
+	}
+}
+
+// Command returns a command that runs `sh -c command` inside the sandbox
+// with dir as its working directory. dir must be visible in the sandbox.
+func (s *Sandbox) Command(ctx context.Context, command, dir string) (*exec.Cmd, error) {
+	root, err := sandboxRootDir()
+	if err != nil {
+		return nil, err
+	}
+
+	spec := sandboxSpec{
+		Root:         root,
+		Dir:          "/",
+		ReadOnly:     s.opts.ReadOnlyPaths,
+		CPUSeconds:   s.opts.CPUSeconds,
+		MemoryMB:     s.opts.MemoryMB,
+		MaxProcesses: s.opts.MaxProcesses,
+		Command:      command,
+	}
+	if s.opts.Workspace != "" {
+		if spec.Workspace, err = filepath.Abs(s.opts.Workspace); err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(spec.Workspace); err != nil {
+			return nil, fmt.Errorf("workspace: %w", err)
+		}
+		spec.Dir = spec.Workspace
+	}
+	if dir != "" {
+		if spec.Dir, err = filepath.Abs(dir); err != nil {
+			return nil, err
+		}
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := uintptr(syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS)
+	if !s.opts.Network {
+		flags |= syscall.CLONE_NEWNET
+	}
+	uid, gid := os.Getuid(), os.Getgid()
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe")
+	cmd.Args = []string{sandboxInitArg0, string(data)}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  flags,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: sandboxID(uid), HostID: uid, Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: sandboxID(gid), HostID: gid, Size: 1}},
+		// The setup stage needs CAP_SYS_ADMIN in the new user namespace
+		// for its mounts; it is dropped again before the shell runs.
+		AmbientCaps: []uintptr{capSysAdmin},
+		Pdeathsig:   syscall.SIGKILL,
+	}
+	return cmd, nil
+}
+
+// sandboxID keeps the caller's uid/gid inside the sandbox, except that root
+// becomes nobody so the shell never runs with namespace root privileges.
+func sandboxID(id int) int {
+	if id == 0 {
+		return sandboxFallbackID
+	}
+	return id
+}
+
+// sandboxRootDir returns the directory each sandbox mounts its private root
+// on. The mount only exists inside the sandbox's mount namespace, so one
+// empty directory serves all concurrent commands.
+func sandboxRootDir() (string, error) {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("picooraclaw-sandbox-%d", os.Getuid()))
+	if err := os.Mkdir(dir, 0700); err != nil && !os.IsExist(err) {
+		return "", fmt.Errorf("creating sandbox root: %w", err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return "", err
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !info.IsDir() || !ok || int(st.Uid) != os.Getuid() {
+		return "", fmt.Errorf("sandbox root %s is not a directory owned by the current user", dir)
+	}
+	return dir, nil
+}
+
+// sandboxInit is the setup stage. It never returns.
+func sandboxInit(arg string) {
+	// Ambient capabilities and no_new_privs are per thread; stay on the
+	// thread that will exec.
+	runtime.LockOSThread()
+
+	var spec sandboxSpec
+	if err := json.Unmarshal([]byte(arg), &spec); err != nil {
+		sandboxFail(fmt.Errorf("bad spec: %w", err))
+	}
+	if err := spec.setupMounts(); err != nil {
+		sandboxFail(err)
+	}
+	syscall.Sethostname([]byte("sandbox"))
+	if err := syscall.Chdir(spec.Dir); err != nil {
+		sandboxFail(fmt.Errorf("working directory %s is not visible in the sandbox", spec.Dir))
+	}
+	if err := spec.setLimits(); err != nil {
+		sandboxFail(err)
+	}
+
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientClearAll, 0, 0, 0, 0); errno != 0 {
+		sandboxFail(fmt.Errorf("dropping capabilities: %w", errno))
+	}
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		sandboxFail(fmt.Errorf("setting no_new_privs: %w", errno))
+	}
+
+	err := syscall.Exec("/bin/sh", []string{"sh", "-c", spec.Command}, os.Environ())
+	sandboxFail(fmt.Errorf("exec /bin/sh: %w", err))
+}
+
+func sandboxFail(err error) {
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// setupMounts builds the new root on a tmpfs and pivots into it. Order
+// matters: the workspace is mounted last so that a workspace under /tmp is
+// not hidden by the private /tmp.
+func (spec *sandboxSpec) setupMounts() error {
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("making mounts private: %w", err)
+	}
+	root := spec.Root
+	if err := syscall.Mount("tmpfs", root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("mounting root tmpfs: %w", err)
+	}
+
+	for _, p := range spec.ReadOnly {
+		if err := bindReadOnly(root, p); err != nil {
+			return err
+		}
+	}
+
+	tmp := filepath.Join(root, "tmp")
+	if err := os.MkdirAll(tmp, 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", tmp, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("mounting /tmp: %w", err)
+	}
+	if err := setupSandboxDev(root); err != nil {
+		return err
+	}
+	// /proc needs a fully visible host /proc; some container runtimes mask
+	// parts of it, in which case commands simply run without one.
+	proc := filepath.Join(root, "proc")
+	if err := os.MkdirAll(proc, 0755); err == nil {
+		syscall.Mount("proc", proc, "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+	}
+
+	if spec.Workspace != "" {
+		target := filepath.Join(root, spec.Workspace)
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return fmt.Errorf("creating workspace mount point: %w", err)
+		}
+		if err := syscall.Mount(spec.Workspace, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return fmt.Errorf("mounting workspace: %w", err)
+		}
+	}
+
+	// pivot_root(".", ".") stacks the old root on top of the new one; the
+	// detached unmount then leaves the new root alone.
+	if err := syscall.Chdir(root); err != nil {
+		return err
+	}
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("detaching host root: %w", err)
+	}
+	if err := syscall.Chdir("/"); err != nil {
+		return err
+	}
+	return syscall.Mount("", "/", "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, "")
+}
+
+// bindReadOnly makes host path src visible read-only at the same path under
+// root. Missing paths are skipped and top-level symlinks (/bin -> usr/bin)
+// are recreated rather than mounted.
+func bindReadOnly(root, src string) error {
+	info, err := os.Lstat(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	target := filepath.Join(root, src)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(link, target)
+	case info.IsDir():
+		err = os.MkdirAll(target, 0755)
+	default:
+		err = os.WriteFile(target, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(src, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("mounting %s: %w", src, err)
+	}
+	// A recursive bind copies submounts (e.g. /etc/resolv.conf in a
+	// container) and read-only has to be applied to each of them. Flags
+	// the kernel locked when the namespace was created must be kept.
+	mounts, err := mountsUnder(target)
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		var st syscall.Statfs_t
+		if err := syscall.Statfs(m, &st); err != nil {
+			return fmt.Errorf("statfs %s: %w", m, err)
+		}
+		flags := uintptr(syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY) | uintptr(st.Flags)&sandboxPreservedMntFlg
+		if err := syscall.Mount("", m, "", flags, ""); err != nil {
+			return fmt.Errorf("remounting %s read-only: %w", strings.TrimPrefix(m, root), err)
+		}
+	}
+	return nil
+}
+
+// mountsUnder lists the mount points at or below dir, parents first.
+func mountsUnder(dir string) ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		mp := unescapeMountPath(fields[4])
+		if mp == dir || strings.HasPrefix(mp, dir+"/") {
+			out = append(out, mp)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// unescapeMountPath decodes the octal escapes (\040 for space) used in
+// /proc/self/mountinfo.
+func unescapeMountPath(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// setupSandboxDev creates a minimal /dev with the host's harmless device
+// nodes bind-mounted in.
+func setupSandboxDev(root string) error {
+	dev := filepath.Join(root, "dev")
+	if err := os.MkdirAll(dev, 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount("tmpfs", dev, "tmpfs", syscall.MS_NOSUID|syscall.MS_NOEXEC, "mode=0755"); err != nil {
+		return fmt.Errorf("mounting /dev: %w", err)
+	}
+	for _, name := range []string{"null", "zero", "full", "random", "urandom", "tty"} {
+		src := "/dev/" + name
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		target := filepath.Join(dev, name)
+		if err := os.WriteFile(target, nil, 0666); err != nil {
+			return err
+		}
+		if err := syscall.Mount(src, target, "", syscall.MS_BIND, ""); err != nil {
+			return fmt.Errorf("mounting %s: %w", src, err)
+		}
+	}
+	for name, link := range map[string]string{
+		"fd": "/proc/self/fd", "stdin": "/proc/self/fd/0", "stdout": "/proc/self/fd/1", "stderr": "/proc/self/fd/2",
+	} {
+		if err := os.Symlink(link, filepath.Join(dev, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setLimits applies the resource limits, never above the inherited hard
+// limit (raising it needs privileges the sandbox does not have).
+func (spec *sandboxSpec) setLimits() error {
+	limits := []struct {
+		resource int
+		name     string
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, "cpu", uint64(spec.CPUSeconds)},
+		{syscall.RLIMIT_AS, "memory", uint64(spec.MemoryMB) << 20},
+		{rlimitNproc, "processes", uint64(spec.MaxProcesses)},
+	}
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+		var cur syscall.Rlimit
+		if err := syscall.Getrlimit(l.resource, &cur); err != nil {
+			return fmt.Errorf("reading %s limit: %w", l.name, err)
+		}
+		value := l.value
+		if value > cur.Max {
+			value = cur.Max
+		}
+		if err := syscall.Setrlimit(l.resource, &syscall.Rlimit{Cur: value, Max: value}); err != nil {
+			return fmt.Errorf("setting %s limit: %w", l.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sandboxedExec returns an exec tool sandboxed to a fresh workspace, or
+// skips when the kernel does not allow unprivileged user namespaces.
+func sandboxedExec(t *testing.T, opts SandboxOptions) (*ExecTool, string) {
+	t.Helper()
+	workspace := t.TempDir()
+	opts.Workspace = workspace
+	tool := NewExecTool(workspace, false)
+	tool.SetSandbox(NewSandbox(opts))
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"command": "true"})
+	if result.IsError {
+		t.Skipf("sandbox not available here: %s", result.ForLLM)
+	}
+	return tool, workspace
+}
+
+func runSandboxed(tool *ExecTool, command string) *ToolResult {
+	return tool.Execute(context.Background(), map[string]interface{}{"command": command})
+}
+
+func TestSandbox_WorkspaceWritable(t *testing.T) {
+	tool, workspace := sandboxedExec(t, SandboxOptions{})
+
+	result := runSandboxed(tool, "echo inside > out.txt && pwd")
+	if result.IsError {
+		t.Fatalf("command failed: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, workspace) {
+		t.Errorf("expected to run in the workspace, got: %s", result.ForLLM)
+	}
+	data, err := os.ReadFile(filepath.Join(workspace, "out.txt"))
+	if err != nil || strings.TrimSpace(string(data)) != "inside" {
+		t.Errorf("file written in the sandbox not visible on the host: %q, %v", data, err)
+	}
+}
+
+func TestSandbox_HostReadOnlyAndHidden(t *testing.T) {
+	tool, _ := sandboxedExec(t, SandboxOptions{})
+
+	if result := runSandboxed(tool, "touch /usr/picooraclaw-sandbox-probe"); !result.IsError {
+		t.Error("expected /usr to be read-only")
+	}
+
+	secret := t.TempDir()
+	os.WriteFile(filepath.Join(secret, "key"), []byte("s3cret"), 0644)
+	if result := runSandboxed(tool, "cat "+filepath.Join(secret, "key")); !result.IsError || strings.Contains(result.ForLLM, "s3cret") {
+		t.Errorf("expected paths outside the workspace to be hidden, got: %s", result.ForLLM)
+	}
+
+	result := runSandboxed(tool, "echo scratch > /tmp/x && cat /tmp/x")
+	if result.IsError || !strings.Contains(result.ForLLM, "scratch") {
+		t.Errorf("expected a writable private /tmp, got: %s", result.ForLLM)
+	}
+}
+
+func TestSandbox_Namespaces(t *testing.T) {
+	tool, _ := sandboxedExec(t, SandboxOptions{})
+
+	result := runSandboxed(tool, "echo pid=$$; hostname")
+	if result.IsError {
+		t.Fatalf("command failed: %s", result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "pid=1") {
+		t.Errorf("expected the shell to be pid 1 in a new pid namespace, got: %s", result.ForLLM)
+	}
+
+	result = runSandboxed(tool, "cat /proc/net/dev")
+	if result.IsError {
+		t.Skipf("/proc not mountable here: %s", result.ForLLM)
+	}
+	for _, line := range strings.Split(result.ForLLM, "\n")[2:] {
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && name != "lo" {
+			t.Errorf("expected only loopback without network, found %q", name)
+		}
+	}
+}
+
+func TestSandbox_Limits(t *testing.T) {
+	tool, _ := sandboxedExec(t, SandboxOptions{CPUSeconds: 7, MemoryMB: 512})
+
+	result := runSandboxed(tool, "ulimit -t; ulimit -v")
+	if result.IsError {
+		t.Fatalf("command failed: %s", result.ForLLM)
+	}
+	if got := strings.Fields(result.ForLLM); len(got) != 2 || got[0] != "7" || got[1] != "524288" {
+		t.Errorf("limits = %q, want [7 524288]", got)
+	}
+}
+
+func TestSandbox_DenyPatternsStillApply(t *testing.T) {
+	tool, _ := sandboxedExec(t, SandboxOptions{})
+
+	result := runSandboxed(tool, "sudo true")
+	if !result.IsError || !strings.Contains(result.ForLLM, "safety guard") {
+		t.Errorf("expected the regex guard to run before the sandbox, got: %s", result.ForLLM)
+	}
+}
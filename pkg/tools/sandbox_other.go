@@ -0,0 +1,14 @@
+//go:build !linux
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// Command is a stub for non-Linux platforms.
+func (s *Sandbox) Command(ctx context.Context, command, dir string) (*exec.Cmd, error) {
+	return nil, fmt.Errorf("the exec sandbox is only supported on Linux")
+}
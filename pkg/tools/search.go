@@ -0,0 +1,531 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	searchDefaultMaxResults = 100
+	searchMaxContext        = 10
+	searchMaxFileSize       = 5 << 20
+	searchMaxLineLength     = 300
+	searchBinaryProbe       = 8000
+)
+
+// SearchFilesTool finds files by glob and searches their contents, like a
+// small ripgrep. It honours .gitignore files and the workspace restriction
+// of the other filesystem tools.
+type SearchFilesTool struct {
+	workspace string
+	restrict  bool
+}
+
+func NewSearchFilesTool(workspace string, restrict bool) *SearchFilesTool {
+	return &SearchFilesTool{workspace: workspace, restrict: restrict}
+}
+
+func (t *SearchFilesTool) Name() string {
+	return "search_files"
+}
+
+func (t *SearchFilesTool) Description() string {
+	return "Search files under a directory. With only 'glob' it lists matching files; with 'pattern' it searches file contents and returns matching lines as path:line:text. Skips .git, hidden files, .gitignore'd paths and binary files unless asked otherwise."
+}
+
+func (t *SearchFilesTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Directory (or file) to search. Default: the workspace",
+			},
+			"glob": map[string]interface{}{
+				"type":        "string",
+				"description": "File name pattern, e.g. '*.go', '**/*.{md,txt}' or 'cmd/**/main.go'. Patterns without '/' match the file name at any depth",
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression (RE2 syntax) to search for in file contents",
+			},
+			"literal": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Treat pattern as a plain string instead of a regular expression",
+			},
+			"ignore_case": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Case-insensitive content search",
+			},
+			"context": map[string]interface{}{
+				"type":        "integer",
+				"description": "Lines of context to show before and after each match (max 10)",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of files (glob only) or matching lines to return. Default: 100",
+			},
+			"include_hidden": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also search hidden files and directories (names starting with '.')",
+			},
+			"include_ignored": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also search paths excluded by .gitignore",
+			},
+		},
+	}
+}
+
+// searchRequest holds the parsed arguments of one search.
+type searchRequest struct {
+	globs          []string
+	re             *regexp.Regexp
+	context        int
+	maxResults     int
+	includeHidden  bool
+	includeIgnored bool
+}
+
+// searchState accumulates results while walking.
+type searchState struct {
+	out       strings.Builder
+	results   int
+	files     int
+	skipped   int
+	truncated bool
+}
+
+func (t *SearchFilesTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	req := searchRequest{maxResults: searchDefaultMaxResults}
+	if v, ok := args["max_results"].(float64); ok && v > 0 {
+		req.maxResults = int(v)
+	}
+	if v, ok := args["context"].(float64); ok && v > 0 {
+		req.context = min(int(v), searchMaxContext)
+	}
+	req.includeHidden, _ = args["include_hidden"].(bool)
+	req.includeIgnored, _ = args["include_ignored"].(bool)
+
+	glob, _ := args["glob"].(string)
+	pattern, _ := args["pattern"].(string)
+	if glob == "" && pattern == "" {
+		return ErrorResult("at least one of glob or pattern is required")
+	}
+	if glob != "" {
+		req.globs = expandBraces(filepath.ToSlash(glob))
+		for _, g := range req.globs {
+			if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+				return ErrorResult(fmt.Sprintf("invalid glob %q: %v", glob, err))
+			}
+		}
+	}
+	if pattern != "" {
+		expr := pattern
+		if literal, _ := args["literal"].(bool); literal {
+			expr = regexp.QuoteMeta(pattern)
+		}
+		if ignoreCase, _ := args["ignore_case"].(bool); ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+		}
+		req.re = re
+	}
+
+	searchPath, _ := args["path"].(string)
+	if searchPath == "" {
+		searchPath = "."
+	}
+	root, err := validatePath(searchPath, t.workspace, t.restrict)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to access path: %v", err))
+	}
+
+	w := &searchWalker{tool: t, req: req}
+	if !info.IsDir() {
+		w.display = func(string) string { return searchPath }
+		w.searchFile(ctx, root, filepath.Base(root), info)
+	} else {
+		rules := w.setup(root)
+		if err := w.walk(ctx, root, "", rules); err != nil {
+			return ErrorResult(fmt.Sprintf("search aborted: %v", err))
+		}
+	}
+	return SilentResult(w.summary())
+}
+
+// searchWalker walks a directory tree for one search.
+type searchWalker struct {
+	tool    *SearchFilesTool
+	req     searchRequest
+	state   searchState
+	display func(rel string) string // how a path relative to the search root is shown
+	prefix  string                  // search root relative to the .gitignore top
+}
+
+// setup decides how paths are displayed and loads the .gitignore files of
+// the directories between the workspace and the search root.
+func (w *searchWalker) setup(root string) []ignoreRule {
+	top := root
+	if w.tool.workspace != "" {
+		if ws, err := filepath.Abs(w.tool.workspace); err == nil && isWithinWorkspace(root, ws) {
+			top = ws
+		}
+	}
+	rel, _ := filepath.Rel(top, root)
+	if rel == "." {
+		rel = ""
+	}
+	w.prefix = filepath.ToSlash(rel)
+	if top == root && root != w.workspaceAbs() {
+		// Outside the workspace: show absolute paths.
+		w.display = func(r string) string { return filepath.Join(root, r) }
+	} else {
+		// Workspace-relative paths work with the other file tools.
+		w.display = func(r string) string { return path.Join(w.prefix, r) }
+	}
+
+	if w.req.includeIgnored {
+		return nil
+	}
+	var rules []ignoreRule
+	dir, base := top, ""
+	for _, part := range strings.Split(w.prefix, "/") {
+		if part == "" {
+			break
+		}
+		rules = append(rules, loadIgnoreFile(dir, base)...)
+		dir = filepath.Join(dir, part)
+		base = path.Join(base, part)
+	}
+	return rules
+}
+
+func (w *searchWalker) workspaceAbs() string {
+	if w.tool.workspace == "" {
+		return ""
+	}
+	ws, _ := filepath.Abs(w.tool.workspace)
+	return ws
+}
+
+func (w *searchWalker) walk(ctx context.Context, dir, rel string, rules []ignoreRule) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !w.req.includeIgnored {
+		rules = append(rules[:len(rules):len(rules)], loadIgnoreFile(dir, path.Join(w.prefix, rel))...)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		w.state.skipped++
+		return nil
+	}
+	for _, entry := range entries {
+		if w.state.truncated {
+			return nil
+		}
+		name := entry.Name()
+		entryRel := path.Join(rel, name)
+		full := filepath.Join(dir, name)
+		isDir := entry.IsDir()
+
+		if name == ".git" || (!w.req.includeHidden && strings.HasPrefix(name, ".")) {
+			continue
+		}
+		if entry.Type()&os.ModeSymlink != 0 {
+			// Follow links to files only, and only inside the workspace.
+			if _, err := validatePath(full, w.tool.workspace, w.tool.restrict); err != nil {
+				continue
+			}
+			info, err := os.Stat(full)
+			if err != nil || info.IsDir() {
+				continue
+			}
+		}
+		if isIgnored(rules, path.Join(w.prefix, entryRel), isDir) {
+			continue
+		}
+		if isDir {
+			if err := w.walk(ctx, full, entryRel, rules); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(w.req.globs) > 0 && !matchAnyGlob(w.req.globs, entryRel) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			w.state.skipped++
+			continue
+		}
+		w.searchFile(ctx, full, entryRel, info)
+	}
+	return nil
+}
+
+// searchFile lists or greps one file.
+func (w *searchWalker) searchFile(ctx context.Context, full, rel string, info os.FileInfo) {
+	st := &w.state
+	if w.req.re == nil {
+		st.files++
+		fmt.Fprintf(&st.out, "%s\n", w.display(rel))
+		st.results++
+		st.truncated = st.results >= w.req.maxResults
+		return
+	}
+	if info.Size() > searchMaxFileSize {
+		st.skipped++
+		return
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		st.skipped++
+		return
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	if head, _ := br.Peek(searchBinaryProbe); bytes.IndexByte(head, 0) >= 0 {
+		st.skipped++
+		return
+	}
+
+	name := w.display(rel)
+	var before []string
+	lastPrinted, afterLeft, matched := 0, 0, false
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		if lineNo%1000 == 0 && ctx.Err() != nil {
+			return
+		}
+		line := scanner.Text()
+		if w.req.re.MatchString(line) {
+			if !matched {
+				st.files++
+				matched = true
+			}
+			if lastPrinted > 0 && lineNo-len(before) > lastPrinted+1 {
+				st.out.WriteString("--\n")
+			}
+			for i, ctxLine := range before {
+				fmt.Fprintf(&st.out, "%s-%d-%s\n", name, lineNo-len(before)+i, clipLine(ctxLine))
+			}
+			before = before[:0]
+			fmt.Fprintf(&st.out, "%s:%d:%s\n", name, lineNo, clipLine(line))
+			lastPrinted, afterLeft = lineNo, w.req.context
+			st.results++
+			if st.results >= w.req.maxResults {
+				st.truncated = true
+				return
+			}
+			continue
+		}
+		if afterLeft > 0 {
+			fmt.Fprintf(&st.out, "%s-%d-%s\n", name, lineNo, clipLine(line))
+			lastPrinted = lineNo
+			afterLeft--
+			continue
+		}
+		if w.req.context > 0 {
+			if len(before) == w.req.context {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, line)
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		st.skipped++
+	}
+}
+
+func (w *searchWalker) summary() string {
+	st := &w.state
+	if st.results == 0 {
+		msg := "No files matched"
+		if w.req.re != nil {
+			msg = "No matches found"
+		}
+		if st.skipped > 0 {
+			msg += fmt.Sprintf(" (%d binary, oversized or unreadable files skipped)", st.skipped)
+		}
+		return msg
+	}
+	out := st.out.String()
+	if w.req.re != nil {
+		out += fmt.Sprintf("\n[%d matching lines in %d files", st.results, st.files)
+	} else {
+		out += fmt.Sprintf("\n[%d files", st.results)
+	}
+	if st.skipped > 0 {
+		out += fmt.Sprintf(", %d files skipped", st.skipped)
+	}
+	if st.truncated {
+		out += fmt.Sprintf("; stopped at max_results=%d, narrow the search for more", w.req.maxResults)
+	}
+	return out + "]"
+}
+
+func clipLine(line string) string {
+	if len(line) <= searchMaxLineLength {
+		return line
+	}
+	cut := searchMaxLineLength
+	for cut > 0 && !isRuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "…"
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// expandBraces turns "*.{go,md}" into "*.go" and "*.md". Braces do not nest.
+func expandBraces(pattern string) []string {
+	open := strings.IndexByte(pattern, '{')
+	if open < 0 {
+		return []string{pattern}
+	}
+	end := strings.IndexByte(pattern[open:], '}')
+	if end < 0 {
+		return []string{pattern}
+	}
+	end += open
+	var out []string
+	for _, alt := range strings.Split(pattern[open+1:end], ",") {
+		out = append(out, expandBraces(pattern[:open]+alt+pattern[end+1:])...)
+	}
+	return out
+}
+
+// matchAnyGlob reports whether rel (slash separated, relative to the search
+// root) matches one of the globs. Globs without a slash match the file name.
+func matchAnyGlob(globs []string, rel string) bool {
+	for _, g := range globs {
+		if !strings.Contains(g, "/") {
+			if ok, _ := path.Match(g, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if matchGlobPath(strings.Split(strings.TrimPrefix(g, "/"), "/"), strings.Split(rel, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlobPath matches path segments, where a "**" segment matches any
+// number of directories.
+func matchGlobPath(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchGlobPath(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// ignoreRule is one line of a .gitignore file.
+type ignoreRule struct {
+	base     string // directory of the .gitignore, relative to the walk top
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+func loadIgnoreFile(dir, base string) []ignoreRule {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.segments = strings.Split(line, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isIgnored applies the rules in order; the last matching rule wins.
+func isIgnored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		p := rel
+		if r.base != "" {
+			if !strings.HasPrefix(rel, r.base+"/") {
+				continue
+			}
+			p = rel[len(r.base)+1:]
+		}
+		var ok bool
+		if r.anchored {
+			ok = matchGlobPath(r.segments, strings.Split(p, "/"))
+		} else {
+			ok, _ = path.Match(r.segments[0], path.Base(p))
+		}
+		if ok {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
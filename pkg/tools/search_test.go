@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSearchTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestSearchFiles_GlobAndIgnore(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{
+		".gitignore":        "build/\n*.log\n!keep.log\n/top.txt\n",
+		"main.go":           "package main",
+		"top.txt":           "x",
+		"docs/top.txt":      "x",
+		"docs/a.md":         "x",
+		"cmd/app/main.go":   "package main",
+		"build/out.go":      "package out",
+		"debug.log":         "x",
+		"logs/keep.log":     "x",
+		"sub/.gitignore":    "*.md\n",
+		"sub/readme.md":     "x",
+		".hidden/secret.go": "x",
+	})
+	tool := NewSearchFilesTool(root, true)
+	ctx := context.Background()
+
+	tests := []struct {
+		args map[string]interface{}
+		want []string
+	}{
+		{map[string]interface{}{"glob": "*.go"}, []string{"cmd/app/main.go", "main.go"}},
+		{map[string]interface{}{"glob": "cmd/**/*.go"}, []string{"cmd/app/main.go"}},
+		{map[string]interface{}{"glob": "*.{log,txt}"}, []string{"docs/top.txt", "logs/keep.log"}},
+		{map[string]interface{}{"glob": "*.md"}, []string{"docs/a.md"}},
+		{map[string]interface{}{"glob": "*.md", "path": "sub", "include_ignored": true}, []string{"sub/readme.md"}},
+		{map[string]interface{}{"glob": "*.go", "include_hidden": true, "include_ignored": true}, []string{".hidden/secret.go", "build/out.go", "cmd/app/main.go", "main.go"}},
+	}
+	for _, tt := range tests {
+		result := tool.Execute(ctx, tt.args)
+		if result.IsError {
+			t.Fatalf("%v: %s", tt.args, result.ForLLM)
+		}
+		lines := strings.Split(result.ForLLM, "\n\n[")[0]
+		if got := strings.Split(lines, "\n"); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%v = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestSearchFiles_ContentWithContext(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{
+		"a.txt":   "one\ntwo\nneedle 1\nthree\nfour\nfive\nsix\nNEEDLE 2\nseven\n",
+		"bin.dat": "needle\x00binary",
+	})
+	tool := NewSearchFilesTool(root, true)
+
+	result := tool.Execute(context.Background(), map[string]interface{}{
+		"pattern": "needle", "ignore_case": true, "context": float64(1),
+	})
+	want := "a.txt-2-two\na.txt:3:needle 1\na.txt-4-three\n--\na.txt-7-six\na.txt:8:NEEDLE 2\na.txt-9-seven\n"
+	if !strings.HasPrefix(result.ForLLM, want) {
+		t.Errorf("got:\n%s\nwant prefix:\n%s", result.ForLLM, want)
+	}
+	if !strings.Contains(result.ForLLM, "[2 matching lines in 1 files, 1 files skipped]") {
+		t.Errorf("summary missing binary skip: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"pattern": "e.", "literal": true,
+	})
+	if !strings.HasPrefix(result.ForLLM, "No matches found") {
+		t.Errorf("literal search should not treat '.' as a wildcard: %s", result.ForLLM)
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{
+		"pattern": "e", "max_results": float64(2),
+	})
+	if strings.Count(result.ForLLM, "a.txt:") != 2 || !strings.Contains(result.ForLLM, "max_results=2") {
+		t.Errorf("max_results not applied: %s", result.ForLLM)
+	}
+}
+
+func TestSearchFiles_Restrict(t *testing.T) {
+	root := writeSearchTree(t, map[string]string{"in.txt": "x"})
+	outside := writeSearchTree(t, map[string]string{"secret.txt": "x"})
+	tool := NewSearchFilesTool(root, true)
+
+	if result := tool.Execute(context.Background(), map[string]interface{}{"glob": "*", "path": outside}); !result.IsError {
+		t.Errorf("expected a path outside the workspace to be rejected: %s", result.ForLLM)
+	}
+
+	if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skip("symlinks not supported")
+	}
+	result := tool.Execute(context.Background(), map[string]interface{}{"glob": "*.txt"})
+	if strings.Contains(result.ForLLM, "link.txt") {
+		t.Errorf("symlink escaping the workspace was listed: %s", result.ForLLM)
+	}
+}
@@ -0,0 +1,457 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SensorSample is a reading as logged for time-series queries.
+type SensorSample struct {
+	Sensor   string    `json:"sensor"`
+	Bus      string    `json:"bus"`
+	Address  int       `json:"address"`
+	Quantity string    `json:"quantity"`
+	Value    float64   `json:"value"`
+	Unit     string    `json:"unit"`
+	Time     time.Time `json:"time"`
+}
+
+// SensorLogger stores sensor readings and answers history queries. It is
+// backed by Oracle when the database is enabled.
+type SensorLogger interface {
+	LogReadings(ctx context.Context, samples []SensorSample) error
+	History(ctx context.Context, sensor, quantity string, since time.Time, limit int) ([]SensorSample, error)
+}
+
+// i2cBus gives the sensor drivers access to I2C devices; tests fake it.
+type i2cBus interface {
+	Buses() []string
+	// Scan reports which of addrs answer on bus.
+	Scan(bus string, addrs []int) ([]int, error)
+	Open(bus string, addr int) (i2cDevice, error)
+}
+
+type detectedSensor struct {
+	Model   string `json:"model"`
+	Driver  string `json:"driver"`
+	Bus     string `json:"bus"`
+	Address string `json:"address"`
+	addr    int
+}
+
+// SensorTool reads common I2C sensors through Go drivers, so the model
+// gets calibrated values with units instead of raw registers.
+type SensorTool struct {
+	mu       sync.Mutex
+	bus      i2cBus
+	buses    []string // buses the tool may use; all when empty
+	logAll   bool     // log every reading, not just those asked for
+	logger   SensorLogger
+	detected map[string]detectedSensor // by bus:address
+}
+
+// NewSensorTool creates the sensor tool.
+func NewSensorTool() *SensorTool {
+	return &SensorTool{bus: newI2CBus(), detected: make(map[string]detectedSensor)}
+}
+
+// SetLogger enables logging readings and the history action.
+func (t *SensorTool) SetLogger(logger SensorLogger) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logger = logger
+}
+
+// Configure takes the "buses" option, limiting the tool to the listed bus
+// numbers, and "log", which logs every reading when a logger is set.
+func (t *SensorTool) Configure(options map[string]interface{}) error {
+	if err := checkOptions(options, "buses", "log"); err != nil {
+		return err
+	}
+	buses, err := optionStrings(options, "buses")
+	if err != nil {
+		return err
+	}
+	for _, b := range buses {
+		if !isValidBusID(b) {
+			return fmt.Errorf("buses: invalid bus %q", b)
+		}
+	}
+	if v, ok := options["log"]; ok {
+		if t.logAll, ok = v.(bool); !ok {
+			return fmt.Errorf("log: expected true or false")
+		}
+	}
+	t.buses = buses
+	return nil
+}
+
+func (t *SensorTool) Name() string {
+	return "sensor"
+}
+
+func (t *SensorTool) Description() string {
+	names := make([]string, len(sensorDrivers))
+	for i, d := range sensorDrivers {
+		names[i] = d.Name
+	}
+	return fmt.Sprintf("Read common I2C sensors with calibrated values and units (%s and compatibles). Actions: drivers (supported parts), detect (find sensors on the I2C buses), read (read one sensor or all detected ones; log: true stores readings), history (logged readings over time, needs Oracle). Prefer this over raw i2c reads for supported parts. Linux only.", strings.Join(names, ", "))
+}
+
+func (t *SensorTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"drivers", "detect", "read", "history"},
+				"description": "Action to perform",
+			},
+			"bus": map[string]interface{}{
+				"type":        "string",
+				"description": "I2C bus number (e.g. \"1\"). detect and read use all buses when omitted.",
+			},
+			"address": map[string]interface{}{
+				"type":        "integer",
+				"description": "For read: 7-bit address of the sensor (e.g. 0x76). Needs bus.",
+			},
+			"sensor": map[string]interface{}{
+				"type":        "string",
+				"description": "Sensor model or driver (e.g. bme280, sht31). For read: which detected sensor(s); with bus and address, skips detection. For history: which sensor.",
+			},
+			"quantity": map[string]interface{}{
+				"type":        "string",
+				"description": "For history: quantity such as temperature, humidity, pressure, current",
+			},
+			"since_minutes": map[string]interface{}{
+				"type":        "integer",
+				"description": "For history: how far back to look (default 60)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "For history: maximum samples (default 100, max 1000)",
+			},
+			"log": map[string]interface{}{
+				"type":        "boolean",
+				"description": "For read: store the readings for later history queries",
+			},
+			"shunt_ohms": map[string]interface{}{
+				"type":        "number",
+				"description": "INA219 shunt resistor in ohms (default 0.1)",
+			},
+			"range_v": map[string]interface{}{
+				"type":        "number",
+				"description": "ADS1115 full-scale range in volts: 6.144, 4.096 (default), 2.048, 1.024, 0.512 or 0.256",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *SensorTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	action, _ := args["action"].(string)
+	bus, _ := args["bus"].(string)
+	if bus != "" && !t.allowedBus(bus) {
+		return ErrorResult(fmt.Sprintf("bus %s is not enabled for the sensor tool (allowed: %s)", bus, strings.Join(t.buses, ", ")))
+	}
+
+	switch action {
+	case "drivers":
+		return t.drivers()
+	case "detect":
+		found, err := t.detect(bus)
+		if err != nil {
+			return ErrorResult(err.Error())
+		}
+		if len(found) == 0 {
+			return SilentResult("No supported sensors found. Use the i2c tool to scan for other devices, and check wiring, pull-ups and pinmux (see hardware skill).")
+		}
+		result, _ := json.MarshalIndent(found, "", "  ")
+		return SilentResult(fmt.Sprintf("Found %d sensor(s):\n%s", len(found), result))
+	case "read":
+		return t.read(ctx, bus, args)
+	case "history":
+		return t.history(ctx, args)
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s (valid: drivers, detect, read, history)", action))
+}
+
+func (t *SensorTool) allowedBus(bus string) bool {
+	if len(t.buses) == 0 {
+		return true
+	}
+	for _, b := range t.buses {
+		if b == bus {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *SensorTool) drivers() *ToolResult {
+	type driverInfo struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Addresses   []string `json:"addresses"`
+	}
+	list := make([]driverInfo, len(sensorDrivers))
+	for i, d := range sensorDrivers {
+		list[i] = driverInfo{Name: d.Name, Description: d.Description}
+		for _, a := range d.Addresses {
+			list[i].Addresses = append(list[i].Addresses, fmt.Sprintf("0x%02x", a))
+		}
+	}
+	result, _ := json.MarshalIndent(list, "", "  ")
+	return SilentResult(string(result))
+}
+
+// detect scans the drivers' addresses on bus (all allowed buses when
+// empty) and probes the devices that answer.
+func (t *SensorTool) detect(bus string) ([]detectedSensor, error) {
+	buses := []string{bus}
+	if bus == "" {
+		buses = nil
+		for _, b := range t.bus.Buses() {
+			if t.allowedBus(b) {
+				buses = append(buses, b)
+			}
+		}
+		if len(buses) == 0 {
+			return nil, fmt.Errorf("no I2C buses found; load i2c-dev and check the device tree (see hardware skill)")
+		}
+	}
+
+	var candidates []int
+	seen := make(map[int]bool)
+	for _, d := range sensorDrivers {
+		for _, a := range d.Addresses {
+			if !seen[a] {
+				seen[a] = true
+				candidates = append(candidates, a)
+			}
+		}
+	}
+	sort.Ints(candidates)
+
+	var found []detectedSensor
+	for _, b := range buses {
+		for k, s := range t.detected {
+			if s.Bus == b {
+				delete(t.detected, k)
+			}
+		}
+		present, err := t.bus.Scan(b, candidates)
+		if err != nil {
+			if bus != "" {
+				return nil, err
+			}
+			continue
+		}
+		for _, addr := range present {
+			if s, ok := t.probe(b, addr, nil); ok {
+				t.detected[fmt.Sprintf("%s:%d", b, addr)] = s
+				found = append(found, s)
+			}
+		}
+	}
+	return found, nil
+}
+
+// probe identifies the sensor at addr, trying only driver when given.
+func (t *SensorTool) probe(bus string, addr int, driver *sensorDriver) (detectedSensor, bool) {
+	dev, err := t.bus.Open(bus, addr)
+	if err != nil {
+		return detectedSensor{}, false
+	}
+	defer dev.Close()
+	for _, d := range sensorDrivers {
+		if driver != nil && d != driver {
+			continue
+		}
+		if !hasAddress(d, addr) {
+			continue
+		}
+		if model := d.Probe(dev); model != "" {
+			return detectedSensor{Model: model, Driver: d.Name, Bus: bus, Address: fmt.Sprintf("0x%02x", addr), addr: addr}, true
+		}
+	}
+	return detectedSensor{}, false
+}
+
+func hasAddress(d *sensorDriver, addr int) bool {
+	for _, a := range d.Addresses {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+type sensorResult struct {
+	detectedSensor
+	Readings []SensorReading `json:"readings,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+func (t *SensorTool) read(ctx context.Context, bus string, args map[string]interface{}) *ToolResult {
+	opts := sensorOptions{}
+	opts.ShuntOhms, _ = args["shunt_ohms"].(float64)
+	opts.RangeV, _ = args["range_v"].(float64)
+	name, _ := args["sensor"].(string)
+	name = strings.ToLower(strings.TrimSpace(name))
+	var driver *sensorDriver
+	if name != "" {
+		if driver = sensorDriverByName(name); driver == nil {
+			return ErrorResult(fmt.Sprintf("unknown sensor %s; use action drivers to list supported parts", name))
+		}
+	}
+
+	var targets []detectedSensor
+	if addrF, ok := args["address"].(float64); ok {
+		addr := int(addrF)
+		if bus == "" {
+			return ErrorResult("bus is required with address")
+		}
+		if addr < 0x03 || addr > 0x77 {
+			return ErrorResult("address must be in valid 7-bit range (0x03-0x77)")
+		}
+		s, ok := t.probe(bus, addr, driver)
+		if !ok {
+			return ErrorResult(fmt.Sprintf("no supported sensor answers at 0x%02x on bus %s; use action detect, or the i2c tool for other parts", addr, bus))
+		}
+		targets = append(targets, s)
+	} else {
+		if len(t.detected) == 0 {
+			if _, err := t.detect(bus); err != nil {
+				return ErrorResult(err.Error())
+			}
+		}
+		for _, s := range t.detected {
+			if (bus == "" || s.Bus == bus) && (driver == nil || s.Driver == driver.Name) {
+				targets = append(targets, s)
+			}
+		}
+		if len(targets) == 0 {
+			return ErrorResult("no matching sensors detected; use action detect to rescan")
+		}
+		sort.Slice(targets, func(i, j int) bool {
+			if targets[i].Bus != targets[j].Bus {
+				return targets[i].Bus < targets[j].Bus
+			}
+			return targets[i].addr < targets[j].addr
+		})
+	}
+
+	now := time.Now()
+	results := make([]sensorResult, 0, len(targets))
+	var samples []SensorSample
+	for _, s := range targets {
+		r := sensorResult{detectedSensor: s}
+		readings, err := t.readSensor(s, opts)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		r.Readings = readings
+		for _, rd := range readings {
+			samples = append(samples, SensorSample{
+				Sensor: s.Model, Bus: s.Bus, Address: s.addr,
+				Quantity: rd.Quantity, Value: rd.Value, Unit: rd.Unit, Time: now,
+			})
+		}
+		results = append(results, r)
+	}
+
+	out, _ := json.MarshalIndent(results, "", "  ")
+	text := string(out)
+	if logReq, _ := args["log"].(bool); (logReq || t.logAll) && len(samples) > 0 {
+		switch {
+		case t.logger == nil:
+			if logReq {
+				text += "\n(not logged: sensor logging needs Oracle to be enabled)"
+			}
+		default:
+			if err := t.logger.LogReadings(ctx, samples); err != nil {
+				text += fmt.Sprintf("\n(logging failed: %v)", err)
+			} else {
+				text += fmt.Sprintf("\n(logged %d values)", len(samples))
+			}
+		}
+	}
+	return SilentResult(text)
+}
+
+func (t *SensorTool) readSensor(s detectedSensor, opts sensorOptions) ([]SensorReading, error) {
+	driver := sensorDriverByName(s.Driver)
+	dev, err := t.bus.Open(s.Bus, s.addr)
+	if err != nil {
+		return nil, err
+	}
+	defer dev.Close()
+	return driver.Read(dev, s.Model, opts)
+}
+
+func (t *SensorTool) history(ctx context.Context, args map[string]interface{}) *ToolResult {
+	if t.logger == nil {
+		return ErrorResult("no sensor history: logging needs Oracle to be enabled (oracle.enabled)")
+	}
+	sensor, _ := args["sensor"].(string)
+	quantity, _ := args["quantity"].(string)
+	since := time.Hour
+	if m, ok := args["since_minutes"].(float64); ok && m > 0 {
+		since = time.Duration(m) * time.Minute
+	}
+	limit := 100
+	if l, ok := args["limit"].(float64); ok && l >= 1 {
+		limit = min(int(l), 1000)
+	}
+	samples, err := t.logger.History(ctx, strings.ToLower(sensor), quantity, time.Now().Add(-since), limit)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to query sensor history: %v", err))
+	}
+	if len(samples) == 0 {
+		return SilentResult(fmt.Sprintf("No readings logged in the last %s. Use read with log: true to record some.", since))
+	}
+
+	type stats struct {
+		Sensor   string  `json:"sensor"`
+		Quantity string  `json:"quantity"`
+		Unit     string  `json:"unit"`
+		Count    int     `json:"count"`
+		Min      float64 `json:"min"`
+		Max      float64 `json:"max"`
+		Mean     float64 `json:"mean"`
+		Latest   float64 `json:"latest"`
+	}
+	byKey := make(map[string]*stats)
+	var keys []string
+	for _, s := range samples { // newest first
+		key := s.Sensor + "/" + s.Quantity
+		st, ok := byKey[key]
+		if !ok {
+			st = &stats{Sensor: s.Sensor, Quantity: s.Quantity, Unit: s.Unit, Min: s.Value, Max: s.Value, Latest: s.Value}
+			byKey[key] = st
+			keys = append(keys, key)
+		}
+		st.Count++
+		st.Min = math.Min(st.Min, s.Value)
+		st.Max = math.Max(st.Max, s.Value)
+		st.Mean += s.Value
+	}
+	summary := make([]stats, 0, len(keys))
+	for _, k := range keys {
+		st := byKey[k]
+		st.Mean = round(st.Mean/float64(st.Count), 3)
+		summary = append(summary, *st)
+	}
+	out, _ := json.MarshalIndent(map[string]interface{}{"summary": summary, "samples": samples}, "", "  ")
+	return SilentResult(string(out))
+}
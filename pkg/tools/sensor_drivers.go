@@ -0,0 +1,504 @@
+package tools
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// SensorReading is one calibrated value from a sensor.
+type SensorReading struct {
+	Quantity string  `json:"quantity"`
+	Value    float64 `json:"value"`
+	Unit     string  `json:"unit"`
+}
+
+// i2cDevice is an open device on an I2C bus.
+type i2cDevice interface {
+	Write(p []byte) error
+	Read(p []byte) error
+	Close() error
+}
+
+// sensorOptions are per-read driver settings.
+type sensorOptions struct {
+	ShuntOhms float64 // INA219 shunt resistor
+	RangeV    float64 // ADS1115 full-scale range
+}
+
+// sensorDriver knows how to identify and read one family of parts.
+// Probe returns the exact model (e.g. "bmp280" for the bme280 driver) or
+// "" when the device at the address is something else.
+type sensorDriver struct {
+	Name        string
+	Description string
+	Addresses   []int
+	Probe       func(dev i2cDevice) string
+	Read        func(dev i2cDevice, model string, opts sensorOptions) ([]SensorReading, error)
+}
+
+// sensorDrivers is the registry of supported parts, in probe order: parts
+// with a checksummed or ID-register probe come before those sharing their
+// addresses with weaker probes.
+var sensorDrivers = []*sensorDriver{
+	{
+		Name:        "bme280",
+		Description: "Bosch BME280 temperature/humidity/pressure, BMP280 temperature/pressure",
+		Addresses:   []int{0x76, 0x77},
+		Probe:       probeBME280,
+		Read:        readBME280,
+	},
+	{
+		Name:        "sht3x",
+		Description: "Sensirion SHT30/SHT31/SHT35 temperature/humidity",
+		Addresses:   []int{0x44, 0x45},
+		Probe:       probeSHT3x,
+		Read:        readSHT3x,
+	},
+	{
+		Name:        "aht20",
+		Description: "Aosong AHT20/AHT21 temperature/humidity",
+		Addresses:   []int{0x38},
+		Probe:       probeAHT20,
+		Read:        readAHT20,
+	},
+	{
+		Name:        "mpu6050",
+		Description: "InvenSense MPU6050/MPU6500 accelerometer/gyroscope",
+		Addresses:   []int{0x68, 0x69},
+		Probe:       probeMPU6050,
+		Read:        readMPU6050,
+	},
+	{
+		Name:        "ads1115",
+		Description: "TI ADS1115 4-channel 16-bit ADC (single-ended voltages)",
+		Addresses:   []int{0x48, 0x49, 0x4A, 0x4B},
+		Probe:       probeADS1115,
+		Read:        readADS1115,
+	},
+	{
+		Name:        "ina219",
+		Description: "TI INA219 bus voltage/current/power monitor",
+		Addresses:   []int{0x40, 0x41, 0x44, 0x45},
+		Probe:       probeINA219,
+		Read:        readINA219,
+	},
+}
+
+func sensorDriverByName(name string) *sensorDriver {
+	for _, d := range sensorDrivers {
+		if d.Name == name {
+			return d
+		}
+	}
+	// Accept model names served by a driver.
+	switch name {
+	case "bmp280":
+		return sensorDriverByName("bme280")
+	case "sht30", "sht31", "sht35":
+		return sensorDriverByName("sht3x")
+	case "aht21":
+		return sensorDriverByName("aht20")
+	case "mpu6500":
+		return sensorDriverByName("mpu6050")
+	}
+	return nil
+}
+
+func readRegs(dev i2cDevice, reg byte, n int) ([]byte, error) {
+	if err := dev.Write([]byte{reg}); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, n)
+	if err := dev.Read(buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// sensirionCRC is the CRC-8 used by Sensirion and Aosong parts
+// (polynomial 0x31, init 0xFF).
+func sensirionCRC(data []byte) byte {
+	crc := byte(0xFF)
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x31
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// sensorSleep is replaced in tests so conversions don't slow them down.
+var sensorSleep = time.Sleep
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// BME280 / BMP280
+
+func probeBME280(dev i2cDevice) string {
+	id, err := readRegs(dev, 0xD0, 1)
+	if err != nil {
+		return ""
+	}
+	switch id[0] {
+	case 0x60:
+		return "bme280"
+	case 0x56, 0x57, 0x58:
+		return "bmp280"
+	}
+	return ""
+}
+
+func readBME280(dev i2cDevice, model string, opts sensorOptions) ([]SensorReading, error) {
+	cal, err := readRegs(dev, 0x88, 24)
+	if err != nil {
+		return nil, fmt.Errorf("reading calibration: %w", err)
+	}
+	u16 := func(b []byte, i int) float64 { return float64(binary.LittleEndian.Uint16(b[i:])) }
+	s16 := func(b []byte, i int) float64 { return float64(int16(binary.LittleEndian.Uint16(b[i:]))) }
+	t1, t2, t3 := u16(cal, 0), s16(cal, 2), s16(cal, 4)
+	p1 := u16(cal, 6)
+	var p [10]float64
+	for i := 2; i <= 9; i++ {
+		p[i] = s16(cal, 6+2*(i-1))
+	}
+
+	humidity := model == "bme280"
+	var h1, h2, h3, h4, h5, h6 float64
+	if humidity {
+		a1, err := readRegs(dev, 0xA1, 1)
+		if err != nil {
+			return nil, fmt.Errorf("reading calibration: %w", err)
+		}
+		e, err := readRegs(dev, 0xE1, 7)
+		if err != nil {
+			return nil, fmt.Errorf("reading calibration: %w", err)
+		}
+		h1 = float64(a1[0])
+		h2 = s16(e, 0)
+		h3 = float64(e[2])
+		h4 = float64(int16(uint16(e[3])<<8|uint16(e[4]&0x0F)<<4) >> 4)
+		h5 = float64(int16(uint16(e[5])<<8|uint16(e[4]&0xF0)) >> 4)
+		h6 = float64(int8(e[6]))
+		// Humidity oversampling x1; takes effect with the ctrl_meas write.
+		if err := dev.Write([]byte{0xF2, 0x01}); err != nil {
+			return nil, err
+		}
+	}
+	// Temperature and pressure oversampling x1, forced mode: one
+	// measurement, then back to sleep.
+	if err := dev.Write([]byte{0xF4, 0x25}); err != nil {
+		return nil, err
+	}
+	for i := 0; i < 20; i++ {
+		sensorSleep(5 * time.Millisecond)
+		status, err := readRegs(dev, 0xF3, 1)
+		if err != nil {
+			return nil, err
+		}
+		if status[0]&0x08 == 0 {
+			break
+		}
+	}
+	data, err := readRegs(dev, 0xF7, 8)
+	if err != nil {
+		return nil, fmt.Errorf("reading measurement: %w", err)
+	}
+	adcP := float64(uint32(data[0])<<12 | uint32(data[1])<<4 | uint32(data[2])>>4)
+	adcT := float64(uint32(data[3])<<12 | uint32(data[4])<<4 | uint32(data[5])>>4)
+	adcH := float64(uint32(data[6])<<8 | uint32(data[7]))
+	if adcT == 0x80000 {
+		return nil, fmt.Errorf("no measurement available (sensor skipped the conversion)")
+	}
+
+	// Floating-point compensation from the Bosch datasheets.
+	v1 := (adcT/16384 - t1/1024) * t2
+	v2 := (adcT/131072 - t1/8192) * (adcT/131072 - t1/8192) * t3
+	tFine := v1 + v2
+	readings := []SensorReading{{Quantity: "temperature", Value: round(tFine/5120, 2), Unit: "°C"}}
+
+	v1 = tFine/2 - 64000
+	v2 = v1 * v1 * p[6] / 32768
+	v2 += v1 * p[5] * 2
+	v2 = v2/4 + p[4]*65536
+	v1 = (p[3]*v1*v1/524288 + p[2]*v1) / 524288
+	v1 = (1 + v1/32768) * p1
+	if v1 != 0 {
+		pa := 1048576 - adcP
+		pa = (pa - v2/4096) * 6250 / v1
+		v1 = p[9] * pa * pa / 2147483648
+		v2 = pa * p[8] / 32768
+		pa += (v1 + v2 + p[7]) / 16
+		readings = append(readings, SensorReading{Quantity: "pressure", Value: round(pa/100, 2), Unit: "hPa"})
+	}
+
+	if humidity {
+		h := tFine - 76800
+		h = (adcH - (h4*64 + h5/16384*h)) * (h2 / 65536 * (1 + h6/67108864*h*(1+h3/67108864*h)))
+		h *= 1 - h1*h/524288
+		readings = append(readings, SensorReading{Quantity: "humidity", Value: round(math.Max(0, math.Min(100, h)), 2), Unit: "%RH"})
+	}
+	return readings, nil
+}
+
+// SHT3x
+
+func probeSHT3x(dev i2cDevice) string {
+	// Read status register; the reply is checksummed.
+	if err := dev.Write([]byte{0xF3, 0x2D}); err != nil {
+		return ""
+	}
+	buf := make([]byte, 3)
+	if err := dev.Read(buf); err != nil || sensirionCRC(buf[:2]) != buf[2] {
+		return ""
+	}
+	return "sht3x"
+}
+
+func readSHT3x(dev i2cDevice, model string, opts sensorOptions) ([]SensorReading, error) {
+	// Single shot, high repeatability, no clock stretching.
+	if err := dev.Write([]byte{0x24, 0x00}); err != nil {
+		return nil, err
+	}
+	sensorSleep(16 * time.Millisecond)
+	buf := make([]byte, 6)
+	if err := dev.Read(buf); err != nil {
+		return nil, fmt.Errorf("reading measurement: %w", err)
+	}
+	if sensirionCRC(buf[0:2]) != buf[2] || sensirionCRC(buf[3:5]) != buf[5] {
+		return nil, fmt.Errorf("checksum mismatch (check wiring)")
+	}
+	rawT := float64(binary.BigEndian.Uint16(buf[0:]))
+	rawH := float64(binary.BigEndian.Uint16(buf[3:]))
+	return []SensorReading{
+		{Quantity: "temperature", Value: round(-45+175*rawT/65535, 2), Unit: "°C"},
+		{Quantity: "humidity", Value: round(100*rawH/65535, 2), Unit: "%RH"},
+	}, nil
+}
+
+// AHT20
+
+func probeAHT20(dev i2cDevice) string {
+	status := make([]byte, 1)
+	if err := dev.Read(status); err != nil || status[0] == 0xFF {
+		return ""
+	}
+	return "aht20"
+}
+
+func readAHT20(dev i2cDevice, model string, opts sensorOptions) ([]SensorReading, error) {
+	status := make([]byte, 1)
+	if err := dev.Read(status); err != nil {
+		return nil, err
+	}
+	if status[0]&0x08 == 0 {
+		// Not calibrated since power-on: load the calibration.
+		if err := dev.Write([]byte{0xBE, 0x08, 0x00}); err != nil {
+			return nil, err
+		}
+		sensorSleep(10 * time.Millisecond)
+	}
+	if err := dev.Write([]byte{0xAC, 0x33, 0x00}); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 7)
+	for i := 0; ; i++ {
+		sensorSleep(80 * time.Millisecond)
+		if err := dev.Read(buf); err != nil {
+			return nil, fmt.Errorf("reading measurement: %w", err)
+		}
+		if buf[0]&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return nil, fmt.Errorf("measurement did not complete")
+		}
+	}
+	if sensirionCRC(buf[:6]) != buf[6] {
+		return nil, fmt.Errorf("checksum mismatch (check wiring)")
+	}
+	rawH := float64(uint32(buf[1])<<12 | uint32(buf[2])<<4 | uint32(buf[3])>>4)
+	rawT := float64(uint32(buf[3]&0x0F)<<16 | uint32(buf[4])<<8 | uint32(buf[5]))
+	return []SensorReading{
+		{Quantity: "temperature", Value: round(rawT*200/(1<<20)-50, 2), Unit: "°C"},
+		{Quantity: "humidity", Value: round(rawH*100/(1<<20), 2), Unit: "%RH"},
+	}, nil
+}
+
+// MPU6050
+
+func probeMPU6050(dev i2cDevice) string {
+	id, err := readRegs(dev, 0x75, 1)
+	if err != nil {
+		return ""
+	}
+	switch id[0] {
+	case 0x68:
+		return "mpu6050"
+	case 0x70:
+		return "mpu6500"
+	}
+	return ""
+}
+
+func readMPU6050(dev i2cDevice, model string, opts sensorOptions) ([]SensorReading, error) {
+	pwr, err := readRegs(dev, 0x6B, 1)
+	if err != nil {
+		return nil, err
+	}
+	if pwr[0]&0x40 != 0 {
+		// Wake from sleep using the gyro clock and let the filters settle.
+		if err := dev.Write([]byte{0x6B, 0x01}); err != nil {
+			return nil, err
+		}
+		sensorSleep(100 * time.Millisecond)
+	}
+	cfg, err := readRegs(dev, 0x1B, 2) // GYRO_CONFIG, ACCEL_CONFIG
+	if err != nil {
+		return nil, err
+	}
+	gyroScale := 131.0 / float64(int(1)<<(cfg[0]>>3&3))    // LSB per °/s
+	accelScale := 16384.0 / float64(int(1)<<(cfg[1]>>3&3)) // LSB per g
+	data, err := readRegs(dev, 0x3B, 14)
+	if err != nil {
+		return nil, fmt.Errorf("reading measurement: %w", err)
+	}
+	v := func(i int) float64 { return float64(int16(binary.BigEndian.Uint16(data[2*i:]))) }
+	temp := v(3)/340 + 36.53
+	if model == "mpu6500" {
+		temp = v(3)/333.87 + 21
+	}
+	return []SensorReading{
+		{Quantity: "accel_x", Value: round(v(0)/accelScale, 4), Unit: "g"},
+		{Quantity: "accel_y", Value: round(v(1)/accelScale, 4), Unit: "g"},
+		{Quantity: "accel_z", Value: round(v(2)/accelScale, 4), Unit: "g"},
+		{Quantity: "gyro_x", Value: round(v(4)/gyroScale, 3), Unit: "°/s"},
+		{Quantity: "gyro_y", Value: round(v(5)/gyroScale, 3), Unit: "°/s"},
+		{Quantity: "gyro_z", Value: round(v(6)/gyroScale, 3), Unit: "°/s"},
+		{Quantity: "temperature", Value: round(temp, 2), Unit: "°C"},
+	}, nil
+}
+
+// ADS1115
+
+func probeADS1115(dev i2cDevice) string {
+	cfg, err := readRegs(dev, 0x01, 2)
+	if err != nil {
+		return ""
+	}
+	// The pointer does not auto-increment, so read each threshold alone.
+	lo, err := readRegs(dev, 0x02, 2)
+	if err != nil {
+		return ""
+	}
+	hi, err := readRegs(dev, 0x03, 2)
+	if err != nil {
+		return ""
+	}
+	// Power-on config, or the default comparator thresholds which are
+	// rarely changed; TMP102s sharing these addresses read differently.
+	if binary.BigEndian.Uint16(cfg) == 0x8583 ||
+		(binary.BigEndian.Uint16(lo) == 0x8000 && binary.BigEndian.Uint16(hi) == 0x7FFF) {
+		return "ads1115"
+	}
+	return ""
+}
+
+// ads1115Ranges maps full-scale ranges in volts to PGA settings.
+var ads1115Ranges = map[float64]uint16{6.144: 0, 4.096: 1, 2.048: 2, 1.024: 3, 0.512: 4, 0.256: 5}
+
+func readADS1115(dev i2cDevice, model string, opts sensorOptions) ([]SensorReading, error) {
+	fsr := opts.RangeV
+	if fsr == 0 {
+		fsr = 4.096
+	}
+	pga, ok := ads1115Ranges[fsr]
+	if !ok {
+		return nil, fmt.Errorf("range_v must be one of 6.144, 4.096, 2.048, 1.024, 0.512, 0.256")
+	}
+	readings := make([]SensorReading, 0, 4)
+	for ch := 0; ch < 4; ch++ {
+		// Start a single-shot conversion of AINch against GND at 128 SPS,
+		// comparator off.
+		cfg := uint16(1<<15) | uint16(4+ch)<<12 | pga<<9 | 1<<8 | 4<<5 | 3
+		if err := dev.Write([]byte{0x01, byte(cfg >> 8), byte(cfg)}); err != nil {
+			return nil, err
+		}
+		done := false
+		for i := 0; i < 10 && !done; i++ {
+			sensorSleep(2 * time.Millisecond)
+			status, err := readRegs(dev, 0x01, 2)
+			if err != nil {
+				return nil, err
+			}
+			done = status[0]&0x80 != 0
+		}
+		if !done {
+			return nil, fmt.Errorf("conversion of A%d did not complete", ch)
+		}
+		raw, err := readRegs(dev, 0x00, 2)
+		if err != nil {
+			return nil, err
+		}
+		volts := float64(int16(binary.BigEndian.Uint16(raw))) * fsr / 32768
+		readings = append(readings, SensorReading{Quantity: fmt.Sprintf("A%d", ch), Value: round(volts, 5), Unit: "V"})
+	}
+	return readings, nil
+}
+
+// INA219
+
+func probeINA219(dev i2cDevice) string {
+	cfg, err := readRegs(dev, 0x00, 2)
+	if err != nil {
+		return ""
+	}
+	bus, err := readRegs(dev, 0x02, 2)
+	if err != nil {
+		return ""
+	}
+	// The reset bit self-clears and bit 2 of the bus voltage register is
+	// always 0; a PCA9685 at 0x40 fails the second check.
+	c := binary.BigEndian.Uint16(cfg)
+	if c == 0 || c&0x8000 != 0 || binary.BigEndian.Uint16(bus)&0x04 != 0 {
+		return ""
+	}
+	return "ina219"
+}
+
+func readINA219(dev i2cDevice, model string, opts sensorOptions) ([]SensorReading, error) {
+	shunt := opts.ShuntOhms
+	if shunt == 0 {
+		shunt = 0.1
+	}
+	raw, err := readRegs(dev, 0x01, 2)
+	if err != nil {
+		return nil, fmt.Errorf("reading shunt voltage: %w", err)
+	}
+	shuntMV := float64(int16(binary.BigEndian.Uint16(raw))) * 0.01
+	raw, err = readRegs(dev, 0x02, 2)
+	if err != nil {
+		return nil, fmt.Errorf("reading bus voltage: %w", err)
+	}
+	busReg := binary.BigEndian.Uint16(raw)
+	if busReg&0x01 != 0 {
+		return nil, fmt.Errorf("math overflow: current exceeds the configured range")
+	}
+	busV := float64(busReg>>3) * 0.004
+	currentMA := shuntMV / shunt
+	return []SensorReading{
+		{Quantity: "bus_voltage", Value: round(busV, 3), Unit: "V"},
+		{Quantity: "shunt_voltage", Value: round(shuntMV, 3), Unit: "mV"},
+		{Quantity: "current", Value: round(currentMA, 2), Unit: "mA"},
+		{Quantity: "power", Value: round(busV*currentMA, 2), Unit: "mW"},
+	}, nil
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// i2cDev is the i2cBus backed by the i2c-dev character devices.
+type i2cDev struct{}
+
+func newI2CBus() i2cBus {
+	return i2cDev{}
+}
+
+func (i2cDev) Buses() []string {
+	matches, _ := filepath.Glob("/dev/i2c-*")
+	buses := make([]string, 0, len(matches))
+	for _, m := range matches {
+		if bus := strings.TrimPrefix(m, "/dev/i2c-"); isValidBusID(bus) {
+			buses = append(buses, bus)
+		}
+	}
+	sort.Slice(buses, func(i, j int) bool {
+		a, _ := strconv.Atoi(buses[i])
+		b, _ := strconv.Atoi(buses[j])
+		return a < b
+	})
+	return buses
+}
+
+// Scan probes addrs the same way as the i2c tool's scan. Addresses owned
+// by kernel drivers are left out: the driver, not this tool, reads them.
+func (i2cDev) Scan(bus string, addrs []int) ([]int, error) {
+	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
+	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v (check permissions and i2c-dev module)", devPath, err)
+	}
+	defer syscall.Close(fd)
+
+	var funcs uintptr
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cFuncs, uintptr(unsafe.Pointer(&funcs)))
+	if errno != 0 {
+		return nil, fmt.Errorf("failed to query I2C adapter capabilities on %s: %v", devPath, errno)
+	}
+	hasQuick := funcs&i2cFuncSmbusQuick != 0
+	if !hasQuick && funcs&i2cFuncSmbusReadByte == 0 {
+		return nil, fmt.Errorf("I2C adapter %s supports neither SMBus Quick nor Read Byte — cannot probe safely", devPath)
+	}
+
+	var present []int
+	for _, addr := range addrs {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSlave, uintptr(addr))
+		if errno == 0 && smbusProbe(fd, addr, hasQuick) {
+			present = append(present, addr)
+		}
+	}
+	return present, nil
+}
+
+func (i2cDev) Open(bus string, addr int) (i2cDevice, error) {
+	devPath := fmt.Sprintf("/dev/i2c-%s", bus)
+	fd, err := syscall.Open(devPath, syscall.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", devPath, err)
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), i2cSlave, uintptr(addr))
+	if errno != 0 {
+		syscall.Close(fd)
+		if errno == syscall.EBUSY {
+			return nil, fmt.Errorf("device 0x%02x is in use by a kernel driver", addr)
+		}
+		return nil, fmt.Errorf("failed to set I2C address 0x%02x: %v", addr, errno)
+	}
+	return &i2cDevFile{fd: fd}, nil
+}
+
+type i2cDevFile struct {
+	fd int
+}
+
+func (d *i2cDevFile) Write(p []byte) error {
+	n, err := syscall.Write(d.fd, p)
+	if err == nil && n != len(p) {
+		err = fmt.Errorf("short write (%d of %d bytes)", n, len(p))
+	}
+	return err
+}
+
+func (d *i2cDevFile) Read(p []byte) error {
+	n, err := syscall.Read(d.fd, p)
+	if err == nil && n != len(p) {
+		err = fmt.Errorf("short read (%d of %d bytes)", n, len(p))
+	}
+	return err
+}
+
+func (d *i2cDevFile) Close() error {
+	return syscall.Close(d.fd)
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package tools
+
+import "fmt"
+
+var errI2CUnsupported = fmt.Errorf("I2C is only supported on Linux")
+
+// i2cUnsupported is the i2cBus for non-Linux platforms.
+type i2cUnsupported struct{}
+
+func newI2CBus() i2cBus {
+	return i2cUnsupported{}
+}
+
+func (i2cUnsupported) Buses() []string {
+	return nil
+}
+
+func (i2cUnsupported) Scan(bus string, addrs []int) ([]int, error) {
+	return nil, errI2CUnsupported
+}
+
+func (i2cUnsupported) Open(bus string, addr int) (i2cDevice, error) {
+	return nil, errI2CUnsupported
+}
@@ -0,0 +1,340 @@
+package tools
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeChip models the register interface of one I2C part.
+type fakeChip interface {
+	write(p []byte) error
+	read(p []byte) error
+}
+
+// fakeI2C is an i2cBus with chips at fixed addresses.
+type fakeI2C struct {
+	chips map[string]map[int]fakeChip
+}
+
+func (f *fakeI2C) Buses() []string {
+	var buses []string
+	for b := range f.chips {
+		buses = append(buses, b)
+	}
+	sort.Strings(buses)
+	return buses
+}
+
+func (f *fakeI2C) Scan(bus string, addrs []int) ([]int, error) {
+	var present []int
+	for _, a := range addrs {
+		if _, ok := f.chips[bus][a]; ok {
+			present = append(present, a)
+		}
+	}
+	return present, nil
+}
+
+func (f *fakeI2C) Open(bus string, addr int) (i2cDevice, error) {
+	chip, ok := f.chips[bus][addr]
+	if !ok {
+		return nil, fmt.Errorf("no device")
+	}
+	return fakeI2CDevice{chip}, nil
+}
+
+type fakeI2CDevice struct{ fakeChip }
+
+func (d fakeI2CDevice) Write(p []byte) error { return d.write(p) }
+func (d fakeI2CDevice) Read(p []byte) error  { return d.read(p) }
+func (d fakeI2CDevice) Close() error         { return nil }
+
+// regChip has byte registers with an auto-incrementing pointer.
+type regChip struct {
+	regs    [256]byte
+	ptr     byte
+	onWrite func(c *regChip, reg, v byte)
+}
+
+func (c *regChip) write(p []byte) error {
+	c.ptr = p[0]
+	for i, b := range p[1:] {
+		c.regs[c.ptr+byte(i)] = b
+		if c.onWrite != nil {
+			c.onWrite(c, c.ptr+byte(i), b)
+		}
+	}
+	return nil
+}
+
+func (c *regChip) read(p []byte) error {
+	for i := range p {
+		p[i] = c.regs[c.ptr+byte(i)]
+	}
+	return nil
+}
+
+// wordChip has 16-bit big-endian registers and a fixed pointer.
+type wordChip struct {
+	regs    map[byte]uint16
+	ptr     byte
+	onWrite func(c *wordChip, reg byte, v uint16)
+}
+
+func (c *wordChip) write(p []byte) error {
+	c.ptr = p[0]
+	if len(p) == 3 {
+		v := binary.BigEndian.Uint16(p[1:])
+		c.regs[c.ptr] = v
+		if c.onWrite != nil {
+			c.onWrite(c, c.ptr, v)
+		}
+	}
+	return nil
+}
+
+func (c *wordChip) read(p []byte) error {
+	binary.BigEndian.PutUint16(p, c.regs[c.ptr])
+	return nil
+}
+
+// cmdChip answers 16-bit commands (Sensirion) or single status reads (AHT20).
+type cmdChip struct {
+	replies map[string][]byte
+	last    string
+	status  []byte
+}
+
+func (c *cmdChip) write(p []byte) error {
+	c.last = fmt.Sprintf("%X", p)
+	return nil
+}
+
+func (c *cmdChip) read(p []byte) error {
+	reply, ok := c.replies[c.last]
+	if len(p) == 1 || !ok {
+		reply = c.status
+	}
+	if reply == nil {
+		return fmt.Errorf("NACK")
+	}
+	copy(p, reply)
+	return nil
+}
+
+func withCRC(words ...uint16) []byte {
+	var out []byte
+	for _, w := range words {
+		b := []byte{byte(w >> 8), byte(w)}
+		out = append(out, b[0], b[1], sensirionCRC(b))
+	}
+	return out
+}
+
+func newBME280() *regChip {
+	c := &regChip{}
+	c.regs[0xD0] = 0x60
+	cal := []int{27504, 26435, -1000, 36477, -10685, 3024, 2855, 140, -7, 15500, -14600, 6000}
+	for i, v := range cal {
+		binary.LittleEndian.PutUint16(c.regs[0x88+2*i:], uint16(int16(v)))
+	}
+	c.regs[0xA1] = 75
+	copy(c.regs[0xE1:], []byte{0x6A, 0x01, 0x00, 0x13, 0x29, 0x03, 0x1E})
+	c.onWrite = func(c *regChip, reg, v byte) {
+		if reg == 0xF4 && v&0x03 == 0x01 {
+			copy(c.regs[0xF7:], []byte{0x65, 0x5A, 0xC0, 0x7E, 0xED, 0x00, 0x76, 0x7D})
+		}
+	}
+	return c
+}
+
+func newMPU6050() *regChip {
+	c := &regChip{}
+	c.regs[0x75] = 0x68
+	c.regs[0x6B] = 0x40 // asleep after power-on
+	c.regs[0x1B] = 0x08 // ±500 °/s
+	data := []int16{0, 0, 16384, -521, 131, 0, -131}
+	for i, v := range data {
+		binary.BigEndian.PutUint16(c.regs[0x3B+2*i:], uint16(v))
+	}
+	return c
+}
+
+func newADS1115() *wordChip {
+	return &wordChip{
+		regs: map[byte]uint16{0x01: 0x8583, 0x02: 0x8000, 0x03: 0x7FFF},
+		onWrite: func(c *wordChip, reg byte, v uint16) {
+			if reg == 0x01 && v&0x8000 != 0 {
+				ch := int(v>>12&7) - 4
+				c.regs[0x00] = uint16(ch * 8000)
+				c.regs[0x01] = v | 0x8000
+			}
+		},
+	}
+}
+
+func newSensorTestTool(t *testing.T) *SensorTool {
+	t.Helper()
+	old := sensorSleep
+	sensorSleep = func(time.Duration) {}
+	t.Cleanup(func() { sensorSleep = old })
+
+	bus := &fakeI2C{chips: map[string]map[int]fakeChip{
+		"1": {
+			0x76: newBME280(),
+			0x44: &cmdChip{replies: map[string][]byte{"F32D": withCRC(0x8010), "2400": withCRC(0x6666, 0x8000)}},
+			0x38: &cmdChip{status: []byte{0x18}, replies: map[string][]byte{"AC3300": append([]byte{0x18, 0x80, 0x00, 0x06, 0x00, 0x00}, sensirionCRC([]byte{0x18, 0x80, 0x00, 0x06, 0x00, 0x00}))}},
+			0x68: newMPU6050(),
+			0x48: newADS1115(),
+			0x41: &wordChip{regs: map[byte]uint16{0x00: 0x399F, 0x01: 1000, 0x02: 1250 << 3}},
+			0x40: &wordChip{regs: map[byte]uint16{0x00: 0x1104, 0x02: 0xE2E4}}, // PCA9685
+		},
+		"2": {0x77: newBME280()},
+	}}
+	tool := NewSensorTool()
+	tool.bus = bus
+	return tool
+}
+
+func sensorValues(t *testing.T, out string) map[string]map[string]SensorReading {
+	t.Helper()
+	var results []sensorResult
+	if i := strings.LastIndex(out, "]"); i >= 0 {
+		out = out[:i+1]
+	}
+	if err := json.Unmarshal([]byte(out), &results); err != nil {
+		t.Fatalf("bad read output: %v\n%s", err, out)
+	}
+	values := make(map[string]map[string]SensorReading)
+	for _, r := range results {
+		if r.Error != "" {
+			t.Errorf("%s at %s: %s", r.Model, r.Address, r.Error)
+		}
+		values[r.Model] = make(map[string]SensorReading)
+		for _, rd := range r.Readings {
+			values[r.Model][rd.Quantity] = rd
+		}
+	}
+	return values
+}
+
+func TestSensorTool_DetectAndRead(t *testing.T) {
+	tool := newSensorTestTool(t)
+	if err := tool.Configure(map[string]interface{}{"buses": []interface{}{"1"}}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "detect"})
+	for _, want := range []string{`"model": "bme280"`, `"model": "sht3x"`, `"model": "aht20"`, `"model": "mpu6050"`, `"model": "ads1115"`, `"address": "0x41"`} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("detect should find %s:\n%s", want, result.ForLLM)
+		}
+	}
+	if strings.Contains(result.ForLLM, `"address": "0x40"`) || strings.Contains(result.ForLLM, `"bus": "2"`) {
+		t.Errorf("PCA9685 and bus 2 should not be detected:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read"})
+	values := sensorValues(t, result.ForLLM)
+	for _, tc := range []struct {
+		model, quantity string
+		want            float64
+		unit            string
+	}{
+		{"bme280", "temperature", 25.08, "°C"},
+		{"bme280", "pressure", 1006.53, "hPa"},
+		{"bme280", "humidity", 56.85, "%RH"},
+		{"sht3x", "temperature", 25, "°C"},
+		{"sht3x", "humidity", 50, "%RH"},
+		{"aht20", "temperature", 25, "°C"},
+		{"aht20", "humidity", 50, "%RH"},
+		{"mpu6050", "accel_z", 1, "g"},
+		{"mpu6050", "gyro_x", 2, "°/s"},
+		{"mpu6050", "gyro_z", -2, "°/s"},
+		{"mpu6050", "temperature", 35, "°C"},
+		{"ads1115", "A0", 0, "V"},
+		{"ads1115", "A3", 3, "V"},
+		{"ina219", "bus_voltage", 5, "V"},
+		{"ina219", "current", 100, "mA"},
+		{"ina219", "power", 500, "mW"},
+	} {
+		got, ok := values[tc.model][tc.quantity]
+		if !ok || got.Value != tc.want || got.Unit != tc.unit {
+			t.Errorf("%s %s = %v %s, want %v %s", tc.model, tc.quantity, got.Value, got.Unit, tc.want, tc.unit)
+		}
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "sensor": "ina219", "shunt_ohms": 0.05})
+	if values := sensorValues(t, result.ForLLM); len(values) != 1 || values["ina219"]["current"].Value != 200 {
+		t.Errorf("sensor filter and shunt option: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "bus": "2", "address": float64(0x77)})
+	if !result.IsError || !strings.Contains(result.ForLLM, "bus 2 is not enabled") {
+		t.Errorf("bus outside the option list should be refused: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "bus": "1", "address": float64(0x40)})
+	if !result.IsError || !strings.Contains(result.ForLLM, "no supported sensor") {
+		t.Errorf("unknown part should be refused: %s", result.ForLLM)
+	}
+}
+
+type fakeSensorLogger struct {
+	samples []SensorSample
+}
+
+func (l *fakeSensorLogger) LogReadings(ctx context.Context, samples []SensorSample) error {
+	l.samples = append(l.samples, samples...)
+	return nil
+}
+
+func (l *fakeSensorLogger) History(ctx context.Context, sensor, quantity string, since time.Time, limit int) ([]SensorSample, error) {
+	var out []SensorSample
+	for i := len(l.samples) - 1; i >= 0 && len(out) < limit; i-- {
+		s := l.samples[i]
+		if (sensor == "" || s.Sensor == sensor) && (quantity == "" || s.Quantity == quantity) && !s.Time.Before(since) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func TestSensorTool_LogAndHistory(t *testing.T) {
+	tool := newSensorTestTool(t)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "history"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "needs Oracle") {
+		t.Errorf("history without a logger: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "bus": "2", "address": float64(0x77), "log": true})
+	if !strings.Contains(result.ForLLM, "not logged") {
+		t.Errorf("log without a logger should say so: %s", result.ForLLM)
+	}
+
+	logger := &fakeSensorLogger{}
+	tool.SetLogger(logger)
+	tool.Execute(ctx, map[string]interface{}{"action": "read", "bus": "2", "address": float64(0x77), "log": true})
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "bus": "2", "address": float64(0x77), "sensor": "bme280", "log": true})
+	if !strings.HasSuffix(result.ForLLM, "(logged 3 values)") || len(logger.samples) != 6 {
+		t.Fatalf("readings not logged: %s (%d samples)", result.ForLLM, len(logger.samples))
+	}
+	if s := logger.samples[0]; s.Sensor != "bme280" || s.Bus != "2" || s.Address != 0x77 || s.Quantity != "temperature" || s.Unit != "°C" {
+		t.Errorf("unexpected sample: %+v", s)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "history", "sensor": "BME280", "quantity": "pressure"})
+	if result.IsError || !strings.Contains(result.ForLLM, `"count": 2`) || !strings.Contains(result.ForLLM, `"mean": 1006.53`) {
+		t.Errorf("history: %s", result.ForLLM)
+	}
+
+	if err := NewSensorTool().Configure(map[string]interface{}{"log": "yes"}); err == nil {
+		t.Error("non-boolean log option should be rejected")
+	}
+}
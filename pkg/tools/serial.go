@@ -0,0 +1,516 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultSerialPorts are the device paths the serial tool may open unless
+// the "ports" option replaces them.
+var defaultSerialPorts = []string{
+	"/dev/ttyUSB*", "/dev/ttyACM*", "/dev/ttyAMA*", "/dev/ttyS*", "/dev/ttyTHS*",
+	"/dev/ttymxc*", "/dev/serial0", "/dev/serial1", "/dev/serial/by-id/*", "/dev/serial/by-path/*",
+}
+
+// serialSettings are the line settings of a port.
+type serialSettings struct {
+	Baud     int
+	DataBits int
+	Parity   string // none, even or odd
+	StopBits int
+	RTSCTS   bool
+}
+
+func (s serialSettings) String() string {
+	flow := ""
+	if s.RTSCTS {
+		flow = ", RTS/CTS"
+	}
+	return fmt.Sprintf("%d %d%s%d%s", s.Baud, s.DataBits, strings.ToUpper(s.Parity[:1]), s.StopBits, flow)
+}
+
+var defaultSerialSettings = serialSettings{Baud: 115200, DataBits: 8, Parity: "none", StopBits: 1}
+
+// serialConn is an open port. Read waits up to timeout for data and
+// returns 0 bytes and no error when none arrived.
+type serialConn interface {
+	Read(p []byte, timeout time.Duration) (int, error)
+	Write(p []byte) (int, error)
+	Close() error
+}
+
+type serialPort struct {
+	settings serialSettings
+	conn     serialConn
+	pending  []byte // read past a delimiter, returned by the next read
+}
+
+// SerialTool talks to UART devices such as GPS modules, LoRa radios and
+// microcontrollers. Ports stay open between calls so that data arriving
+// between a write and the following read is not lost; configure reopens a
+// port with new line settings.
+type SerialTool struct {
+	mu      sync.Mutex
+	allowed []string // glob patterns of ports the tool may open
+	ports   map[string]*serialPort
+	open    func(path string, s serialSettings) (serialConn, error)
+}
+
+// NewSerialTool creates the serial tool.
+func NewSerialTool() *SerialTool {
+	return &SerialTool{
+		allowed: defaultSerialPorts,
+		ports:   make(map[string]*serialPort),
+		open:    openSerialPort,
+	}
+}
+
+// Configure takes the "ports" option, a list of device paths or glob
+// patterns replacing the default set of serial devices.
+func (t *SerialTool) Configure(options map[string]interface{}) error {
+	if err := checkOptions(options, "ports"); err != nil {
+		return err
+	}
+	ports, err := optionStrings(options, "ports")
+	if err != nil {
+		return err
+	}
+	for _, p := range ports {
+		if _, err := filepath.Match(p, ""); err != nil || !strings.HasPrefix(p, "/dev/") {
+			return fmt.Errorf("ports: invalid pattern %q", p)
+		}
+	}
+	if len(ports) > 0 {
+		t.allowed = ports
+	}
+	return nil
+}
+
+func (t *SerialTool) Name() string {
+	return "serial"
+}
+
+func (t *SerialTool) Description() string {
+	return "Talk to serial/UART devices (GPS modules, LoRa radios, microcontrollers, modems). Actions: list (find ports), configure (baud, data bits, parity, stop bits, flow control), write (send data), read (receive with timeout and optional delimiter), expect (run a send/expect transaction, e.g. AT commands), close. Writes require confirm: true. Linux only."
+}
+
+func (t *SerialTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"list", "configure", "write", "read", "expect", "close"},
+				"description": "Action to perform",
+			},
+			"port": map[string]interface{}{
+				"type":        "string",
+				"description": "Serial device, e.g. /dev/ttyUSB0 or /dev/serial/by-id/... Required except for list.",
+			},
+			"baud": map[string]interface{}{
+				"type":        "integer",
+				"description": "Baud rate for configure (default 115200)",
+			},
+			"data_bits": map[string]interface{}{
+				"type":        "integer",
+				"description": "Data bits for configure: 5-8 (default 8)",
+			},
+			"parity": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"none", "even", "odd"},
+				"description": "Parity for configure (default none)",
+			},
+			"stop_bits": map[string]interface{}{
+				"type":        "integer",
+				"description": "Stop bits for configure: 1 or 2 (default 1)",
+			},
+			"rtscts": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Hardware flow control for configure (default false)",
+			},
+			"data": map[string]interface{}{
+				"type":        "string",
+				"description": "Data to write. Include line endings such as \\r\\n yourself.",
+			},
+			"encoding": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"text", "hex"},
+				"description": "How data and send steps are given (default text; hex like \"41 54 0d\")",
+			},
+			"until": map[string]interface{}{
+				"type":        "string",
+				"description": "For read: stop once this text (e.g. \"\\n\") has been received",
+			},
+			"timeout_ms": map[string]interface{}{
+				"type":        "integer",
+				"description": "For read and each expect step: how long to wait (default 1000, max 30000)",
+			},
+			"max_bytes": map[string]interface{}{
+				"type":        "integer",
+				"description": "For read: maximum bytes to return (default 4096)",
+			},
+			"steps": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"send":       map[string]interface{}{"type": "string", "description": "Data to send"},
+						"expect":     map[string]interface{}{"type": "string", "description": "Regular expression to wait for"},
+						"timeout_ms": map[string]interface{}{"type": "integer", "description": "Wait for this step (default timeout_ms)"},
+					},
+				},
+				"description": "For expect: steps run in order; each may send data and then wait for a pattern. The transaction stops at the first pattern not seen in time.",
+			},
+			"confirm": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Must be true for write and for expect with send steps. Safety guard to prevent accidental writes.",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *SerialTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	if action == "list" {
+		return t.list()
+	}
+
+	path, _ := args["port"].(string)
+	path = filepath.Clean(strings.TrimSpace(path))
+	if path == "." {
+		return ErrorResult("port is required (e.g. /dev/ttyUSB0); use action list to find ports")
+	}
+	if !t.allowedPort(path) {
+		return ErrorResult(fmt.Sprintf("port %s is not an allowed serial device (allowed: %s)", path, strings.Join(t.allowed, ", ")))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch action {
+	case "configure":
+		return t.configure(path, args)
+	case "write":
+		return t.write(path, args)
+	case "read":
+		return t.read(ctx, path, args)
+	case "expect":
+		return t.expect(ctx, path, args)
+	case "close":
+		if p, ok := t.ports[path]; ok {
+			p.conn.Close()
+			delete(t.ports, path)
+			return SilentResult(fmt.Sprintf("Closed %s", path))
+		}
+		return SilentResult(fmt.Sprintf("%s was not open", path))
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s (valid: list, configure, write, read, expect, close)", action))
+}
+
+func (t *SerialTool) allowedPort(path string) bool {
+	for _, pattern := range t.allowed {
+		if ok, _ := filepath.Match(pattern, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *SerialTool) list() *ToolResult {
+	seen := make(map[string]bool)
+	var lines []string
+	for _, pattern := range t.allowed {
+		matches, _ := filepath.Glob(pattern)
+		for _, m := range matches {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			line := m
+			if target, err := filepath.EvalSymlinks(m); err == nil && target != m {
+				line += " -> " + target
+			}
+			t.mu.Lock()
+			if p, ok := t.ports[m]; ok {
+				line += " (open, " + p.settings.String() + ")"
+			}
+			t.mu.Unlock()
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return SilentResult("No serial ports found. USB adapters show up as /dev/ttyUSB* or /dev/ttyACM*; on-board UARTs may need enabling in the device tree (see hardware skill).")
+	}
+	sort.Strings(lines)
+	return SilentResult(fmt.Sprintf("Found %d serial port(s):\n%s", len(lines), strings.Join(lines, "\n")))
+}
+
+// port returns the open port, opening it with default settings if needed.
+func (t *SerialTool) port(path string) (*serialPort, error) {
+	if p, ok := t.ports[path]; ok {
+		return p, nil
+	}
+	conn, err := t.open(path, defaultSerialSettings)
+	if err != nil {
+		return nil, err
+	}
+	p := &serialPort{settings: defaultSerialSettings, conn: conn}
+	t.ports[path] = p
+	return p, nil
+}
+
+// drop closes a port after an I/O error so the next call reopens it.
+func (t *SerialTool) drop(path string) {
+	if p, ok := t.ports[path]; ok {
+		p.conn.Close()
+		delete(t.ports, path)
+	}
+}
+
+func (t *SerialTool) configure(path string, args map[string]interface{}) *ToolResult {
+	s := defaultSerialSettings
+	if p, ok := t.ports[path]; ok {
+		s = p.settings
+	}
+	if v, ok := args["baud"].(float64); ok {
+		s.Baud = int(v)
+	}
+	if v, ok := args["data_bits"].(float64); ok {
+		s.DataBits = int(v)
+	}
+	if v, ok := args["parity"].(string); ok && v != "" {
+		s.Parity = v
+	}
+	if v, ok := args["stop_bits"].(float64); ok {
+		s.StopBits = int(v)
+	}
+	if v, ok := args["rtscts"].(bool); ok {
+		s.RTSCTS = v
+	}
+	if s.DataBits < 5 || s.DataBits > 8 {
+		return ErrorResult("data_bits must be between 5 and 8")
+	}
+	if s.StopBits != 1 && s.StopBits != 2 {
+		return ErrorResult("stop_bits must be 1 or 2")
+	}
+	if s.Parity != "none" && s.Parity != "even" && s.Parity != "odd" {
+		return ErrorResult("parity must be none, even or odd")
+	}
+
+	// Open with the new settings before closing the old descriptor, so a
+	// rejected configuration leaves the port as it was.
+	conn, err := t.open(path, s)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to configure %s: %v", path, err))
+	}
+	t.drop(path)
+	t.ports[path] = &serialPort{settings: s, conn: conn}
+	return SilentResult(fmt.Sprintf("Configured %s: %s", path, s))
+}
+
+// serialPayload decodes data given as text or hex.
+func serialPayload(data, encoding string) ([]byte, error) {
+	if encoding != "hex" {
+		return []byte(data), nil
+	}
+	clean := strings.NewReplacer(" ", "", ",", "", "0x", "", "0X", "", ":", "").Replace(data)
+	b, err := hex.DecodeString(clean)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex data: %v", err)
+	}
+	return b, nil
+}
+
+func (t *SerialTool) write(path string, args map[string]interface{}) *ToolResult {
+	if confirm, _ := args["confirm"].(bool); !confirm {
+		return ErrorResult("write operations require confirm: true. Please confirm with the user before writing to serial devices, as commands can reconfigure or reflash hardware.")
+	}
+	data, _ := args["data"].(string)
+	encoding, _ := args["encoding"].(string)
+	payload, err := serialPayload(data, encoding)
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	if len(payload) == 0 {
+		return ErrorResult("data is required for write")
+	}
+	p, err := t.port(path)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open %s: %v", path, err))
+	}
+	n, err := p.conn.Write(payload)
+	if err != nil {
+		t.drop(path)
+		return ErrorResult(fmt.Sprintf("failed to write to %s: %v", path, err))
+	}
+	return SilentResult(fmt.Sprintf("Wrote %d byte(s) to %s", n, path))
+}
+
+func serialTimeout(v interface{}, def time.Duration) time.Duration {
+	if ms, ok := v.(float64); ok && ms > 0 {
+		return min(time.Duration(ms)*time.Millisecond, 30*time.Second)
+	}
+	return def
+}
+
+// readUntil collects data until match reports the end of the wanted data,
+// max bytes arrive or the timeout passes. Bytes after the end stay pending
+// for the next read.
+func (t *SerialTool) readUntil(ctx context.Context, p *serialPort, timeout time.Duration, max int, match func([]byte) int) ([]byte, bool, error) {
+	buf := p.pending
+	p.pending = nil
+	deadline := time.Now().Add(timeout)
+	chunk := make([]byte, 512)
+	for {
+		if end := match(buf); end >= 0 {
+			p.pending = append(p.pending, buf[end:]...)
+			return buf[:end], true, nil
+		}
+		if len(buf) >= max {
+			p.pending = append(p.pending, buf[max:]...)
+			return buf[:max], false, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 || ctx.Err() != nil {
+			return buf, false, ctx.Err()
+		}
+		n, err := p.conn.Read(chunk, min(remaining, 100*time.Millisecond))
+		if err != nil {
+			return buf, false, err
+		}
+		buf = append(buf, chunk[:n]...)
+	}
+}
+
+func (t *SerialTool) read(ctx context.Context, path string, args map[string]interface{}) *ToolResult {
+	timeout := serialTimeout(args["timeout_ms"], time.Second)
+	max := 4096
+	if v, ok := args["max_bytes"].(float64); ok && v >= 1 {
+		max = min(int(v), 65536)
+	}
+	until, _ := args["until"].(string)
+	match := func(b []byte) int { return -1 }
+	if until != "" {
+		match = func(b []byte) int {
+			if i := bytes.Index(b, []byte(until)); i >= 0 {
+				return i + len(until)
+			}
+			return -1
+		}
+	}
+
+	p, err := t.port(path)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open %s: %v", path, err))
+	}
+	data, found, err := t.readUntil(ctx, p, timeout, max, match)
+	if err != nil && ctx.Err() == nil {
+		t.drop(path)
+		return ErrorResult(fmt.Sprintf("failed to read from %s: %v", path, err))
+	}
+	if len(data) == 0 {
+		return SilentResult(fmt.Sprintf("No data from %s within %s", path, timeout))
+	}
+	note := ""
+	if until != "" && !found {
+		note = fmt.Sprintf(" (delimiter %s not seen)", strconv.Quote(until))
+	}
+	return SilentResult(fmt.Sprintf("Read %d byte(s) from %s%s:\n%s", len(data), path, note, formatSerialData(data)))
+}
+
+// formatSerialData shows text as is, and binary data as hex.
+func formatSerialData(data []byte) string {
+	if !isBinary(data) {
+		return string(data)
+	}
+	return "hex: " + strings.ToUpper(hex.EncodeToString(data))
+}
+
+func (t *SerialTool) expect(ctx context.Context, path string, args map[string]interface{}) *ToolResult {
+	rawSteps, _ := args["steps"].([]interface{})
+	if len(rawSteps) == 0 {
+		return ErrorResult("steps are required for expect, e.g. [{\"send\": \"AT\\r\", \"expect\": \"OK\"}]")
+	}
+	if len(rawSteps) > 32 {
+		return ErrorResult("at most 32 steps per transaction")
+	}
+	encoding, _ := args["encoding"].(string)
+	timeout := serialTimeout(args["timeout_ms"], time.Second)
+
+	type step struct {
+		send    []byte
+		expect  *regexp.Regexp
+		timeout time.Duration
+	}
+	steps := make([]step, 0, len(rawSteps))
+	sends := false
+	for i, raw := range rawSteps {
+		m, _ := raw.(map[string]interface{})
+		send, _ := m["send"].(string)
+		pattern, _ := m["expect"].(string)
+		if send == "" && pattern == "" {
+			return ErrorResult(fmt.Sprintf("step %d needs send or expect", i+1))
+		}
+		s := step{timeout: serialTimeout(m["timeout_ms"], timeout)}
+		var err error
+		if s.send, err = serialPayload(send, encoding); err != nil {
+			return ErrorResult(fmt.Sprintf("step %d: %v", i+1, err))
+		}
+		if pattern != "" {
+			if s.expect, err = regexp.Compile(pattern); err != nil {
+				return ErrorResult(fmt.Sprintf("step %d: invalid expect pattern: %v", i+1, err))
+			}
+		}
+		sends = sends || len(s.send) > 0
+		steps = append(steps, s)
+	}
+	if confirm, _ := args["confirm"].(bool); sends && !confirm {
+		return ErrorResult("expect with send steps requires confirm: true. Please confirm with the user before writing to serial devices.")
+	}
+
+	p, err := t.port(path)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("failed to open %s: %v", path, err))
+	}
+	var transcript strings.Builder
+	for i, s := range steps {
+		if len(s.send) > 0 {
+			if _, err := p.conn.Write(s.send); err != nil {
+				t.drop(path)
+				return ErrorResult(fmt.Sprintf("step %d: failed to write to %s: %v\n%s", i+1, path, err, transcript.String()))
+			}
+			fmt.Fprintf(&transcript, "> %s\n", strconv.Quote(string(s.send)))
+		}
+		if s.expect == nil {
+			continue
+		}
+		re := s.expect
+		data, found, err := t.readUntil(ctx, p, s.timeout, 65536, func(b []byte) int {
+			if loc := re.FindIndex(b); loc != nil {
+				return loc[1]
+			}
+			return -1
+		})
+		if len(data) > 0 {
+			fmt.Fprintf(&transcript, "< %s\n", strconv.Quote(string(data)))
+		}
+		if err != nil && ctx.Err() == nil {
+			t.drop(path)
+			return ErrorResult(fmt.Sprintf("step %d: failed to read from %s: %v\n%s", i+1, path, err, transcript.String()))
+		}
+		if !found {
+			return ErrorResult(fmt.Sprintf("step %d: %q not seen within %s\n%s", i+1, re.String(), s.timeout, transcript.String()))
+		}
+	}
+	return SilentResult(fmt.Sprintf("Transaction on %s completed (%d steps):\n%s", path, len(steps), strings.TrimRight(transcript.String(), "\n")))
+}
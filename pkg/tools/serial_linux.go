@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// serialBauds maps supported baud rates to termios speed constants.
+var serialBauds = map[int]uint32{
+	300: unix.B300, 600: unix.B600, 1200: unix.B1200, 2400: unix.B2400,
+	4800: unix.B4800, 9600: unix.B9600, 19200: unix.B19200, 38400: unix.B38400,
+	57600: unix.B57600, 115200: unix.B115200, 230400: unix.B230400,
+	460800: unix.B460800, 500000: unix.B500000, 576000: unix.B576000,
+	921600: unix.B921600, 1000000: unix.B1000000, 1500000: unix.B1500000,
+	2000000: unix.B2000000, 3000000: unix.B3000000, 4000000: unix.B4000000,
+}
+
+type ttyConn struct {
+	fd int
+}
+
+// openSerialPort opens a tty without making it the controlling terminal
+// and puts it in raw mode with the given line settings.
+func openSerialPort(path string, s serialSettings) (serialConn, error) {
+	speed, ok := serialBauds[s.Baud]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", s.Baud)
+	}
+	fd, err := unix.Open(path, unix.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	tio, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("not a serial device: %v", err)
+	}
+
+	// Raw mode, as cfmakeraw(3).
+	tio.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR |
+		unix.ICRNL | unix.IXON | unix.IXOFF | unix.IXANY | unix.INPCK
+	tio.Oflag &^= unix.OPOST
+	tio.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+
+	tio.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	tio.Cflag |= unix.CLOCAL | unix.CREAD | speed
+	tio.Cflag |= map[int]uint32{5: unix.CS5, 6: unix.CS6, 7: unix.CS7, 8: unix.CS8}[s.DataBits]
+	switch s.Parity {
+	case "even":
+		tio.Cflag |= unix.PARENB
+		tio.Iflag |= unix.INPCK
+	case "odd":
+		tio.Cflag |= unix.PARENB | unix.PARODD
+		tio.Iflag |= unix.INPCK
+	}
+	if s.StopBits == 2 {
+		tio.Cflag |= unix.CSTOPB
+	}
+	if s.RTSCTS {
+		tio.Cflag |= unix.CRTSCTS
+	}
+	tio.Ispeed, tio.Ospeed = speed, speed
+	tio.Cc[unix.VMIN], tio.Cc[unix.VTIME] = 0, 0
+
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, tio); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to apply line settings: %v", err)
+	}
+	// Drop whatever arrived before the port was configured.
+	unix.IoctlSetInt(fd, unix.TCFLSH, unix.TCIFLUSH)
+	return &ttyConn{fd: fd}, nil
+}
+
+func (c *ttyConn) Read(p []byte, timeout time.Duration) (int, error) {
+	fds := []unix.PollFd{{Fd: int32(c.fd), Events: unix.POLLIN}}
+	n, err := unix.Poll(fds, int(timeout.Milliseconds()))
+	if err == unix.EINTR || n == 0 {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if fds[0].Revents&(unix.POLLHUP|unix.POLLERR) != 0 && fds[0].Revents&unix.POLLIN == 0 {
+		return 0, fmt.Errorf("device disconnected")
+	}
+	n, err = unix.Read(c.fd, p)
+	if err == unix.EAGAIN || err == unix.EINTR {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (c *ttyConn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		n, err := unix.Write(c.fd, p[written:])
+		if err == unix.EAGAIN || err == unix.EINTR {
+			fds := []unix.PollFd{{Fd: int32(c.fd), Events: unix.POLLOUT}}
+			if n, _ := unix.Poll(fds, 5000); n == 0 {
+				return written, fmt.Errorf("write timed out")
+			}
+			continue
+		}
+		if err != nil {
+			return written, err
+		}
+		written += n
+	}
+	// Wait until the data has left the UART (tcdrain).
+	unix.IoctlSetInt(c.fd, unix.TCSBRK, 1)
+	return written, nil
+}
+
+func (c *ttyConn) Close() error {
+	return unix.Close(c.fd)
+}
@@ -0,0 +1,198 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPty returns the master side of a pseudo-terminal pair and the path
+// of its slave, which stands in for a UART device.
+func openPty(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+	fd := int(master.Fd())
+	if err := unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); err != nil {
+		t.Fatalf("unlock pty: %v", err)
+	}
+	n, err := unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	if err != nil {
+		t.Fatalf("pty number: %v", err)
+	}
+	return master, fmt.Sprintf("/dev/pts/%d", n)
+}
+
+func newPtySerialTool(t *testing.T) (*SerialTool, *os.File, string) {
+	t.Helper()
+	master, port := openPty(t)
+	tool := NewSerialTool()
+	if err := tool.Configure(map[string]interface{}{"ports": []interface{}{"/dev/pts/*"}}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for path := range tool.ports {
+			tool.drop(path)
+		}
+	})
+	return tool, master, port
+}
+
+func readMaster(t *testing.T, master *os.File, want int) string {
+	t.Helper()
+	master.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 0, want)
+	chunk := make([]byte, 256)
+	for len(buf) < want {
+		n, err := master.Read(chunk)
+		if err != nil {
+			t.Fatalf("reading pty master: %v (got %q)", err, buf)
+		}
+		buf = append(buf, chunk[:n]...)
+	}
+	return string(buf)
+}
+
+func TestSerialTool_ConfigureReadWrite(t *testing.T) {
+	tool, master, port := newPtySerialTool(t)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{
+		"action": "configure", "port": port, "baud": 9600.0, "parity": "none", "stop_bits": 2.0,
+	})
+	if result.IsError || !strings.Contains(result.ForLLM, "9600 8N2") {
+		t.Fatalf("configure failed: %s", result.ForLLM)
+	}
+	tio, err := unix.IoctlGetTermios(int(tool.ports[port].conn.(*ttyConn).fd), unix.TCGETS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The pty driver forces 8 data bits without parity, so only check the rest.
+	if tio.Cflag&unix.CBAUD != unix.B9600 || tio.Cflag&unix.CSTOPB == 0 || tio.Lflag&(unix.ICANON|unix.ECHO) != 0 || tio.Oflag&unix.OPOST != 0 {
+		t.Errorf("termios not applied: cflag=%#o lflag=%#o oflag=%#o", tio.Cflag, tio.Lflag, tio.Oflag)
+	}
+	if r := tool.Execute(ctx, map[string]interface{}{"action": "configure", "port": port, "baud": 12345.0}); !r.IsError {
+		t.Error("unsupported baud rate should fail")
+	}
+	if p := tool.ports[port]; p == nil || p.settings.Baud != 9600 {
+		t.Error("a rejected configure should keep the port open with its settings")
+	}
+
+	master.WriteString("$GPGGA,123519,4807.038,N\r\n$GPRMC,123520\r\n")
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "port": port, "until": "\n", "timeout_ms": 2000.0})
+	if result.IsError || !strings.HasSuffix(result.ForLLM, ":\n$GPGGA,123519,4807.038,N\r\n") {
+		t.Errorf("first line: %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "port": port, "until": "\n"})
+	if !strings.HasSuffix(result.ForLLM, ":\n$GPRMC,123520\r\n") {
+		t.Errorf("data after the delimiter should be kept for the next read: %q", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "port": port, "timeout_ms": 50.0})
+	if result.IsError || !strings.HasPrefix(result.ForLLM, "No data from") {
+		t.Errorf("idle read: %q", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"action": "write", "port": port, "data": "AT\r"})
+	if !result.IsError || !strings.Contains(result.ForLLM, "confirm: true") {
+		t.Errorf("write without confirm should be refused: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "write", "port": port, "data": "41 54 0d", "encoding": "hex", "confirm": true})
+	if result.IsError {
+		t.Fatalf("write failed: %s", result.ForLLM)
+	}
+	if got := readMaster(t, master, 3); got != "AT\r" {
+		t.Errorf("device received %q", got)
+	}
+
+	master.Write([]byte{0x00, 0xB5, 0x62})
+	result = tool.Execute(ctx, map[string]interface{}{"action": "read", "port": port, "max_bytes": 3.0, "timeout_ms": 2000.0})
+	if !strings.HasSuffix(result.ForLLM, "hex: 00B562") {
+		t.Errorf("binary data should be shown as hex: %q", result.ForLLM)
+	}
+}
+
+func TestSerialTool_Expect(t *testing.T) {
+	tool, master, port := newPtySerialTool(t)
+	ctx := context.Background()
+	if r := tool.Execute(ctx, map[string]interface{}{"action": "configure", "port": port}); r.IsError {
+		t.Fatal(r.ForLLM)
+	}
+
+	// A modem answering AT commands.
+	go func() {
+		buf := make([]byte, 64)
+		var line []byte
+		for {
+			n, err := master.Read(buf)
+			if err != nil {
+				return
+			}
+			line = append(line, buf[:n]...)
+			for {
+				i := strings.IndexByte(string(line), '\r')
+				if i < 0 {
+					break
+				}
+				switch string(line[:i]) {
+				case "AT":
+					master.WriteString("\r\nOK\r\n")
+				case "AT+CSQ":
+					master.WriteString("\r\n+CSQ: 17,99\r\n\r\nOK\r\n")
+				}
+				line = line[i+1:]
+			}
+		}
+	}()
+
+	steps := []interface{}{
+		map[string]interface{}{"send": "AT\r", "expect": "OK\r\n"},
+		map[string]interface{}{"send": "AT+CSQ\r", "expect": `\+CSQ: \d+,\d+`},
+		map[string]interface{}{"expect": "OK"},
+	}
+	result := tool.Execute(ctx, map[string]interface{}{"action": "expect", "port": port, "steps": steps})
+	if !result.IsError || !strings.Contains(result.ForLLM, "confirm: true") {
+		t.Errorf("send steps without confirm should be refused: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "expect", "port": port, "steps": steps, "confirm": true, "timeout_ms": 2000.0})
+	if result.IsError || !strings.Contains(result.ForLLM, `< "\r\n+CSQ: 17,99"`) || !strings.HasSuffix(result.ForLLM, `< "\r\n\r\nOK"`) {
+		t.Errorf("unexpected transcript:\n%s", result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{
+		"action": "expect", "port": port, "confirm": true,
+		"steps": []interface{}{map[string]interface{}{"send": "ATZ\r", "expect": "OK", "timeout_ms": 100.0}},
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, `step 1: "OK" not seen within 100ms`) {
+		t.Errorf("missing response should fail the step: %s", result.ForLLM)
+	}
+}
+
+func TestSerialTool_Ports(t *testing.T) {
+	tool, _, port := newPtySerialTool(t)
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "list"})
+	if !strings.Contains(result.ForLLM, port) {
+		t.Errorf("list should include %s: %s", port, result.ForLLM)
+	}
+	for _, p := range []string{"/dev/sda", "/dev/pts/../sda", "/etc/passwd"} {
+		result = tool.Execute(ctx, map[string]interface{}{"action": "read", "port": p})
+		if !result.IsError || !strings.Contains(result.ForLLM, "not an allowed serial device") {
+			t.Errorf("%s should be refused: %s", p, result.ForLLM)
+		}
+	}
+	if NewSerialTool().allowedPort(port) {
+		t.Error("pseudo-terminals should not be allowed by default")
+	}
+	if err := NewSerialTool().Configure(map[string]interface{}{"ports": []interface{}{"ttyUSB0"}}); err == nil {
+		t.Error("ports outside /dev should be rejected")
+	}
+}
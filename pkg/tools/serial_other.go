@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tools
+
+import "fmt"
+
+// openSerialPort is a stub for non-Linux platforms.
+func openSerialPort(path string, s serialSettings) (serialConn, error) {
+	return nil, fmt.Errorf("serial ports are only supported on Linux")
+}
@@ -91,6 +91,9 @@ type ExecTool struct {
 	denyPatterns        []*regexp.Regexp
 	allowPatterns       []*regexp.Regexp
 	restrictToWorkspace bool
+	sandbox             *Sandbox
+	policy              *ExecPolicySet
+	agent               string
 }
 
 func NewExecTool(workingDir string, restrict bool) *ExecTool {
@@ -134,47 +137,37 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 		return ErrorResult("command is required")
 	}
 
-	cwd := t.workingDir
-	if wd, ok := args["working_dir"].(string); ok && wd != "" {
-		cwd = wd
-	}
-
-	if cwd == "" {
-		wd, err := os.Getwd()
-		if err == nil {
-			cwd = wd
-		}
+	cwd := t.resolveWorkingDir(args)
+	policy := t.resolvePolicy(ctx)
+	if guardError := t.guardCommand(command, cwd, policy); guardError != "" {
+		return ErrorResult(guardError)
 	}
 
-	if guardError := t.guardCommand(command, cwd); guardError != "" {
-		return ErrorResult(guardError)
+	timeout := t.timeout
+	if policy.Timeout > 0 {
+		timeout = policy.Timeout
 	}
 
 	// Apply timeout (default 5 minutes if not configured to prevent runaway processes)
 	var cmdCtx context.Context
 	var cancel context.CancelFunc
-	if t.timeout > 0 {
-		cmdCtx, cancel = context.WithTimeout(ctx, t.timeout)
+	if timeout > 0 {
+		cmdCtx, cancel = context.WithTimeout(ctx, timeout)
 	} else {
 		cmdCtx, cancel = context.WithTimeout(ctx, 5*time.Minute)
 	}
 	defer cancel()
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
-	} else {
-		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
-	}
-	if cwd != "" {
-		cmd.Dir = cwd
+	cmd, err := t.buildCommand(cmdCtx, command, cwd, policy)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("Sandbox unavailable: %v", err))
 	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 	output := stdout.String()
 	if stderr.Len() > 0 {
 		output += "\nSTDERR:\n" + stderr.String()
@@ -183,8 +176,8 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	if err != nil {
 		if cmdCtx.Err() == context.DeadlineExceeded {
 			var msg string
-			if t.timeout > 0 {
-				msg = fmt.Sprintf("Command timed out after %v", t.timeout)
+			if timeout > 0 {
+				msg = fmt.Sprintf("Command timed out after %v", timeout)
 			} else {
 				msg = "Command timed out"
 			}
@@ -221,27 +214,68 @@ func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) *To
 	}
 }
 
-func (t *ExecTool) guardCommand(command, cwd string) string {
-	cmd := strings.TrimSpace(command)
-	lower := strings.ToLower(cmd)
+// resolveWorkingDir returns the working_dir argument, the tool's working
+// directory or the process's, in that order.
+func (t *ExecTool) resolveWorkingDir(args map[string]interface{}) string {
+	cwd := t.workingDir
+	if wd, ok := args["working_dir"].(string); ok && wd != "" {
+		cwd = wd
+	}
 
-	for _, pattern := range t.denyPatterns {
-		if pattern.MatchString(lower) {
-			return "Command blocked by safety guard (dangerous pattern detected)"
+	if cwd == "" {
+		wd, err := os.Getwd()
+		if err == nil {
+			cwd = wd
 		}
 	}
+	return cwd
+}
 
-	if len(t.allowPatterns) > 0 {
-		allowed := false
-		for _, pattern := range t.allowPatterns {
-			if pattern.MatchString(lower) {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			return "Command blocked by safety guard (not in allowlist)"
+// buildCommand creates the command for a command line that already passed
+// guardCommand. cmdCtx bounds its lifetime. Errors come from the sandbox.
+func (t *ExecTool) buildCommand(cmdCtx context.Context, command, cwd string, policy *ExecPolicy) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	if t.sandbox != nil {
+		var err error
+		if cmd, err = t.sandbox.Command(cmdCtx, command, cwd); err != nil {
+			return nil, err
 		}
+	} else if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(cmdCtx, "powershell", "-NoProfile", "-NonInteractive", "-Command", command)
+	} else {
+		cmd = exec.CommandContext(cmdCtx, "sh", "-c", command)
+	}
+	if cwd != "" {
+		cmd.Dir = cwd
+	}
+	if env := policy.Environ(os.Environ()); env != nil {
+		cmd.Env = env
+	}
+	return cmd, nil
+}
+
+// resolvePolicy returns the exec policy for the caller in ctx. Without a
+// configured policy only the built-in deny patterns apply.
+func (t *ExecTool) resolvePolicy(ctx context.Context) *ExecPolicy {
+	if t.policy == nil {
+		return &ExecPolicy{}
+	}
+	return t.policy.Resolve(ExecCaller{
+		Agent:   t.agent,
+		Channel: ToolChannel(ctx),
+		ChatID:  ToolChatID(ctx),
+		Sender:  ToolSender(ctx),
+	})
+}
+
+func (t *ExecTool) guardCommand(command, cwd string, policy *ExecPolicy) string {
+	cmd := strings.TrimSpace(command)
+
+	if decision := policy.check(cmd, t.denyPatterns, t.allowPatterns); !decision.Allowed {
+		return fmt.Sprintf("Command blocked by %s (%s)", decision.Source, decision.Reason)
+	}
+	if reason := policy.CheckDir(cwd); reason != "" {
+		return fmt.Sprintf("Command blocked by exec policy (%s)", reason)
 	}
 
 	if t.restrictToWorkspace {
@@ -289,6 +323,19 @@ func (t *ExecTool) SetRestrictToWorkspace(restrict bool) {
 	t.restrictToWorkspace = restrict
 }
 
+// SetSandbox runs every command through the given sandbox. The deny
+// patterns and workspace checks still apply.
+func (t *ExecTool) SetSandbox(sandbox *Sandbox) {
+	t.sandbox = sandbox
+}
+
+// SetPolicy applies a configured exec policy. agent names the registry the
+// tool belongs to ("main" or "subagent") for per-agent overrides.
+func (t *ExecTool) SetPolicy(policy *ExecPolicySet, agent string) {
+	t.policy = policy
+	t.agent = agent
+}
+
 func (t *ExecTool) SetAllowPatterns(patterns []string) error {
 	t.allowPatterns = make([]*regexp.Regexp, 0, len(patterns))
 	for _, p := range patterns {
@@ -7,15 +7,37 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strings"
 )
 
 // SPITool provides SPI bus interaction for high-speed peripheral communication.
-type SPITool struct{}
+type SPITool struct {
+	devices []string // devices the agent may use, as "X.Y"; all when empty
+}
 
 func NewSPITool() *SPITool {
 	return &SPITool{}
 }
 
+// Configure takes the "devices" option, limiting the tool to the listed
+// devices ("2.0" for /dev/spidev2.0).
+func (t *SPITool) Configure(options map[string]interface{}) error {
+	if err := checkOptions(options, "devices"); err != nil {
+		return err
+	}
+	devices, err := optionStrings(options, "devices")
+	if err != nil {
+		return err
+	}
+	for _, d := range devices {
+		if matched, _ := regexp.MatchString(`^\d+\.\d+$`, d); !matched {
+			return fmt.Errorf("devices: invalid device %q", d)
+		}
+	}
+	t.devices = devices
+	return nil
+}
+
 func (t *SPITool) Name() string {
 	return "spi"
 }
@@ -77,6 +99,16 @@ func (t *SPITool) Execute(ctx context.Context, args map[string]interface{}) *Too
 		return ErrorResult("action is required")
 	}
 
+	if dev, _ := args["device"].(string); dev != "" && action != "list" && len(t.devices) > 0 {
+		allowed := false
+		for _, d := range t.devices {
+			allowed = allowed || d == dev
+		}
+		if !allowed {
+			return ErrorResult(fmt.Sprintf("device %s is not enabled for the spi tool (allowed: %s)", dev, strings.Join(t.devices, ", ")))
+		}
+	}
+
 	switch action {
 	case "list":
 		return t.list()
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jasperan/picooraclaw/pkg/docs"
+)
+
+// SQLResult is a bounded, text-rendered query result.
+type SQLResult struct {
+	Columns   []string
+	Rows      [][]string
+	Truncated bool
+}
+
+// SQLColumn describes a table column.
+type SQLColumn struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Comment  string
+}
+
+// SQLTable is the schema metadata shown by describe_table.
+type SQLTable struct {
+	Owner      string
+	Name       string
+	Kind       string
+	Comment    string
+	Columns    []SQLColumn
+	PrimaryKey []string
+}
+
+// SQLQuerier is the database access sql_query needs. Implementations must
+// reject anything but a single read-only query and enforce their own row
+// and time limits.
+type SQLQuerier interface {
+	Query(ctx context.Context, stmt string, binds interface{}, maxRows int) (*SQLResult, error)
+	DescribeTable(ctx context.Context, name string) (*SQLTable, error)
+	ListTables(ctx context.Context, schema string) (*SQLResult, error)
+	Schemas() []string
+}
+
+// SQLQueryTool lets the agent run read-only SELECT queries against Oracle.
+type SQLQueryTool struct {
+	db SQLQuerier
+}
+
+// NewSQLQueryTool creates the sql_query tool.
+func NewSQLQueryTool(db SQLQuerier) *SQLQueryTool {
+	return &SQLQueryTool{db: db}
+}
+
+func (t *SQLQueryTool) Name() string { return "sql_query" }
+
+func (t *SQLQueryTool) Description() string {
+	desc := "Run a read-only SQL query against the Oracle database and get the rows back as a markdown table. " +
+		"Only a single SELECT or WITH statement is accepted; pass values through bind variables (:name) instead of quoting them into the SQL. " +
+		"Use describe_table to see columns, types and comments before writing a query."
+	if schemas := t.db.Schemas(); len(schemas) > 0 {
+		desc += " Schemas available to describe_table and list_tables: " + strings.Join(schemas, ", ") + "."
+	}
+	return desc
+}
+
+func (t *SQLQueryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"query", "describe_table", "list_tables"},
+				"description": "query (default) runs sql; describe_table shows a table's columns; list_tables lists the tables of a schema",
+			},
+			"sql": map[string]interface{}{
+				"type":        "string",
+				"description": "SELECT or WITH statement for query",
+			},
+			"binds": map[string]interface{}{
+				"type":        "object",
+				"description": `Bind variable values by name, e.g. {"status": "open"} for :status, or {"1": 42} for :1`,
+			},
+			"max_rows": map[string]interface{}{
+				"type":        "integer",
+				"description": "Return at most this many rows (capped by the configured limit)",
+			},
+			"table": map[string]interface{}{
+				"type":        "string",
+				"description": "Table or view for describe_table, as SCHEMA.TABLE or TABLE",
+			},
+			"schema": map[string]interface{}{
+				"type":        "string",
+				"description": "Schema for list_tables",
+			},
+		},
+	}
+}
+
+func (t *SQLQueryTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	action, _ := args["action"].(string)
+	switch action {
+	case "", "query":
+		return t.query(ctx, args)
+	case "describe_table":
+		return t.describe(ctx, args)
+	case "list_tables":
+		schema, _ := args["schema"].(string)
+		res, err := t.db.ListTables(ctx, schema)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("list_tables failed: %v", err))
+		}
+		if len(res.Rows) == 0 {
+			return NewToolResult("No tables found")
+		}
+		return NewToolResult(formatSQLResult(res))
+	}
+	return ErrorResult(fmt.Sprintf("unknown action: %s", action))
+}
+
+func (t *SQLQueryTool) query(ctx context.Context, args map[string]interface{}) *ToolResult {
+	stmt, _ := args["sql"].(string)
+	if strings.TrimSpace(stmt) == "" {
+		return ErrorResult("sql is required")
+	}
+	maxRows := 0
+	if n, ok := args["max_rows"].(float64); ok && n > 0 {
+		maxRows = int(n)
+	}
+	res, err := t.db.Query(ctx, stmt, args["binds"], maxRows)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("Query failed: %v", err))
+	}
+	if len(res.Rows) == 0 {
+		return NewToolResult("Query returned no rows (columns: " + strings.Join(res.Columns, ", ") + ")")
+	}
+	return NewToolResult(formatSQLResult(res))
+}
+
+func (t *SQLQueryTool) describe(ctx context.Context, args map[string]interface{}) *ToolResult {
+	name, _ := args["table"].(string)
+	if strings.TrimSpace(name) == "" {
+		return ErrorResult("table is required for describe_table")
+	}
+	info, err := t.db.DescribeTable(ctx, name)
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("describe_table failed: %v", err))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s.%s (%s)\n", info.Owner, info.Name, strings.ToLower(info.Kind))
+	if info.Comment != "" {
+		sb.WriteString(info.Comment + "\n")
+	}
+	if len(info.PrimaryKey) > 0 {
+		sb.WriteString("Primary key: " + strings.Join(info.PrimaryKey, ", ") + "\n")
+	}
+	rows := [][]string{{"Column", "Type", "Null", "Comment"}}
+	for _, c := range info.Columns {
+		null := "NOT NULL"
+		if c.Nullable {
+			null = ""
+		}
+		rows = append(rows, []string{c.Name, c.Type, null, c.Comment})
+	}
+	sb.WriteString("\n" + docs.MarkdownTable(rows))
+	return NewToolResult(strings.TrimRight(sb.String(), "\n"))
+}
+
+func formatSQLResult(res *SQLResult) string {
+	rows := make([][]string, 0, len(res.Rows)+1)
+	rows = append(rows, res.Columns)
+	rows = append(rows, res.Rows...)
+	var sb strings.Builder
+	sb.WriteString(docs.MarkdownTable(rows))
+	if res.Truncated {
+		fmt.Fprintf(&sb, "\n(first %d rows shown; more rows matched, narrow the query or aggregate)", len(res.Rows))
+	} else {
+		fmt.Fprintf(&sb, "\n(%d row(s))", len(res.Rows))
+	}
+	return sb.String()
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeSQLQuerier struct {
+	gotStmt  string
+	gotBinds interface{}
+	gotMax   int
+}
+
+func (f *fakeSQLQuerier) Query(ctx context.Context, stmt string, binds interface{}, maxRows int) (*SQLResult, error) {
+	f.gotStmt, f.gotBinds, f.gotMax = stmt, binds, maxRows
+	if strings.HasPrefix(stmt, "DELETE") {
+		return nil, fmt.Errorf("only SELECT and WITH queries are allowed")
+	}
+	if strings.Contains(stmt, "none") {
+		return &SQLResult{Columns: []string{"ID"}}, nil
+	}
+	return &SQLResult{
+		Columns:   []string{"ID", "NAME"},
+		Rows:      [][]string{{"1", "desk | lamp"}, {"2", "NULL"}},
+		Truncated: maxRows == 2,
+	}, nil
+}
+
+func (f *fakeSQLQuerier) DescribeTable(ctx context.Context, name string) (*SQLTable, error) {
+	if name != "app.items" {
+		return nil, fmt.Errorf("schema SYS is not allowlisted")
+	}
+	return &SQLTable{
+		Owner: "APP", Name: "ITEMS", Kind: "TABLE", Comment: "Inventory",
+		Columns: []SQLColumn{
+			{Name: "ID", Type: "NUMBER(10)"},
+			{Name: "NAME", Type: "VARCHAR2(100)", Nullable: true, Comment: "Display name"},
+		},
+		PrimaryKey: []string{"ID"},
+	}, nil
+}
+
+func (f *fakeSQLQuerier) ListTables(ctx context.Context, schema string) (*SQLResult, error) {
+	return &SQLResult{Columns: []string{"TABLE_NAME", "TABLE_TYPE", "COMMENTS"}, Rows: [][]string{{"ITEMS", "TABLE", "Inventory"}}}, nil
+}
+
+func (f *fakeSQLQuerier) Schemas() []string { return []string{"APP"} }
+
+func TestSQLQueryTool_Query(t *testing.T) {
+	db := &fakeSQLQuerier{}
+	tool := NewSQLQueryTool(db)
+	ctx := context.Background()
+	if !strings.Contains(tool.Description(), "APP") {
+		t.Errorf("description should list the schemas: %s", tool.Description())
+	}
+
+	binds := map[string]interface{}{"kind": "lamp"}
+	result := tool.Execute(ctx, map[string]interface{}{"sql": "SELECT id, name FROM items WHERE kind = :kind", "binds": binds})
+	want := "| ID | NAME |\n| --- | --- |\n| 1 | desk \\| lamp |\n| 2 | NULL |\n\n(2 row(s))"
+	if result.IsError || result.ForLLM != want {
+		t.Errorf("unexpected result:\n%s", result.ForLLM)
+	}
+	if db.gotBinds == nil || db.gotMax != 0 {
+		t.Errorf("binds and limit not passed through: %v %d", db.gotBinds, db.gotMax)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"sql": "SELECT * FROM items", "max_rows": 2.0})
+	if !strings.Contains(result.ForLLM, "first 2 rows shown") {
+		t.Errorf("truncation should be reported: %s", result.ForLLM)
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"sql": "SELECT * FROM none"})
+	if result.IsError || result.ForLLM != "Query returned no rows (columns: ID)" {
+		t.Errorf("unexpected empty result: %s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"sql": "DELETE FROM items"}); !result.IsError {
+		t.Error("rejected statement should be an error")
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{}); !result.IsError {
+		t.Error("missing sql should be an error")
+	}
+}
+
+func TestSQLQueryTool_DescribeAndList(t *testing.T) {
+	tool := NewSQLQueryTool(&fakeSQLQuerier{})
+	ctx := context.Background()
+
+	result := tool.Execute(ctx, map[string]interface{}{"action": "describe_table", "table": "app.items"})
+	want := "APP.ITEMS (table)\nInventory\nPrimary key: ID\n\n" +
+		"| Column | Type | Null | Comment |\n| --- | --- | --- | --- |\n" +
+		"| ID | NUMBER(10) | NOT NULL |  |\n| NAME | VARCHAR2(100) |  | Display name |"
+	if result.IsError || result.ForLLM != want {
+		t.Errorf("unexpected describe output:\n%s", result.ForLLM)
+	}
+	if result := tool.Execute(ctx, map[string]interface{}{"action": "describe_table", "table": "sys.user$"}); !result.IsError {
+		t.Error("describe_table outside the allowlist should be an error")
+	}
+	result = tool.Execute(ctx, map[string]interface{}{"action": "list_tables"})
+	if result.IsError || !strings.Contains(result.ForLLM, "| ITEMS | TABLE | Inventory |") {
+		t.Errorf("unexpected list output:\n%s", result.ForLLM)
+	}
+}
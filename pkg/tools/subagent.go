@@ -82,7 +82,9 @@ func (sm *SubagentManager) Spawn(ctx context.Context, task, label, originChannel
 	sm.tasks[taskID] = subagentTask
 
 	// Start task in background with context cancellation support
-	go sm.runTask(ctx, subagentTask, callback)
+	// Spawned tasks run in the background and must not hold up user turns
+	// on a rate-limited provider.
+	go sm.runTask(providers.WithPriority(ctx, providers.PriorityBackground), subagentTask, callback)
 
 	if label != "" {
 		return fmt.Sprintf("Spawned subagent '%s' for task: %s", label, task), nil
@@ -0,0 +1,414 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ToolOutputOptions configures how oversized tool results are spilled to
+// disk. Zero values pick the defaults.
+type ToolOutputOptions struct {
+	Dir          string        // scratch directory, one subdirectory per session
+	Threshold    int           // results longer than this many bytes are spilled, default 16000
+	PreviewChars int           // bytes of head and tail shown in place of the result, default 2000
+	IdleTimeout  time.Duration // sessions unused this long are ended, default 24h
+}
+
+// ToolOutputStore keeps tool results that are too large for the
+// conversation in per-session scratch files. The model gets a head/tail
+// preview and a handle it can page through or search with
+// read_tool_output. Like background processes, the files belong to the
+// session (channel and chat) that produced them. Chats have no explicit end,
+// so the files are removed after IdleTimeout without tool calls, or when the
+// store is closed at shutdown; EndSession removes them sooner.
+type ToolOutputStore struct {
+	opts ToolOutputOptions
+
+	mu       sync.Mutex
+	sessions map[string]*outputSession
+	closed   bool
+	stop     chan struct{}
+}
+
+type outputSession struct {
+	dir      string
+	lastUsed time.Time
+}
+
+var outputHandleRe = regexp.MustCompile(`^out-[0-9a-f]{8}$`)
+
+// NewToolOutputStore creates the store, removes scratch directories left
+// idle by earlier runs and starts the idle-session reaper.
+func NewToolOutputStore(opts ToolOutputOptions) *ToolOutputStore {
+	if opts.Threshold <= 0 {
+		opts.Threshold = 16000
+	}
+	if opts.PreviewChars <= 0 {
+		opts.PreviewChars = 2000
+	}
+	if opts.PreviewChars > opts.Threshold/2 {
+		opts.PreviewChars = opts.Threshold / 2
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 24 * time.Hour
+	}
+	s := &ToolOutputStore{
+		opts:     opts,
+		sessions: make(map[string]*outputSession),
+		stop:     make(chan struct{}),
+	}
+	s.pruneStale()
+	go s.reapIdle()
+	return s
+}
+
+// pruneStale removes session directories not written to within IdleTimeout,
+// such as those of a previous process that did not shut down cleanly.
+func (s *ToolOutputStore) pruneStale() {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err == nil && e.IsDir() && time.Since(info.ModTime()) > s.opts.IdleTimeout {
+			os.RemoveAll(filepath.Join(s.opts.Dir, e.Name()))
+		}
+	}
+}
+
+func (s *ToolOutputStore) reapIdle() {
+	interval := s.opts.IdleTimeout / 4
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			var idle []string
+			s.mu.Lock()
+			for key, sess := range s.sessions {
+				if now.Sub(sess.lastUsed) > s.opts.IdleTimeout {
+					idle = append(idle, key)
+				}
+			}
+			s.mu.Unlock()
+			for _, key := range idle {
+				s.EndSession(key)
+			}
+		}
+	}
+}
+
+// session returns the session, creating it and marking it used.
+func (s *ToolOutputStore) session(key string) *outputSession {
+	sess, ok := s.sessions[key]
+	if !ok {
+		sess = &outputSession{dir: filepath.Join(s.opts.Dir, sessionDirName(key))}
+		s.sessions[key] = sess
+	}
+	sess.lastUsed = time.Now()
+	return sess
+}
+
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+func sessionDirName(key string) string {
+	name := unsafeDirChars.ReplaceAllString(key, "_")
+	if name == "" || strings.Trim(name, ".") == "" {
+		name = "_"
+	}
+	return name
+}
+
+// EndSession removes the session's scratch files.
+func (s *ToolOutputStore) EndSession(sessionKey string) {
+	s.mu.Lock()
+	sess := s.sessions[sessionKey]
+	delete(s.sessions, sessionKey)
+	s.mu.Unlock()
+	if sess != nil {
+		os.RemoveAll(sess.dir)
+	}
+}
+
+// Close removes every session's scratch files and stops the reaper.
+func (s *ToolOutputStore) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.stop)
+	keys := make([]string, 0, len(s.sessions))
+	for key := range s.sessions {
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+	for _, key := range keys {
+		s.EndSession(key)
+	}
+}
+
+// Spill replaces an oversized result's ForLLM with a preview and a handle.
+// Results within the threshold, and results that cannot be saved, are
+// returned unchanged.
+func (s *ToolOutputStore) Spill(sessionKey, toolName string, result *ToolResult) *ToolResult {
+	if result == nil || len(result.ForLLM) <= s.opts.Threshold {
+		return result
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return result
+	}
+	dir := s.session(sessionKey).dir
+	s.mu.Unlock()
+
+	handle, err := newOutputHandle()
+	if err == nil {
+		err = os.MkdirAll(dir, 0700)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, handle+".txt"), []byte(result.ForLLM), 0600)
+	}
+	if err != nil {
+		return result
+	}
+
+	spilled := *result
+	spilled.ForLLM = s.preview(toolName, handle, result.ForLLM)
+	return &spilled
+}
+
+func newOutputHandle() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "out-" + hex.EncodeToString(b), nil
+}
+
+// preview shows whole lines from the start and end of content, about
+// PreviewChars of each.
+func (s *ToolOutputStore) preview(toolName, handle, content string) string {
+	lines := strings.Count(content, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		lines++
+	}
+	head := content[:previewCut(content, s.opts.PreviewChars, true)]
+	tail := content[previewCut(content, len(content)-s.opts.PreviewChars, false):]
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s output is %s (%d lines) and was saved as %s. Showing its start and end; "+
+		"use read_tool_output with handle %q to read more by line offset or to search it with a pattern.]\n\n",
+		toolName, formatByteSize(int64(len(content))), lines, handle, handle)
+	sb.WriteString(head)
+	if !strings.HasSuffix(head, "\n") {
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "... [%s omitted] ...\n", formatByteSize(int64(len(content)-len(head)-len(tail))))
+	sb.WriteString(tail)
+	return sb.String()
+}
+
+// previewCut moves offset n to a line boundary: back to just after the
+// last newline before it for a head, forward past the next newline for a
+// tail. A line longer than the preview is cut mid-line at a rune boundary.
+func previewCut(content string, n int, head bool) int {
+	n = max(0, min(n, len(content)))
+	if head {
+		if i := strings.LastIndexByte(content[:n], '\n'); i >= 0 {
+			return i + 1
+		}
+		for n > 0 && !utf8.RuneStart(content[n]) {
+			n--
+		}
+		return n
+	}
+	if n == 0 {
+		return 0
+	}
+	if i := strings.IndexByte(content[n-1:], '\n'); i >= 0 && n+i < len(content) {
+		return n + i
+	}
+	for n < len(content) && !utf8.RuneStart(content[n]) {
+		n++
+	}
+	return n
+}
+
+// open returns the scratch file of handle in the session.
+func (s *ToolOutputStore) open(sessionKey, handle string) (*os.File, error) {
+	if !outputHandleRe.MatchString(handle) {
+		return nil, fmt.Errorf("invalid handle %q", handle)
+	}
+	// The session may not be known yet when its files were left by an
+	// earlier run.
+	s.mu.Lock()
+	dir := s.session(sessionKey).dir
+	s.mu.Unlock()
+	f, err := os.Open(filepath.Join(dir, handle+".txt"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no saved output %s in this session", handle)
+	}
+	return f, err
+}
+
+// ReadToolOutputTool pages through or searches results saved by a
+// ToolOutputStore.
+type ReadToolOutputTool struct {
+	store *ToolOutputStore
+}
+
+// NewReadToolOutputTool creates the read_tool_output tool.
+func NewReadToolOutputTool(store *ToolOutputStore) *ReadToolOutputTool {
+	return &ReadToolOutputTool{store: store}
+}
+
+func (t *ReadToolOutputTool) Name() string { return "read_tool_output" }
+
+func (t *ReadToolOutputTool) Description() string {
+	return "Read a tool result that was too large for the conversation and was saved with a handle. " +
+		"Page through it by line offset, or pass a pattern to get only the matching lines with their line numbers."
+}
+
+func (t *ReadToolOutputTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"handle": map[string]interface{}{
+				"type":        "string",
+				"description": "Handle from the truncated result, e.g. out-1a2b3c4d",
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "First line to read, starting at 1 (default 1)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of lines to read (default 200)",
+			},
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "Regular expression; return matching lines instead of a page",
+			},
+			"context": map[string]interface{}{
+				"type":        "integer",
+				"description": "Lines of context around each match (default 0)",
+			},
+		},
+		"required": []string{"handle"},
+	}
+}
+
+func (t *ReadToolOutputTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	handle, _ := args["handle"].(string)
+	session := ToolChannel(ctx) + ":" + ToolChatID(ctx)
+	f, err := t.store.open(session, strings.TrimSpace(handle))
+	if err != nil {
+		return ErrorResult(err.Error())
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return ErrorResult(fmt.Sprintf("failed to read %s: %v", handle, err))
+	}
+
+	if pattern, _ := args["pattern"].(string); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("invalid pattern: %v", err))
+		}
+		around := 0
+		if n, ok := args["context"].(float64); ok && n > 0 {
+			around = min(int(n), 20)
+		}
+		return SilentResult(t.grep(lines, re, around))
+	}
+
+	offset, limit := 1, 200
+	if n, ok := args["offset"].(float64); ok && n >= 1 {
+		offset = int(n)
+	}
+	if n, ok := args["limit"].(float64); ok && n >= 1 {
+		limit = int(n)
+	}
+	if offset > len(lines) {
+		return ErrorResult(fmt.Sprintf("offset %d is past the end (%d lines)", offset, len(lines)))
+	}
+
+	var sb strings.Builder
+	end := offset - 1
+	for end < len(lines) && end < offset-1+limit {
+		line := lines[end]
+		if sb.Len()+len(line) > t.store.opts.Threshold {
+			if end > offset-1 {
+				break
+			}
+			line = line[:previewCut(line, t.store.opts.Threshold, true)] + "...[line cut]"
+		}
+		sb.WriteString(line + "\n")
+		end++
+	}
+	header := fmt.Sprintf("[%s lines %d-%d of %d", handle, offset, end, len(lines))
+	if end < len(lines) {
+		header += fmt.Sprintf("; continue with offset %d", end+1)
+	}
+	return SilentResult(header + "]\n" + sb.String())
+}
+
+func (t *ReadToolOutputTool) grep(lines []string, re *regexp.Regexp, around int) string {
+	const maxMatches = 200
+	var sb strings.Builder
+	matches, last := 0, -1
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		matches++
+		if matches > maxMatches || sb.Len() > t.store.opts.Threshold {
+			continue
+		}
+		from, to := max(i-around, last+1), min(i+around, len(lines)-1)
+		if last >= 0 && from > last+1 {
+			sb.WriteString("--\n")
+		}
+		for j := from; j <= to; j++ {
+			fmt.Fprintf(&sb, "%d: %s\n", j+1, lines[j])
+		}
+		last = to
+	}
+	if matches == 0 {
+		return "No lines match"
+	}
+	shown := min(matches, maxMatches)
+	if sb.Len() > t.store.opts.Threshold {
+		return fmt.Sprintf("%d matching lines, output cut; narrow the pattern:\n%s", matches, sb.String())
+	}
+	if shown < matches {
+		return fmt.Sprintf("%d matching lines, first %d shown:\n%s", matches, shown, sb.String())
+	}
+	return fmt.Sprintf("%d matching lines:\n%s", matches, sb.String())
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type bigOutputTool struct{ lines int }
+
+func (t *bigOutputTool) Name() string        { return "big" }
+func (t *bigOutputTool) Description() string { return "prints many lines" }
+func (t *bigOutputTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{"type": "object"}
+}
+func (t *bigOutputTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
+	var sb strings.Builder
+	for i := 1; i <= t.lines; i++ {
+		fmt.Fprintf(&sb, "line %04d status=%s\n", i, map[bool]string{true: "FAIL", false: "ok"}[i%250 == 0])
+	}
+	return NewToolResult(sb.String())
+}
+
+func newSpillRegistry(t *testing.T) (*ToolRegistry, *ToolOutputStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store := NewToolOutputStore(ToolOutputOptions{Dir: dir, Threshold: 4000, PreviewChars: 500})
+	t.Cleanup(store.Close)
+	registry := NewToolRegistry()
+	registry.Register(&bigOutputTool{lines: 1000})
+	registry.SetOutputStore(store)
+	return registry, store, dir
+}
+
+func TestToolOutputStore_SpillAndRead(t *testing.T) {
+	registry, _, dir := newSpillRegistry(t)
+	ctx := context.Background()
+
+	result := registry.ExecuteWithContext(ctx, "big", nil, "telegram", "42", nil)
+	handle := regexp.MustCompile(`out-[0-9a-f]{8}`).FindString(result.ForLLM)
+	if handle == "" || len(result.ForLLM) > 2000 {
+		t.Fatalf("expected a short preview with a handle, got %d bytes:\n%s", len(result.ForLLM), result.ForLLM)
+	}
+	if !strings.Contains(result.ForLLM, "(1000 lines)") || !strings.Contains(result.ForLLM, "\nline 0001 status=ok\n") ||
+		!strings.HasSuffix(result.ForLLM, "line 1000 status=FAIL\n") || !strings.Contains(result.ForLLM, "omitted] ...\nline 09") {
+		t.Errorf("unexpected preview:\n%s", result.ForLLM)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "telegram_42", handle+".txt")); err != nil {
+		t.Errorf("scratch file missing: %v", err)
+	}
+
+	read := func(channel, chatID string, args map[string]interface{}) *ToolResult {
+		args["handle"] = handle
+		return registry.ExecuteWithContext(ctx, "read_tool_output", args, channel, chatID, nil)
+	}
+	page := read("telegram", "42", map[string]interface{}{"offset": 101.0, "limit": 3.0})
+	want := fmt.Sprintf("[%s lines 101-103 of 1000; continue with offset 104]\nline 0101 status=ok\nline 0102 status=ok\nline 0103 status=ok\n", handle)
+	if page.IsError || page.ForLLM != want {
+		t.Errorf("unexpected page:\n%s", page.ForLLM)
+	}
+	page = read("telegram", "42", map[string]interface{}{"offset": 1.0, "limit": 1000.0})
+	if !strings.Contains(page.ForLLM, "continue with offset") || len(page.ForLLM) > 4100 {
+		t.Errorf("a page should stay within the threshold, got %d bytes", len(page.ForLLM))
+	}
+	grep := read("telegram", "42", map[string]interface{}{"pattern": "FAIL", "context": 1.0})
+	if !strings.HasPrefix(grep.ForLLM, "4 matching lines:\n249: line 0249 status=ok\n250: line 0250 status=FAIL\n251:") ||
+		!strings.Contains(grep.ForLLM, "--\n499:") {
+		t.Errorf("unexpected grep output:\n%s", grep.ForLLM)
+	}
+	if r := read("slack", "7", map[string]interface{}{}); !r.IsError {
+		t.Error("another session should not see the output")
+	}
+	if r := registry.ExecuteWithContext(ctx, "read_tool_output", map[string]interface{}{"handle": "../telegram_42/x"}, "slack", "7", nil); !r.IsError {
+		t.Error("malformed handle should be refused")
+	}
+}
+
+func TestToolOutputStore_EndSession(t *testing.T) {
+	registry, store, dir := newSpillRegistry(t)
+	ctx := context.Background()
+
+	registry.ExecuteWithContext(ctx, "big", nil, "cli", "direct", nil)
+	small := store.Spill("cli:direct", "small", NewToolResult("short"))
+	if small.ForLLM != "short" {
+		t.Errorf("small results should pass through, got %q", small.ForLLM)
+	}
+	sessionDir := filepath.Join(dir, "cli_direct")
+	if entries, _ := os.ReadDir(sessionDir); len(entries) != 1 {
+		t.Fatalf("expected one scratch file, got %d", len(entries))
+	}
+	store.EndSession("cli:direct")
+	if _, err := os.Stat(sessionDir); !os.IsNotExist(err) {
+		t.Error("scratch files should be removed with the session")
+	}
+
+	registry.ExecuteWithContext(ctx, "big", nil, "cli", "direct", nil)
+	store.Close()
+	if _, err := os.Stat(sessionDir); !os.IsNotExist(err) {
+		t.Error("scratch files should be removed on Close")
+	}
+}
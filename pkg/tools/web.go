@@ -1,6 +1,7 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -10,42 +11,39 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/docs"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 const (
 	userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
 )
 
+// SearchProvider is a web search backend. Providers return structured
+// results; formatting, de-duplication and caching are done by WebSearchTool.
 type SearchProvider interface {
-	Search(ctx context.Context, query string, count int) (string, error)
+	Name() string
+	Search(ctx context.Context, query string, count int) ([]SearchResult, error)
 }
 
 type BraveSearchProvider struct {
 	apiKey string
 }
 
-func (p *BraveSearchProvider) Search(ctx context.Context, query string, count int) (string, error) {
+func (p *BraveSearchProvider) Name() string {
+	return "Brave"
+}
+
+func (p *BraveSearchProvider) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
 	searchURL := fmt.Sprintf("https://api.search.brave.com/res/v1/web/search?q=%s&count=%d",
 		url.QueryEscape(query), count)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Subscription-Token", p.apiKey)
-
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := searchGet(ctx, client, searchURL, map[string]string{"X-Subscription-Token": p.apiKey})
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
 	var searchResp struct {
@@ -54,64 +52,58 @@ func (p *BraveSearchProvider) Search(ctx context.Context, query string, count in
 				Title       string `json:"title"`
 				URL         string `json:"url"`
 				Description string `json:"description"`
+				Age         string `json:"age"`
+				PageAge     string `json:"page_age"`
 			} `json:"results"`
 		} `json:"web"`
 	}
 
 	if err := json.Unmarshal(body, &searchResp); err != nil {
-		// Log error body for debugging
-		fmt.Printf("Brave API Error Body: %s\n", string(body))
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	results := searchResp.Web.Results
-	if len(results) == 0 {
-		return fmt.Sprintf("No results for: %s", query), nil
-	}
-
-	var lines []string
-	lines = append(lines, fmt.Sprintf("Results for: %s", query))
-	for i, item := range results {
-		if i >= count {
-			break
-		}
-		lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, item.Title, item.URL))
-		if item.Description != "" {
-			lines = append(lines, fmt.Sprintf("   %s", item.Description))
+	results := make([]SearchResult, 0, len(searchResp.Web.Results))
+	for _, item := range searchResp.Web.Results {
+		date := shortDate(item.PageAge)
+		if date == "" {
+			date = item.Age
 		}
-	}
-
-	return strings.Join(lines, "\n"), nil
+		results = append(results, SearchResult{
+			Title:   stripTags(item.Title),
+			URL:     item.URL,
+			Snippet: stripTags(item.Description),
+			Date:    date,
+		})
+	}
+	return results, nil
 }
 
 type DuckDuckGoSearchProvider struct{}
 
-func (p *DuckDuckGoSearchProvider) Search(ctx context.Context, query string, count int) (string, error) {
+func (p *DuckDuckGoSearchProvider) Name() string {
+	return "DuckDuckGo"
+}
+
+func (p *DuckDuckGoSearchProvider) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
 	searchURL := fmt.Sprintf("https://html.duckduckgo.com/html/?q=%s", url.QueryEscape(query))
 
 	req, err := http.NewRequestWithContext(ctx, "GET", searchURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("User-Agent", userAgent)
 
 	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	body, err := doSearchRequest(client, req)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	return p.extractResults(string(body), count, query)
+	return p.extractResults(string(body), count), nil
 }
 
-func (p *DuckDuckGoSearchProvider) extractResults(html string, count int, query string) (string, error) {
+func (p *DuckDuckGoSearchProvider) extractResults(html string, count int) []SearchResult {
 	// Simple regex based extraction for DDG HTML
 	// Strategy: Find all result containers or key anchors directly
 
@@ -121,30 +113,14 @@ func (p *DuckDuckGoSearchProvider) extractResults(html string, count int, query
 	reLink := regexp.MustCompile(`<a[^>]*class="[^"]*result__a[^"]*"[^>]*href="([^"]+)"[^>]*>([\s\S]*?)</a>`)
 	matches := reLink.FindAllStringSubmatch(html, count+5)
 
-	if len(matches) == 0 {
-		return fmt.Sprintf("No results found or extraction failed. Query: %s", query), nil
-	}
-
-	var lines []string
-	lines = append(lines, fmt.Sprintf("Results for: %s (via DuckDuckGo)", query))
-
-	// Pre-compile snippet regex to run inside the loop
-	// We'll search for snippets relative to the link position or just globally if needed
-	// But simple global search for snippets might mismatch order.
-	// Since we only have the raw HTML string, let's just extract snippets globally and assume order matches (risky but simple for regex)
-	// Or better: Let's assume the snippet follows the link in the HTML
-
-	// A better regex approach: iterate through text and find matches in order
-	// But for now, let's grab all snippets too
+	// Snippets are matched globally and paired with links by position,
+	// since each snippet follows its link in the HTML.
 	reSnippet := regexp.MustCompile(`<a class="result__snippet[^"]*".*?>([\s\S]*?)</a>`)
 	snippetMatches := reSnippet.FindAllStringSubmatch(html, count+5)
 
-	maxItems := min(len(matches), count)
-
-	for i := 0; i < maxItems; i++ {
-		urlStr := matches[i][1]
-		title := stripTags(matches[i][2])
-		title = strings.TrimSpace(title)
+	results := make([]SearchResult, 0, len(matches))
+	for i, m := range matches {
+		urlStr := m[1]
 
 		// URL decoding if needed
 		if strings.Contains(urlStr, "uddg=") {
@@ -155,32 +131,34 @@ func (p *DuckDuckGoSearchProvider) extractResults(html string, count int, query
 				}
 			}
 		}
+		if i := strings.Index(urlStr, "&rut="); i >= 0 {
+			urlStr = urlStr[:i]
+		}
 
-		lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, title, urlStr))
-
-		// Attempt to attach snippet if available and index aligns
+		r := SearchResult{Title: strings.TrimSpace(stripTags(m[2])), URL: urlStr}
 		if i < len(snippetMatches) {
-			snippet := stripTags(snippetMatches[i][1])
-			snippet = strings.TrimSpace(snippet)
-			if snippet != "" {
-				lines = append(lines, fmt.Sprintf("   %s", snippet))
-			}
+			r.Snippet = strings.TrimSpace(stripTags(snippetMatches[i][1]))
 		}
+		results = append(results, r)
 	}
-
-	return strings.Join(lines, "\n"), nil
+	return results
 }
 
+var tagPattern = regexp.MustCompile(`<[^>]+>`)
+
 func stripTags(content string) string {
-	re := regexp.MustCompile(`<[^>]+>`)
-	return re.ReplaceAllString(content, "")
+	return html.UnescapeString(tagPattern.ReplaceAllString(content, ""))
 }
 
 type PerplexitySearchProvider struct {
 	apiKey string
 }
 
-func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, count int) (string, error) {
+func (p *PerplexitySearchProvider) Name() string {
+	return "Perplexity"
+}
+
+func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
 	searchURL := "https://api.perplexity.ai/chat/completions"
 
 	payload := map[string]interface{}{
@@ -194,12 +172,12 @@ func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, cou
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", searchURL, strings.NewReader(string(payloadBytes)))
+	req, err := http.NewRequestWithContext(ctx, "POST", searchURL, bytes.NewReader(payloadBytes))
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -207,19 +185,9 @@ func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, cou
 	req.Header.Set("User-Agent", userAgent)
 
 	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	body, err := doSearchRequest(client, req)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Perplexity API error: %s", string(body))
+		return nil, fmt.Errorf("Perplexity API error: %w", err)
 	}
 
 	var searchResp struct {
@@ -228,22 +196,86 @@ func (p *PerplexitySearchProvider) Search(ctx context.Context, query string, cou
 				Content string `json:"content"`
 			} `json:"message"`
 		} `json:"choices"`
+		SearchResults []struct {
+			Title string `json:"title"`
+			URL   string `json:"url"`
+			Date  string `json:"date"`
+		} `json:"search_results"`
 	}
 
 	if err := json.Unmarshal(body, &searchResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	if len(searchResp.Choices) == 0 {
-		return fmt.Sprintf("No results for: %s", query), nil
+	var content string
+	if len(searchResp.Choices) > 0 {
+		content = searchResp.Choices[0].Message.Content
 	}
+	listed := parseNumberedResults(content)
 
-	return fmt.Sprintf("Results for: %s (via Perplexity)\n%s", query, searchResp.Choices[0].Message.Content), nil
+	// Prefer the sources the API reports; take snippets from the answer
+	// text where it lists the same URL.
+	if len(searchResp.SearchResults) > 0 {
+		snippets := make(map[string]string, len(listed))
+		for _, r := range listed {
+			snippets[r.URL] = r.Snippet
+		}
+		results := make([]SearchResult, 0, len(searchResp.SearchResults))
+		for _, s := range searchResp.SearchResults {
+			results = append(results, SearchResult{Title: s.Title, URL: s.URL, Snippet: snippets[s.URL], Date: shortDate(s.Date)})
+		}
+		return results, nil
+	}
+	return listed, nil
 }
 
+var (
+	numberedTitlePattern = regexp.MustCompile(`^\s*\d+[.)]\s+(.+)$`)
+	urlPattern           = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+)
+
+// parseNumberedResults reads results written as "1. Title\n   URL\n
+// Description", the format Perplexity is asked to answer in. Markdown
+// links and bold titles are tolerated.
+func parseNumberedResults(text string) []SearchResult {
+	var results []SearchResult
+	var cur *SearchResult
+	for _, line := range strings.Split(text, "\n") {
+		if m := numberedTitlePattern.FindStringSubmatch(line); m != nil {
+			results = append(results, SearchResult{})
+			cur = &results[len(results)-1]
+			line = m[1]
+		} else if cur == nil {
+			continue
+		}
+		if cur.URL == "" {
+			if u := urlPattern.FindString(line); u != "" {
+				cur.URL = strings.TrimRight(u, ".,;:")
+				line = strings.Replace(line, u, "", 1)
+			}
+		}
+		line = strings.Trim(strings.TrimSpace(line), "*[]()-–: ")
+		switch {
+		case line == "":
+		case cur.Title == "":
+			cur.Title = line
+		case cur.Snippet == "":
+			cur.Snippet = line
+		default:
+			cur.Snippet += " " + line
+		}
+	}
+	return results
+}
+
+// DefaultSearchOrder is the provider fallback order used when none is
+// configured.
+var DefaultSearchOrder = []string{"perplexity", "brave", "searxng", "json", "duckduckgo"}
+
 type WebSearchTool struct {
-	provider   SearchProvider
-	maxResults int
+	providers  []SearchProvider
+	maxResults []int // per provider, parallel to providers
+	cache      *searchCache
 }
 
 type WebSearchToolOptions struct {
@@ -255,36 +287,69 @@ type WebSearchToolOptions struct {
 	PerplexityAPIKey     string
 	PerplexityMaxResults int
 	PerplexityEnabled    bool
+	SearXNGURL           string
+	SearXNGMaxResults    int
+	SearXNGEnabled       bool
+	SearXNGCategories    string
+	SearXNGLanguage      string
+	JSON                 JSONSearchOptions
+	JSONMaxResults       int
+	JSONEnabled          bool
+	Order                []string      // provider names, tried in turn; defaults to DefaultSearchOrder
+	CacheTTL             time.Duration // 0 disables the query cache
 }
 
+// NewWebSearchTool returns a search tool over the enabled providers in
+// fallback order, or nil when none is usable. Unknown names in Order are
+// ignored; enabled providers missing from Order are not used.
 func NewWebSearchTool(opts WebSearchToolOptions) *WebSearchTool {
-	var provider SearchProvider
-	maxResults := 5
-
-	// Priority: Perplexity > Brave > DuckDuckGo
-	if opts.PerplexityEnabled && opts.PerplexityAPIKey != "" {
-		provider = &PerplexitySearchProvider{apiKey: opts.PerplexityAPIKey}
-		if opts.PerplexityMaxResults > 0 {
-			maxResults = opts.PerplexityMaxResults
+	order := opts.Order
+	if len(order) == 0 {
+		order = DefaultSearchOrder
+	}
+
+	t := &WebSearchTool{cache: newSearchCache(opts.CacheTTL)}
+	add := func(p SearchProvider, max int) {
+		if max <= 0 {
+			max = 5
 		}
-	} else if opts.BraveEnabled && opts.BraveAPIKey != "" {
-		provider = &BraveSearchProvider{apiKey: opts.BraveAPIKey}
-		if opts.BraveMaxResults > 0 {
-			maxResults = opts.BraveMaxResults
+		t.providers = append(t.providers, p)
+		t.maxResults = append(t.maxResults, max)
+	}
+	seen := make(map[string]bool)
+	for _, name := range order {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if seen[name] {
+			continue
 		}
-	} else if opts.DuckDuckGoEnabled {
-		provider = &DuckDuckGoSearchProvider{}
-		if opts.DuckDuckGoMaxResults > 0 {
-			maxResults = opts.DuckDuckGoMaxResults
+		seen[name] = true
+		switch name {
+		case "perplexity":
+			if opts.PerplexityEnabled && opts.PerplexityAPIKey != "" {
+				add(&PerplexitySearchProvider{apiKey: opts.PerplexityAPIKey}, opts.PerplexityMaxResults)
+			}
+		case "brave":
+			if opts.BraveEnabled && opts.BraveAPIKey != "" {
+				add(&BraveSearchProvider{apiKey: opts.BraveAPIKey}, opts.BraveMaxResults)
+			}
+		case "searxng":
+			if opts.SearXNGEnabled && opts.SearXNGURL != "" {
+				add(NewSearXNGSearchProvider(opts.SearXNGURL, opts.SearXNGCategories, opts.SearXNGLanguage), opts.SearXNGMaxResults)
+			}
+		case "json":
+			if opts.JSONEnabled && opts.JSON.URL != "" {
+				add(NewJSONSearchProvider(opts.JSON), opts.JSONMaxResults)
+			}
+		case "duckduckgo":
+			if opts.DuckDuckGoEnabled {
+				add(&DuckDuckGoSearchProvider{}, opts.DuckDuckGoMaxResults)
+			}
 		}
-	} else {
-		return nil
 	}
-
-	return &WebSearchTool{
-		provider:   provider,
-		maxResults: maxResults,
+	if len(t.providers) == 0 {
+		return nil
 	}
+	return t
 }
 
 func (t *WebSearchTool) Name() string {
@@ -292,7 +357,7 @@ func (t *WebSearchTool) Name() string {
 }
 
 func (t *WebSearchTool) Description() string {
-	return "Search the web for current information. Returns titles, URLs, and snippets from search results."
+	return "Search the web for current information. Returns titles, URLs, dates, and snippets from search results."
 }
 
 func (t *WebSearchTool) Parameters() map[string]interface{} {
@@ -314,24 +379,64 @@ func (t *WebSearchTool) Parameters() map[string]interface{} {
 	}
 }
 
+// Search tries each provider in order and returns the first non-empty,
+// de-duplicated result set. count <= 0 uses the provider's configured
+// maximum. Errors are only returned when every provider failed.
+func (t *WebSearchTool) Search(ctx context.Context, query string, count int) (*SearchResponse, error) {
+	if resp, ok := t.cache.get(query, count); ok {
+		return resp, nil
+	}
+
+	var errs []string
+	var empty *SearchResponse
+	for i, p := range t.providers {
+		n := count
+		if n <= 0 {
+			n = t.maxResults[i]
+		}
+		results, err := p.Search(ctx, query, n)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			errs = append(errs, fmt.Sprintf("%s: %v", p.Name(), err))
+			continue
+		}
+		resp := &SearchResponse{Query: query, Provider: p.Name(), Results: dedupeResults(results, n)}
+		if len(resp.Results) == 0 {
+			if empty == nil {
+				empty = resp
+			}
+			continue
+		}
+		t.cache.put(resp, count)
+		return resp, nil
+	}
+	if empty != nil {
+		return empty, nil
+	}
+	return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+}
+
 func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	query, ok := args["query"].(string)
-	if !ok {
+	if !ok || strings.TrimSpace(query) == "" {
 		return ErrorResult("query is required")
 	}
 
-	count := t.maxResults
+	count := 0
 	if c, ok := args["count"].(float64); ok {
 		if int(c) > 0 && int(c) <= 10 {
 			count = int(c)
 		}
 	}
 
-	result, err := t.provider.Search(ctx, query, count)
+	resp, err := t.Search(ctx, query, count)
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("search failed: %v", err))
 	}
 
+	result := resp.Format()
 	return &ToolResult{
 		ForLLM:  result,
 		ForUser: result,
@@ -340,6 +445,9 @@ func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}
 
 type WebFetchTool struct {
 	maxChars int
+	policy   *NetPolicy
+	cache    *WebCache
+	client   *http.Client
 }
 
 func NewWebFetchTool(maxChars int) *WebFetchTool {
@@ -348,15 +456,43 @@ func NewWebFetchTool(maxChars int) *WebFetchTool {
 	}
 	return &WebFetchTool{
 		maxChars: maxChars,
+		client: &http.Client{
+			Timeout: 60 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        10,
+				IdleConnTimeout:     30 * time.Second,
+				DisableCompression:  false,
+				TLSHandshakeTimeout: 15 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 5 {
+					return fmt.Errorf("stopped after 5 redirects")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// SetNetPolicy restricts which hosts and addresses may be fetched.
+func (t *WebFetchTool) SetNetPolicy(policy *NetPolicy) {
+	t.policy = policy
+	if policy != nil {
+		t.client = policy.Client(60*time.Second, 5)
 	}
 }
 
+// SetCache enables the on-disk response cache.
+func (t *WebFetchTool) SetCache(cache *WebCache) {
+	t.cache = cache
+}
+
 func (t *WebFetchTool) Name() string {
 	return "web_fetch"
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetch a URL and extract readable content (HTML to text). Use this to get weather info, news, articles, or any web content."
+	return "Fetch a URL and extract readable content (the main text of HTML pages as markdown with links; PDF, DOCX and XLSX documents are converted too). Use this to get weather info, news, articles, or any web content."
 }
 
 func (t *WebFetchTool) Parameters() map[string]interface{} {
@@ -372,11 +508,26 @@ func (t *WebFetchTool) Parameters() map[string]interface{} {
 				"description": "Maximum characters to extract",
 				"minimum":     100.0,
 			},
+			"pages": map[string]interface{}{
+				"type":        "string",
+				"description": "For PDF and XLSX documents: pages or sheets to extract, e.g. \"1-3\"",
+			},
+			"full_page": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Convert the whole HTML page instead of only its main content",
+			},
+			"refresh": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Bypass the cache and fetch the URL again",
+			},
 		},
 		"required": []string{"url"},
 	}
 }
 
+// webFetchMaxBytes caps the size of a fetched response.
+const webFetchMaxBytes = 20 << 20
+
 func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{}) *ToolResult {
 	urlStr, ok := args["url"].(string)
 	if !ok {
@@ -387,13 +538,8 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("invalid URL: %v", err))
 	}
-
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return ErrorResult("only http/https URLs are allowed")
-	}
-
-	if parsedURL.Host == "" {
-		return ErrorResult("missing domain in URL")
+	if err := t.policy.CheckURL(parsedURL); err != nil {
+		return ErrorResult(err.Error())
 	}
 
 	maxChars := t.maxChars
@@ -403,45 +549,23 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		}
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
-	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to create request: %v", err))
-	}
-
-	req.Header.Set("User-Agent", userAgent)
-
-	client := &http.Client{
-		Timeout: 60 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     30 * time.Second,
-			DisableCompression:  false,
-			TLSHandshakeTimeout: 15 * time.Second,
-		},
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 5 {
-				return fmt.Errorf("stopped after 5 redirects")
-			}
-			return nil
-		},
-	}
-
-	resp, err := client.Do(req)
+	refresh, _ := args["refresh"].(bool)
+	resp, cacheStatus, err := t.fetch(ctx, urlStr, refresh)
 	if err != nil {
-		return ErrorResult(fmt.Sprintf("request failed: %v", err))
+		return ErrorResult(err.Error())
 	}
-	defer resp.Body.Close()
+	body, contentType := resp.Body, resp.ContentType
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return ErrorResult(fmt.Sprintf("failed to read response: %v", err))
-	}
-
-	contentType := resp.Header.Get("Content-Type")
+	var text, extractor, document string
 
-	var text, extractor string
-
-	if strings.Contains(contentType, "application/json") {
+	if format := fetchedDocumentFormat(contentType, parsedURL.Path, body); format != "" {
+		pages, _ := args["pages"].(string)
+		doc, err := docs.Extract(body, format, docs.Options{Pages: pages})
+		if err != nil {
+			return ErrorResult(fmt.Sprintf("failed to extract %s: %v", urlStr, err))
+		}
+		text, extractor, document = doc.Text, format, doc.Summary()
+	} else if strings.Contains(contentType, "application/json") {
 		var jsonData interface{}
 		if err := json.Unmarshal(body, &jsonData); err == nil {
 			formatted, _ := json.MarshalIndent(jsonData, "", "  ")
@@ -453,8 +577,8 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 		}
 	} else if strings.Contains(contentType, "text/html") || len(body) > 0 &&
 		(strings.HasPrefix(string(body), "<!DOCTYPE") || strings.HasPrefix(strings.ToLower(string(body)), "<html")) {
-		text = t.extractText(string(body))
-		extractor = "text"
+		fullPage, _ := args["full_page"].(bool)
+		text, extractor = t.htmlToMarkdown(body, resp.FinalURL, fullPage)
 	} else {
 		text = string(body)
 		extractor = "raw"
@@ -467,42 +591,155 @@ func (t *WebFetchTool) Execute(ctx context.Context, args map[string]interface{})
 
 	result := map[string]interface{}{
 		"url":       urlStr,
-		"status":    resp.StatusCode,
+		"status":    resp.Status,
 		"extractor": extractor,
 		"truncated": truncated,
 		"length":    len(text),
 		"text":      text,
 	}
+	if document != "" {
+		result["document"] = document
+	}
+	if resp.FinalURL != "" && resp.FinalURL != urlStr {
+		result["final_url"] = resp.FinalURL
+	}
+	if cacheStatus != "" {
+		result["cache"] = cacheStatus
+	}
 
 	resultJSON, _ := json.MarshalIndent(result, "", "  ")
 
+	summary := fmt.Sprintf("Fetched %d bytes from %s (extractor: %s, truncated: %v", len(text), urlStr, extractor, truncated)
+	if cacheStatus != "" {
+		summary += ", cache: " + cacheStatus
+	}
+	summary += ")"
+	if resp.Status >= 400 {
+		summary += fmt.Sprintf("\nHTTP status %d", resp.Status)
+	}
 	return &ToolResult{
-		ForLLM:  fmt.Sprintf("Fetched %d bytes from %s (extractor: %s, truncated: %v)", len(text), urlStr, extractor, truncated),
+		ForLLM:  summary + "\n\n" + text,
 		ForUser: string(resultJSON),
 	}
 }
 
-func (t *WebFetchTool) extractText(htmlContent string) string {
-	re := regexp.MustCompile(`<script[\s\S]*?</script>`)
-	result := re.ReplaceAllLiteralString(htmlContent, "")
-	re = regexp.MustCompile(`<style[\s\S]*?</style>`)
-	result = re.ReplaceAllLiteralString(result, "")
-	re = regexp.MustCompile(`<[^>]+>`)
-	result = re.ReplaceAllLiteralString(result, "")
+// fetch returns the response for a URL, from the cache when it is fresh
+// or the server confirms it has not changed.
+func (t *WebFetchTool) fetch(ctx context.Context, urlStr string, refresh bool) (*cachedResponse, string, error) {
+	var cached *cachedResponse
+	if t.cache != nil && !refresh {
+		entry, fresh := t.cache.get(urlStr)
+		if fresh {
+			return entry, "hit", nil
+		}
+		cached = entry
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cached.FetchedAt = time.Now()
+		t.cache.put(cached)
+		return cached, "revalidated", nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, webFetchMaxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read response: %v", err)
+	}
+	if len(body) > webFetchMaxBytes {
+		return nil, "", fmt.Errorf("response is larger than %d MB", webFetchMaxBytes>>20)
+	}
 
-	result = strings.TrimSpace(result)
+	entry := &cachedResponse{
+		URL:          urlStr,
+		FinalURL:     resp.Request.URL.String(),
+		Status:       resp.StatusCode,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now(),
+		Body:         body,
+	}
+	if t.cache != nil && resp.StatusCode == http.StatusOK &&
+		!strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		t.cache.put(entry)
+	}
+	return entry, "", nil
+}
 
-	re = regexp.MustCompile(`\s+`)
-	result = re.ReplaceAllLiteralString(result, " ")
+// fetchedDocumentFormat reports whether a response is a PDF or Office
+// document, going by the Content-Type and then by the URL and first bytes
+// (servers often send documents as application/octet-stream).
+func fetchedDocumentFormat(contentType, urlPath string, body []byte) string {
+	format := docs.DetectMediaType(contentType)
+	if format == "" {
+		format = docs.Detect(urlPath, body[:min(len(body), 8000)])
+	}
+	switch format {
+	case docs.FormatPDF, docs.FormatDOCX, docs.FormatXLSX:
+		return format
+	}
+	return ""
+}
 
-	lines := strings.Split(result, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			cleanLines = append(cleanLines, line)
+// htmlToMarkdown converts a page to markdown, keeping only the main
+// content unless fullPage is set.
+func (t *WebFetchTool) htmlToMarkdown(body []byte, pageURL string, fullPage bool) (string, string) {
+	root, err := html.Parse(bytes.NewReader(body))
+	if err != nil {
+		return string(body), "raw"
+	}
+	base, _ := url.Parse(pageURL)
+	if base != nil {
+		// A <base href> changes what relative links point at.
+		if href := baseHref(root); href != "" {
+			if b, err := base.Parse(href); err == nil {
+				base = b
+			}
 		}
 	}
 
-	return strings.Join(cleanLines, "\n")
+	text, extractor := docs.Readable(root, base), "readability"
+	if fullPage {
+		text, extractor = docs.HTMLToMarkdown(root), "markdown"
+	}
+	if title := docs.HTMLTitle(root); title != "" && !strings.HasPrefix(text, "# "+title) {
+		text = "# " + title + "\n\n" + text
+	}
+	return text, extractor
+}
+
+func baseHref(n *html.Node) string {
+	if n.Type == html.ElementNode && n.DataAtom == atom.Base {
+		for _, a := range n.Attr {
+			if a.Key == "href" {
+				return a.Val
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href := baseHref(c); href != "" {
+			return href
+		}
+	}
+	return ""
 }
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestWebTool_WebFetch_Success verifies successful URL fetching
@@ -254,3 +255,266 @@ func TestWebTool_WebFetch_MissingDomain(t *testing.T) {
 		t.Errorf("Expected domain error message, got ForLLM: %s", result.ForLLM)
 	}
 }
+
+// TestWebTool_WebFetch_Readability verifies main-content extraction with absolute links
+func TestWebTool_WebFetch_Readability(t *testing.T) {
+	page := `<html><head><title>Release notes</title></head><body>
+<nav class="menu"><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+<div class="sidebar"><p>Subscribe to our newsletter for weekly updates, offers and more news.</p></div>
+<article class="post">
+<h1>Version 2.0</h1>
+<p>This release rewrites the scheduler, adds retries, and cuts memory use in half for large jobs.</p>
+<p>See the <a href="/docs/upgrade">upgrade guide</a> before moving production workloads, since the config format changed.</p>
+</article>
+<footer><p>Copyright 2026 Example Corp. All rights reserved, worldwide.</p></footer>
+</body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	tool := NewWebFetchTool(50000)
+	result := tool.Execute(context.Background(), map[string]interface{}{"url": server.URL + "/notes"})
+	if result.IsError {
+		t.Fatal(result.ForLLM)
+	}
+	for _, want := range []string{"# Release notes", "# Version 2.0", "[upgrade guide](" + server.URL + "/docs/upgrade)", "extractor: readability"} {
+		if !strings.Contains(result.ForLLM, want) {
+			t.Errorf("missing %q in: %s", want, result.ForLLM)
+		}
+	}
+	for _, unwanted := range []string{"Subscribe", "Copyright", "Blog"} {
+		if strings.Contains(result.ForLLM, unwanted) {
+			t.Errorf("page furniture %q leaked into: %s", unwanted, result.ForLLM)
+		}
+	}
+
+	result = tool.Execute(context.Background(), map[string]interface{}{"url": server.URL, "full_page": true})
+	if !strings.Contains(result.ForLLM, "Copyright") {
+		t.Errorf("full_page should keep the footer: %s", result.ForLLM)
+	}
+}
+
+// TestWebTool_WebFetch_Cache verifies TTL hits and ETag revalidation
+func TestWebTool_WebFetch_Cache(t *testing.T) {
+	requests, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("cached body"))
+	}))
+	defer server.Close()
+
+	cache := NewWebCache(t.TempDir(), time.Hour, 1<<20)
+	tool := NewWebFetchTool(50000)
+	tool.SetCache(cache)
+	ctx := context.Background()
+	args := map[string]interface{}{"url": server.URL}
+
+	tool.Execute(ctx, args)
+	result := tool.Execute(ctx, args)
+	if requests != 1 || !strings.Contains(result.ForLLM, "cache: hit") || !strings.Contains(result.ForLLM, "cached body") {
+		t.Errorf("expected a cache hit (requests=%d): %s", requests, result.ForLLM)
+	}
+
+	cache.ttl = 0 // everything is stale now
+	result = tool.Execute(ctx, args)
+	if requests != 2 || notModified != 1 || !strings.Contains(result.ForLLM, "cache: revalidated") || !strings.Contains(result.ForLLM, "cached body") {
+		t.Errorf("expected an ETag revalidation (requests=%d): %s", requests, result.ForLLM)
+	}
+
+	result = tool.Execute(ctx, map[string]interface{}{"url": server.URL, "refresh": true})
+	if requests != 3 || strings.Contains(result.ForLLM, "cache:") {
+		t.Errorf("refresh should bypass the cache (requests=%d): %s", requests, result.ForLLM)
+	}
+}
+
+// TestWebTool_WebSearch_SearXNG verifies the SearXNG JSON API mapping
+func TestWebTool_WebSearch_SearXNG(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" || r.URL.Query().Get("q") != "oracle 23ai" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"results": [
+			{"title": "Oracle AI Vector Search", "url": "https://docs.oracle.com/vectors/", "content": "Store  and query\nvectors", "publishedDate": "2025-03-01T10:00:00Z"},
+			{"title": "Duplicate", "url": "http://www.docs.oracle.com/vectors#intro", "content": "same page"},
+			{"title": "Blog", "url": "https://blogs.oracle.com/23ai", "content": "What's new"}
+		]}`))
+	}))
+	defer server.Close()
+
+	tool := NewWebSearchTool(WebSearchToolOptions{SearXNGEnabled: true, SearXNGURL: server.URL + "/"})
+	if tool == nil {
+		t.Fatal("expected a tool with SearXNG enabled")
+	}
+	resp, err := tool.Search(context.Background(), "oracle 23ai", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchResult{
+		{Title: "Oracle AI Vector Search", URL: "https://docs.oracle.com/vectors/", Snippet: "Store and query vectors", Date: "2025-03-01"},
+		{Title: "Blog", URL: "https://blogs.oracle.com/23ai", Snippet: "What's new"},
+	}
+	if resp.Provider != "SearXNG" || len(resp.Results) != len(want) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	for i := range want {
+		if resp.Results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, resp.Results[i], want[i])
+		}
+	}
+
+	result := tool.Execute(context.Background(), map[string]interface{}{"query": "oracle 23ai"})
+	if result.IsError || !strings.Contains(result.ForLLM, "(via SearXNG)") ||
+		!strings.Contains(result.ForLLM, "2025-03-01 — Store and query vectors") {
+		t.Errorf("unexpected output: %s", result.ForLLM)
+	}
+}
+
+// TestWebTool_WebSearch_JSONProvider verifies templated requests and field paths
+func TestWebTool_WebSearch_JSONProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Method != "POST" || r.Header.Get("X-Api-Key") != "k" || body["q"] != `say "hi"` || r.URL.Query().Get("n") != "3" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data": {"hits": [
+			{"name": "First", "link": "https://a.example/1", "meta": {"summary": "one", "published": ["2024-05-06"]}},
+			{"name": "Second", "link": "https://a.example/2", "meta": {"summary": "two"}}
+		]}}`))
+	}))
+	defer server.Close()
+
+	tool := NewWebSearchTool(WebSearchToolOptions{
+		JSONEnabled: true,
+		JSON: JSONSearchOptions{
+			Name:         "Custom",
+			URL:          server.URL + "/api?n={count}",
+			Method:       "post",
+			Headers:      map[string]string{"X-Api-Key": "k"},
+			Body:         `{"q": "{query}"}`,
+			ResultsPath:  "$.data.hits",
+			SnippetField: "meta.summary",
+			DateField:    "meta.published[0]",
+		},
+	})
+	resp, err := tool.Search(context.Background(), `say "hi"`, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "Custom" || len(resp.Results) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	want := SearchResult{Title: "First", URL: "https://a.example/1", Snippet: "one", Date: "2024-05-06"}
+	if resp.Results[0] != want {
+		t.Errorf("got %+v, want %+v", resp.Results[0], want)
+	}
+}
+
+// TestWebTool_WebSearch_FallbackAndCache verifies provider order, fallback on
+// errors and empty results, and the query cache
+func TestWebTool_WebSearch_FallbackAndCache(t *testing.T) {
+	var failing, empty, good int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			failing++
+			http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+		case "/empty/search":
+			empty++
+			w.Write([]byte(`{"results": []}`))
+		default:
+			good++
+			w.Write([]byte(`{"items": [{"title": "Hit", "url": "https://example.com"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	tool := NewWebSearchTool(WebSearchToolOptions{
+		JSONEnabled:    true,
+		JSON:           JSONSearchOptions{URL: server.URL + "/fail?q={query}", ResultsPath: "items"},
+		SearXNGEnabled: true,
+		SearXNGURL:     server.URL + "/empty",
+		Order:          []string{"json", "searxng", "duckduckgo"},
+		CacheTTL:       time.Minute,
+	})
+	// DuckDuckGo is not enabled, so after the JSON provider fails and
+	// SearXNG is empty there is nothing left: an empty, uncached response.
+	resp, err := tool.Search(context.Background(), "q", 5)
+	if err != nil || len(resp.Results) != 0 || resp.Provider != "SearXNG" {
+		t.Fatalf("expected empty SearXNG response, got %+v, %v", resp, err)
+	}
+
+	tool.providers = append(tool.providers, NewJSONSearchProvider(JSONSearchOptions{Name: "Good", URL: server.URL + "/ok", ResultsPath: "items"}))
+	tool.maxResults = append(tool.maxResults, 5)
+	resp, err = tool.Search(context.Background(), "q", 5)
+	if err != nil || resp.Provider != "Good" || len(resp.Results) != 1 || resp.Cached {
+		t.Fatalf("expected fallback to Good, got %+v, %v", resp, err)
+	}
+	resp, err = tool.Search(context.Background(), "  Q ", 5)
+	if err != nil || !resp.Cached || resp.Provider != "Good" {
+		t.Fatalf("expected a cached response, got %+v, %v", resp, err)
+	}
+	if failing != 2 || empty != 2 || good != 1 {
+		t.Errorf("unexpected request counts: fail=%d empty=%d good=%d", failing, empty, good)
+	}
+
+	tool.providers = tool.providers[:1]
+	tool.cache = nil
+	if _, err := tool.Search(context.Background(), "q", 5); err == nil || !strings.Contains(err.Error(), "HTTP 429") {
+		t.Errorf("expected the provider error, got %v", err)
+	}
+}
+
+func TestParseNumberedResults(t *testing.T) {
+	text := "1. **Go 1.24 Release Notes**\n   https://go.dev/doc/go1.24\n   Changes in the release.\n\n2. [Go blog](https://go.dev/blog/)\n   Posts about Go."
+	got := parseNumberedResults(text)
+	want := []SearchResult{
+		{Title: "Go 1.24 Release Notes", URL: "https://go.dev/doc/go1.24", Snippet: "Changes in the release."},
+		{Title: "Go blog", URL: "https://go.dev/blog/", Snippet: "Posts about Go."},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLookupJSONPath(t *testing.T) {
+	var doc interface{}
+	json.Unmarshal([]byte(`{"a": {"b": [{"c": 1}, {"c": 2}], "d-e": "x"}}`), &doc)
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"a.b[0].c", "1"},
+		{"$.a.b[-1].c", "2"},
+		{"a['d-e']", "x"},
+	}
+	for _, c := range cases {
+		v, ok := lookupJSONPath(doc, c.path)
+		if !ok || jsonString(v) != c.want {
+			t.Errorf("%s = %v, %v; want %s", c.path, v, ok, c.want)
+		}
+	}
+	if v, ok := lookupJSONPath(doc, "a.b[*].c"); !ok || len(v.([]interface{})) != 2 {
+		t.Errorf("wildcard = %v, %v", v, ok)
+	}
+	if _, ok := lookupJSONPath(doc, "a.missing"); ok {
+		t.Error("missing key should not be found")
+	}
+}
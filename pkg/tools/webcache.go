@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebCache stores fetched responses on disk. Entries younger than the TTL
+// are served directly; older ones are revalidated with their ETag or
+// Last-Modified date. The directory is trimmed to maxBytes, oldest first.
+type WebCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	mu       sync.Mutex
+}
+
+type cachedResponse struct {
+	URL          string    `json:"url"`
+	FinalURL     string    `json:"final_url,omitempty"` // after redirects
+	Status       int       `json:"status"`
+	ContentType  string    `json:"content_type"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	Body         []byte    `json:"body"`
+}
+
+// NewWebCache returns a cache in dir, or nil when ttl is not positive.
+func NewWebCache(dir string, ttl time.Duration, maxBytes int64) *WebCache {
+	if ttl <= 0 || dir == "" {
+		return nil
+	}
+	return &WebCache{dir: dir, ttl: ttl, maxBytes: maxBytes}
+}
+
+func (c *WebCache) path(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// get returns the cached response for a URL and whether it is still fresh.
+func (c *WebCache) get(rawURL string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := os.ReadFile(c.path(rawURL))
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != rawURL {
+		return nil, false
+	}
+	return &entry, time.Since(entry.FetchedAt) < c.ttl
+}
+
+func (c *WebCache) put(entry *cachedResponse) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return
+	}
+	path := c.path(entry.URL)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	c.prune()
+}
+
+// prune removes the least recently written entries until the cache fits
+// in maxBytes. Callers hold c.mu.
+func (c *WebCache) prune() {
+	if c.maxBytes <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		path string
+		size int64
+		mod  time.Time
+	}
+	var files []file
+	var total int64
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{filepath.Join(c.dir, e.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].mod.Before(files[j].mod) })
+	for _, f := range files {
+		if total <= c.maxBytes {
+			return
+		}
+		if os.Remove(f.path) == nil {
+			total -= f.size
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchResult is one web search hit.
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+	Date    string `json:"date,omitempty"`
+}
+
+// SearchResponse is what web_search returns: the results and the provider
+// that produced them.
+type SearchResponse struct {
+	Query    string         `json:"query"`
+	Provider string         `json:"provider"`
+	Results  []SearchResult `json:"results"`
+	Cached   bool           `json:"cached,omitempty"`
+}
+
+// Format renders the response in the numbered layout the LLM sees.
+func (r *SearchResponse) Format() string {
+	if len(r.Results) == 0 {
+		return fmt.Sprintf("No results for: %s", r.Query)
+	}
+	lines := []string{fmt.Sprintf("Results for: %s (via %s)", r.Query, r.Provider)}
+	for i, item := range r.Results {
+		lines = append(lines, fmt.Sprintf("%d. %s\n   %s", i+1, item.Title, item.URL))
+		snippet := item.Snippet
+		if item.Date != "" {
+			snippet = strings.TrimSpace(item.Date + " — " + snippet)
+		}
+		if snippet != "" {
+			lines = append(lines, "   "+snippet)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dedupeResults drops results pointing at the same page (ignoring scheme,
+// "www.", fragments and trailing slashes) and results without a URL, and
+// keeps at most count.
+func dedupeResults(results []SearchResult, count int) []SearchResult {
+	seen := make(map[string]bool)
+	out := make([]SearchResult, 0, min(len(results), count))
+	for _, r := range results {
+		r.Title = strings.TrimSpace(r.Title)
+		r.URL = strings.TrimSpace(r.URL)
+		r.Snippet = strings.Join(strings.Fields(r.Snippet), " ")
+		if r.URL == "" {
+			continue
+		}
+		key := r.URL
+		if u, err := url.Parse(r.URL); err == nil && u.Host != "" {
+			key = strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimSuffix(u.EscapedPath(), "/")
+			if u.RawQuery != "" {
+				key += "?" + u.RawQuery
+			}
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if r.Title == "" {
+			r.Title = r.URL
+		}
+		out = append(out, r)
+		if len(out) >= count {
+			break
+		}
+	}
+	return out
+}
+
+// searchGet performs a GET request and returns the body, failing on
+// non-2xx statuses.
+func searchGet(ctx context.Context, client *http.Client, reqURL string, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return doSearchRequest(client, req)
+}
+
+func doSearchRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 5<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body[:min(len(body), 200)])))
+	}
+	return body, nil
+}
+
+// SearXNGSearchProvider queries a SearXNG instance through its JSON API.
+// The instance must have "json" in search.formats of its settings.yml.
+type SearXNGSearchProvider struct {
+	baseURL    string
+	categories string
+	language   string
+	client     *http.Client
+}
+
+func NewSearXNGSearchProvider(baseURL, categories, language string) *SearXNGSearchProvider {
+	return &SearXNGSearchProvider{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		categories: categories,
+		language:   language,
+		client:     &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (p *SearXNGSearchProvider) Name() string {
+	return "SearXNG"
+}
+
+func (p *SearXNGSearchProvider) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	params := url.Values{"q": {query}, "format": {"json"}}
+	if p.categories != "" {
+		params.Set("categories", p.categories)
+	}
+	if p.language != "" {
+		params.Set("language", p.language)
+	}
+	body, err := searchGet(ctx, p.client, p.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"publishedDate"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response (is the json format enabled?): %w", err)
+	}
+	results := make([]SearchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content, Date: shortDate(r.PublishedDate)})
+	}
+	return results, nil
+}
+
+// shortDate trims an RFC 3339 timestamp to its date.
+func shortDate(s string) string {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Format("2006-01-02")
+	}
+	if len(s) > 10 && s[4] == '-' && s[7] == '-' {
+		return s[:10]
+	}
+	return s
+}
+
+// JSONSearchOptions describes a search API returning JSON. URL and Body
+// may contain {query} (escaped for their context) and {count}. Field paths
+// use the syntax of lookupJSONPath, relative to each result.
+type JSONSearchOptions struct {
+	Name         string
+	URL          string
+	Method       string // GET (default) or POST
+	Headers      map[string]string
+	Body         string
+	ResultsPath  string // path to the array of results, e.g. "data.items"
+	TitleField   string // default "title"
+	URLField     string // default "url", then "link"
+	SnippetField string // default "snippet", then "description", then "content"
+	DateField    string // default "date", then "published_date"
+}
+
+// JSONSearchProvider adapts any JSON search API described by
+// JSONSearchOptions.
+type JSONSearchProvider struct {
+	opts   JSONSearchOptions
+	client *http.Client
+}
+
+func NewJSONSearchProvider(opts JSONSearchOptions) *JSONSearchProvider {
+	if opts.Name == "" {
+		opts.Name = "JSON search"
+	}
+	opts.Method = strings.ToUpper(opts.Method)
+	if opts.Method == "" {
+		opts.Method = "GET"
+	}
+	return &JSONSearchProvider{opts: opts, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+func (p *JSONSearchProvider) Name() string {
+	return p.opts.Name
+}
+
+func (p *JSONSearchProvider) Search(ctx context.Context, query string, count int) ([]SearchResult, error) {
+	reqURL := strings.NewReplacer("{query}", url.QueryEscape(query), "{count}", strconv.Itoa(count)).Replace(p.opts.URL)
+	var body io.Reader
+	if p.opts.Body != "" {
+		quoted, _ := json.Marshal(query)
+		escaped := string(quoted[1 : len(quoted)-1])
+		body = strings.NewReader(strings.NewReplacer("{query}", escaped, "{count}", strconv.Itoa(count)).Replace(p.opts.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, p.opts.Method, reqURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range p.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	data, err := doSearchRequest(p.client, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	list, ok := lookupJSONPath(doc, p.opts.ResultsPath)
+	if !ok {
+		return nil, fmt.Errorf("results path %q not found in response", p.opts.ResultsPath)
+	}
+	items, ok := list.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("results path %q is not an array", p.opts.ResultsPath)
+	}
+
+	field := func(item interface{}, configured string, defaults ...string) string {
+		if configured != "" {
+			defaults = []string{configured}
+		}
+		for _, path := range defaults {
+			if v, ok := lookupJSONPath(item, path); ok {
+				if s := jsonString(v); s != "" {
+					return s
+				}
+			}
+		}
+		return ""
+	}
+	results := make([]SearchResult, 0, len(items))
+	for _, item := range items {
+		results = append(results, SearchResult{
+			Title:   field(item, p.opts.TitleField, "title", "name"),
+			URL:     field(item, p.opts.URLField, "url", "link"),
+			Snippet: field(item, p.opts.SnippetField, "snippet", "description", "content"),
+			Date:    shortDate(field(item, p.opts.DateField, "date", "published_date")),
+		})
+	}
+	return results, nil
+}
+
+// searchCache remembers recent responses for a short time, so repeated
+// queries within a conversation do not hit the providers again.
+type searchCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]searchCacheEntry
+}
+
+type searchCacheEntry struct {
+	resp    SearchResponse
+	expires time.Time
+}
+
+const searchCacheMaxEntries = 256
+
+func newSearchCache(ttl time.Duration) *searchCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &searchCache{ttl: ttl, entries: make(map[string]searchCacheEntry)}
+}
+
+func searchCacheKey(query string, count int) string {
+	return fmt.Sprintf("%d:%s", count, strings.Join(strings.Fields(strings.ToLower(query)), " "))
+}
+
+func (c *searchCache) get(query string, count int) (*SearchResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[searchCacheKey(query, count)]
+	if !ok || time.Now().After(e.expires) {
+		return nil, false
+	}
+	resp := e.resp
+	resp.Results = append([]SearchResult(nil), e.resp.Results...)
+	resp.Cached = true
+	return &resp, true
+}
+
+func (c *searchCache) put(resp *SearchResponse, count int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= searchCacheMaxEntries {
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: drop the entry closest to expiring.
+		for len(c.entries) >= searchCacheMaxEntries {
+			var oldest string
+			for k, e := range c.entries {
+				if oldest == "" || e.expires.Before(c.entries[oldest].expires) {
+					oldest = k
+				}
+			}
+			delete(c.entries, oldest)
+		}
+	}
+	c.entries[searchCacheKey(resp.Query, count)] = searchCacheEntry{resp: *resp, expires: now.Add(c.ttl)}
+}
@@ -0,0 +1,122 @@
+package usage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/models"
+	"github.com/jasperan/picooraclaw/pkg/providers"
+)
+
+// Price is a model's price in USD per million tokens.
+type Price struct {
+	Input       float64
+	Output      float64
+	CachedInput float64 // prompt tokens served from the provider's cache
+	CacheWrite  float64 // prompt tokens written to the cache (Anthropic)
+	Reasoning   float64 // thinking tokens, billed as output by most providers
+}
+
+// cacheDiscounts are the list-price ratios of cached to regular input,
+// keyed by normalized model prefix; the longest prefix wins. Models not
+// listed get no discount so estimates err on the high side.
+var cacheDiscounts = map[string]struct{ read, write float64 }{
+	"claude":   {0.1, 1.25},
+	"gpt-5":    {0.1, 1},
+	"gpt-4.1":  {0.25, 1},
+	"gpt-4o":   {0.5, 1},
+	"o3":       {0.25, 1},
+	"o4-mini":  {0.25, 1},
+	"gemini":   {0.25, 1},
+	"grok":     {0.25, 1},
+	"deepseek": {0.1, 1},
+	"kimi":     {0.25, 1},
+}
+
+// Pricing resolves per-model prices from config overrides, falling back to
+// the built-in catalog.
+type Pricing struct {
+	overrides map[string]config.ModelPriceConfig
+	keys      []string // override keys, longest first
+}
+
+// NewPricing creates a Pricing with the given overrides, keyed by model ID
+// prefix (matched against both the raw and the normalized model ID).
+func NewPricing(overrides map[string]config.ModelPriceConfig) *Pricing {
+	p := &Pricing{overrides: make(map[string]config.ModelPriceConfig, len(overrides))}
+	for k, v := range overrides {
+		key := strings.ToLower(k)
+		p.overrides[key] = v
+		p.keys = append(p.keys, key)
+	}
+	sort.Slice(p.keys, func(i, j int) bool { return len(p.keys[i]) > len(p.keys[j]) })
+	return p
+}
+
+// PriceFor returns the price of a model and whether it is known.
+func (p *Pricing) PriceFor(model string) (Price, bool) {
+	lower := strings.ToLower(model)
+	normalized := models.NormalizeID(model)
+	for _, key := range p.keys {
+		if strings.HasPrefix(lower, key) || strings.HasPrefix(normalized, key) {
+			o := p.overrides[key]
+			return withDefaults(normalized, Price{
+				Input:       o.Input,
+				Output:      o.Output,
+				CachedInput: o.CachedInput,
+				CacheWrite:  o.CacheWrite,
+				Reasoning:   o.Reasoning,
+			}), true
+		}
+	}
+
+	info, ok := models.Builtin(model)
+	if !ok || (info.InputPrice == 0 && info.OutputPrice == 0) {
+		return Price{}, ok
+	}
+	return withDefaults(normalized, Price{Input: info.InputPrice, Output: info.OutputPrice}), true
+}
+
+func withDefaults(normalized string, price Price) Price {
+	read, write := 1.0, 1.0
+	best := -1
+	for prefix, d := range cacheDiscounts {
+		if strings.HasPrefix(normalized, prefix) && len(prefix) > best {
+			read, write, best = d.read, d.write, len(prefix)
+		}
+	}
+	if price.CachedInput == 0 {
+		price.CachedInput = price.Input * read
+	}
+	if price.CacheWrite == 0 {
+		price.CacheWrite = price.Input * write
+	}
+	if price.Reasoning == 0 {
+		price.Reasoning = price.Output
+	}
+	return price
+}
+
+// Cost returns the estimated cost in USD of one LLM call, or 0 when the
+// model's price is unknown.
+func (p *Pricing) Cost(model string, u providers.UsageInfo) float64 {
+	price, ok := p.PriceFor(model)
+	if !ok {
+		return 0
+	}
+	uncached := u.PromptTokens - u.CacheReadTokens - u.CacheWriteTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+	visible := u.CompletionTokens - u.ReasoningTokens
+	if visible < 0 {
+		visible = 0
+	}
+	total := float64(uncached)*price.Input +
+		float64(u.CacheReadTokens)*price.CachedInput +
+		float64(u.CacheWriteTokens)*price.CacheWrite +
+		float64(visible)*price.Output +
+		float64(u.ReasoningTokens)*price.Reasoning
+	return total / 1000000
+}
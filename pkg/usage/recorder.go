@@ -0,0 +1,125 @@
+package usage
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/logger"
+	"github.com/jasperan/picooraclaw/pkg/providers"
+)
+
+// AlertFunc delivers a spend alert to the user.
+type AlertFunc func(content string) error
+
+// Recorder prices turns, persists them and raises an alert the first time
+// each day's spend crosses the configured threshold. The day of the last
+// alert is kept in the store, so a restart does not repeat it.
+type Recorder struct {
+	store      *Store
+	pricing    *Pricing
+	dailyLimit float64
+
+	mu       sync.Mutex
+	alert    AlertFunc
+	day      string
+	dayTotal float64
+	alerted  bool
+	now      func() time.Time
+}
+
+// NewRecorder creates a Recorder for the workspace store.
+func NewRecorder(store *Store, cfg config.UsageConfig) *Recorder {
+	return &Recorder{
+		store:      store,
+		pricing:    NewPricing(cfg.Pricing),
+		dailyLimit: cfg.DailyAlertUSD,
+		now:        time.Now,
+	}
+}
+
+// Pricing returns the price table used for estimates.
+func (r *Recorder) Pricing() *Pricing {
+	return r.pricing
+}
+
+// SetAlertFunc installs the function used to deliver daily spend alerts.
+func (r *Recorder) SetAlertFunc(fn AlertFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alert = fn
+}
+
+// Add accumulates the usage of one LLM call into rec and prices it.
+func (r *Recorder) Add(rec *Record, u *providers.UsageInfo) {
+	if u == nil {
+		return
+	}
+	rec.Calls++
+	rec.PromptTokens += u.PromptTokens
+	rec.CompletionTokens += u.CompletionTokens
+	rec.CacheReadTokens += u.CacheReadTokens
+	rec.CacheWriteTokens += u.CacheWriteTokens
+	rec.ReasoningTokens += u.ReasoningTokens
+	rec.CostUSD += r.pricing.Cost(rec.Model, *u)
+}
+
+// Record persists a finished turn and checks the daily threshold.
+func (r *Recorder) Record(rec Record) {
+	if rec.Calls == 0 {
+		return
+	}
+	if rec.Time.IsZero() {
+		rec.Time = r.now()
+	}
+	if err := r.store.Append(rec); err != nil {
+		logger.WarnCF("usage", "Failed to record usage", map[string]interface{}{"error": err.Error()})
+	}
+	if r.dailyLimit <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	day := rec.Time.Local().Format("2006-01-02")
+	if day != r.day {
+		r.day = day
+		r.dayTotal = r.spentToday(rec.Time) // includes rec, already appended
+		r.alerted = r.store.AlertedDay() == day
+	} else {
+		r.dayTotal += rec.CostUSD
+	}
+	var alert AlertFunc
+	if !r.alerted && r.dayTotal >= r.dailyLimit && r.alert != nil {
+		r.alerted = true
+		alert = r.alert
+	}
+	total := r.dayTotal
+	r.mu.Unlock()
+
+	if alert != nil {
+		if err := r.store.SetAlertedDay(day); err != nil {
+			logger.WarnCF("usage", "Failed to record spend alert", map[string]interface{}{"error": err.Error()})
+		}
+		msg := fmt.Sprintf("Spend alert: estimated LLM spend today is $%.2f, over the $%.2f daily threshold.", total, r.dailyLimit)
+		if err := alert(msg); err != nil {
+			logger.WarnCF("usage", "Failed to send spend alert", map[string]interface{}{"error": err.Error()})
+		}
+	}
+}
+
+// spentToday sums the cost recorded since local midnight, so the threshold
+// survives restarts.
+func (r *Recorder) spentToday(now time.Time) float64 {
+	local := now.Local()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	records, err := r.store.Load(midnight)
+	if err != nil {
+		logger.WarnCF("usage", "Failed to load today's usage", map[string]interface{}{"error": err.Error()})
+	}
+	var total float64
+	for _, rec := range records {
+		total += rec.CostUSD
+	}
+	return total
+}
@@ -0,0 +1,135 @@
+package usage
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Row is one group of a spend report.
+type Row struct {
+	Key              string
+	Turns            int
+	Calls            int
+	PromptTokens     int
+	CompletionTokens int
+	CacheReadTokens  int
+	CostUSD          float64
+}
+
+// Summarize groups records by "model", "channel" or "sender", ordered by
+// descending cost.
+func Summarize(records []Record, by string) ([]Row, error) {
+	var keyOf func(Record) string
+	switch by {
+	case "model":
+		keyOf = func(r Record) string { return r.Model }
+	case "channel":
+		keyOf = func(r Record) string { return r.Channel }
+	case "sender":
+		keyOf = func(r Record) string {
+			if r.Channel == "" {
+				return r.SenderID
+			}
+			return r.Channel + ":" + r.SenderID
+		}
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (use model, channel or sender)", by)
+	}
+
+	groups := make(map[string]*Row)
+	for _, rec := range records {
+		key := keyOf(rec)
+		if key == "" {
+			key = "(unknown)"
+		}
+		row, ok := groups[key]
+		if !ok {
+			row = &Row{Key: key}
+			groups[key] = row
+		}
+		row.Turns++
+		row.Calls += rec.Calls
+		row.PromptTokens += rec.PromptTokens
+		row.CompletionTokens += rec.CompletionTokens
+		row.CacheReadTokens += rec.CacheReadTokens
+		row.CostUSD += rec.CostUSD
+	}
+
+	rows := make([]Row, 0, len(groups))
+	for _, row := range groups {
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].CostUSD != rows[j].CostUSD {
+			return rows[i].CostUSD > rows[j].CostUSD
+		}
+		return rows[i].Key < rows[j].Key
+	})
+	return rows, nil
+}
+
+// WriteTable renders rows as an aligned text table with a total line.
+func WriteTable(w io.Writer, rows []Row, by string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "%s\tTURNS\tCALLS\tPROMPT\tCACHED\tCOMPLETION\tCOST (USD)\t\n", strings.ToUpper(by))
+	var total Row
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%.4f\t\n", r.Key, r.Turns, r.Calls, r.PromptTokens, r.CacheReadTokens, r.CompletionTokens, r.CostUSD)
+		total.Turns += r.Turns
+		total.Calls += r.Calls
+		total.PromptTokens += r.PromptTokens
+		total.CacheReadTokens += r.CacheReadTokens
+		total.CompletionTokens += r.CompletionTokens
+		total.CostUSD += r.CostUSD
+	}
+	fmt.Fprintf(tw, "TOTAL\t%d\t%d\t%d\t%d\t%d\t%.4f\t\n", total.Turns, total.Calls, total.PromptTokens, total.CacheReadTokens, total.CompletionTokens, total.CostUSD)
+	return tw.Flush()
+}
+
+// WriteCSV renders rows as CSV with a header line.
+func WriteCSV(w io.Writer, rows []Row, by string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{by, "turns", "calls", "prompt_tokens", "cache_read_tokens", "completion_tokens", "cost_usd"})
+	for _, r := range rows {
+		cw.Write([]string{
+			r.Key,
+			strconv.Itoa(r.Turns),
+			strconv.Itoa(r.Calls),
+			strconv.Itoa(r.PromptTokens),
+			strconv.Itoa(r.CacheReadTokens),
+			strconv.Itoa(r.CompletionTokens),
+			strconv.FormatFloat(r.CostUSD, 'f', 6, 64),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ParseSince parses a relative window ("7d", "12h", "30m") or a date
+// ("2026-01-31") into the start time of a report.
+func ParseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days < 0 {
+			return time.Time{}, fmt.Errorf("invalid --since %q", s)
+		}
+		return now.AddDate(0, 0, -days), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use e.g. 7d, 24h or 2006-01-02)", s)
+}
@@ -0,0 +1,131 @@
+// Package usage records per-turn token usage and estimated cost, and
+// builds spend reports from the recorded history.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is the token usage and estimated cost of one agent turn, summed
+// over all LLM calls the turn made.
+type Record struct {
+	Time             time.Time `json:"time"`
+	SessionKey       string    `json:"session_key,omitempty"`
+	Channel          string    `json:"channel,omitempty"`
+	ChatID           string    `json:"chat_id,omitempty"`
+	SenderID         string    `json:"sender_id,omitempty"`
+	Model            string    `json:"model"`
+	Calls            int       `json:"calls"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CacheReadTokens  int       `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int       `json:"cache_write_tokens,omitempty"`
+	ReasoningTokens  int       `json:"reasoning_tokens,omitempty"`
+	CostUSD          float64   `json:"cost_usd"`
+}
+
+// Store appends records to a JSON Lines file under the workspace.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewStore returns the usage store for a workspace.
+func NewStore(workspace string) *Store {
+	return &Store{path: filepath.Join(workspace, "usage", "usage.jsonl")}
+}
+
+// Path returns the file the store writes to.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// alertPath is the file holding the day of the last spend alert.
+func (s *Store) alertPath() string {
+	return filepath.Join(filepath.Dir(s.path), "alerted")
+}
+
+// AlertedDay returns the day ("2006-01-02") the last spend alert was sent,
+// or "" if none was.
+func (s *Store) AlertedDay() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.alertPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// SetAlertedDay records that the spend alert for day has been sent.
+func (s *Store) SetAlertedDay(day string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.alertPath(), []byte(day+"\n"), 0644)
+}
+
+// Append writes one record.
+func (s *Store) Append(rec Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Load returns the records at or after since, oldest first. Malformed lines
+// are skipped so a torn write cannot break reporting.
+func (s *Store) Load(since time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		if !rec.Time.Before(since) {
+			records = append(records, rec)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return records, fmt.Errorf("reading %s: %w", s.path, err)
+	}
+	return records, nil
+}
@@ -0,0 +1,170 @@
+package usage
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jasperan/picooraclaw/pkg/config"
+	"github.com/jasperan/picooraclaw/pkg/providers"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPricing_BuiltinWithCacheAndReasoning(t *testing.T) {
+	p := NewPricing(nil)
+
+	// claude-sonnet-4: $3 in, $15 out, cache read 0.1x, cache write 1.25x.
+	got := p.Cost("claude-sonnet-4-5", providers.UsageInfo{
+		PromptTokens:     1000000,
+		CacheReadTokens:  500000,
+		CacheWriteTokens: 100000,
+		CompletionTokens: 100000,
+	})
+	want := 0.4*3 + 0.5*0.3 + 0.1*3.75 + 0.1*15
+	if !approx(got, want) {
+		t.Errorf("Cost(claude) = %v, want %v", got, want)
+	}
+
+	if got := p.Cost("test-model", providers.UsageInfo{PromptTokens: 1000}); got != 0 {
+		t.Errorf("Cost(unknown) = %v, want 0", got)
+	}
+}
+
+func TestPricing_ConfigOverride(t *testing.T) {
+	p := NewPricing(map[string]config.ModelPriceConfig{
+		"xai.grok-4": {Input: 1, Output: 2, Reasoning: 4},
+	})
+
+	price, ok := p.PriceFor("xai.grok-4")
+	if !ok || price.Input != 1 || price.CachedInput != 0.25 || price.Reasoning != 4 {
+		t.Errorf("PriceFor(override) = %+v, %v", price, ok)
+	}
+
+	got := p.Cost("xai.grok-4", providers.UsageInfo{
+		PromptTokens:     1000000,
+		CompletionTokens: 1000000,
+		ReasoningTokens:  500000,
+	})
+	if !approx(got, 1+0.5*2+0.5*4) {
+		t.Errorf("Cost(override) = %v", got)
+	}
+}
+
+func TestStore_AppendAndLoad(t *testing.T) {
+	store := NewStore(t.TempDir())
+	now := time.Now()
+	store.Append(Record{Time: now.Add(-48 * time.Hour), Model: "old", Calls: 1})
+	store.Append(Record{Time: now, Model: "new", Calls: 1})
+
+	records, err := store.Load(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(records) != 1 || records[0].Model != "new" {
+		t.Errorf("Load() = %+v, want only the recent record", records)
+	}
+}
+
+func TestSummarizeAndWrite(t *testing.T) {
+	records := []Record{
+		{Model: "gpt-4o", Channel: "telegram", SenderID: "1", Calls: 2, PromptTokens: 100, CostUSD: 0.5},
+		{Model: "gpt-4o-mini", Channel: "telegram", SenderID: "2", Calls: 1, PromptTokens: 50, CostUSD: 0.1},
+		{Model: "gpt-4o", Channel: "discord", SenderID: "1", Calls: 1, PromptTokens: 10, CostUSD: 0.2},
+	}
+
+	rows, err := Summarize(records, "model")
+	if err != nil {
+		t.Fatalf("Summarize() error: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Key != "gpt-4o" || rows[0].Turns != 2 || rows[0].Calls != 3 || !approx(rows[0].CostUSD, 0.7) {
+		t.Errorf("Summarize(model) = %+v", rows)
+	}
+
+	rows, _ = Summarize(records, "sender")
+	if len(rows) != 3 || rows[0].Key != "telegram:1" {
+		t.Errorf("Summarize(sender) = %+v", rows)
+	}
+
+	if _, err := Summarize(records, "day"); err == nil {
+		t.Error("expected error for unknown grouping")
+	}
+
+	var buf bytes.Buffer
+	WriteCSV(&buf, rows, "sender")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "sender,turns,calls,prompt_tokens,cache_read_tokens,completion_tokens,cost_usd" {
+		t.Errorf("WriteCSV() =\n%s", buf.String())
+	}
+
+	buf.Reset()
+	WriteTable(&buf, rows, "sender")
+	if !strings.Contains(buf.String(), "TOTAL") || !strings.Contains(buf.String(), "0.8000") {
+		t.Errorf("WriteTable() missing total:\n%s", buf.String())
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"7d", now.AddDate(0, 0, -7)},
+		{"12h", now.Add(-12 * time.Hour)},
+		{"2026-03-01", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseSince(tt.in, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseSince(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseSince("last week", now); err == nil {
+		t.Error("expected error for invalid window")
+	}
+}
+
+func TestRecorder_DailyAlertOnce(t *testing.T) {
+	store := NewStore(t.TempDir())
+	r := NewRecorder(store, config.UsageConfig{DailyAlertUSD: 1})
+
+	var alerts []string
+	r.SetAlertFunc(func(content string) error {
+		alerts = append(alerts, content)
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		rec := Record{Model: "gpt-4o"}
+		// 200k prompt tokens at $2.50/M = $0.50 per turn.
+		r.Add(&rec, &providers.UsageInfo{PromptTokens: 200000})
+		r.Record(rec)
+	}
+
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "$1.00") {
+		t.Errorf("alerts = %v, want exactly one after crossing $1", alerts)
+	}
+
+	records, _ := store.Load(time.Time{})
+	if len(records) != 3 || !approx(records[0].CostUSD, 0.5) {
+		t.Errorf("stored records = %+v", records)
+	}
+
+	// A restart on the same day must not repeat the alert.
+	restarted := NewRecorder(store, config.UsageConfig{DailyAlertUSD: 1})
+	restarted.SetAlertFunc(func(content string) error {
+		alerts = append(alerts, content)
+		return nil
+	})
+	rec := Record{Model: "gpt-4o"}
+	restarted.Add(&rec, &providers.UsageInfo{PromptTokens: 200000})
+	restarted.Record(rec)
+	if len(alerts) != 1 {
+		t.Errorf("alerts after restart = %v, want no repeat", alerts)
+	}
+}